@@ -1,3 +1,5 @@
+//go:build ignore
+
 package main
 
 import (
@@ -12,7 +14,7 @@ func main() {
 	// Load database path from environment
 	dbPath := os.Getenv("DB_PATH")
 	if dbPath == "" {
-		dbPath = "./car_specs.db"
+		dbPath = "./vehicles.db"
 	}
 
 	// Open database
@@ -1,4 +1,4 @@
-$dbPath = "car_specs.db"
+$dbPath = "vehicles.db"
 
 # SQL to insert generations
 $sql = @"
@@ -4,38 +4,65 @@ import (
 	"log"
 	"net/http"
 	"os"
-	"path/filepath"
 
-	"github.com/emirh/car-specs/backend/internal/database"
+	"github.com/emirh/car-specs/backend/internal/config"
 	"github.com/emirh/car-specs/backend/internal/handlers"
+	"github.com/emirh/car-specs/backend/internal/merge"
 	"github.com/emirh/car-specs/backend/internal/repository"
 	"github.com/emirh/car-specs/backend/internal/service"
+	"github.com/emirh/car-specs/backend/internal/storage"
 )
 
 func main() {
 	// Initialize database
-	if err := database.InitDB(); err != nil {
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		log.Fatalf("Failed to load config: %v", err)
+	}
+	db, err := storage.Open(cfg)
+	if err != nil {
 		log.Fatalf("Failed to initialize database: %v", err)
 	}
-	defer database.CloseDB()
+	defer db.Close()
+	log.Printf("✓ Database connection established: %s", cfg.Database.Path)
 
 	// Initialize repositories
-	brandRepo := repository.NewBrandRepository(database.DB)
-	modelRepo := repository.NewModelRepository(database.DB)
-	generationRepo := repository.NewGenerationRepository(database.DB)
-	trimRepo := repository.NewTrimRepository(database.DB)
+	brandRepo := repository.NewBrandRepository(db)
+	modelRepo := repository.NewModelRepository(db)
+	generationRepo := repository.NewGenerationRepository(db)
+	trimRepo := repository.NewTrimRepository(db)
+	provenanceRepo := repository.NewProvenanceRepository(db)
+	conflictRepo := repository.NewMergeConflictRepository(db)
+	reviewRepo := repository.NewReviewRepository(db)
+	changeRepo := repository.NewChangeRepository(db)
+	auditRepo := repository.NewAuditRepository(db)
 
 	// Initialize services
-	brandService := service.NewBrandService(brandRepo)
-	modelService := service.NewModelService(modelRepo, brandRepo)
-	generationService := service.NewGenerationService(generationRepo, modelRepo)
-	trimService := service.NewTrimService(trimRepo, modelRepo)
+	brandService := service.NewBrandService(brandRepo, auditRepo)
+	modelService := service.NewModelService(modelRepo, brandRepo, auditRepo)
+	generationService := service.NewGenerationService(generationRepo, modelRepo, auditRepo)
+	reviewService := service.NewReviewService(reviewRepo, trimRepo, generationRepo)
+	trimService := service.NewTrimService(trimRepo, modelRepo, generationRepo, provenanceRepo, reviewService, auditRepo)
+	mergePolicy, err := merge.LoadPolicy(cfg.MergePolicyPath)
+	if err != nil {
+		log.Fatalf("Failed to load merge policy: %v", err)
+	}
+	mergeService := service.NewMergeService(trimService, provenanceRepo, conflictRepo, mergePolicy)
+	changeService := service.NewChangeService(changeRepo, trimService, provenanceRepo)
+	auditService := service.NewAuditService(auditRepo)
+	trashService := service.NewTrashService(repository.NewTrashRepository(db), auditRepo, brandRepo, modelRepo, generationRepo, trimRepo)
 
 	// Initialize handlers
 	brandHandler := handlers.NewBrandHandler(brandService)
 	modelHandler := handlers.NewModelHandler(modelService, trimService, brandService)
 	generationHandler := handlers.NewGenerationHandler(generationService)
 	trimHandler := handlers.NewTrimHandler(trimService)
+	mergeHandler := handlers.NewMergeHandler(mergeService)
+	reviewHandler := handlers.NewReviewHandler(reviewService)
+	changeHandler := handlers.NewChangeHandler(changeService)
+	auditHandler := handlers.NewAuditHandler(auditService)
+	trashHandler := handlers.NewTrashHandler(trashService)
+	exportHandler := handlers.NewExportHandler(db)
 
 	// Setup routes
 	mux := http.NewServeMux()
@@ -44,8 +71,8 @@ func main() {
 	corsHandler := func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			w.Header().Set("Access-Control-Allow-Origin", "*")
-			w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-			w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+			w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, PATCH, DELETE, OPTIONS")
+			w.Header().Set("Access-Control-Allow-Headers", "Content-Type, X-Actor")
 
 			if r.Method == "OPTIONS" {
 				w.WriteHeader(http.StatusOK)
@@ -106,6 +133,9 @@ func main() {
 	// Generation routes
 	mux.HandleFunc("GET /api/models/{modelId}/generations", generationHandler.HandleListByModel)
 	mux.HandleFunc("GET /api/generations/{generationId}", generationHandler.HandleGetGeneration)
+	mux.HandleFunc("POST /api/models/{modelId}/generations", generationHandler.HandleCreateGeneration)
+	mux.HandleFunc("PUT /api/generations/{generationId}", generationHandler.HandleUpdateGeneration)
+	mux.HandleFunc("DELETE /api/generations/{generationId}", generationHandler.HandleDeleteGeneration)
 
 	// Legacy/Frontend aggregate route
 	mux.HandleFunc("/api/vehicles", modelHandler.HandleListVehicles)
@@ -121,13 +151,39 @@ func main() {
 		}
 	})
 	mux.HandleFunc("GET /api/trims/{id}", trimHandler.HandleGetTrim)
+	mux.HandleFunc("PUT /api/trims/{id}", trimHandler.HandleUpdateTrim)
+	mux.HandleFunc("PATCH /api/trims/{id}", trimHandler.HandlePatchTrim)
 	mux.HandleFunc("DELETE /api/trims/{id}", trimHandler.HandleDeleteTrim)
+	mux.HandleFunc("GET /api/trims/{id}/citations", trimHandler.HandleGetCitations)
+	mux.HandleFunc("GET /api/trims/{id}/history", trimHandler.HandleGetTrimHistory)
+	mux.HandleFunc("POST /api/trims/{id}/history/{entryId}/restore", trimHandler.HandleRestoreTrimVersion)
+	mux.HandleFunc("GET /api/audit", auditHandler.HandleListAudit)
+	mux.HandleFunc("GET /api/trash", trashHandler.HandleListTrash)
+	mux.HandleFunc("POST /api/trash/{entityType}/{id}/restore", trashHandler.HandleRestore)
+	mux.HandleFunc("POST /api/trims/{id}/merge", mergeHandler.HandleMergeTrim)
+	mux.HandleFunc("GET /api/conflicts", mergeHandler.HandleListConflicts)
+
+	// Review queue
+	mux.HandleFunc("GET /api/review/issues", reviewHandler.HandleListIssues)
+	mux.HandleFunc("POST /api/review/issues/resolve", reviewHandler.HandleResolveIssues)
+	mux.HandleFunc("PATCH /api/review/issues/{id}", reviewHandler.HandleResolveIssue)
+
+	// Changes found by re-scraping
+	mux.HandleFunc("GET /api/changes", changeHandler.HandleListChanges)
+	mux.HandleFunc("PATCH /api/changes/{id}", changeHandler.HandleResolveChange)
+
 	mux.HandleFunc("/api/models/{modelId}/trims", trimHandler.HandleListTrimsByModel)
 	mux.HandleFunc("GET /api/generations/{generationId}/trims", trimHandler.HandleListTrimsByGeneration)
 
 	// Search route
 	mux.HandleFunc("/api/search", trimHandler.HandleSearchTrims)
 
+	// Catalogue export, streamed as CSV, JSON Lines or Parquet
+	mux.HandleFunc("GET /api/export", exportHandler.HandleExport)
+
+	// Side-by-side comparison route
+	mux.HandleFunc("GET /api/compare", trimHandler.HandleCompareTrims)
+
 	// Featured route for homepage
 	mux.HandleFunc("/api/featured", trimHandler.HandleGetFeaturedTrims)
 
@@ -144,21 +200,30 @@ func main() {
 	}
 
 	log.Printf("🚀 Server starting on port %s", port)
-	absDB, _ := filepath.Abs(os.Getenv("DB_PATH"))
-	if os.Getenv("DB_PATH") == "" {
-		absDB, _ = filepath.Abs("./vehicles.db")
-	}
-	log.Printf("📊 Database: %s", absDB)
+	log.Printf("📊 Database: %s", cfg.Database.Path)
 	log.Printf("🔗 API endpoints:")
 	log.Printf("   - GET    /api/brands")
 	log.Printf("   - POST   /api/brands")
 	log.Printf("   - GET    /api/brands/{id}")
 	log.Printf("   - GET    /api/brands/{brandId}/models")
 	log.Printf("   - GET    /api/models/{modelId}/generations")
+	log.Printf("   - POST   /api/models/{modelId}/generations")
 	log.Printf("   - GET    /api/generations/{generationId}")
+	log.Printf("   - PUT    /api/generations/{generationId}")
+	log.Printf("   - DELETE /api/generations/{generationId}")
 	log.Printf("   - GET    /api/generations/{generationId}/trims")
 	log.Printf("   - GET    /api/models/{modelId}/trims")
-	log.Printf("   - GET    /api/search")
+	log.Printf("   - PUT    /api/trims/{id}")
+	log.Printf("   - PATCH  /api/trims/{id}")
+	log.Printf("   - GET    /api/trims/{id}/citations")
+	log.Printf("   - POST   /api/trims/{id}/merge")
+	log.Printf("   - GET    /api/conflicts")
+	log.Printf("   - GET    /api/review/issues")
+	log.Printf("   - PATCH  /api/review/issues/{id}")
+	log.Printf("   - POST   /api/review/issues/resolve")
+	log.Printf("   - GET    /api/search?q=")
+	log.Printf("   - GET    /api/export?format=csv|jsonl|parquet")
+	log.Printf("   - GET    /api/compare?trims=1,2,3")
 	log.Printf("   - GET    /health")
 
 	if err := http.ListenAndServe(":"+port, corsHandler(mux)); err != nil {
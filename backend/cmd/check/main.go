@@ -0,0 +1,101 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/emirh/car-specs/backend/internal/check"
+	"github.com/emirh/car-specs/backend/internal/config"
+	"github.com/emirh/car-specs/backend/internal/service"
+	"github.com/emirh/car-specs/backend/internal/storage"
+)
+
+// Exit codes: 0 clean, 1 findings fail the check, 2 the check could not run
+const (
+	exitFailed = 1
+	exitError  = 2
+)
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "Usage: check [-fix] [-strict] [-rules a,b] [-o report.json] [-list]")
+	fmt.Fprintln(os.Stderr, "")
+	fmt.Fprintln(os.Stderr, "Runs the consistency rules over the whole catalogue and prints a JSON report.")
+	fmt.Fprintln(os.Stderr, "Exits 1 when errors remain (or warnings, with -strict) so it can gate data releases.")
+	fmt.Fprintln(os.Stderr, "The database is taken from DB_PATH (default backend/vehicles.db).")
+	fmt.Fprintln(os.Stderr, "")
+	flag.PrintDefaults()
+}
+
+func main() {
+	fix := flag.Bool("fix", false, "apply the safe fixes before reporting")
+	strict := flag.Bool("strict", false, "fail on warnings as well as errors")
+	ruleNames := flag.String("rules", "", "comma-separated rules to run (default all)")
+	output := flag.String("o", "", "write the report to this file instead of stdout")
+	list := flag.Bool("list", false, "list the rules and exit")
+	flag.Usage = usage
+	flag.Parse()
+
+	if *list {
+		for _, rule := range check.Rules {
+			fmt.Printf("%-24s %s\n", rule.Name, rule.Description)
+		}
+		return
+	}
+
+	var names []string
+	if *ruleNames != "" {
+		names = strings.Split(*ruleNames, ",")
+	}
+	rules, err := check.Select(names)
+	if err != nil {
+		fatal(err)
+	}
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		fatal(fmt.Errorf("failed to load config: %w", err))
+	}
+	db, err := storage.Open(cfg)
+	if err != nil {
+		fatal(fmt.Errorf("failed to initialize database: %w", err))
+	}
+	defer db.Close()
+
+	report, err := check.Run(db, rules, *fix, service.CommandActor("check"))
+	if err != nil {
+		fatal(err)
+	}
+	report.Database = cfg.Database.Path
+
+	var out io.Writer = os.Stdout
+	if *output != "" {
+		f, err := os.Create(*output)
+		if err != nil {
+			fatal(fmt.Errorf("failed to create report: %w", err))
+		}
+		defer f.Close()
+		out = f
+	}
+	encoder := json.NewEncoder(out)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(report); err != nil {
+		fatal(fmt.Errorf("failed to write report: %w", err))
+	}
+
+	s := report.Summary
+	fmt.Fprintf(os.Stderr, "%d rules: %d errors, %d warnings, %d fixed\n", len(report.Rules), s.Errors, s.Warnings, s.Fixed)
+	if report.Failed(*strict) {
+		fmt.Fprintln(os.Stderr, "❌ Check failed")
+		os.Exit(exitFailed)
+	}
+	fmt.Fprintln(os.Stderr, "✓ Check passed")
+}
+
+func fatal(err error) {
+	fmt.Fprintf(os.Stderr, "❌ %v\n", err)
+	os.Exit(exitError)
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/emirh/car-specs/backend/internal/config"
+	"github.com/emirh/car-specs/backend/internal/export"
+	"github.com/emirh/car-specs/backend/internal/handlers"
+	"github.com/emirh/car-specs/backend/internal/storage"
+)
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "Usage: export [-format csv|jsonl|parquet] [-o FILE] [FILTERS]")
+	fmt.Fprintln(os.Stderr, "")
+	fmt.Fprintln(os.Stderr, "Writes every trim with its brand, model, generation, specs and sources.")
+	fmt.Fprintln(os.Stderr, "FILTERS are /api/search parameters, e.g. 'brand=Audi&fuel_type=diesel'.")
+	fmt.Fprintln(os.Stderr, "CSV and JSON Lines exports can be imported again with")
+	fmt.Fprintln(os.Stderr, "data/import_profiles/export.yaml.")
+	fmt.Fprintln(os.Stderr, "The database is taken from DB_PATH (default backend/vehicles.db).")
+	fmt.Fprintln(os.Stderr, "")
+	flag.PrintDefaults()
+}
+
+func main() {
+	format := flag.String("format", "", "csv, jsonl or parquet (default: from -o, else jsonl)")
+	output := flag.String("o", "", "file to write (default: standard output)")
+	flag.Usage = usage
+	flag.Parse()
+
+	filters := map[string]interface{}{}
+	switch flag.NArg() {
+	case 0:
+	case 1:
+		query, err := url.ParseQuery(flag.Arg(0))
+		if err != nil {
+			fatal(fmt.Errorf("invalid filters: %w", err))
+		}
+		if filters, err = handlers.ParseSearchFilters(query); err != nil {
+			fatal(err)
+		}
+	default:
+		usage()
+		os.Exit(2)
+	}
+
+	if *format == "" {
+		*format = export.FormatJSONL
+		if ext := strings.TrimPrefix(filepath.Ext(*output), "."); export.ValidFormat(ext) {
+			*format = ext
+		}
+	}
+	if !export.ValidFormat(*format) {
+		fatal(fmt.Errorf("unknown format %q (csv, jsonl or parquet)", *format))
+	}
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		fatal(fmt.Errorf("failed to load config: %w", err))
+	}
+	db, err := storage.Open(cfg)
+	if err != nil {
+		fatal(fmt.Errorf("failed to initialize database: %w", err))
+	}
+	defer db.Close()
+
+	var w io.Writer = os.Stdout
+	var file *os.File
+	if *output != "" {
+		if file, err = os.Create(*output); err != nil {
+			fatal(err)
+		}
+		w = file
+	}
+	buffered := bufio.NewWriter(w)
+
+	count, err := export.Export(db, buffered, *format, filters)
+	if err == nil {
+		err = buffered.Flush()
+	}
+	if file != nil {
+		if closeErr := file.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			os.Remove(*output)
+		}
+	}
+	if err != nil {
+		fatal(err)
+	}
+
+	if *output != "" {
+		fmt.Fprintf(os.Stderr, "✓ Exported %d trims to %s\n", count, *output)
+	} else {
+		fmt.Fprintf(os.Stderr, "✓ Exported %d trims\n", count)
+	}
+}
+
+func fatal(err error) {
+	fmt.Fprintf(os.Stderr, "❌ %v\n", err)
+	os.Exit(1)
+}
@@ -1,191 +1,136 @@
 package main
 
 import (
-	"encoding/csv"
-	"log"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
 	"os"
-	"strconv"
+	"strings"
 
-	"github.com/emirh/car-specs/backend/internal/database"
-	"github.com/emirh/car-specs/backend/internal/models"
-	"github.com/emirh/car-specs/backend/internal/repository"
-	"github.com/emirh/car-specs/backend/internal/service"
+	"github.com/emirh/car-specs/backend/internal/config"
+	"github.com/emirh/car-specs/backend/internal/importer"
+	"github.com/emirh/car-specs/backend/internal/merge"
+	"github.com/emirh/car-specs/backend/internal/storage"
 )
 
-func main() {
-	log.Println("=== CSV Vehicle Importer ===")
+func usage() {
+	fmt.Fprintln(os.Stderr, "Usage: importer [-profile FILE] [-dry-run] [-max-errors N] [-json] [DATASET]")
+	fmt.Fprintln(os.Stderr, "")
+	fmt.Fprintln(os.Stderr, "Imports a CSV or JSON dataset. The profile maps its columns onto catalogue")
+	fmt.Fprintln(os.Stderr, "fields, see data/import_profiles. DATASET defaults to vehicles.csv.")
+	fmt.Fprintln(os.Stderr, "The database is taken from DB_PATH (default backend/vehicles.db).")
+	fmt.Fprintln(os.Stderr, "")
+	fmt.Fprintln(os.Stderr, "Exit status is 0 when every row was imported, 1 when rows were invalid or the")
+	fmt.Fprintln(os.Stderr, "import was aborted, 2 when it couldn't run.")
+	fmt.Fprintln(os.Stderr, "")
+	flag.PrintDefaults()
+}
 
-	// Initialize database
-	if err := database.InitDB(); err != nil {
-		log.Fatalf("Failed to initialize database: %v", err)
+func main() {
+	profilePath := flag.String("profile", "data/import_profiles/vehicles.yaml", "mapping profile (.yaml, .yml or .json)")
+	dryRun := flag.Bool("dry-run", false, "run the import and report what it would do, then roll it back")
+	maxErrors := flag.Int("max-errors", -1, "abort and store nothing once more rows than this are invalid (-1: no limit)")
+	asJSON := flag.Bool("json", false, "print the report as JSON")
+	flag.Usage = usage
+	flag.Parse()
+
+	dataset := "vehicles.csv"
+	switch flag.NArg() {
+	case 0:
+	case 1:
+		dataset = flag.Arg(0)
+	default:
+		usage()
+		os.Exit(2)
 	}
-	defer database.CloseDB()
-
-	// Initialize repositories
-	brandRepo := repository.NewBrandRepository(database.DB)
-	modelRepo := repository.NewModelRepository(database.DB)
-	trimRepo := repository.NewTrimRepository(database.DB)
 
-	// Initialize services
-	brandService := service.NewBrandService(brandRepo)
-	modelService := service.NewModelService(modelRepo, brandRepo)
-	trimService := service.NewTrimService(trimRepo, modelRepo)
-
-	// Open CSV file
-	csvFile := "vehicles.csv"
-	file, err := os.Open(csvFile)
+	profile, err := importer.LoadProfile(*profilePath)
 	if err != nil {
-		log.Fatalf("Failed to open CSV file: %v", err)
+		fatal(err)
 	}
-	defer file.Close()
 
-	// Parse CSV
-	reader := csv.NewReader(file)
-	records, err := reader.ReadAll()
+	cfg, err := config.LoadConfig()
 	if err != nil {
-		log.Fatalf("Failed to read CSV: %v", err)
+		fatal(fmt.Errorf("failed to load config: %w", err))
 	}
-
-	// Skip header row
-	if len(records) < 2 {
-		log.Fatal("CSV file is empty or has no data rows")
+	mergePolicy, err := merge.LoadPolicy(cfg.MergePolicyPath)
+	if err != nil {
+		fatal(err)
 	}
+	db, err := storage.Open(cfg)
+	if err != nil {
+		fatal(fmt.Errorf("failed to initialize database: %w", err))
+	}
+	defer db.Close()
 
-	log.Printf("Found %d vehicles to import\n", len(records)-1)
-
-	// Statistics
-	stats := struct {
-		brandsCreated int
-		modelsCreated int
-		trimsCreated  int
-		errors        int
-	}{}
+	result, err := importer.Import(db, profile, dataset, importer.Options{DryRun: *dryRun, MaxErrors: *maxErrors, MergePolicy: &mergePolicy})
+	if err != nil && !errors.Is(err, importer.ErrTooManyErrors) {
+		fatal(err)
+	}
 
-	// Process each row
-	for i, record := range records[1:] {
-		if len(record) < 15 {
-			log.Printf("Row %d: Invalid format (expected 15 columns, got %d), skipping\n", i+2, len(record))
-			stats.errors++
-			continue
+	if *asJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(result); err != nil {
+			fatal(err)
 		}
+	} else {
+		printReport(dataset, profile.Name, result)
+	}
 
-		// Parse CSV columns (now with Image_URL as column 14)
-		brandName := record[0]
-		modelName := record[1]
-		trimName := record[2]
-		year := parseInt(record[3])
-		hp := parseInt(record[4])
-		torque := parseInt(record[5])
-		accel := parseFloat(record[6])
-		fuelType := record[7]
-		transmission := record[8]
-		drivetrain := record[9]
-		displacement := parseInt(record[10])
-		topSpeed := parseInt(record[11])
-		fuelConsumption := parseFloat(record[12])
-		bodyStyle := record[13]
-		imageURL := record[14]
-
-		log.Printf("\n[%d/%d] Processing: %s %s %s", i+1, len(records)-1, brandName, modelName, trimName)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "❌ Import aborted, nothing was stored: %v\n", err)
+		os.Exit(1)
+	}
+	if result.Count(importer.RowInvalid) > 0 {
+		os.Exit(1)
+	}
+}
 
-		// Step 1: Get or Create Brand
-		brand, err := brandService.GetBrandByName(brandName)
-		if err != nil {
-			// Brand doesn't exist, create it
-			brand, err = brandService.CreateBrand(brandName, nil, nil)
-			if err != nil {
-				log.Printf("  ❌ Failed to create brand: %v", err)
-				stats.errors++
-				continue
-			}
-			log.Printf("  ✓ Created brand: %s (ID: %d)", brandName, brand.ID)
-			stats.brandsCreated++
-		} else {
-			log.Printf("  ✓ Found existing brand: %s (ID: %d)", brandName, brand.ID)
-		}
+// printReport lists what happened to every row, then the totals
+func printReport(dataset, profile string, result *importer.Result) {
+	if result.DryRun {
+		fmt.Printf("Dry run of %s with profile %s, nothing is stored\n", dataset, profile)
+	} else {
+		fmt.Printf("Importing %s with profile %s\n", dataset, profile)
+	}
 
-		// Step 2: Get or Create Model
-		modelsList, err := modelService.ListModelsByBrand(brand.ID)
-		if err != nil {
-			log.Printf("  ❌ Failed to list models: %v", err)
-			stats.errors++
-			continue
+	for _, row := range result.Rows {
+		switch row.Action {
+		case importer.RowCreated:
+			fmt.Printf("+ Line %d: create %s\n", row.Line, row.Trim)
+		case importer.RowUpdated:
+			fmt.Printf("~ Line %d: update %s (trim %d)\n", row.Line, row.Trim, row.TrimID)
+		case importer.RowUnchanged:
+			fmt.Printf("= Line %d: unchanged %s (trim %d)\n", row.Line, row.Trim, row.TrimID)
+		case importer.RowSkipped:
+			fmt.Printf("- Line %d: skip %s, %s\n", row.Line, row.Trim, row.Reason)
+		case importer.RowInvalid:
+			fmt.Printf("❌ Line %d: invalid %s: %s\n", row.Line, row.Trim, row.Reason)
 		}
-
-		var model *models.Model
-		for _, m := range modelsList {
-			if m.Name == modelName {
-				model = m
-				break
-			}
+		if len(row.Created) > 0 {
+			fmt.Printf("      new %s\n", strings.Join(row.Created, ", "))
 		}
-
-		if model == nil {
-			// Model doesn't exist, create it
-			model, err = modelService.CreateModel(brand.ID, modelName, &bodyStyle, nil)
-			if err != nil {
-				log.Printf("  ❌ Failed to create model: %v", err)
-				stats.errors++
-				continue
+		for _, change := range row.Changes {
+			if row.Action == importer.RowCreated {
+				fmt.Printf("      %s: %s\n", change.Field, change.After)
+			} else {
+				fmt.Printf("      %s: %s → %s\n", change.Field, change.Before, change.After)
 			}
-			log.Printf("  ✓ Created model: %s (ID: %d)", modelName, model.ID)
-			stats.modelsCreated++
-		} else {
-			log.Printf("  ✓ Found existing model: %s (ID: %d)", modelName, model.ID)
 		}
-
-		// Step 3: Create Trim
-		trim := &models.Trim{
-			ModelID:             model.ID,
-			Name:                trimName,
-			Year:                year,
-			FuelType:            &fuelType,
-			PowerHP:             &hp,
-			TorqueNM:            &torque,
-			Acceleration0To100:  &accel,
-			TransmissionType:    &transmission,
-			Drivetrain:          &drivetrain,
-			DisplacementCC:      &displacement,
-			TopSpeedKmh:         &topSpeed,
-			FuelConsumptionComb: &fuelConsumption,
-			ImageURL:            &imageURL,
-			Market:              "TR",
-			Currency:            "TRY",
-			SeatingCapacity:     5,
-		}
-
-		if err := trimService.CreateTrim(trim); err != nil {
-			log.Printf("  ❌ Failed to create trim: %v", err)
-			stats.errors++
-			continue
-		}
-		log.Printf("  ✓ Created trim: %s (ID: %d)", trimName, trim.ID)
-		stats.trimsCreated++
 	}
 
-	// Print summary
-	log.Println("\n=== Import Summary ===")
-	log.Printf("Brands created: %d", stats.brandsCreated)
-	log.Printf("Models created: %d", stats.modelsCreated)
-	log.Printf("Trims created:  %d", stats.trimsCreated)
-	log.Printf("Errors:         %d", stats.errors)
-	log.Printf("Total vehicles: %d", stats.trimsCreated)
-	log.Println("\n✓ Import complete!")
-}
-
-// Helper functions
-func parseInt(s string) int {
-	if s == "" {
-		return 0
+	verb := ""
+	if result.DryRun {
+		verb = "would be "
 	}
-	val, _ := strconv.Atoi(s)
-	return val
+	fmt.Printf("\n%d %screated, %d %supdated, %d unchanged, %d skipped, %d invalid\n",
+		result.Count(importer.RowCreated), verb, result.Count(importer.RowUpdated), verb,
+		result.Count(importer.RowUnchanged), result.Count(importer.RowSkipped), result.Count(importer.RowInvalid))
 }
 
-func parseFloat(s string) float64 {
-	if s == "" {
-		return 0
-	}
-	val, _ := strconv.ParseFloat(s, 64)
-	return val
+func fatal(err error) {
+	fmt.Fprintf(os.Stderr, "❌ %v\n", err)
+	os.Exit(2)
 }
@@ -11,10 +11,13 @@ import (
 	"strings"
 	"time"
 
-	"github.com/emirh/car-specs/backend/internal/database"
+	"github.com/emirh/car-specs/backend/internal/config"
+	"github.com/emirh/car-specs/backend/internal/enums"
+	"github.com/emirh/car-specs/backend/internal/merge"
 	"github.com/emirh/car-specs/backend/internal/models"
 	"github.com/emirh/car-specs/backend/internal/repository"
 	"github.com/emirh/car-specs/backend/internal/service"
+	"github.com/emirh/car-specs/backend/internal/storage"
 	"github.com/joho/godotenv"
 )
 
@@ -31,6 +34,8 @@ type NinjasCarResponse struct {
 	Highway_MPG  interface{} `json:"highway_mpg"` // Can be int or string
 	City_MPG     interface{} `json:"city_mpg"`    // Can be int or string
 	Transmission string      `json:"transmission"`
+
+	SourceURL string `json:"-"` // Query that returned this car, cited as its source
 }
 
 // Google Custom Search response structure
@@ -44,17 +49,19 @@ type IngestionService struct {
 	brandService   *service.BrandService
 	modelService   *service.ModelService
 	trimService    *service.TrimService
+	mergeService   *service.MergeService
 	ninjasAPIKey   string
 	googleAPIKey   string
 	searchEngineID string
 	httpClient     *http.Client
 }
 
-func NewIngestionService(brandSvc *service.BrandService, modelSvc *service.ModelService, trimSvc *service.TrimService) *IngestionService {
+func NewIngestionService(brandSvc *service.BrandService, modelSvc *service.ModelService, trimSvc *service.TrimService, mergeSvc *service.MergeService) *IngestionService {
 	return &IngestionService{
 		brandService:   brandSvc,
 		modelService:   modelSvc,
 		trimService:    trimSvc,
+		mergeService:   mergeSvc,
 		ninjasAPIKey:   os.Getenv("NINJAS_API_KEY"),
 		googleAPIKey:   os.Getenv("GOOGLE_API_KEY"),
 		searchEngineID: os.Getenv("SEARCH_ENGINE_ID"),
@@ -94,6 +101,9 @@ func (s *IngestionService) FetchCarsFromNinjas(make string, year int) ([]NinjasC
 	if err := json.NewDecoder(resp.Body).Decode(&cars); err != nil {
 		return nil, fmt.Errorf("failed to decode response: %w", err)
 	}
+	for i := range cars {
+		cars[i].SourceURL = reqURL
+	}
 
 	return cars, nil
 }
@@ -188,10 +198,11 @@ func (s *IngestionService) ProcessCar(car NinjasCarResponse) error {
 		return fmt.Errorf("failed to list trims: %w", err)
 	}
 
+	var existing *models.Trim
 	for _, trim := range existingTrims {
 		if trim.Year == car.Year {
-			log.Printf("  ⏭️  Trim already exists for year %d, skipping", car.Year)
-			return nil
+			existing = trim
+			break
 		}
 	}
 
@@ -206,33 +217,65 @@ func (s *IngestionService) ProcessCar(car NinjasCarResponse) error {
 	cityMPG := toInt(car.City_MPG)
 	highwayMPG := toInt(car.Highway_MPG)
 
-	// Convert displacement from liters to CC
-	displacementCC := int(displacement * 1000)
+	// Values the API leaves out stay nil: a 0 would be cited (and merged) as data
+	var displacementCC, cylinderCount *int
+	var fuelConsumption *float64
+	if displacement > 0 {
+		cc := int(displacement * 1000) // Liters to CC
+		displacementCC = &cc
+	}
+	if cylinders > 0 {
+		cylinderCount = &cylinders
+	}
 
 	// Convert MPG to L/100km (approximate)
-	var fuelConsumption float64
 	if cityMPG > 0 && highwayMPG > 0 {
 		avgMPG := float64(cityMPG+highwayMPG) / 2.0
-		fuelConsumption = 235.214 / avgMPG // MPG to L/100km conversion
+		consumption := 235.214 / avgMPG // MPG to L/100km conversion
+		fuelConsumption = &consumption
 	}
 
 	trim := &models.Trim{
 		ModelID:             model.ID,
 		Name:                fmt.Sprintf("%s %d", car.Model, car.Year),
 		Year:                car.Year,
-		FuelType:            &fuelType,
-		DisplacementCC:      &displacementCC,
-		Cylinders:           &cylinders,
-		TransmissionType:    &transmission,
-		Drivetrain:          &drivetrain,
-		FuelConsumptionComb: &fuelConsumption,
+		FuelType:            fuelType,
+		DisplacementCC:      displacementCC,
+		Cylinders:           cylinderCount,
+		TransmissionType:    transmission,
+		Drivetrain:          drivetrain,
+		FuelConsumptionComb: fuelConsumption,
 		Market:              "US",
 		Currency:            "USD",
 		SeatingCapacity:     5,
 	}
 
-	// Step 5: Create Trim
-	if err := s.trimService.CreateTrim(trim); err != nil {
+	// Step 5: Create Trim, citing the API Ninjas query for its values
+	title := fmt.Sprintf("API Ninjas: %s %s %d", car.Make, car.Model, car.Year)
+	market := "US"
+	retrieved := time.Now().UTC()
+	source := &models.SourceDocument{
+		URL:         car.SourceURL,
+		Title:       &title,
+		SourceType:  models.SourceAPINinjas,
+		MarketScope: &market,
+		RetrievedAt: &retrieved,
+	}
+	if existing != nil {
+		// Seen before: merge the values in under the source priority rules
+		result, err := s.mergeService.Ingest(existing.ID, trim, source)
+		if err != nil {
+			return fmt.Errorf("failed to merge trim %d: %w", existing.ID, err)
+		}
+		for _, f := range result.Fields {
+			if f.Status != models.MergeUnchanged {
+				log.Printf("  ↻ %s: %s (%s -> %s)", f.Field, f.Status, f.Previous, f.Value)
+			}
+		}
+		log.Printf("  ✓ Merged into existing trim for year %d (ID: %d)", car.Year, existing.ID)
+		return nil
+	}
+	if err := s.trimService.CreateTrim(trim, source, nil); err != nil {
 		return fmt.Errorf("failed to create trim: %w", err)
 	}
 	log.Printf("  ✓ Created trim: %s (ID: %d)", trim.Name, trim.ID)
@@ -288,47 +331,18 @@ func toFloat(val interface{}) float64 {
 	return 0.0
 }
 
-// Helper functions to map API Ninjas data to our schema
-func mapFuelType(apiType string) string {
-	switch strings.ToLower(apiType) {
-	case "gas", "gasoline":
-		return "Benzin"
-	case "diesel":
-		return "Dizel"
-	case "electric":
-		return "Elektrik"
-	case "hybrid":
-		return "Hybrid"
-	default:
-		return "Benzin"
-	}
+// Helper functions to map API Ninjas data to our schema.
+// Unknown values are left empty rather than guessed.
+func mapFuelType(apiType string) *string {
+	return enums.FuelType.Canonical(apiType)
 }
 
-func mapTransmission(apiTrans string) string {
-	lower := strings.ToLower(apiTrans)
-	if strings.Contains(lower, "auto") || strings.Contains(lower, "a") {
-		return "Automatic"
-	}
-	if strings.Contains(lower, "manual") || strings.Contains(lower, "m") {
-		return "Manual"
-	}
-	if strings.Contains(lower, "cvt") {
-		return "CVT"
-	}
-	return "Automatic"
+func mapTransmission(apiTrans string) *string {
+	return enums.Transmission.Canonical(apiTrans)
 }
 
-func mapDrivetrain(apiDrive string) string {
-	switch strings.ToLower(apiDrive) {
-	case "fwd":
-		return "FWD"
-	case "rwd":
-		return "RWD"
-	case "awd", "4wd":
-		return "AWD"
-	default:
-		return "FWD"
-	}
+func mapDrivetrain(apiDrive string) *string {
+	return enums.Drivetrain.Canonical(apiDrive)
 }
 
 func main() {
@@ -346,23 +360,40 @@ func main() {
 	}
 
 	// Initialize database
-	if err := database.InitDB(); err != nil {
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		log.Fatalf("Failed to load config: %v", err)
+	}
+	db, err := storage.Open(cfg)
+	if err != nil {
 		log.Fatalf("Failed to initialize database: %v", err)
 	}
-	defer database.CloseDB()
+	defer db.Close()
+	log.Printf("✓ Database connection established: %s", cfg.Database.Path)
 
 	// Initialize repositories
-	brandRepo := repository.NewBrandRepository(database.DB)
-	modelRepo := repository.NewModelRepository(database.DB)
-	trimRepo := repository.NewTrimRepository(database.DB)
-
-	// Initialize services
-	brandService := service.NewBrandService(brandRepo)
-	modelService := service.NewModelService(modelRepo, brandRepo)
-	trimService := service.NewTrimService(trimRepo, modelRepo)
+	brandRepo := repository.NewBrandRepository(db)
+	modelRepo := repository.NewModelRepository(db)
+	trimRepo := repository.NewTrimRepository(db)
+	provenanceRepo := repository.NewProvenanceRepository(db)
+	reviewRepo := repository.NewReviewRepository(db)
+	auditRepo := repository.NewAuditRepository(db)
+
+	// Initialize services; their writes are audited as this import
+	actor := service.CommandActor("importer:api_ninjas")
+	brandService := service.NewBrandService(brandRepo, auditRepo).As(actor)
+	modelService := service.NewModelService(modelRepo, brandRepo, auditRepo).As(actor)
+	generationRepo := repository.NewGenerationRepository(db)
+	reviewService := service.NewReviewService(reviewRepo, trimRepo, generationRepo)
+	trimService := service.NewTrimService(trimRepo, modelRepo, generationRepo, provenanceRepo, reviewService, auditRepo).As(actor)
+	mergePolicy, err := merge.LoadPolicy(cfg.MergePolicyPath)
+	if err != nil {
+		log.Fatalf("Failed to load merge policy: %v", err)
+	}
+	mergeService := service.NewMergeService(trimService, provenanceRepo, repository.NewMergeConflictRepository(db), mergePolicy)
 
 	// Initialize ingestion service
-	ingestionService := NewIngestionService(brandService, modelService, trimService)
+	ingestionService := NewIngestionService(brandService, modelService, trimService, mergeService)
 
 	// Target brands and years
 	targetBrands := []string{"bmw", "audi", "volkswagen", "mercedes-benz", "toyota", "ford"}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+
+	"github.com/emirh/car-specs/backend/internal/config"
+	"github.com/emirh/car-specs/backend/internal/migrate"
+	"github.com/emirh/car-specs/backend/internal/storage"
+	"github.com/emirh/car-specs/backend/migrations"
+)
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "Usage: migrate [-dry-run] status|up|down|to N")
+	fmt.Fprintln(os.Stderr, "")
+	fmt.Fprintln(os.Stderr, "  status   list migrations and whether they are applied")
+	fmt.Fprintln(os.Stderr, "  up       apply all pending migrations")
+	fmt.Fprintln(os.Stderr, "  down     roll back the most recent migration")
+	fmt.Fprintln(os.Stderr, "  to N     migrate up or down to version N (0 rolls back everything)")
+	fmt.Fprintln(os.Stderr, "")
+	fmt.Fprintln(os.Stderr, "The database is taken from DB_PATH (default backend/vehicles.db).")
+	flag.PrintDefaults()
+}
+
+func main() {
+	dryRun := flag.Bool("dry-run", false, "run migrations in a transaction and roll back")
+	flag.Usage = usage
+	flag.Parse()
+
+	if flag.NArg() < 1 {
+		usage()
+		os.Exit(2)
+	}
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		log.Fatalf("Failed to load config: %v", err)
+	}
+
+	// The schema check would refuse an outdated database, which is exactly what we're here to fix
+	db, err := storage.OpenUnchecked(cfg)
+	if err != nil {
+		log.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer db.Close()
+	log.Printf("📁 Database: %s", cfg.Database.Path)
+
+	migrator, err := migrations.NewMigrator(db)
+	if err != nil {
+		log.Fatalf("❌ Failed to load migrations: %v", err)
+	}
+	migrator.DryRun = *dryRun
+	migrator.Log = os.Stdout
+
+	switch flag.Arg(0) {
+	case "status":
+		err = printStatus(migrator)
+	case "up":
+		err = migrator.Up()
+	case "down":
+		err = migrator.Down()
+	case "to":
+		if flag.NArg() != 2 {
+			usage()
+			os.Exit(2)
+		}
+		target, convErr := strconv.Atoi(flag.Arg(1))
+		if convErr != nil {
+			log.Fatalf("❌ Invalid version %q", flag.Arg(1))
+		}
+		err = migrator.To(target)
+	default:
+		usage()
+		os.Exit(2)
+	}
+
+	if err != nil {
+		log.Fatalf("❌ %v", err)
+	}
+
+	if flag.Arg(0) != "status" {
+		current, err := migrator.CurrentVersion()
+		if err != nil {
+			log.Fatalf("❌ %v", err)
+		}
+		fmt.Printf("✓ Schema version: %d (latest %d)\n", current, migrator.Latest())
+	}
+}
+
+// printStatus prints one line per known or applied migration
+func printStatus(migrator *migrate.Migrator) error {
+	statuses, err := migrator.Status()
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("%-8s %-30s %-10s %s\n", "VERSION", "NAME", "STATE", "APPLIED AT")
+	for _, st := range statuses {
+		state := "pending"
+		appliedAt := ""
+		if st.Applied {
+			state = "applied"
+			appliedAt = st.AppliedAt.Format("2006-01-02 15:04:05")
+		}
+		if st.Modified {
+			state = "modified"
+		}
+		if st.Missing {
+			state = "missing"
+		}
+		fmt.Printf("%04d     %-30s %-10s %s\n", st.Version, st.Name, state, appliedAt)
+	}
+	return nil
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/emirh/car-specs/backend/internal/config"
+	"github.com/emirh/car-specs/backend/internal/models"
+	"github.com/emirh/car-specs/backend/internal/repository"
+	"github.com/emirh/car-specs/backend/internal/service"
+	"github.com/emirh/car-specs/backend/internal/storage"
+)
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "Usage: purge [-days N] [-dry-run]")
+	fmt.Fprintln(os.Stderr, "")
+	fmt.Fprintln(os.Stderr, "Permanently removes brands, models, generations and trims that have been in the")
+	fmt.Fprintln(os.Stderr, "trash longer than the retention period. Their audit log entries are kept.")
+	fmt.Fprintln(os.Stderr, "The database is taken from DB_PATH (default backend/vehicles.db).")
+	fmt.Fprintln(os.Stderr, "")
+	flag.PrintDefaults()
+}
+
+func main() {
+	days := flag.Int("days", int(service.DefaultRetention/(24*time.Hour)), "retention period: purge rows deleted more than this many days ago")
+	dryRun := flag.Bool("dry-run", false, "count what would be purged without removing it")
+	flag.Usage = usage
+	flag.Parse()
+
+	if *days < 0 {
+		fatal(fmt.Errorf("-days must not be negative"))
+	}
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		fatal(fmt.Errorf("failed to load config: %w", err))
+	}
+	db, err := storage.Open(cfg)
+	if err != nil {
+		fatal(fmt.Errorf("failed to initialize database: %w", err))
+	}
+	defer db.Close()
+
+	trash := service.NewTrashService(
+		repository.NewTrashRepository(db), repository.NewAuditRepository(db),
+		repository.NewBrandRepository(db), repository.NewModelRepository(db),
+		repository.NewGenerationRepository(db), repository.NewTrimRepository(db),
+	)
+	counts, err := trash.Purge(time.Duration(*days)*24*time.Hour, *dryRun)
+	if err != nil {
+		fatal(err)
+	}
+
+	verb := "Purged"
+	if *dryRun {
+		verb = "Would purge"
+	}
+	fmt.Printf("%s %d brands, %d models, %d generations and %d trims deleted more than %d days ago\n", verb,
+		counts[models.EntityBrand], counts[models.EntityModel], counts[models.EntityGeneration], counts[models.EntityTrim], *days)
+}
+
+func fatal(err error) {
+	fmt.Fprintf(os.Stderr, "❌ %v\n", err)
+	os.Exit(1)
+}
@@ -1,292 +1,490 @@
 package main
 
 import (
+	"context"
 	"database/sql"
+	"flag"
 	"fmt"
 	"log"
-	"net/url"
-	"regexp"
-	"strconv"
+	"os"
+	"os/signal"
+	"path/filepath"
 	"strings"
 	"time"
 
-	"github.com/emirh/car-specs/backend/internal/db"
-	"github.com/gocolly/colly/v2"
+	"github.com/emirh/car-specs/backend/internal/config"
+	"github.com/emirh/car-specs/backend/internal/fetch"
+	"github.com/emirh/car-specs/backend/internal/merge"
+	"github.com/emirh/car-specs/backend/internal/models"
+	"github.com/emirh/car-specs/backend/internal/repository"
+	"github.com/emirh/car-specs/backend/internal/scraper"
+	"github.com/emirh/car-specs/backend/internal/service"
+	"github.com/emirh/car-specs/backend/internal/storage"
+	"github.com/emirh/car-specs/backend/internal/validation"
 )
 
-// Global DB
-var database *sql.DB
+// Years taken from data/manual_overrides.json are cited to this source
+const overridesSourceURL = "manual://data/manual_overrides.json"
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "Usage: scraper [-jobs data/scrape_jobs.json] [-only Brand/Model]")
+	fmt.Fprintln(os.Stderr, "       scraper -source ultimatespecs -brand Audi -model A3 -url https://...")
+	fmt.Fprintln(os.Stderr, "       scraper -only Audi/A3 -record internal/scraper/testdata")
+	fmt.Fprintln(os.Stderr, "       scraper -resume")
+	fmt.Fprintln(os.Stderr, "       scraper -apply review")
+	fmt.Fprintln(os.Stderr, "")
+	fmt.Fprintln(os.Stderr, "Crawls the brand/model trees listed in the jobs file, or the single job given by flags.")
+	fmt.Fprintln(os.Stderr, "Pages are cached and each job's progress is saved under -state, so an interrupted crawl can -resume.")
+	fmt.Fprintln(os.Stderr, "Re-scraped pages that extract the same values are skipped; changed values are merged in (-apply auto)")
+	fmt.Fprintln(os.Stderr, "or recorded as pending changes for GET /api/changes (-apply review).")
+	fmt.Fprintf(os.Stderr, "Sources: %s\n", strings.Join(scraper.Names(), ", "))
+	fmt.Fprintln(os.Stderr, "")
+	flag.PrintDefaults()
+}
 
 func main() {
-	// 1. Initialize DB
-	var err error
-	database, err = db.InitDB("vehicles.db")
+	jobsPath := flag.String("jobs", "data/scrape_jobs.json", "JSON file listing the brand/model trees to crawl")
+	only := flag.String("only", "", "crawl only the jobs of this Brand or Brand/Model")
+	source := flag.String("source", "", "crawl one job from this source instead of the jobs file")
+	brand := flag.String("brand", "", "brand of the single job")
+	model := flag.String("model", "", "model of the single job")
+	modelURL := flag.String("url", "", "model page of the single job")
+	record := flag.String("record", "", "record each job as a test fixture set under this directory instead of saving to the database")
+	stateDir := flag.String("state", ".crawl", "directory for the page cache and crawl frontiers")
+	resume := flag.Bool("resume", false, "skip the trims an interrupted run already stored")
+	delay := flag.Duration("delay", fetch.DefaultOptions().Delay, "minimum time between requests to one site (robots.txt Crawl-delay wins if longer)")
+	concurrency := flag.Int("concurrency", fetch.DefaultOptions().MaxConcurrent, "maximum requests in flight to one site")
+	maxAge := flag.Duration("max-age", fetch.DefaultOptions().MaxAge, "use cached pages this fresh without revalidating them")
+	noCache := flag.Bool("no-cache", false, "fetch every page from the site")
+	apply := flag.String("apply", "auto", "what to do with changed values of stored trims: auto (merge them in) or review (hold them as pending changes)")
+	flag.Usage = usage
+	flag.Parse()
+
+	base := store{counts: &runCounts{}}
+	switch *apply {
+	case "auto":
+	case "review":
+		base.holdChanges = true
+	default:
+		log.Fatalf("Invalid -apply %q (use auto or review)", *apply)
+	}
+
+	jobs, err := loadJobs(*jobsPath, scraper.Job{Source: *source, Brand: *brand, Model: *model, URL: *modelURL})
 	if err != nil {
 		log.Fatal(err)
 	}
-	defer database.Close()
-
-	// 2. Load manual overrides
-	if err := LoadOverrides("../../data/manual_overrides.json"); err != nil {
-		log.Printf("Warning: Failed to load overrides: %v", err)
+	jobs = filterJobs(jobs, *only)
+	if len(jobs) == 0 {
+		log.Fatalf("No jobs match %q", *only)
 	}
 
-	// 3. Ensure Audi and A3 exist
-	brandID := ensureBrand("Audi")
-	modelID := ensureModel(brandID, "A3")
+	// Ctrl-C stops after the page being fetched
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
 
-	// 4. Setup Collector
-	c := colly.NewCollector(
-		colly.UserAgent("Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/91.0.4472.124 Safari/537.36"),
-	)
-	c.SetRequestTimeout(120 * time.Second)
+	fetchOpts := fetch.DefaultOptions()
+	fetchOpts.Delay = *delay
+	fetchOpts.MaxConcurrent = *concurrency
+	fetchOpts.MaxAge = *maxAge
+	state, err := config.ResolvePath(*stateDir)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if !*noCache {
+		fetchOpts.CacheDir = filepath.Join(state, "cache")
+	}
 
-	// Rate Limiting
-	// c.Limit(&colly.LimitRule{
-	// 	DomainGlob:  "*ultimatespecs.com*",
-	// 	Delay:       1 * time.Second,
-	// 	RandomDelay: 1 * time.Second,
-	// })
+	if *record != "" {
+		// Record what the sites serve now, not what the cache holds
+		fetchOpts.CacheDir = ""
+		recordFixtures(ctx, jobs, *record, fetchOpts)
+		return
+	}
 
-	c.OnError(func(r *colly.Response, err error) {
-		log.Printf("Request URL: %s failed with error: %v", r.Request.URL, err)
-	})
+	// 1. Initialize DB
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		log.Fatal(err)
+	}
+	database, err := storage.OpenAndMigrate(cfg)
+	if err != nil {
+		log.Fatal(err)
+	}
+	log.Printf("Using database: %s", cfg.Database.Path)
+	defer database.Close()
+	if base.policy, err = merge.LoadPolicy(cfg.MergePolicyPath); err != nil {
+		log.Fatal(err)
+	}
 
-	// --- HANDLERS ---
+	// 2. Load manual overrides
+	overridesPath, err := config.ResolvePath("data/manual_overrides.json")
+	if err == nil {
+		err = LoadOverrides(overridesPath)
+	}
+	if err != nil {
+		log.Printf("Warning: Failed to load overrides: %v", err)
+	}
 
-	// A. Audi A3 Main Page -> Find Generations
-	c.OnHTML("div.home_models_line", func(e *colly.HTMLElement) {
-		genTitle := strings.TrimSpace(e.ChildText("h2"))
-		if genTitle == "" {
-			return
+	// 3. Crawl every job through one fetch client, so limits hold across jobs
+	fetcher := scraper.NewHTTPFetcher(fetch.New(fetchOpts))
+	for _, job := range jobs {
+		src, err := scraper.New(job.Source, fetcher)
+		if err != nil {
+			log.Fatal(err)
+		}
+		frontier, err := scraper.OpenFrontier(filepath.Join(state, "frontier", job.Key()+".json"), *resume)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if done := frontier.Counts()[scraper.PageDone]; done > 0 {
+			fmt.Printf("Resuming %s %s: %d trims already stored\n", job.Brand, job.Model, done)
 		}
 
-		fmt.Printf("Found Generation Block: %s\n", genTitle)
-
-		// Parse Generation Code (e.g., "Type 8Y" -> "8Y")
-		genCode := parseGenerationCode(genTitle)
-
-		// Create Generation in DB
-		genID := ensureGeneration(modelID, genCode, genTitle)
-
-		// Iterate over Body Styles
-		e.ForEach("a", func(_ int, el *colly.HTMLElement) {
-			link := el.Attr("href")
-			bodyStyleName := strings.TrimSpace(el.ChildText("h3"))
-
-			if link != "" && bodyStyleName != "" {
-				if strings.HasPrefix(link, "/") {
-					link = "https://www.ultimatespecs.com" + link
-				}
-
-				fmt.Printf("  -> Body Style: %s (%s)\n", bodyStyleName, link)
-
-				fmt.Printf("  -> Body Style: %s (%s)\n", bodyStyleName, link)
-
-				// URL Hack: Pass context via URL query params
-				u, _ := url.Parse(link)
-				q := u.Query()
-				q.Set("gen_id", strconv.FormatInt(genID, 10))
-				u.RawQuery = q.Encode()
-
-				c.Visit(u.String())
-			}
-		})
-	})
-
-	// B. Body Style Page -> Find Trims (Engines)
-	c.OnHTML("div.content-wrapper", func(e *colly.HTMLElement) {
-		e.ForEach("a", func(_ int, el *colly.HTMLElement) {
-			link := el.Attr("href")
-			text := strings.TrimSpace(el.Text)
+		fmt.Printf("Starting %s scraper for %s %s...\n", src.Name(), job.Brand, job.Model)
+		st := base
+		st.actor = service.CommandActor("scraper:" + src.Name())
+		db := st.bind(database)
+		brandID, err := db.ensureBrand(job.Brand)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if brandID == 0 {
+			continue
+		}
+		modelID, err := db.ensureModel(brandID, job.Model)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if modelID == 0 {
+			continue
+		}
+		sink := &catalogueSink{
+			store:       db,
+			sourceType:  src.SourceType(),
+			modelID:     modelID,
+			generations: make(map[string]int64),
+		}
+		stats, err := scraper.Crawl(ctx, src, job, sink, frontier)
+		if err != nil {
+			log.Printf("Error crawling %s %s: %v", job.Brand, job.Model, err)
+		}
+		fmt.Printf("%s %s: %d generations, %d trims, %d skipped, %d failed\n", job.Brand, job.Model, stats.Generations, stats.Trims, stats.Skipped, stats.Failed)
+		if ctx.Err() != nil {
+			fmt.Println("Interrupted; run again with -resume to continue")
+			break
+		}
+	}
 
-			// Filter irrelevant links
-			if strings.Contains(link, "car-specs/Audi") && !strings.Contains(text, "Versions") && !strings.Contains(text, "View more") {
-				if strings.HasPrefix(link, "/") {
-					link = "https://www.ultimatespecs.com" + link
-				}
+	fmt.Printf("\nScraped %d trims, %d with data-quality issues (see GET /api/review/issues)\n", base.counts.scraped, base.counts.flagged)
+	fmt.Printf("Skipped %d unchanged pages; found %d changed values (see GET /api/changes)\n", base.counts.unchanged, base.counts.changed)
+}
 
-				// Verify it's a trim page (usually ends in .html)
-				if strings.HasSuffix(link, ".html") {
-					// Check params from URL (Handler A passes it)
-					qReq := e.Request.URL.Query()
-					genIDStr := qReq.Get("gen_id")
-
-					if genIDStr == "" {
-						// Fallback to context
-						genIDStr = e.Request.Ctx.Get("gen_id")
-					}
-
-					if genIDStr == "" {
-						return
-					}
-
-					fmt.Printf("    -> Found Trim: %s\n", text)
-
-					// URL Hack: Pass context via URL query params to ensure persistence
-					u, _ := url.Parse(link)
-					q := u.Query()
-					q.Set("gen_id", genIDStr)
-					q.Set("trim_name", text)
-					q.Set("fuel_type", parseFuelType(text))
-					u.RawQuery = q.Encode()
-
-					// Use Visit instead of Request to ensure full Colly pipeline with URL params
-					c.Visit(u.String())
-				}
-			}
-		})
-	})
+// recordFixtures crawls each job live and stores every page it fetched, the
+// job and the trims extracted as a fixture set for the adapter tests
+func recordFixtures(ctx context.Context, jobs []scraper.Job, dir string, opts fetch.Options) {
+	for _, job := range jobs {
+		setDir := filepath.Join(dir, job.Key())
+		opts.Transport = &scraper.RecordingTransport{Dir: filepath.Join(setDir, scraper.FixturePagesDir)}
+		src, err := scraper.New(job.Source, scraper.NewHTTPFetcher(fetch.New(opts)))
+		if err != nil {
+			log.Fatal(err)
+		}
 
-	// C. Trim Page -> Parse Specs
-	c.OnHTML("body", func(e *colly.HTMLElement) {
-		// Parse from URL
-		q := e.Request.URL.Query()
-		trimName := q.Get("trim_name")
+		fmt.Printf("Recording %s %s from %s into %s...\n", job.Brand, job.Model, src.Name(), setDir)
+		collected := &scraper.Collector{}
+		stats, err := scraper.Crawl(ctx, src, job, collected, nil)
+		if err != nil {
+			log.Fatalf("Error crawling %s %s: %v", job.Brand, job.Model, err)
+		}
+		if err := scraper.WriteJSON(filepath.Join(setDir, scraper.FixtureJobFile), job); err != nil {
+			log.Fatal(err)
+		}
+		if err := scraper.WriteJSON(filepath.Join(setDir, scraper.FixtureGoldenFile), collected); err != nil {
+			log.Fatal(err)
+		}
+		fmt.Printf("%s %s: %d generations, %d trims, %d failed\n", job.Brand, job.Model, stats.Generations, stats.Trims, stats.Failed)
+	}
+}
 
-		if trimName == "" {
-			// Try context as fallback
-			trimName = e.Request.Ctx.Get("trim_name")
+// loadJobs returns the single job given by flags, or the jobs file
+func loadJobs(path string, single scraper.Job) ([]scraper.Job, error) {
+	if single.Source != "" {
+		if err := single.Validate(); err != nil {
+			return nil, err
 		}
+		return []scraper.Job{single}, nil
+	}
+	resolved, err := config.ResolvePath(path)
+	if err != nil {
+		return nil, err
+	}
+	return scraper.LoadJobs(resolved)
+}
 
-		if trimName == "" {
-			return
+// filterJobs keeps the jobs matching "Brand" or "Brand/Model" (any case)
+func filterJobs(jobs []scraper.Job, only string) []scraper.Job {
+	if only == "" {
+		return jobs
+	}
+	brand, model, _ := strings.Cut(only, "/")
+
+	var kept []scraper.Job
+	for _, job := range jobs {
+		if strings.EqualFold(job.Brand, brand) && (model == "" || strings.EqualFold(job.Model, model)) {
+			kept = append(kept, job)
 		}
+	}
+	return kept
+}
 
-		// Create context manually from URL params
-		ctxMock := colly.NewContext()
-		ctxMock.Put("gen_id", q.Get("gen_id"))
-		ctxMock.Put("trim_name", trimName)
-		ctxMock.Put("fuel_type", q.Get("fuel_type"))
+// store saves what the crawl finds through the repositories and services
+// bound to db, acting as actor
+type store struct {
+	db          repository.Querier
+	actor       models.Actor
+	policy      merge.Policy
+	holdChanges bool // leave the changes of re-scraped trims pending for review (-apply review)
+	counts      *runCounts
+
+	provenance *repository.ProvenanceRepository // the page every scraped value came from
+	trimRepo   *repository.TrimRepository
+	modelRepo  *repository.ModelRepository // models take the body style of their scraped trims
+	auditLog   *repository.AuditRepository
+	reviews    *service.ReviewService // data-quality issues of scraped trims
+	trims      *service.TrimService
+	changes    *service.ChangeService // page fingerprints and what re-scraped pages changed
+	merger     *service.MergeService  // merges re-scraped values into stored trims
+}
 
-		fmt.Printf("      -> Scraping Specs for: %s\n", trimName)
+// runCounts are the totals a run reports
+type runCounts struct {
+	scraped, flagged   int // trims saved or merged, and those with data-quality issues
+	unchanged, changed int // pages skipped as unchanged, and field changes found on the others
+}
 
-		// Parse table data
-		specs := make(map[string]string)
-		e.ForEach("table tr", func(_ int, el *colly.HTMLElement) {
-			label := strings.TrimSpace(el.ChildText("td:nth-child(1)"))
-			value := strings.TrimSpace(el.ChildText("td:nth-child(2)"))
-			if label != "" {
-				label = strings.TrimSuffix(label, ":")
-				label = strings.TrimSpace(label)
-				specs[label] = value
-			}
-		})
+// bind returns a copy of s whose repositories and services run on db
+func (s store) bind(db repository.Querier) *store {
+	s.db = db
+	s.provenance = repository.NewProvenanceRepository(db)
+	s.trimRepo = repository.NewTrimRepository(db)
+	s.modelRepo = repository.NewModelRepository(db)
+	s.auditLog = repository.NewAuditRepository(db)
+	generations := repository.NewGenerationRepository(db)
+	s.reviews = service.NewReviewService(repository.NewReviewRepository(db), s.trimRepo, generations)
+	s.trims = service.NewTrimService(s.trimRepo, s.modelRepo, generations, s.provenance, s.reviews, s.auditLog).As(s.actor)
+	s.changes = service.NewChangeService(repository.NewChangeRepository(db), s.trims, s.provenance).As(s.actor)
+	s.merger = service.NewMergeService(s.trims, s.provenance, repository.NewMergeConflictRepository(db), s.policy).As(s.actor)
+	return &s
+}
 
-		saveTrim(ctxMock, specs)
+// inTx runs fn with s bound to a transaction, so a row and its audit entry,
+// citations and review are stored together or not at all
+func (s *store) inTx(fn func(tx *store) error) error {
+	return repository.Transact(s.db, func(tx repository.Querier) error {
+		return fn(s.bind(tx))
 	})
+}
 
-	fmt.Println("Starting Scraper for Audi A3...")
-	c.Visit("https://www.ultimatespecs.com/car-specs/Audi-models/Audi-A3")
+// catalogueSink stores the generations and trims of one job's model
+type catalogueSink struct {
+	*store
+	sourceType  string
+	modelID     int64
+	generations map[string]int64 // generation code -> id
+}
 
-	// Save validation report
-	if err := SaveReport("validation_report.json"); err != nil {
-		log.Printf("Warning: Failed to save validation report: %v", err)
+func (s *catalogueSink) Generation(job scraper.Job, gen scraper.Generation) error {
+	fmt.Printf("Found Generation Block: %s\n", gen.Name)
+	id, err := s.ensureGeneration(s.modelID, gen)
+	if err != nil {
+		return err
 	}
+	if id == 0 {
+		return fmt.Errorf("generation %s was not stored", gen.Code)
+	}
+	s.generations[gen.Code] = id
+	return nil
+}
+
+func (s *catalogueSink) Trim(job scraper.Job, gen scraper.Generation, trim *scraper.Trim) error {
+	fmt.Printf("      -> Scraping Specs for: %s\n", trim.Name)
+	return s.saveTrim(job, s.sourceType, s.modelID, s.generations[gen.Code], trim)
 }
 
 // --- DB HELPERS ---
 
-func ensureBrand(name string) int64 {
+// ensureBrand returns the brand's ID, creating it if needed, or 0 when it is
+// in the trash. The ensure helpers never add rows under a deleted one, and
+// store each row they create together with its audit entry.
+func (s *store) ensureBrand(name string) (int64, error) {
 	var id int64
-	err := database.QueryRow("SELECT id FROM brands WHERE name = ?", name).Scan(&id)
-	if err == sql.ErrNoRows {
-		res, err := database.Exec("INSERT INTO brands (name) VALUES (?)", name)
+	err := s.inTx(func(tx *store) error {
+		var deleted bool
+		err := tx.db.QueryRow("SELECT id, deleted_at IS NOT NULL FROM brands WHERE name = ?", name).Scan(&id, &deleted)
+		if deleted {
+			fmt.Printf("Skipping brand %s: it is in the trash\n", name)
+			id = 0
+			return nil
+		}
+		if err != sql.ErrNoRows {
+			return err
+		}
+		res, err := tx.db.Exec("INSERT INTO brands (name) VALUES (?)", name)
 		if err != nil {
-			log.Fatal(err)
+			return fmt.Errorf("failed to create brand %s: %w", name, err)
+		}
+		if id, err = res.LastInsertId(); err != nil {
+			return err
 		}
-		id, _ = res.LastInsertId()
 		fmt.Printf("Created Brand: %s (ID: %d)\n", name, id)
+		return tx.auditCreate(models.EntityBrand, id, &models.Brand{ID: id, Name: name})
+	})
+	if err != nil {
+		return 0, err
 	}
-	return id
+	return id, nil
 }
 
-func ensureModel(brandID int64, name string) int64 {
+func (s *store) ensureModel(brandID int64, name string) (int64, error) {
 	var id int64
-	err := database.QueryRow("SELECT id FROM models WHERE brand_id = ? AND name = ?", brandID, name).Scan(&id)
-	if err == sql.ErrNoRows {
-		res, err := database.Exec("INSERT INTO models (brand_id, name) VALUES (?, ?)", brandID, name)
+	err := s.inTx(func(tx *store) error {
+		var deleted bool
+		err := tx.db.QueryRow("SELECT id, deleted_at IS NOT NULL FROM models WHERE brand_id = ? AND name = ?", brandID, name).Scan(&id, &deleted)
+		if deleted {
+			fmt.Printf("Skipping model %s: it is in the trash\n", name)
+			id = 0
+			return nil
+		}
+		if err != sql.ErrNoRows {
+			return err
+		}
+		res, err := tx.db.Exec("INSERT INTO models (brand_id, name) VALUES (?, ?)", brandID, name)
 		if err != nil {
-			log.Fatal(err)
+			return fmt.Errorf("failed to create model %s: %w", name, err)
+		}
+		if id, err = res.LastInsertId(); err != nil {
+			return err
 		}
-		id, _ = res.LastInsertId()
 		fmt.Printf("Created Model: %s (ID: %d)\n", name, id)
+		return tx.auditCreate(models.EntityModel, id, &models.Model{ID: id, BrandID: brandID, Name: name})
+	})
+	if err != nil {
+		return 0, err
 	}
-	return id
+	return id, nil
 }
 
-func ensureGeneration(modelID int64, code, name string) int64 {
-	var id int64
-	err := database.QueryRow("SELECT id FROM generations WHERE model_id = ? AND code = ?", modelID, code).Scan(&id)
-
-	startYear, endYear := parseYears(name)
+// setModelBodyStyle fills in a model's body style from one of its scraped
+// trims. Body style belongs to the model, not the trim; a model sold in
+// several body styles keeps the one stored first.
+func (s *store) setModelBodyStyle(modelID int64, bodyStyle string) error {
+	if bodyStyle == "" {
+		return nil
+	}
+	return s.inTx(func(tx *store) error {
+		before, err := tx.modelRepo.GetByID(modelID, false)
+		if err != nil {
+			return fmt.Errorf("failed to read model %d: %w", modelID, err)
+		}
+		if before.BodyStyle != nil {
+			return nil
+		}
+		after := *before
+		after.BodyStyle = &bodyStyle
+		if err := tx.modelRepo.Update(&after); err != nil {
+			return fmt.Errorf("failed to set the body style of model %d: %w", modelID, err)
+		}
+		fmt.Printf("Set body style of model %s: %s\n", before.Name, bodyStyle)
+		return tx.auditLog.Record(tx.actor, models.EntityModel, modelID, models.AuditUpdate, before, &after)
+	})
+}
 
-	if err == sql.ErrNoRows {
-		res, err := database.Exec(`
+func (s *store) ensureGeneration(modelID int64, gen scraper.Generation) (int64, error) {
+	var id int64
+	err := s.inTx(func(tx *store) error {
+		var deleted bool
+		err := tx.db.QueryRow("SELECT id, deleted_at IS NOT NULL FROM generations WHERE model_id = ? AND code = ?", modelID, gen.Code).Scan(&id, &deleted)
+		if deleted {
+			fmt.Printf("Skipping generation %s: it is in the trash\n", gen.Code)
+			id = 0
+			return nil
+		}
+		if err != sql.ErrNoRows {
+			return err
+		}
+		res, err := tx.db.Exec(`
 			INSERT INTO generations (model_id, code, name, start_year, end_year) 
 			VALUES (?, ?, ?, ?, ?)`,
-			modelID, code, name, startYear, endYear)
+			modelID, gen.Code, gen.Name, gen.StartYear, nullYear(gen.EndYear))
 		if err != nil {
-			log.Printf("Error creating generation %s: %v", name, err)
-			return 0
+			return fmt.Errorf("failed to create generation %s: %w", gen.Name, err)
+		}
+		if id, err = res.LastInsertId(); err != nil {
+			return err
 		}
-		id, _ = res.LastInsertId()
-		fmt.Printf("Created Generation: %s (ID: %d)\n", code, id)
+		fmt.Printf("Created Generation: %s (ID: %d)\n", gen.Code, id)
+		name := gen.Name
+		return tx.auditCreate(models.EntityGeneration, id, &models.Generation{
+			ID: id, ModelID: modelID, Code: gen.Code, Name: &name, StartYear: gen.StartYear, EndYear: gen.EndYear,
+		})
+	})
+	if err != nil {
+		return 0, err
 	}
-	return id
+	return id, nil
 }
 
-func saveTrim(ctx *colly.Context, specs map[string]string) {
-	genID, _ := strconv.ParseInt(ctx.Get("gen_id"), 10, 64)
-	name := ctx.Get("trim_name")
-	fuelType := ctx.Get("fuel_type")
+// auditCreate records a row the scraper inserted in the audit log
+func (s *store) auditCreate(entityType string, id int64, row interface{}) error {
+	return s.auditLog.Record(s.actor, entityType, id, models.AuditCreate, nil, row)
+}
 
-	// Parse Specs
-	// Normalized keys: "Horsepower", "Maximum torque"
-	hpStr := specs["Horsepower"]
-	if hpStr == "" {
-		hpStr = specs["Power"]
+func nullYear(year *int) sql.NullInt64 {
+	if year == nil {
+		return sql.NullInt64{}
 	}
+	return sql.NullInt64{Int64: int64(*year), Valid: true}
+}
 
-	hp := parseHP(hpStr)
-
-	// Fetch Model ID from Generation
-	var modelID int64
-	err := database.QueryRow("SELECT model_id FROM generations WHERE id = ?", genID).Scan(&modelID)
-	if err != nil {
-		if genID == 0 {
-			modelID = 0
+// saveTrim stores a scraped trim, or merges a re-scraped page into the trim
+// stored from it. Each page is stored in one transaction.
+func (s *store) saveTrim(job scraper.Job, sourceType string, modelID, genID int64, t *scraper.Trim) error {
+	// A page that yields what it did last time has nothing new to store
+	fingerprint := t.Fingerprint()
+	if t.SourceURL != "" {
+		same, err := s.changes.PageUnchanged(t.SourceURL, fingerprint)
+		if err != nil {
+			return err
+		}
+		if same {
+			s.counts.unchanged++
+			fmt.Printf("      = Unchanged since the last scrape: %s\n", t.Name)
+			return nil
 		}
 	}
 
-	// Parse production years
-	prodYears := specs["Production years"]
-	if prodYears == "" {
-		prodYears = specs["Years"]
-	}
-	startYear, endYear := parseYears(prodYears)
+	name := t.Name
+	startYear, endYear := t.StartYear, nullYear(t.EndYear)
 
-	// Parse Year from trim name or generation or use startYear
-	nameYear := parseTrimYear(name)
+	// Year from the trim name, else the start of production
 	if startYear == 0 {
-		startYear = nameYear
+		startYear = t.Year
 	}
-
-	year := nameYear
+	year := t.Year
 	if year == 0 {
 		year = startYear
 	}
 
 	// Fallback: If still no years, try to get from generation overrides
+	yearsFromOverride := false
 	if startYear == 0 {
-		// Get generation code from database
 		var genCode string
-		err := database.QueryRow("SELECT code FROM generations WHERE id = ?", genID).Scan(&genCode)
+		err := s.db.QueryRow("SELECT code FROM generations WHERE id = ?", genID).Scan(&genCode)
 		if err == nil && genCode != "" {
-			// Try to get years from overrides
-			overrideStart, overrideEnd, found := GetGenerationYears("Audi", "A3", genCode)
+			overrideStart, overrideEnd, found := GetGenerationYears(job.Brand, job.Model, genCode)
 			if found {
 				startYear = overrideStart
+				yearsFromOverride = true
 				if overrideEnd != nil {
 					endYear.Valid = true
 					endYear.Int64 = int64(*overrideEnd)
@@ -296,113 +494,218 @@ func saveTrim(ctx *colly.Context, specs map[string]string) {
 		}
 	}
 
-	// Check duplicate
-	var exists int
-	database.QueryRow("SELECT 1 FROM trims WHERE generation_id = ? AND name = ?", genID, name).Scan(&exists)
-	if exists == 1 {
-		fmt.Printf("Skipping existing trim: %s\n", name)
-		return
+	cited := scrapedValues{
+		sourceType: sourceType, trim: t, year: year,
+		startYear: startYear, endYear: endYear, yearsFromOverride: yearsFromOverride,
 	}
 
-	// Validate data quality
-	validationReport := ValidateTrimData(name, year, startYear, endYear, hp, specs)
-	AddToReport(validationReport)
+	return s.inTx(func(tx *store) error {
+		// An existing trim is diffed against the page; a deleted one stays deleted
+		var existingID int64
+		var deleted bool
+		tx.db.QueryRow("SELECT id, deleted_at IS NOT NULL FROM trims WHERE generation_id = ? AND name = ?", genID, name).Scan(&existingID, &deleted)
+		if deleted {
+			fmt.Printf("      - Skipped, in the trash: %s\n", name)
+			return nil
+		}
+		if err := tx.setModelBodyStyle(modelID, t.BodyStyle); err != nil {
+			return err
+		}
+		if existingID != 0 {
+			if err := tx.updateScrapedTrim(existingID, cited); err != nil {
+				return err
+			}
+			return tx.recordPage(t, existingID, sourceType, fingerprint)
+		}
 
-	_, err = database.Exec(`
-		INSERT INTO trims (generation_id, model_id, name, year, start_year, end_year, fuel_type, power_hp, created_at, updated_at)
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)
-	`, genID, modelID, name, year, sql.NullInt64{Int64: int64(startYear), Valid: startYear != 0}, endYear, fuelType, hp)
+		trimID, err := tx.createScrapedTrim(genID, cited)
+		if err != nil {
+			return err
+		}
+		return tx.recordPage(t, trimID, sourceType, fingerprint)
+	})
+}
 
-	if err != nil {
-		log.Printf("Error insert trim %s: %v", name, err)
-	} else {
-		fmt.Printf("Saved Trim: %s (HP: %d, Years: %d-%v)\n", name, hp, startYear, endYear.Int64)
+// createScrapedTrim stores a trim first found on a page. Values the page
+// didn't give stay NULL. The trim service audits it; the page is cited
+// here, since the years may come from the overrides file instead.
+func (s *store) createScrapedTrim(genID int64, v scrapedValues) (int64, error) {
+	trim := &models.Trim{GenerationID: genID, Name: v.trim.Name, Year: v.year}
+	if v.startYear != 0 {
+		startYear := v.startYear
+		trim.StartYear = &startYear
+	}
+	if v.endYear.Valid {
+		endYear := int(v.endYear.Int64)
+		trim.EndYear = &endYear
 	}
+	if v.trim.FuelType != "" {
+		fuelType := v.trim.FuelType
+		trim.FuelType = &fuelType
+	}
+	if v.trim.PowerHP != 0 {
+		powerHP := v.trim.PowerHP
+		trim.PowerHP = &powerHP
+	}
+	if err := s.trims.CreateTrim(trim, nil, nil); err != nil {
+		return 0, err
+	}
+	fmt.Printf("Saved Trim: %s (HP: %d, Years: %d-%v)\n", trim.Name, v.trim.PowerHP, v.startYear, v.endYear.Int64)
+
+	if err := s.citeScrapedTrim(trim.ID, v); err != nil {
+		return 0, fmt.Errorf("failed to record sources for %s: %w", trim.Name, err)
+	}
+	if err := s.reviewScrapedTrim(trim.ID, v.trim.ProductionYears, v.sourceType); err != nil {
+		return 0, err
+	}
+	return trim.ID, nil
 }
 
-// --- PARSERS ---
+// updateScrapedTrim records the values a re-scraped page reports differently
+// from the stored trim as changes. Unless changes are held for review, the
+// page is then cited as one more source and merged in; changes the merge
+// doesn't take (a more trusted source disagrees) stay pending.
+func (s *store) updateScrapedTrim(trimID int64, v scrapedValues) error {
+	found, err := s.changes.DetectChanges(trimID, v.pageValues(), v.page())
+	if err != nil {
+		return fmt.Errorf("failed to detect changes: %w", err)
+	}
+	s.counts.changed += len(found)
+	for _, c := range found {
+		fmt.Printf("      Δ %s.%s: %s -> %s\n", v.trim.Name, c.Field, c.OldValue, c.NewValue)
+	}
+	if s.holdChanges {
+		return nil
+	}
 
-func parseTrimYear(name string) int {
-	re := regexp.MustCompile(`\b(19|20)\d{2}\b`)
-	matches := re.FindStringSubmatch(name)
-	if len(matches) > 0 {
-		y, _ := strconv.Atoi(matches[0])
-		return y
+	if err := s.citeScrapedTrim(trimID, v); err != nil {
+		return fmt.Errorf("failed to record sources: %w", err)
+	}
+	result, err := s.merger.MergeTrim(trimID, false)
+	if err != nil {
+		return fmt.Errorf("failed to merge: %w", err)
+	}
+	for _, f := range result.Fields {
+		if f.Status != models.MergeUnchanged {
+			fmt.Printf("Merged %s.%s: %s (%s -> %s)\n", v.trim.Name, f.Field, f.Status, f.Previous, f.Value)
+		}
 	}
-	return 0
+	if _, err := s.changes.SettleChanges(trimID); err != nil {
+		return err
+	}
+	return s.reviewScrapedTrim(trimID, v.trim.ProductionYears, v.sourceType)
 }
 
-func parseGenerationCode(s string) string {
-	re := regexp.MustCompile(`Type\s+(\w+)`)
-	matches := re.FindStringSubmatch(s)
-	if len(matches) > 1 {
-		return matches[1]
+// recordPage stores the fingerprint of the page a trim was scraped from
+func (s *store) recordPage(t *scraper.Trim, trimID int64, sourceType, fingerprint string) error {
+	if t.SourceURL == "" {
+		return nil
 	}
-	return "Unknown"
+	return s.changes.RecordPage(t.SourceURL, trimID, sourceType, fingerprint)
 }
 
-func parseYears(s string) (int, sql.NullInt64) {
-	s = strings.TrimSpace(s)
-	if s == "" {
-		return 0, sql.NullInt64{}
+// reviewScrapedTrim validates a saved trim, passing the raw production years
+// so an end year that failed to parse is flagged
+func (s *store) reviewScrapedTrim(trimID int64, prodYears, sourceType string) error {
+	trim, err := s.trimRepo.GetByID(trimID, false)
+	if err != nil {
+		return fmt.Errorf("failed to review trim %d: %w", trimID, err)
 	}
-
-	// Pattern 1: (2013 - 2016) or (2013 - Present) or 2013 - 2016
-	re := regexp.MustCompile(`(\d{4})\s*-\s*(\d{4}|Present)`)
-	matches := re.FindStringSubmatch(s)
-	if len(matches) > 1 {
-		start, _ := strconv.Atoi(matches[1])
-		var end sql.NullInt64
-		if matches[2] != "Present" {
-			e, _ := strconv.Atoi(matches[2])
-			end = sql.NullInt64{Int64: int64(e), Valid: true}
+	issues, err := s.reviews.ReviewTrim(validation.Input{Trim: trim, ProductionYears: prodYears}, sourceType)
+	if err != nil {
+		return fmt.Errorf("failed to review trim %d: %w", trimID, err)
+	}
+	s.counts.scraped++
+	if len(issues) > 0 {
+		s.counts.flagged++
+		for _, issue := range issues {
+			fmt.Printf("      ⚠ %s: %s\n", issue.Field, issue.Message)
 		}
-		return start, end
 	}
+	return nil
+}
 
-	// Pattern 2: Single year like "2024" or "(2024)"
-	reSingle := regexp.MustCompile(`(\d{4})`)
-	match := reSingle.FindStringSubmatch(s)
-	if len(match) > 1 {
-		start, _ := strconv.Atoi(match[1])
-		return start, sql.NullInt64{}
-	}
+// scrapedValues are the values saveTrim stores for a trim page
+type scrapedValues struct {
+	sourceType        string
+	trim              *scraper.Trim
+	year, startYear   int
+	endYear           sql.NullInt64
+	yearsFromOverride bool
+}
 
-	return 0, sql.NullInt64{}
+// page is the source document of the trim page
+func (v scrapedValues) page() *models.SourceDocument {
+	now := time.Now().UTC()
+	name := v.trim.Name
+	return &models.SourceDocument{URL: v.trim.SourceURL, Title: &name, SourceType: v.sourceType, RetrievedAt: &now}
 }
 
-func parseBodyStyle(raw string) string {
-	if strings.Contains(raw, "Sportback") {
-		return "Sportback"
+// pageValues are the trim values read from the trim page itself (not the
+// name, which matched it to the trim, nor years taken from the overrides
+// file, nor the body style, which is the model's)
+func (v scrapedValues) pageValues() map[string]interface{} {
+	values := map[string]interface{}{}
+	if v.year != 0 {
+		values["year"] = v.year
 	}
-	if strings.Contains(raw, "Sedan") || strings.Contains(raw, "Limousine") {
-		return "Sedan"
+	if v.trim.PowerHP != 0 {
+		values["power_hp"] = v.trim.PowerHP
 	}
-	if strings.Contains(raw, "Cabrio") {
-		return "Cabriolet"
+	if v.trim.FuelType != "" {
+		values["fuel_type"] = v.trim.FuelType
 	}
-	return "Hatchback"
+	if !v.yearsFromOverride {
+		if v.startYear != 0 {
+			values["start_year"] = v.startYear
+		}
+		if v.endYear.Valid {
+			values["end_year"] = v.endYear.Int64
+		}
+	}
+	return values
 }
 
-func parseFuelType(name string) string {
-	if strings.Contains(name, "TFSI") {
-		return "Petrol"
+// citeScrapedTrim records the trim page as the source of the scraped values
+// and the manual overrides file as the source of years taken from it
+func (s *store) citeScrapedTrim(trimID int64, v scrapedValues) error {
+	now := time.Now().UTC()
+	name := v.trim.Name
+	page := v.page()
+	if err := s.provenance.EnsureSource(page); err != nil {
+		return err
 	}
-	if strings.Contains(name, "TDI") {
-		return "Diesel"
+
+	values := map[string]interface{}{"name": name}
+	if v.year != 0 {
+		values["year"] = v.year
 	}
-	if strings.Contains(name, "e-tron") || strings.Contains(name, "TFSIe") {
-		return "Hybrid"
+	if v.trim.PowerHP != 0 {
+		values["power_hp"] = v.trim.PowerHP
+	}
+	if err := s.provenance.CiteFields(trimID, values, page.ID, ""); err != nil {
+		return err
+	}
+	if v.trim.FuelType != "" {
+		if err := s.provenance.CiteField(trimID, "fuel_type", v.trim.FuelType, page.ID, v.trim.Notes["fuel_type"]); err != nil {
+			return err
+		}
 	}
-	return "Petrol"
-}
 
-func parseHP(val string) int {
-	re := regexp.MustCompile(`(\d+)\s*PS`)
-	m := re.FindStringSubmatch(val)
-	if len(m) > 1 {
-		v, _ := strconv.Atoi(m[1])
-		return v
+	years := map[string]interface{}{}
+	if v.startYear != 0 {
+		years["start_year"] = v.startYear
+	}
+	if v.endYear.Valid {
+		years["end_year"] = v.endYear.Int64
+	}
+	yearSource := page
+	if v.yearsFromOverride {
+		title := "Manual generation overrides"
+		yearSource = &models.SourceDocument{URL: overridesSourceURL, Title: &title, SourceType: models.SourceManual, RetrievedAt: &now}
+		if err := s.provenance.EnsureSource(yearSource); err != nil {
+			return err
+		}
 	}
-	return 0
+	return s.provenance.CiteFields(trimID, years, yearSource.ID, "")
 }
@@ -0,0 +1,185 @@
+package main
+
+import (
+	"database/sql"
+	"path/filepath"
+	"testing"
+
+	"github.com/emirh/car-specs/backend/internal/merge"
+	"github.com/emirh/car-specs/backend/internal/models"
+	"github.com/emirh/car-specs/backend/internal/scraper"
+	"github.com/emirh/car-specs/backend/internal/service"
+	"github.com/emirh/car-specs/backend/migrations"
+	_ "modernc.org/sqlite"
+)
+
+// openTestDB returns a store on a migrated temporary database
+func openTestDB(t *testing.T) *store {
+	t.Helper()
+	db, err := sql.Open("sqlite", filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("failed to open db: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	migrator, err := migrations.NewMigrator(db)
+	if err != nil {
+		t.Fatalf("failed to load migrations: %v", err)
+	}
+	if err := migrator.Up(); err != nil {
+		t.Fatalf("failed to migrate: %v", err)
+	}
+
+	st := store{actor: service.CommandActor("scraper:ultimatespecs"), policy: merge.DefaultPolicy(), counts: &runCounts{}}
+	return st.bind(db)
+}
+
+func fixtureTrim(powerHP int) *scraper.Trim {
+	return &scraper.Trim{
+		Name:            "35 TFSI",
+		SourceURL:       "https://www.ultimatespecs.com/car-specs/Audi/1/Audi-A3-Sedan-35-TFSI.html",
+		Year:            2021,
+		StartYear:       2020,
+		ProductionYears: "2020 - present",
+		FuelType:        "petrol",
+		BodyStyle:       "sedan",
+		PowerHP:         powerHP,
+		Specs:           map[string]string{"Horsepower": "150 HP"},
+		Notes:           map[string]string{"body_style": "parsed from body style heading"},
+	}
+}
+
+func TestSaveTrim(t *testing.T) {
+	s := openTestDB(t)
+	job := scraper.Job{Source: "ultimatespecs", Brand: "Audi", Model: "A3"}
+	brandID, err := s.ensureBrand(job.Brand)
+	if err != nil {
+		t.Fatalf("ensureBrand() error = %v", err)
+	}
+	modelID, err := s.ensureModel(brandID, job.Model)
+	if err != nil {
+		t.Fatalf("ensureModel() error = %v", err)
+	}
+	genID, err := s.ensureGeneration(modelID, scraper.Generation{Code: "8Y", Name: "Audi A3 Type 8Y", StartYear: 2020})
+	if err != nil {
+		t.Fatalf("ensureGeneration() error = %v", err)
+	}
+
+	if err := s.saveTrim(job, models.SourceUltimateSpecs, modelID, genID, fixtureTrim(150)); err != nil {
+		t.Fatalf("saveTrim() error = %v", err)
+	}
+	var trimID int64
+	var fuelType string
+	var powerHP int
+	err = s.db.QueryRow("SELECT id, fuel_type, power_hp FROM trims WHERE generation_id = ? AND name = '35 TFSI'", genID).
+		Scan(&trimID, &fuelType, &powerHP)
+	if err != nil {
+		t.Fatalf("scraped trim was not stored: %v", err)
+	}
+	if fuelType != "petrol" || powerHP != 150 {
+		t.Errorf("trim = %s, %d hp, want petrol, 150 hp", fuelType, powerHP)
+	}
+
+	// The body style is the model's
+	model, err := s.modelRepo.GetByID(modelID, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if model.BodyStyle == nil || *model.BodyStyle != "sedan" {
+		t.Errorf("model body style = %v, want sedan", model.BodyStyle)
+	}
+
+	citations, err := s.provenance.ListFieldCitations(trimID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, field := range []string{"name", "year", "power_hp", "fuel_type", "start_year"} {
+		if len(citations[field]) != 1 {
+			t.Errorf("%s citations = %+v, want the trim page", field, citations[field])
+		}
+	}
+
+	// A re-scraped page with a new value is merged in
+	if err := s.saveTrim(job, models.SourceUltimateSpecs, modelID, genID, fixtureTrim(163)); err != nil {
+		t.Fatalf("saveTrim() of a re-scraped page error = %v", err)
+	}
+	trim, err := s.trimRepo.GetByID(trimID, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if trim.PowerHP == nil || *trim.PowerHP != 163 {
+		t.Errorf("power_hp after re-scrape = %v, want 163", trim.PowerHP)
+	}
+	if s.counts.changed == 0 {
+		t.Error("re-scrape recorded no changes")
+	}
+
+	// Citing the same page again replaces its citations
+	if err := s.citeScrapedTrim(trimID, scrapedValues{sourceType: models.SourceUltimateSpecs, trim: fixtureTrim(163), year: 2021, startYear: 2020}); err != nil {
+		t.Fatalf("citeScrapedTrim() error = %v", err)
+	}
+	if citations, err = s.provenance.ListFieldCitations(trimID); err != nil {
+		t.Fatal(err)
+	}
+	if len(citations["fuel_type"]) != 1 || len(citations["power_hp"]) != 1 || string(citations["power_hp"][0].Value) != "163" {
+		t.Errorf("citations after citing again = %+v", citations)
+	}
+}
+
+// TestEnsureBrandNeedsAudit checks a brand whose audit entry fails is not stored
+func TestEnsureBrandNeedsAudit(t *testing.T) {
+	s := openTestDB(t)
+	if _, err := s.db.Exec("ALTER TABLE audit_log RENAME TO audit_log_away"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.ensureBrand("Audi"); err == nil {
+		t.Fatal("ensureBrand() without an audit log succeeded")
+	}
+	var count int
+	if err := s.db.QueryRow("SELECT COUNT(*) FROM brands").Scan(&count); err != nil {
+		t.Fatal(err)
+	}
+	if count != 0 {
+		t.Errorf("%d brands stored without an audit entry", count)
+	}
+}
+
+// TestSaveTrimWithoutPower checks a value the page didn't give is stored as
+// NULL, and the trim is audited
+func TestSaveTrimWithoutPower(t *testing.T) {
+	s := openTestDB(t)
+	job := scraper.Job{Source: "ultimatespecs", Brand: "Audi", Model: "A3"}
+	brandID, err := s.ensureBrand(job.Brand)
+	if err != nil {
+		t.Fatal(err)
+	}
+	modelID, err := s.ensureModel(brandID, job.Model)
+	if err != nil {
+		t.Fatal(err)
+	}
+	genID, err := s.ensureGeneration(modelID, scraper.Generation{Code: "8Y", Name: "Audi A3 Type 8Y", StartYear: 2020})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := s.saveTrim(job, models.SourceUltimateSpecs, modelID, genID, fixtureTrim(0)); err != nil {
+		t.Fatalf("saveTrim() error = %v", err)
+	}
+	var trimID int64
+	var powerHP sql.NullInt64
+	if err := s.db.QueryRow("SELECT id, power_hp FROM trims WHERE generation_id = ?", genID).Scan(&trimID, &powerHP); err != nil {
+		t.Fatalf("scraped trim was not stored: %v", err)
+	}
+	if powerHP.Valid {
+		t.Errorf("power_hp = %d, want NULL", powerHP.Int64)
+	}
+	var audited int
+	err = s.db.QueryRow("SELECT COUNT(*) FROM audit_log WHERE entity_type = ? AND entity_id = ? AND action = ?",
+		models.EntityTrim, trimID, models.AuditCreate).Scan(&audited)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if audited != 1 {
+		t.Errorf("trim has %d create audit entries, want 1", audited)
+	}
+}
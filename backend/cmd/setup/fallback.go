@@ -4,40 +4,68 @@ import (
 	"fmt"
 	"log"
 	"strings"
+
+	"github.com/emirh/car-specs/backend/internal/enums"
+	"github.com/emirh/car-specs/backend/internal/models"
 )
 
 // CreateFallbackTrim creates a trim entry with estimated/default values when API Ninjas has no data
 //
 //	This ensures we don't skip cars entirely just because specs aren't available
-func (s *SetupService) CreateFallbackTrim(modelID int64, brand, model string, year int, imageURL, generation string) error {
+func (s *SetupService) CreateFallbackTrim(modelID, generationID int64, brand, model string, year int, imageURL, generation string) error {
 	// Build a reasonable trim name
 	trimName := fmt.Sprintf("%s %d", strings.Title(model), year)
 
 	query := `
 		INSERT INTO trims (
-			model_id, name, year, generation, market,
+			model_id, generation_id, name, year, generation, market,
 			fuel_type, transmission_type,
 			seating_capacity, doors,
 			image_url
-		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 	`
 
-	_, err := s.db.Exec(query,
+	res, err := s.db.Exec(query,
 		modelID,
+		generationID,
 		trimName,
 		year,
 		generation,
-		"TR",       // Turkish market
-		"Gasoline", // Default fuel type
-		"Manual",   // Most common in Turkey
-		5,          // Standard seating
-		4,          // Most cars are 4-door
+		"TR",                     // Turkish market
+		enums.FuelPetrol,         // Default fuel type
+		enums.TransmissionManual, // Most common in Turkey
+		5,                        // Standard seating
+		4,                        // Most cars are 4-door
 		imageURL,
 	)
 
 	if err != nil {
 		return fmt.Errorf("failed to create fall back trim: %w", err)
 	}
+	trimID, err := s.auditCreatedTrim(res)
+	if err != nil {
+		return fmt.Errorf("failed to create fall back trim: %w", err)
+	}
+
+	// Only the name and year come from the curated target list; the rest is guessed
+	targets := map[string]interface{}{"name": trimName, "year": year}
+	if err := s.cite(trimID, "manual://cmd/setup/targets", "Turkish market target list", models.SourceManual, targets, ""); err != nil {
+		return err
+	}
+	if err := s.citeImage(trimID, imageURL); err != nil {
+		return err
+	}
+	defaults := map[string]interface{}{
+		"fuel_type":         enums.FuelPetrol,
+		"transmission_type": enums.TransmissionManual,
+		"doors":             4,
+	}
+	if err := s.citeDefaults(trimID, defaults); err != nil {
+		return err
+	}
+	if _, err := s.reviews.ReviewTrimByID(trimID, models.SourceManual); err != nil {
+		return err
+	}
 
 	log.Printf("  ⚠️  Created fallback entry (no API data available)")
 	return nil
@@ -9,12 +9,16 @@ import (
 	"net/http"
 	"net/url"
 	"os"
-	"path/filepath"
 	"strings"
 	"time"
 
+	"github.com/emirh/car-specs/backend/internal/config"
+	"github.com/emirh/car-specs/backend/internal/enums"
+	"github.com/emirh/car-specs/backend/internal/models"
+	"github.com/emirh/car-specs/backend/internal/repository"
+	"github.com/emirh/car-specs/backend/internal/service"
+	"github.com/emirh/car-specs/backend/internal/storage"
 	"github.com/joho/godotenv"
-	_ "modernc.org/sqlite"
 )
 
 // TargetCar represents a specific car model to scrape for the Turkish market
@@ -39,6 +43,8 @@ type NinjasCarResponse struct {
 	Highway_MPG  interface{} `json:"highway_mpg"`
 	City_MPG     interface{} `json:"city_mpg"`
 	Transmission string      `json:"transmission"`
+
+	SourceURL string `json:"-"` // Query that returned this car, cited as its source
 }
 
 // SerpApi Google Images response structure
@@ -52,6 +58,10 @@ type SerpApiResponse struct {
 
 type SetupService struct {
 	db           *sql.DB
+	provenance   *repository.ProvenanceRepository
+	reviews      *service.ReviewService
+	audit        *repository.AuditRepository
+	actor        models.Actor
 	ninjasAPIKey string
 	serpApiKey   string
 	httpClient   *http.Client
@@ -60,6 +70,10 @@ type SetupService struct {
 func NewSetupService(db *sql.DB) *SetupService {
 	return &SetupService{
 		db:           db,
+		provenance:   repository.NewProvenanceRepository(db),
+		reviews:      service.NewReviewService(repository.NewReviewRepository(db), repository.NewTrimRepository(db), repository.NewGenerationRepository(db)),
+		audit:        repository.NewAuditRepository(db),
+		actor:        service.CommandActor("setup"),
 		ninjasAPIKey: os.Getenv("NINJAS_API_KEY"),
 		serpApiKey:   os.Getenv("SERPAPI_KEY"),
 		httpClient:   &http.Client{Timeout: 30 * time.Second},
@@ -153,96 +167,6 @@ func (s *SetupService) DropAllTables() error {
 	return nil
 }
 
-// CreateSchema creates all tables from scratch
-func (s *SetupService) CreateSchema() error {
-	log.Println("🏗️  Creating fresh schema...")
-
-	migrations := []string{
-		// Brands table
-		`CREATE TABLE IF NOT EXISTS brands (
-			id INTEGER PRIMARY KEY AUTOINCREMENT,
-			name TEXT NOT NULL UNIQUE,
-			country TEXT,
-			logo_url TEXT,
-			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
-			updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
-		)`,
-
-		// Models table
-		`CREATE TABLE IF NOT EXISTS models (
-			id INTEGER PRIMARY KEY AUTOINCREMENT,
-			brand_id INTEGER NOT NULL,
-			name TEXT NOT NULL,
-			body_style TEXT,
-			segment TEXT,
-			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
-			updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
-			FOREIGN KEY (brand_id) REFERENCES brands(id) ON DELETE CASCADE,
-			UNIQUE(brand_id, name)
-		)`,
-
-		// Trims table with image_url
-		`CREATE TABLE IF NOT EXISTS trims (
-			id INTEGER PRIMARY KEY AUTOINCREMENT,
-			model_id INTEGER NOT NULL,
-			name TEXT NOT NULL,
-			year INTEGER NOT NULL,
-			generation TEXT,
-			is_facelift BOOLEAN DEFAULT 0,
-			market TEXT DEFAULT 'TR',
-			engine_type TEXT,
-			fuel_type TEXT,
-			displacement_cc INTEGER,
-			cylinders INTEGER,
-			cylinder_layout TEXT,
-			power_hp INTEGER,
-			power_kw INTEGER,
-			torque_nm INTEGER,
-			engine_code TEXT,
-			acceleration_0_100 REAL,
-			top_speed_kmh INTEGER,
-			fuel_consumption_city REAL,
-			fuel_consumption_highway REAL,
-			fuel_consumption_combined REAL,
-			co2_emissions INTEGER,
-			emission_standard TEXT,
-			transmission_type TEXT,
-			gears INTEGER,
-			drivetrain TEXT,
-			length_mm INTEGER,
-			width_mm INTEGER,
-			height_mm INTEGER,
-			wheelbase_mm INTEGER,
-			ground_clearance_mm INTEGER,
-			curb_weight_kg INTEGER,
-			gross_weight_kg INTEGER,
-			luggage_capacity_l INTEGER,
-			luggage_capacity_max_l INTEGER,
-			fuel_tank_capacity_l INTEGER,
-			tire_size_front TEXT,
-			tire_size_rear TEXT,
-			wheel_size_inches INTEGER,
-			seating_capacity INTEGER DEFAULT 5,
-			doors INTEGER,
-			image_url TEXT,
-			msrp_price REAL,
-			currency TEXT DEFAULT 'TRY',
-			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
-			updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
-			FOREIGN KEY (model_id) REFERENCES models(id) ON DELETE CASCADE
-		)`,
-	}
-
-	for i, migration := range migrations {
-		if _, err := s.db.Exec(migration); err != nil {
-			return fmt.Errorf("failed to run migration %d: %w", i, err)
-		}
-	}
-
-	log.Println("  ✓ Schema created successfully")
-	return nil
-}
-
 // FetchCarFromNinjas queries API Ninjas with improved retry logic
 func (s *SetupService) FetchCarFromNinjas(brand, model string, year int) (*NinjasCarResponse, error) {
 	// Generate multiple query variations to improve match rate
@@ -313,6 +237,7 @@ func (s *SetupService) FetchCarFromNinjas(brand, model string, year int) (*Ninja
 		if len(cars) > 0 {
 			// Success! Found a match
 			log.Printf("  ✓ Found %d variant(s) using query: %s %s %d", len(cars), brand, modelVar, year)
+			cars[0].SourceURL = queryURL
 			return &cars[0], nil
 		}
 
@@ -426,7 +351,11 @@ func toFloat(val interface{}) float64 {
 func (s *SetupService) GetOrCreateBrand(name string) (int64, error) {
 	// Check if exists
 	var id int64
-	err := s.db.QueryRow("SELECT id FROM brands WHERE LOWER(name) = LOWER(?)", name).Scan(&id)
+	var deleted bool
+	err := s.db.QueryRow("SELECT id, deleted_at IS NOT NULL FROM brands WHERE LOWER(name) = LOWER(?)", name).Scan(&id, &deleted)
+	if err == nil && deleted {
+		return 0, fmt.Errorf("brand %s is in the trash", name)
+	}
 	if err == nil {
 		return id, nil
 	}
@@ -437,7 +366,9 @@ func (s *SetupService) GetOrCreateBrand(name string) (int64, error) {
 		return 0, err
 	}
 
-	return result.LastInsertId()
+	return s.auditCreated(result, models.EntityBrand, func(id int64) (interface{}, error) {
+		return repository.NewBrandRepository(s.db).GetByID(id)
+	})
 }
 
 // GetOrCreateModel gets existing model or creates new one
@@ -451,10 +382,14 @@ func (s *SetupService) GetOrCreateModel(brandID int64, name, alias, bodyStyle st
 
 	// Check if exists
 	var id int64
+	var deleted bool
 	err := s.db.QueryRow(
-		"SELECT id FROM models WHERE brand_id = ? AND LOWER(name) = LOWER(?)",
+		"SELECT id, deleted_at IS NOT NULL FROM models WHERE brand_id = ? AND LOWER(name) = LOWER(?)",
 		brandID, modelName,
-	).Scan(&id)
+	).Scan(&id, &deleted)
+	if err == nil && deleted {
+		return 0, fmt.Errorf("model %s is in the trash", modelName)
+	}
 	if err == nil {
 		return id, nil
 	}
@@ -462,17 +397,76 @@ func (s *SetupService) GetOrCreateModel(brandID int64, name, alias, bodyStyle st
 	// Create new
 	result, err := s.db.Exec(
 		"INSERT INTO models (brand_id, name, body_style) VALUES (?, ?, ?)",
-		brandID, modelName, bodyStyle,
+		brandID, modelName, enums.BodyStyle.Canonical(bodyStyle),
 	)
 	if err != nil {
 		return 0, err
 	}
 
-	return result.LastInsertId()
+	return s.auditCreated(result, models.EntityModel, func(id int64) (interface{}, error) {
+		return repository.NewModelRepository(s.db).GetByID(id, false)
+	})
+}
+
+// GetOrCreateGeneration gets existing generation or creates new one.
+// Targets without a generation code are grouped under a per-year generation.
+func (s *SetupService) GetOrCreateGeneration(modelID int64, code string, year int) (int64, error) {
+	if code == "" {
+		code = fmt.Sprintf("%d", year)
+	}
+
+	var id int64
+	var deleted bool
+	err := s.db.QueryRow(
+		"SELECT id, deleted_at IS NOT NULL FROM generations WHERE model_id = ? AND LOWER(code) = LOWER(?)",
+		modelID, code,
+	).Scan(&id, &deleted)
+	if err == nil && deleted {
+		return 0, fmt.Errorf("generation %s is in the trash", code)
+	}
+	if err == nil {
+		return id, nil
+	}
+
+	result, err := s.db.Exec(
+		"INSERT INTO generations (model_id, code, name, start_year) VALUES (?, ?, ?, ?)",
+		modelID, code, code, year,
+	)
+	if err != nil {
+		return 0, err
+	}
+
+	return s.auditCreated(result, models.EntityGeneration, func(id int64) (interface{}, error) {
+		return repository.NewGenerationRepository(s.db).GetByID(id)
+	})
+}
+
+// auditCreated records the row result inserted in the audit log and returns
+// its id. row loads the row for the log.
+func (s *SetupService) auditCreated(result sql.Result, entityType string, row func(id int64) (interface{}, error)) (int64, error) {
+	id, err := result.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+	created, err := row(id)
+	if err != nil {
+		return 0, err
+	}
+	if err := s.audit.Record(s.actor, entityType, id, models.AuditCreate, nil, created); err != nil {
+		return 0, err
+	}
+	return id, nil
+}
+
+// auditCreatedTrim records a trim setup inserted in the audit log and returns its id
+func (s *SetupService) auditCreatedTrim(result sql.Result) (int64, error) {
+	return s.auditCreated(result, models.EntityTrim, func(id int64) (interface{}, error) {
+		return repository.NewTrimRepository(s.db).GetByID(id, false)
+	})
 }
 
 // CreateTrim creates a new trim with Turkish market settings
-func (s *SetupService) CreateTrim(modelID int64, car NinjasCarResponse, imageURL, generation string) error {
+func (s *SetupService) CreateTrim(modelID, generationID int64, car NinjasCarResponse, imageURL, generation string) error {
 	cylinders := toInt(car.Cylinders)
 	displacement := toFloat(car.Displacement)
 	cityMPG := toInt(car.City_MPG)
@@ -492,18 +486,90 @@ func (s *SetupService) CreateTrim(modelID int64, car NinjasCarResponse, imageURL
 		trimName = fmt.Sprintf("%s %s %d", car.Model, generation, car.Year)
 	}
 
-	_, err := s.db.Exec(`
+	fuelType := enums.FuelType.Canonical(car.FuelType)
+	transmission := enums.Transmission.Canonical(car.Transmission)
+	drivetrain := enums.Drivetrain.Canonical(car.Drive)
+
+	res, err := s.db.Exec(`
 		INSERT INTO trims (
-			model_id, name, year, generation, fuel_type, displacement_cc, cylinders,
+			model_id, generation_id, name, year, generation, fuel_type, displacement_cc, cylinders,
 			transmission_type, drivetrain, fuel_consumption_combined,
 			image_url, market, currency, seating_capacity
-		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
-	`, modelID, trimName, car.Year, generation, car.FuelType, displacementCC, cylinders,
-		car.Transmission, car.Drive, fuelConsumption, imageURL, "TR", "TRY", 5)
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, modelID, generationID, trimName, car.Year, generation, fuelType, displacementCC, cylinders,
+		transmission, drivetrain, fuelConsumption, imageURL, "TR", "TRY", 5)
+	if err != nil {
+		return err
+	}
+	trimID, err := s.auditCreatedTrim(res)
+	if err != nil {
+		return err
+	}
 
+	// Cite what came from API Ninjas, and the Turkish market settings as defaults
+	title := fmt.Sprintf("API Ninjas: %s %s %d", car.Make, car.Model, car.Year)
+	fromAPI := map[string]interface{}{
+		"name":              trimName,
+		"year":              car.Year,
+		"fuel_type":         fuelType,
+		"transmission_type": transmission,
+		"drivetrain":        drivetrain,
+	}
+	if displacementCC > 0 {
+		fromAPI["displacement_cc"] = displacementCC
+	}
+	if cylinders > 0 {
+		fromAPI["cylinders"] = cylinders
+	}
+	if err := s.cite(trimID, car.SourceURL, title, models.SourceAPINinjas, fromAPI, ""); err != nil {
+		return err
+	}
+	if fuelConsumption > 0 {
+		consumption := map[string]interface{}{"fuel_consumption_combined": fuelConsumption}
+		if err := s.cite(trimID, car.SourceURL, title, models.SourceAPINinjas, consumption, "converted from city/highway MPG"); err != nil {
+			return err
+		}
+	}
+	if err := s.citeImage(trimID, imageURL); err != nil {
+		return err
+	}
+	_, err = s.reviews.ReviewTrimByID(trimID, models.SourceAPINinjas)
 	return err
 }
 
+// cite records sourceURL as the source of values, skipping empty ones
+func (s *SetupService) cite(trimID int64, sourceURL, title, sourceType string, values map[string]interface{}, note string) error {
+	now := time.Now().UTC()
+	source := &models.SourceDocument{URL: sourceURL, Title: &title, SourceType: sourceType, RetrievedAt: &now}
+	if err := s.provenance.EnsureSource(source); err != nil {
+		return err
+	}
+
+	cited := make(map[string]interface{})
+	for field, value := range values {
+		if v, ok := value.(*string); ok && v == nil {
+			continue
+		}
+		cited[field] = value
+	}
+	return s.provenance.CiteFields(trimID, cited, source.ID, note)
+}
+
+// citeDefaults marks values setup fills in without any source
+func (s *SetupService) citeDefaults(trimID int64, values map[string]interface{}) error {
+	return s.cite(trimID, "default://cmd/setup", "Setup defaults", models.SourceDefault, values, "placeholder, not from a source")
+}
+
+// citeImage cites the image found by search as its own source; the search
+// request itself carries the SerpApi key, so it is not stored
+func (s *SetupService) citeImage(trimID int64, imageURL string) error {
+	if imageURL == "" {
+		return nil
+	}
+	return s.cite(trimID, imageURL, "Image search (SerpApi)", models.SourceImageSearch,
+		map[string]interface{}{"image_url": imageURL}, "")
+}
+
 // PopulateDatabase fetches and populates data using targeted Turkish market list
 func (s *SetupService) PopulateDatabase() error {
 	targets := GetTurkishMarketTargets()
@@ -569,6 +635,13 @@ func (s *SetupService) PopulateDatabase() error {
 			log.Printf("  ✓ Created model: %s", modelName)
 		}
 
+		generationID, err := s.GetOrCreateGeneration(modelID, target.Generation, target.Year)
+		if err != nil {
+			log.Printf("  ❌ Failed to create generation: %v", err)
+			stats.errors++
+			continue
+		}
+
 		// Step 4: Find image (use alias if available for Turkish market)
 		imageURL := ""
 		if s.serpApiKey != "" {
@@ -589,7 +662,7 @@ func (s *SetupService) PopulateDatabase() error {
 		if car != nil {
 			// We have API data - use it!
 			log.Printf("  ✓ Got specs: %s %s", car.Make, car.Model)
-			if err := s.CreateTrim(modelID, *car, imageURL, target.Generation); err != nil {
+			if err := s.CreateTrim(modelID, generationID, *car, imageURL, target.Generation); err != nil {
 				log.Printf("  ❌ Failed to create trim: %v", err)
 				stats.errors++
 				continue
@@ -597,7 +670,7 @@ func (s *SetupService) PopulateDatabase() error {
 		} else {
 			// No API data - create fallback entry
 			log.Printf("  ⚠️  API returned no data, creating fallback entry...")
-			if err := s.CreateFallbackTrim(modelID, target.Brand, target.Model, target.Year, imageURL, target.Generation); err != nil {
+			if err := s.CreateFallbackTrim(modelID, generationID, target.Brand, target.Model, target.Year, imageURL, target.Generation); err != nil {
 				log.Printf("  ❌ Failed to create fallback trim: %v", err)
 				stats.errors++
 				continue
@@ -627,9 +700,9 @@ func (s *SetupService) PopulateDatabase() error {
 }
 
 func main() {
-	log.Println("=== Turkish Market Database - Add New Vehicles ===\n")
+	log.Print("=== Turkish Market Database - Add New Vehicles ===\n\n")
 	log.Println("ℹ️  This script will ADD new vehicles to the database.")
-	log.Println("ℹ️  Existing data will NOT be deleted.\n")
+	log.Print("ℹ️  Existing data will NOT be deleted.\n\n")
 
 	// Load environment variables - try multiple paths
 	envPaths := []string{
@@ -657,25 +730,15 @@ func main() {
 		log.Fatal("❌ NINJAS_API_KEY is required")
 	}
 
-	// Connect to database - use absolute path to ensure we use the same DB as the API
-	dbPath := os.Getenv("DB_PATH")
-	if dbPath == "" {
-		// Get absolute path to backend/vehicles.db
-		cwd, err := os.Getwd()
-		if err != nil {
-			log.Fatalf("Failed to get working directory: %v", err)
-		}
-		// We're in backend/cmd/setup, go up 2 levels to backend/
-		dbPath = filepath.Join(cwd, "..", "..", "vehicles.db")
-		dbPath, err = filepath.Abs(dbPath)
-		if err != nil {
-			log.Fatalf("Failed to get absolute path: %v", err)
-		}
+	// Connect to the same database as the API and bring its schema up to date
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		log.Fatalf("Failed to load config: %v", err)
 	}
 
-	log.Printf("📁 Using database: %s\n", dbPath)
+	log.Printf("📁 Using database: %s\n", cfg.Database.Path)
 
-	db, err := sql.Open("sqlite", dbPath)
+	db, err := storage.OpenAndMigrate(cfg)
 	if err != nil {
 		log.Fatalf("Failed to open database: %v", err)
 	}
@@ -684,15 +747,6 @@ func main() {
 	// Initialize setup service
 	service := NewSetupService(db)
 
-	// Ensure schema exists (will create if not exists, skip if already exists)
-	log.Println("📝 Ensuring database schema exists...")
-	if err := service.CreateSchema(); err != nil {
-		// This is OK - tables might already exist
-		log.Printf("  ℹ️  Tables already exist (this is normal)\n")
-	} else {
-		log.Println("  ✓ Schema is ready")
-	}
-
 	// Populate with targeted Turkish market data (append mode)
 	log.Println("\n🚗 Adding Turkish market vehicles...")
 	if err := service.PopulateDatabase(); err != nil {
@@ -0,0 +1,180 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/emirh/car-specs/backend/internal/config"
+	"github.com/emirh/car-specs/backend/internal/snapshot"
+	"github.com/emirh/car-specs/backend/internal/storage"
+)
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "Usage: snapshot [-dir DIR] [-version V] [-json] create|list|verify SNAPSHOT|diff OLD NEW")
+	fmt.Fprintln(os.Stderr, "")
+	fmt.Fprintln(os.Stderr, "  create          copy the database into DIR/VERSION with a manifest and checksum")
+	fmt.Fprintln(os.Stderr, "  list            list the snapshots in DIR")
+	fmt.Fprintln(os.Stderr, "  verify SNAPSHOT check a snapshot against its checksum")
+	fmt.Fprintln(os.Stderr, "  diff OLD NEW    list the trims added, removed or changed between two snapshots")
+	fmt.Fprintln(os.Stderr, "")
+	fmt.Fprintln(os.Stderr, "SNAPSHOT is a version in DIR, a snapshot directory or a bare database file.")
+	fmt.Fprintln(os.Stderr, "The database is taken from DB_PATH (default backend/vehicles.db).")
+	fmt.Fprintln(os.Stderr, "")
+	flag.PrintDefaults()
+}
+
+func main() {
+	dir := flag.String("dir", "snapshots", "directory snapshots are kept in")
+	version := flag.String("version", "", "version to create (default: the current UTC time, "+snapshot.VersionFormat+")")
+	asJSON := flag.Bool("json", false, "print manifests and diffs as JSON")
+	flag.Usage = usage
+	flag.Parse()
+
+	args := flag.Args()
+	if len(args) == 0 {
+		usage()
+		os.Exit(2)
+	}
+
+	var err error
+	switch {
+	case args[0] == "create" && len(args) == 1:
+		err = create(*dir, *version, *asJSON)
+	case args[0] == "list" && len(args) == 1:
+		err = list(*dir, *asJSON)
+	case args[0] == "verify" && len(args) == 2:
+		err = verify(*dir, args[1])
+	case args[0] == "diff" && len(args) == 3:
+		err = diff(*dir, args[1], args[2], *asJSON)
+	default:
+		usage()
+		os.Exit(2)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "❌ %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func create(dir, version string, asJSON bool) error {
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+	db, err := storage.Open(cfg)
+	if err != nil {
+		return fmt.Errorf("failed to initialize database: %w", err)
+	}
+	defer db.Close()
+
+	s, err := snapshot.Create(db, dir, version)
+	if err != nil {
+		return err
+	}
+	if asJSON {
+		return printJSON(s.Manifest)
+	}
+
+	m := s.Manifest
+	fmt.Printf("✓ Snapshot %s written to %s\n", m.Version, s.Path)
+	fmt.Printf("  schema version %d, %d bytes, sha256 %s\n", m.SchemaVersion, m.Size, m.SHA256)
+	fmt.Printf("  %d brands, %d models, %d generations, %d trims, %d specs\n",
+		m.Totals.Brands, m.Totals.Models, m.Totals.Generations, m.Totals.Trims, m.Totals.Specs)
+	for _, run := range m.SourceRuns {
+		fmt.Printf("  %s: %d writes, last %s\n", run.Source, run.Writes, run.LastWriteAt.Format("2006-01-02 15:04"))
+	}
+	return nil
+}
+
+func list(dir string, asJSON bool) error {
+	snapshots, err := snapshot.List(dir)
+	if err != nil {
+		return err
+	}
+	if asJSON {
+		manifests := []*snapshot.Manifest{}
+		for _, s := range snapshots {
+			manifests = append(manifests, s.Manifest)
+		}
+		return printJSON(manifests)
+	}
+
+	if len(snapshots) == 0 {
+		fmt.Printf("No snapshots in %s\n", dir)
+		return nil
+	}
+	fmt.Printf("%-20s %-17s %-7s %7s %s\n", "VERSION", "CREATED", "SCHEMA", "TRIMS", "SHA256")
+	for _, s := range snapshots {
+		m := s.Manifest
+		fmt.Printf("%-20s %-17s %-7d %7d %.12s\n", m.Version, m.CreatedAt.Format("2006-01-02 15:04"), m.SchemaVersion, m.Totals.Trims, m.SHA256)
+	}
+	return nil
+}
+
+func verify(dir, name string) error {
+	s, err := open(dir, name)
+	if err != nil {
+		return err
+	}
+	if s.Manifest == nil {
+		return fmt.Errorf("%s has no manifest to verify against", s.Path)
+	}
+	if err := s.Verify(); err != nil {
+		return err
+	}
+	fmt.Printf("✓ %s matches its checksum\n", s.Name())
+	return nil
+}
+
+func diff(dir, oldName, newName string, asJSON bool) error {
+	old, err := open(dir, oldName)
+	if err != nil {
+		return err
+	}
+	new, err := open(dir, newName)
+	if err != nil {
+		return err
+	}
+	d, err := snapshot.Compare(old, new)
+	if err != nil {
+		return err
+	}
+	if asJSON {
+		return printJSON(d)
+	}
+
+	fmt.Printf("%s → %s\n", d.From, d.To)
+	for _, ref := range d.Added {
+		fmt.Printf("+ %s (trim %d)\n", ref, ref.ID)
+	}
+	for _, ref := range d.Removed {
+		fmt.Printf("- %s (trim %d)\n", ref, ref.ID)
+	}
+	for _, change := range d.Changed {
+		fmt.Printf("~ %s (trim %d)\n", change.TrimRef, change.ID)
+		for _, c := range change.Changes {
+			fmt.Printf("      %s: %s → %s\n", c.Field, c.Before, c.After)
+		}
+	}
+	fmt.Printf("\n%s\n", d.Summary())
+	return nil
+}
+
+// open finds a snapshot by path, or by version in dir
+func open(dir, name string) (*snapshot.Snapshot, error) {
+	s, err := snapshot.Open(name)
+	if errors.Is(err, os.ErrNotExist) && name == filepath.Base(name) {
+		s, err = snapshot.Open(filepath.Join(dir, name))
+	}
+	return s, err
+}
+
+func printJSON(v interface{}) error {
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(v)
+}
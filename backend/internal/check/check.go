@@ -0,0 +1,177 @@
+// Package check runs consistency rules over the whole catalogue.
+//
+// Per-trim rules come from internal/validation, so a trim is judged the same
+// way on write and in a catalogue check. Catalogue rules look across rows:
+// duplicate names, references left dangling by the legacy schema, enum values
+// nothing maps. Findings whose fix cannot lose information (e.g. taking
+// model_id from the trim's generation) can be applied automatically.
+package check
+
+import (
+	"database/sql"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/emirh/car-specs/backend/internal/models"
+	"github.com/emirh/car-specs/backend/internal/repository"
+)
+
+// Finding severities. Errors fail a check; warnings only do in strict mode.
+const (
+	SeverityError   = "error"
+	SeverityWarning = "warning"
+)
+
+// Finding is one problem in one row
+type Finding struct {
+	Rule     string      `json:"rule"`
+	Severity string      `json:"severity"`
+	Table    string      `json:"table"`
+	ID       int64       `json:"id"`
+	Field    string      `json:"field,omitempty"`
+	Value    interface{} `json:"value,omitempty"`
+	Message  string      `json:"message"`
+	Fixable  bool        `json:"fixable"`
+	Fixed    bool        `json:"fixed"`
+
+	// fix repairs the row, storing fixed in Field; nil unless Fixable
+	fix   func(tx *sql.Tx) error
+	fixed interface{}
+}
+
+// Rule finds one kind of problem across the database
+type Rule struct {
+	Name        string
+	Description string
+	Find        func(db *sql.DB) ([]Finding, error)
+}
+
+// Summary counts findings by outcome. Fixed findings are not counted as errors or warnings.
+type Summary struct {
+	Errors   int `json:"errors"`
+	Warnings int `json:"warnings"`
+	Fixed    int `json:"fixed"`
+}
+
+// Report is the machine-readable result of a check
+type Report struct {
+	Database  string    `json:"database"`
+	CheckedAt time.Time `json:"checked_at"`
+	Fix       bool      `json:"fix"`
+	Rules     []string  `json:"rules"`
+	Summary   Summary   `json:"summary"`
+	Findings  []Finding `json:"findings"`
+}
+
+// Failed reports whether the check should fail a release: on any remaining
+// error, or any remaining warning when strict
+func (r *Report) Failed(strict bool) bool {
+	return r.Summary.Errors > 0 || (strict && r.Summary.Warnings > 0)
+}
+
+// Select returns the rules with the given names, or every rule for none
+func Select(names []string) ([]Rule, error) {
+	if len(names) == 0 {
+		return Rules, nil
+	}
+	byName := make(map[string]Rule)
+	for _, rule := range Rules {
+		byName[rule.Name] = rule
+	}
+
+	var selected []Rule
+	for _, name := range names {
+		rule, ok := byName[strings.TrimSpace(name)]
+		if !ok {
+			return nil, fmt.Errorf("unknown rule: %s", name)
+		}
+		selected = append(selected, rule)
+	}
+	return selected, nil
+}
+
+// Run applies rules to db. With fix, fixable findings are repaired in one
+// transaction, recorded in the audit log as writes by actor and marked Fixed.
+func Run(db *sql.DB, rules []Rule, fix bool, actor models.Actor) (*Report, error) {
+	report := &Report{
+		CheckedAt: time.Now().UTC(),
+		Fix:       fix,
+		Findings:  []Finding{},
+	}
+
+	for _, rule := range rules {
+		report.Rules = append(report.Rules, rule.Name)
+		findings, err := rule.Find(db)
+		if err != nil {
+			return nil, fmt.Errorf("rule %s: %w", rule.Name, err)
+		}
+		report.Findings = append(report.Findings, findings...)
+	}
+	sort.SliceStable(report.Findings, func(i, j int) bool {
+		a, b := report.Findings[i], report.Findings[j]
+		if a.Table != b.Table {
+			return a.Table < b.Table
+		}
+		return a.ID < b.ID
+	})
+
+	if fix {
+		if err := applyFixes(db, report.Findings, actor); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, f := range report.Findings {
+		switch {
+		case f.Fixed:
+			report.Summary.Fixed++
+		case f.Severity == SeverityError:
+			report.Summary.Errors++
+		default:
+			report.Summary.Warnings++
+		}
+	}
+	return report, nil
+}
+
+// auditEntities are the audit log entity types of the tables findings name
+var auditEntities = map[string]string{
+	"brands":      models.EntityBrand,
+	"models":      models.EntityModel,
+	"generations": models.EntityGeneration,
+	"trims":       models.EntityTrim,
+}
+
+func applyFixes(db *sql.DB, findings []Finding, actor models.Actor) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+	audit := repository.NewAuditRepository(tx)
+
+	for i := range findings {
+		f := &findings[i]
+		if !f.Fixable {
+			continue
+		}
+		if err := f.fix(tx); err != nil {
+			return fmt.Errorf("failed to fix %s on %s %d: %w", f.Rule, f.Table, f.ID, err)
+		}
+		if entityType, ok := auditEntities[f.Table]; ok {
+			before := map[string]interface{}{f.Field: f.Value}
+			after := map[string]interface{}{f.Field: f.fixed}
+			if err := audit.Record(actor, entityType, f.ID, models.AuditUpdate, before, after); err != nil {
+				return err
+			}
+		}
+		f.Fixed = true
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit fixes: %w", err)
+	}
+	return nil
+}
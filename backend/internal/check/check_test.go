@@ -0,0 +1,73 @@
+package check
+
+import (
+	"database/sql"
+	"testing"
+
+	"github.com/emirh/car-specs/backend/internal/models"
+)
+
+func TestSelect(t *testing.T) {
+	all, err := Select(nil)
+	if err != nil || len(all) != len(Rules) {
+		t.Fatalf("Select(nil) = %d rules, %v; want all %d", len(all), err, len(Rules))
+	}
+
+	got, err := Select([]string{"year_range", " duplicate_trim_name"})
+	if err != nil {
+		t.Fatalf("Select: %v", err)
+	}
+	if len(got) != 2 || got[0].Name != "year_range" || got[1].Name != "duplicate_trim_name" {
+		t.Errorf("Select kept %v; want year_range, duplicate_trim_name", names(got))
+	}
+
+	if _, err := Select([]string{"bogus"}); err == nil {
+		t.Error("Select accepted an unknown rule")
+	}
+}
+
+func TestRunSummary(t *testing.T) {
+	rules := []Rule{{
+		Name: "fake",
+		Find: func(*sql.DB) ([]Finding, error) {
+			return []Finding{
+				{Rule: "fake", Severity: SeverityWarning, Table: "trims", ID: 2},
+				{Rule: "fake", Severity: SeverityError, Table: "trims", ID: 1},
+				{Rule: "fake", Severity: SeverityWarning, Table: "models", ID: 9},
+			}, nil
+		},
+	}}
+
+	report, err := Run(nil, rules, false, models.Actor{})
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if report.Summary != (Summary{Errors: 1, Warnings: 2}) {
+		t.Errorf("Summary = %+v; want 1 error, 2 warnings", report.Summary)
+	}
+	order := []int64{9, 1, 2}
+	for i, f := range report.Findings {
+		if f.ID != order[i] {
+			t.Errorf("Findings[%d].ID = %d; want %d (sorted by table, id)", i, f.ID, order[i])
+		}
+	}
+
+	if !report.Failed(false) {
+		t.Error("report with an error should fail")
+	}
+	report.Summary.Errors = 0
+	if report.Failed(false) {
+		t.Error("warnings alone should not fail without strict")
+	}
+	if !report.Failed(true) {
+		t.Error("warnings should fail in strict mode")
+	}
+}
+
+func names(rules []Rule) []string {
+	var out []string
+	for _, r := range rules {
+		out = append(out, r.Name)
+	}
+	return out
+}
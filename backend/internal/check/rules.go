@@ -0,0 +1,316 @@
+package check
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/emirh/car-specs/backend/internal/enums"
+	"github.com/emirh/car-specs/backend/internal/models"
+	"github.com/emirh/car-specs/backend/internal/validation"
+)
+
+// Rules run by default: every per-trim validation rule, then the catalogue rules
+var Rules = append(trimRules(), DuplicateTrimName, OrphanedModelID, OrphanedGenerationID, NonCanonicalEnum)
+
+// errorRules are the validation rules whose issues are errors here; the rest
+// (missing or implausible values) are warnings
+var errorRules = map[string]bool{
+	validation.YearRange.Name: true,
+}
+
+// trimRules wraps each validation rule so it runs over every trim
+func trimRules() []Rule {
+	rules := make([]Rule, 0, len(validation.Rules))
+	for _, vr := range validation.Rules {
+		vr := vr
+		severity := SeverityWarning
+		if errorRules[vr.Name] {
+			severity = SeverityError
+		}
+		rules = append(rules, Rule{
+			Name:        vr.Name,
+			Description: vr.Description,
+			Find: func(db *sql.DB) ([]Finding, error) {
+				inputs, err := loadTrims(db)
+				if err != nil {
+					return nil, err
+				}
+				var findings []Finding
+				for _, in := range inputs {
+					for _, issue := range vr.Run(in) {
+						findings = append(findings, Finding{
+							Rule: vr.Name, Severity: severity, Table: "trims", ID: in.Trim.ID,
+							Field: issue.Field, Value: issue.Value, Message: issue.Message,
+						})
+					}
+				}
+				return findings, nil
+			},
+		})
+	}
+	return rules
+}
+
+// loadTrims reads the trim columns the validation rules look at, with each
+// trim's generation. Trims in the trash are not checked. Columns are scanned leniently so legacy rows with NULLs
+// are checked rather than failing the scan.
+func loadTrims(db *sql.DB) ([]validation.Input, error) {
+	generations := make(map[int64]*models.Generation)
+	rows, err := db.Query("SELECT id, model_id, COALESCE(code, ''), COALESCE(start_year, 0), end_year FROM generations")
+	if err != nil {
+		return nil, fmt.Errorf("failed to load generations: %w", err)
+	}
+	for rows.Next() {
+		g := &models.Generation{}
+		if err := rows.Scan(&g.ID, &g.ModelID, &g.Code, &g.StartYear, &g.EndYear); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan generation: %w", err)
+		}
+		generations[g.ID] = g
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to load generations: %w", err)
+	}
+
+	rows, err = db.Query(`
+		SELECT id, COALESCE(generation_id, 0), COALESCE(model_id, 0), name, COALESCE(year, 0),
+			start_year, end_year, power_hp, power_kw
+		FROM trims
+		WHERE deleted_at IS NULL
+		ORDER BY id
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load trims: %w", err)
+	}
+	defer rows.Close()
+
+	var inputs []validation.Input
+	for rows.Next() {
+		t := &models.Trim{}
+		err := rows.Scan(&t.ID, &t.GenerationID, &t.ModelID, &t.Name, &t.Year,
+			&t.StartYear, &t.EndYear, &t.PowerHP, &t.PowerKW)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan trim: %w", err)
+		}
+		inputs = append(inputs, validation.Input{Trim: t, Generation: generations[t.GenerationID]})
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to load trims: %w", err)
+	}
+	return inputs, nil
+}
+
+var DuplicateTrimName = Rule{
+	Name:        "duplicate_trim_name",
+	Description: "two live trims of one generation share a name (ignoring case and surrounding spaces)",
+	Find: func(db *sql.DB) ([]Finding, error) {
+		rows, err := db.Query(`
+			SELECT t.id, t.generation_id, t.name, (
+				SELECT MIN(d.id) FROM trims d
+				WHERE d.generation_id = t.generation_id AND LOWER(TRIM(d.name)) = LOWER(TRIM(t.name))
+					AND d.deleted_at IS NULL
+			) AS first_id
+			FROM trims t
+			WHERE t.deleted_at IS NULL AND t.id != (
+				SELECT MIN(d.id) FROM trims d
+				WHERE d.generation_id = t.generation_id AND LOWER(TRIM(d.name)) = LOWER(TRIM(t.name))
+					AND d.deleted_at IS NULL
+			)
+		`)
+		if err != nil {
+			return nil, err
+		}
+		defer rows.Close()
+
+		var findings []Finding
+		for rows.Next() {
+			var id, generationID, firstID int64
+			var name string
+			if err := rows.Scan(&id, &generationID, &name, &firstID); err != nil {
+				return nil, err
+			}
+			findings = append(findings, Finding{
+				Rule: "duplicate_trim_name", Severity: SeverityError, Table: "trims", ID: id,
+				Field: "name", Value: name,
+				Message: fmt.Sprintf("Same name as trim %d in generation %d", firstID, generationID),
+			})
+		}
+		return findings, rows.Err()
+	},
+}
+
+var OrphanedModelID = Rule{
+	Name:        "orphaned_model_id",
+	Description: "model_id points to no model, or a trim's model_id differs from its generation's",
+	Find: func(db *sql.DB) ([]Finding, error) {
+		var findings []Finding
+
+		// Trims: fixable when the generation belongs to an existing model
+		rows, err := db.Query(`
+			SELECT t.id, t.model_id, m.id IS NOT NULL, g.model_id, mg.id IS NOT NULL
+			FROM trims t
+			LEFT JOIN models m ON m.id = t.model_id
+			LEFT JOIN generations g ON g.id = t.generation_id
+			LEFT JOIN models mg ON mg.id = g.model_id
+			WHERE m.id IS NULL OR (g.id IS NOT NULL AND g.model_id != t.model_id)
+		`)
+		if err != nil {
+			return nil, err
+		}
+		defer rows.Close()
+		for rows.Next() {
+			var id int64
+			var modelID, generationModelID sql.NullInt64
+			var modelExists, generationModelExists bool
+			if err := rows.Scan(&id, &modelID, &modelExists, &generationModelID, &generationModelExists); err != nil {
+				return nil, err
+			}
+
+			f := Finding{
+				Rule: "orphaned_model_id", Severity: SeverityError, Table: "trims", ID: id,
+				Field: "model_id", Value: modelID.Int64,
+			}
+			if !modelExists {
+				f.Message = fmt.Sprintf("Model %d does not exist", modelID.Int64)
+			} else {
+				f.Message = fmt.Sprintf("Generation belongs to model %d", generationModelID.Int64)
+			}
+			if generationModelExists {
+				target := generationModelID.Int64
+				f.Message += fmt.Sprintf("; fix sets model_id to %d from the generation", target)
+				f.Fixable = true
+				f.fixed = target
+				f.fix = func(tx *sql.Tx) error {
+					_, err := tx.Exec("UPDATE trims SET model_id = ?, updated_at = CURRENT_TIMESTAMP WHERE id = ?", target, id)
+					return err
+				}
+			}
+			findings = append(findings, f)
+		}
+		if err := rows.Err(); err != nil {
+			return nil, err
+		}
+
+		// Generations: nothing to take the model from
+		genRows, err := db.Query(`
+			SELECT g.id, g.model_id
+			FROM generations g
+			LEFT JOIN models m ON m.id = g.model_id
+			WHERE m.id IS NULL
+		`)
+		if err != nil {
+			return nil, err
+		}
+		defer genRows.Close()
+		for genRows.Next() {
+			var id int64
+			var modelID sql.NullInt64
+			if err := genRows.Scan(&id, &modelID); err != nil {
+				return nil, err
+			}
+			findings = append(findings, Finding{
+				Rule: "orphaned_model_id", Severity: SeverityError, Table: "generations", ID: id,
+				Field: "model_id", Value: modelID.Int64,
+				Message: fmt.Sprintf("Model %d does not exist", modelID.Int64),
+			})
+		}
+		return findings, genRows.Err()
+	},
+}
+
+var OrphanedGenerationID = Rule{
+	Name:        "orphaned_generation_id",
+	Description: "a trim's generation_id points to no generation",
+	Find: func(db *sql.DB) ([]Finding, error) {
+		rows, err := db.Query(`
+			SELECT t.id, t.generation_id
+			FROM trims t
+			LEFT JOIN generations g ON g.id = t.generation_id
+			WHERE g.id IS NULL
+		`)
+		if err != nil {
+			return nil, err
+		}
+		defer rows.Close()
+
+		var findings []Finding
+		for rows.Next() {
+			var id int64
+			var generationID sql.NullInt64
+			if err := rows.Scan(&id, &generationID); err != nil {
+				return nil, err
+			}
+			findings = append(findings, Finding{
+				Rule: "orphaned_generation_id", Severity: SeverityError, Table: "trims", ID: id,
+				Field: "generation_id", Value: generationID.Int64,
+				Message: fmt.Sprintf("Generation %d does not exist", generationID.Int64),
+			})
+		}
+		return findings, rows.Err()
+	},
+}
+
+// enumColumns are the free-text columns that must hold canonical enum values
+var enumColumns = []struct {
+	table  string
+	column string
+	enum   enums.Enum
+}{
+	{"trims", "fuel_type", enums.FuelType},
+	{"trims", "transmission_type", enums.Transmission},
+	{"trims", "drivetrain", enums.Drivetrain},
+	{"trims", "emission_standard", enums.EmissionStandard},
+	{"models", "body_style", enums.BodyStyle},
+}
+
+var NonCanonicalEnum = Rule{
+	Name:        "non_canonical_enum",
+	Description: "an enum column holds a value that is not canonical; known aliases are fixable",
+	Find: func(db *sql.DB) ([]Finding, error) {
+		var findings []Finding
+		for _, c := range enumColumns {
+			rows, err := db.Query(fmt.Sprintf("SELECT id, %s FROM %s WHERE %s IS NOT NULL", c.column, c.table, c.column))
+			if err != nil {
+				return nil, err
+			}
+			for rows.Next() {
+				var id int64
+				var raw string
+				if err := rows.Scan(&id, &raw); err != nil {
+					rows.Close()
+					return nil, err
+				}
+				if c.enum.Valid(raw) {
+					continue
+				}
+
+				f := Finding{
+					Rule: "non_canonical_enum", Severity: SeverityError, Table: c.table, ID: id,
+					Field: c.column, Value: raw,
+					Message: fmt.Sprintf("%q is not one of %v", raw, c.enum.Values()),
+				}
+				if canonical, ok := c.enum.Normalize(raw); ok {
+					var value interface{} = canonical
+					if canonical == "" {
+						value = nil
+					}
+					f.Message = fmt.Sprintf("%q is an alias; fix stores %v", raw, value)
+					f.Fixable = true
+					f.fixed = value
+					query := fmt.Sprintf("UPDATE %s SET %s = ? WHERE id = ?", c.table, c.column)
+					f.fix = func(tx *sql.Tx) error {
+						_, err := tx.Exec(query, value, id)
+						return err
+					}
+				}
+				findings = append(findings, f)
+			}
+			rows.Close()
+			if err := rows.Err(); err != nil {
+				return nil, err
+			}
+		}
+		return findings, nil
+	},
+}
@@ -1,36 +1,182 @@
 package config
 
 import (
+	"bufio"
 	"fmt"
 	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
 )
 
+// DefaultDatabaseFile is the database every command uses unless DB_PATH says otherwise
+const DefaultDatabaseFile = "vehicles.db"
+
+const modulePath = "github.com/emirh/car-specs/backend"
+
 // Config holds all configuration for the application.
 type Config struct {
 	ApiNinjasKey string
-	DatabaseURL  string
+	Database     DatabaseConfig
+	// MergePolicyPath is a JSON file overriding the default merge policy (MERGE_POLICY)
+	MergePolicyPath string
+}
+
+// DatabaseConfig holds the SQLite connection settings shared by every command.
+type DatabaseConfig struct {
+	// Path is the absolute path of the SQLite file
+	Path string
+
+	BusyTimeout     time.Duration
+	MaxOpenConns    int
+	MaxIdleConns    int
+	ConnMaxLifetime time.Duration
 }
 
 // LoadConfig reads configuration from .env file or environment variables.
+// Commands that need an API key must check ApiNinjasKey themselves.
 func LoadConfig() (*Config, error) {
 	// Attempt to load .env file, but don't fail if it doesn't exist (e.g. production env vars)
 	_ = godotenv.Load()
 
 	apiNinjasKey := os.Getenv("API_NINJAS_KEY")
 	if apiNinjasKey == "" {
-		return nil, fmt.Errorf("API_NINJAS_KEY is not set in environment or .env file")
+		apiNinjasKey = os.Getenv("NINJAS_API_KEY")
 	}
 
-	databaseURL := os.Getenv("DATABASE_URL")
-	if databaseURL == "" {
-		// Default to local sqlite if not set
-		databaseURL = "vehicles.db"
+	dbPath, err := resolveDatabasePath(firstNonEmpty(os.Getenv("DB_PATH"), os.Getenv("DATABASE_URL")))
+	if err != nil {
+		return nil, err
+	}
+
+	busyTimeout, err := durationEnv("DB_BUSY_TIMEOUT", 5*time.Second)
+	if err != nil {
+		return nil, err
+	}
+	maxOpen, err := intEnv("DB_MAX_OPEN_CONNS", 4)
+	if err != nil {
+		return nil, err
+	}
+	maxIdle, err := intEnv("DB_MAX_IDLE_CONNS", 4)
+	if err != nil {
+		return nil, err
+	}
+	maxLifetime, err := durationEnv("DB_CONN_MAX_LIFETIME", time.Hour)
+	if err != nil {
+		return nil, err
 	}
 
 	return &Config{
 		ApiNinjasKey: apiNinjasKey,
-		DatabaseURL:  databaseURL,
+		Database: DatabaseConfig{
+			Path:            dbPath,
+			BusyTimeout:     busyTimeout,
+			MaxOpenConns:    maxOpen,
+			MaxIdleConns:    maxIdle,
+			ConnMaxLifetime: maxLifetime,
+		},
+		MergePolicyPath: os.Getenv("MERGE_POLICY"),
 	}, nil
 }
+
+// resolveDatabasePath turns the configured path into an absolute one.
+// Relative paths are resolved against the backend directory (the one holding go.mod),
+// so commands started from cmd/<name> open the same file as the API.
+func resolveDatabasePath(path string) (string, error) {
+	if path == "" {
+		path = DefaultDatabaseFile
+	}
+	path = strings.TrimPrefix(path, "sqlite://")
+	path = strings.TrimPrefix(path, "file:")
+	return ResolvePath(path)
+}
+
+// ResolvePath makes a path relative to the backend directory absolute, so
+// data files such as data/manual_overrides.json are found from any working directory
+func ResolvePath(path string) (string, error) {
+	if filepath.IsAbs(path) {
+		return path, nil
+	}
+
+	if root, ok := backendRoot(); ok {
+		return filepath.Join(root, path), nil
+	}
+
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve database path %s: %w", path, err)
+	}
+	return abs, nil
+}
+
+// backendRoot walks up from the working directory looking for the backend go.mod
+func backendRoot() (string, bool) {
+	dir, err := os.Getwd()
+	if err != nil {
+		return "", false
+	}
+
+	for {
+		if isBackendModule(filepath.Join(dir, "go.mod")) {
+			return dir, true
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", false
+		}
+		dir = parent
+	}
+}
+
+func isBackendModule(goMod string) bool {
+	f, err := os.Open(goMod)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if strings.HasPrefix(line, "module ") {
+			return strings.TrimSpace(strings.TrimPrefix(line, "module ")) == modulePath
+		}
+	}
+	return false
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+func intEnv(key string, fallback int) (int, error) {
+	v := os.Getenv(key)
+	if v == "" {
+		return fallback, nil
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("%s must be a non-negative integer, got %q", key, v)
+	}
+	return n, nil
+}
+
+func durationEnv(key string, fallback time.Duration) (time.Duration, error) {
+	v := os.Getenv(key)
+	if v == "" {
+		return fallback, nil
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("%s must be a duration like 5s, got %q", key, v)
+	}
+	return d, nil
+}
@@ -1,42 +0,0 @@
-package database
-
-import (
-	"database/sql"
-	"fmt"
-	"log"
-	"os"
-
-	_ "modernc.org/sqlite"
-)
-
-var DB *sql.DB
-
-// InitDB initializes the database connection
-func InitDB() error {
-	dbPath := os.Getenv("DB_PATH")
-	if dbPath == "" {
-		dbPath = "./vehicles.db"
-	}
-
-	var err error
-	DB, err = sql.Open("sqlite", dbPath+"?_pragma=foreign_keys(1)")
-	if err != nil {
-		return fmt.Errorf("failed to open database: %w", err)
-	}
-
-	// Test connection
-	if err := DB.Ping(); err != nil {
-		return fmt.Errorf("failed to ping database: %w", err)
-	}
-
-	log.Println("✓ Database connection established")
-	return nil
-}
-
-// CloseDB closes the database connection
-func CloseDB() error {
-	if DB != nil {
-		return DB.Close()
-	}
-	return nil
-}
@@ -0,0 +1,201 @@
+// Package enums defines the canonical values stored for fuel type, transmission,
+// drivetrain, body style and emission standard, and maps the free-text spellings
+// found in scraped and imported data onto them.
+//
+// Canonical values are lower-case snake_case ("plug_in_hybrid", "dual_clutch").
+// They are what the database holds and the API returns in raw fields; display
+// strings are produced by internal/formatter.
+package enums
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Enum is a closed set of canonical values plus the aliases that map onto them
+type Enum struct {
+	Field   string // JSON field the enum is used for, for error messages
+	values  []string
+	aliases map[string]string
+}
+
+func newEnum(field string, values []string, aliases map[string]string) Enum {
+	e := Enum{Field: field, values: values, aliases: make(map[string]string)}
+	for _, v := range values {
+		e.aliases[v] = v
+	}
+	for alias, v := range aliases {
+		e.aliases[key(alias)] = v
+	}
+	return e
+}
+
+// key folds case, surrounding space and separators so "Plug-in Hybrid",
+// "plug in hybrid" and "plug_in_hybrid" look the same
+func key(raw string) string {
+	k := strings.ToLower(strings.TrimSpace(raw))
+	k = strings.NewReplacer("-", "_", " ", "_", "/", "_").Replace(k)
+	for strings.Contains(k, "__") {
+		k = strings.ReplaceAll(k, "__", "_")
+	}
+	return k
+}
+
+// Values returns the canonical values
+func (e Enum) Values() []string {
+	return append([]string(nil), e.values...)
+}
+
+// Valid reports whether v is a canonical value
+func (e Enum) Valid(v string) bool {
+	for _, value := range e.values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// Normalize maps a free-text value to its canonical value. ok is false when
+// the value is not recognised; empty input returns "", true.
+func (e Enum) Normalize(raw string) (canonical string, ok bool) {
+	if strings.TrimSpace(raw) == "" {
+		return "", true
+	}
+	canonical, ok = e.aliases[key(raw)]
+	return canonical, ok
+}
+
+// Canonical returns the canonical value for raw, or nil when raw is empty or
+// not recognised. Meant for bulk writers (scrapers, importers) that would rather
+// store nothing than an unknown spelling.
+func (e Enum) Canonical(raw string) *string {
+	canonical, ok := e.Normalize(raw)
+	if !ok || canonical == "" {
+		return nil
+	}
+	return &canonical
+}
+
+// NormalizeField normalises an optional field in place, returning an error
+// listing the allowed values when it is not recognised. Empty values become nil.
+func (e Enum) NormalizeField(v **string) error {
+	if *v == nil {
+		return nil
+	}
+	canonical, ok := e.Normalize(**v)
+	if !ok {
+		return fmt.Errorf("invalid %s: %q (allowed: %s)", e.Field, **v, strings.Join(e.values, ", "))
+	}
+	if canonical == "" {
+		*v = nil
+		return nil
+	}
+	*v = &canonical
+	return nil
+}
+
+// Fuel types
+const (
+	FuelPetrol       = "petrol"
+	FuelDiesel       = "diesel"
+	FuelMildHybrid   = "mild_hybrid"
+	FuelHybrid       = "hybrid"
+	FuelPlugInHybrid = "plug_in_hybrid"
+	FuelElectric     = "electric"
+	FuelLPG          = "lpg"
+	FuelCNG          = "cng"
+	FuelHydrogen     = "hydrogen"
+)
+
+var FuelType = newEnum("fuel_type",
+	[]string{FuelPetrol, FuelDiesel, FuelMildHybrid, FuelHybrid, FuelPlugInHybrid, FuelElectric, FuelLPG, FuelCNG, FuelHydrogen},
+	map[string]string{
+		"gas": FuelPetrol, "gasoline": FuelPetrol, "benzin": FuelPetrol, "premium unleaded": FuelPetrol,
+		"regular unleaded": FuelPetrol, "unleaded": FuelPetrol,
+		"dizel": FuelDiesel, "mhev": FuelMildHybrid,
+		"hev": FuelHybrid, "hibrit": FuelHybrid, "full hybrid": FuelHybrid,
+		"phev": FuelPlugInHybrid, "plug-in hibrit": FuelPlugInHybrid,
+		"ev": FuelElectric, "bev": FuelElectric, "electricity": FuelElectric, "elektrik": FuelElectric,
+		"autogas": FuelLPG, "natural gas": FuelCNG, "fuel cell": FuelHydrogen,
+	})
+
+// Transmission types
+const (
+	TransmissionManual      = "manual"
+	TransmissionAutomatic   = "automatic"
+	TransmissionDualClutch  = "dual_clutch"
+	TransmissionCVT         = "cvt"
+	TransmissionSingleSpeed = "single_speed"
+)
+
+var Transmission = newEnum("transmission_type",
+	[]string{TransmissionManual, TransmissionAutomatic, TransmissionDualClutch, TransmissionCVT, TransmissionSingleSpeed},
+	map[string]string{
+		"m": TransmissionManual, "mt": TransmissionManual, "manuel": TransmissionManual,
+		"a": TransmissionAutomatic, "at": TransmissionAutomatic, "auto": TransmissionAutomatic,
+		"otomatik": TransmissionAutomatic, "tiptronic": TransmissionAutomatic, "steptronic": TransmissionAutomatic,
+		"dct": TransmissionDualClutch, "dsg": TransmissionDualClutch, "s tronic": TransmissionDualClutch,
+		"pdk": TransmissionDualClutch, "çift kavramalı": TransmissionDualClutch,
+		"cv": TransmissionCVT, "multitronic": TransmissionCVT, "otomatik (cvt)": TransmissionCVT,
+		"1 speed": TransmissionSingleSpeed,
+	})
+
+// Drivetrains. awd (permanent or on-demand all-wheel drive) and 4wd
+// (part-time four-wheel drive, usually with low range) are kept apart.
+const (
+	DrivetrainFWD = "fwd"
+	DrivetrainRWD = "rwd"
+	DrivetrainAWD = "awd"
+	Drivetrain4WD = "4wd"
+)
+
+var Drivetrain = newEnum("drivetrain",
+	[]string{DrivetrainFWD, DrivetrainRWD, DrivetrainAWD, Drivetrain4WD},
+	map[string]string{
+		"front": DrivetrainFWD, "front wheel drive": DrivetrainFWD, "önden çekiş": DrivetrainFWD,
+		"rear": DrivetrainRWD, "rear wheel drive": DrivetrainRWD, "arkadan itiş": DrivetrainRWD,
+		"all wheel drive": DrivetrainAWD, "quattro": DrivetrainAWD, "4motion": DrivetrainAWD,
+		"xdrive": DrivetrainAWD, "4matic": DrivetrainAWD, "dört tekerlekten çekiş": DrivetrainAWD,
+		"4x4": Drivetrain4WD, "four wheel drive": Drivetrain4WD, "4 çeker": Drivetrain4WD,
+	})
+
+// Body styles
+const (
+	BodySedan       = "sedan"
+	BodyHatchback   = "hatchback"
+	BodyLiftback    = "liftback"
+	BodyWagon       = "wagon"
+	BodyCoupe       = "coupe"
+	BodyConvertible = "convertible"
+	BodyRoadster    = "roadster"
+	BodySUV         = "suv"
+	BodyMPV         = "mpv"
+	BodyPickup      = "pickup"
+	BodyVan         = "van"
+)
+
+var BodyStyle = newEnum("body_style",
+	[]string{BodySedan, BodyHatchback, BodyLiftback, BodyWagon, BodyCoupe, BodyConvertible, BodyRoadster, BodySUV, BodyMPV, BodyPickup, BodyVan},
+	map[string]string{
+		"saloon": BodySedan, "limousine": BodySedan,
+		"hatch": BodyHatchback, "sportback": BodyHatchback,
+		"estate": BodyWagon, "station wagon": BodyWagon, "avant": BodyWagon, "touring": BodyWagon, "variant": BodyWagon,
+		"kombi":     BodyWagon,
+		"coupé":     BodyCoupe,
+		"cabriolet": BodyConvertible, "cabrio": BodyConvertible,
+		"spyder": BodyRoadster, "spider": BodyRoadster,
+		"crossover": BodySUV, "sport utility vehicle": BodySUV,
+		"minivan": BodyMPV, "pickup truck": BodyPickup, "pick up": BodyPickup,
+	})
+
+// Emission standards
+var EmissionStandard = newEnum("emission_standard",
+	[]string{"euro_1", "euro_2", "euro_3", "euro_4", "euro_5", "euro_5a", "euro_5b",
+		"euro_6", "euro_6b", "euro_6c", "euro_6d_temp", "euro_6d", "euro_6e", "euro_7"},
+	map[string]string{
+		"euro1": "euro_1", "euro2": "euro_2", "euro3": "euro_3", "euro4": "euro_4",
+		"euro5": "euro_5", "euro6": "euro_6", "euro7": "euro_7",
+		"euro6d": "euro_6d", "euro6d_temp": "euro_6d_temp", "euro6e": "euro_6e",
+		"eu5": "euro_5", "eu6": "euro_6", "eu6d": "euro_6d",
+	})
@@ -0,0 +1,69 @@
+package enums
+
+import "testing"
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		enum   Enum
+		input  string
+		expect string
+		ok     bool
+	}{
+		{FuelType, "Benzin", FuelPetrol, true},
+		{FuelType, "Gasoline", FuelPetrol, true},
+		{FuelType, " DIZEL ", FuelDiesel, true},
+		{FuelType, "Plug-in Hybrid", FuelPlugInHybrid, true},
+		{FuelType, "plug_in_hybrid", FuelPlugInHybrid, true},
+		{FuelType, "electricity", FuelElectric, true},
+		{FuelType, "", "", true},
+		{FuelType, "steam", "", false},
+		{Transmission, "a", TransmissionAutomatic, true},
+		{Transmission, "Otomatik", TransmissionAutomatic, true},
+		{Transmission, "S tronic", TransmissionDualClutch, true},
+		{Transmission, "DSG", TransmissionDualClutch, true},
+		{Transmission, "Otomatik (CVT)", TransmissionCVT, true},
+		{Drivetrain, "AWD", DrivetrainAWD, true},
+		{Drivetrain, "4WD", Drivetrain4WD, true},
+		{Drivetrain, "4x4", Drivetrain4WD, true},
+		{Drivetrain, "quattro", DrivetrainAWD, true},
+		{Drivetrain, "Önden Çekiş", DrivetrainFWD, true},
+		{BodyStyle, "Cabriolet", BodyConvertible, true},
+		{BodyStyle, "Station Wagon", BodyWagon, true},
+		{BodyStyle, "Avant", BodyWagon, true},
+		{BodyStyle, "Sportback", BodyHatchback, true},
+		{EmissionStandard, "Euro 6d-TEMP", "euro_6d_temp", true},
+		{EmissionStandard, "EURO6", "euro_6", true},
+		{EmissionStandard, "Euro 5b", "euro_5b", true},
+	}
+
+	for _, tc := range tests {
+		got, ok := tc.enum.Normalize(tc.input)
+		if got != tc.expect || ok != tc.ok {
+			t.Errorf("%s.Normalize(%q) = %q, %v; want %q, %v", tc.enum.Field, tc.input, got, ok, tc.expect, tc.ok)
+		}
+	}
+}
+
+func TestNormalizeField(t *testing.T) {
+	str := func(s string) *string { return &s }
+
+	v := str("Dizel")
+	if err := FuelType.NormalizeField(&v); err != nil || v == nil || *v != FuelDiesel {
+		t.Errorf("NormalizeField(Dizel) = %v, %v; want diesel", v, err)
+	}
+
+	v = str("  ")
+	if err := FuelType.NormalizeField(&v); err != nil || v != nil {
+		t.Errorf("NormalizeField(blank) = %v, %v; want nil", v, err)
+	}
+
+	v = str("steam")
+	if err := FuelType.NormalizeField(&v); err == nil {
+		t.Errorf("NormalizeField(steam): expected an error")
+	}
+
+	var missing *string
+	if err := FuelType.NormalizeField(&missing); err != nil || missing != nil {
+		t.Errorf("NormalizeField(nil) = %v, %v; want nil", missing, err)
+	}
+}
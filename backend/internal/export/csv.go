@@ -0,0 +1,129 @@
+package export
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// csvColumn is one column of a flattened Record
+type csvColumn struct {
+	// Name is the field's JSON path, e.g. "brand.name"
+	Name  string
+	index []int
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// csvColumns flattens the fields of t into columns, nested structs first
+// by their own fields. Lists stay in one column as JSON.
+func csvColumns(t reflect.Type, prefix string, index []int) []csvColumn {
+	var columns []csvColumn
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		fieldIndex := append(append([]int{}, index...), i)
+		if f.Type.Kind() == reflect.Struct && f.Type != timeType {
+			columns = append(columns, csvColumns(f.Type, prefix+name+".", fieldIndex)...)
+			continue
+		}
+		columns = append(columns, csvColumn{Name: prefix + name, index: fieldIndex})
+	}
+	return columns
+}
+
+// CSVColumns are the columns of a CSV export, in order
+var CSVColumns = csvColumns(reflect.TypeOf(Record{}), "", nil)
+
+// Flatten returns the values of r as they are written to CSV, in the order
+// of CSVColumns
+func (r *Record) Flatten() ([]string, error) {
+	v := reflect.ValueOf(r).Elem()
+	values := make([]string, len(CSVColumns))
+	for i, c := range CSVColumns {
+		cell, err := csvCell(v.FieldByIndex(c.index))
+		if err != nil {
+			return nil, err
+		}
+		values[i] = cell
+	}
+	return values, nil
+}
+
+type csvWriter struct {
+	writer *csv.Writer
+	header bool
+}
+
+func newCSVWriter(w io.Writer) *csvWriter {
+	return &csvWriter{writer: csv.NewWriter(w)}
+}
+
+// writeHeader writes the header row, once
+func (w *csvWriter) writeHeader() error {
+	if w.header {
+		return nil
+	}
+	w.header = true
+	header := make([]string, len(CSVColumns))
+	for i, c := range CSVColumns {
+		header[i] = c.Name
+	}
+	return w.writer.Write(header)
+}
+
+func (w *csvWriter) Write(r *Record) error {
+	if err := w.writeHeader(); err != nil {
+		return err
+	}
+
+	record, err := r.Flatten()
+	if err != nil {
+		return err
+	}
+	return w.writer.Write(record)
+}
+
+// Close flushes the rows. An empty export still gets its header.
+func (w *csvWriter) Close() error {
+	if err := w.writeHeader(); err != nil {
+		return err
+	}
+	w.writer.Flush()
+	return w.writer.Error()
+}
+
+// csvCell formats one value; missing values and empty lists are empty cells
+func csvCell(v reflect.Value) (string, error) {
+	if v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return "", nil
+		}
+		v = v.Elem()
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		return v.String(), nil
+	case reflect.Int, reflect.Int64:
+		return strconv.FormatInt(v.Int(), 10), nil
+	case reflect.Float64:
+		return strconv.FormatFloat(v.Float(), 'f', -1, 64), nil
+	case reflect.Bool:
+		return strconv.FormatBool(v.Bool()), nil
+	case reflect.Slice:
+		if v.Len() == 0 {
+			return "", nil
+		}
+		data, err := json.Marshal(v.Interface())
+		return string(data), err
+	}
+	if t, ok := v.Interface().(time.Time); ok {
+		return t.Format(time.RFC3339), nil
+	}
+	return "", nil
+}
@@ -0,0 +1,146 @@
+// Package export writes the catalogue, or the part of it matching the
+// search filters, as CSV, JSON Lines or Parquet for partners and analytics.
+package export
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/emirh/car-specs/backend/internal/repository"
+	"github.com/parquet-go/parquet-go"
+)
+
+// Export formats
+const (
+	FormatCSV     = "csv"
+	FormatJSONL   = "jsonl"
+	FormatParquet = "parquet"
+)
+
+// Formats lists the formats Export writes
+var Formats = []string{FormatCSV, FormatJSONL, FormatParquet}
+
+// ValidFormat reports whether Export writes format
+func ValidFormat(format string) bool {
+	for _, f := range Formats {
+		if f == format {
+			return true
+		}
+	}
+	return false
+}
+
+// ContentType returns the MIME type of an export in format
+func ContentType(format string) string {
+	switch format {
+	case FormatCSV:
+		return "text/csv; charset=utf-8"
+	case FormatJSONL:
+		return "application/jsonl"
+	}
+	return "application/vnd.apache.parquet"
+}
+
+// pageSize is how many trims are read, and held in memory, at a time
+const pageSize = 500
+
+// recordWriter writes records in one format
+type recordWriter interface {
+	Write(r *Record) error
+	// Close finishes the file; it does not close the underlying writer
+	Close() error
+}
+
+// Export writes the trims matching the search filters (see
+// repository.TrimRepository.Search) to w and returns how many it wrote
+func Export(db repository.Querier, w io.Writer, format string, filters map[string]interface{}) (int, error) {
+	var out recordWriter
+	switch format {
+	case FormatCSV:
+		out = newCSVWriter(w)
+	case FormatJSONL:
+		out = &jsonlWriter{encoder: json.NewEncoder(w)}
+	case FormatParquet:
+		out = &parquetWriter{writer: parquet.NewGenericWriter[Record](w, parquet.Compression(&parquet.Zstd))}
+	default:
+		return 0, fmt.Errorf("unknown export format %q", format)
+	}
+
+	count := 0
+	err := Each(db, filters, func(r *Record) error {
+		if err := out.Write(r); err != nil {
+			return fmt.Errorf("failed to write trim %d: %w", r.ID, err)
+		}
+		count++
+		return nil
+	})
+	if err != nil {
+		return count, err
+	}
+	return count, out.Close()
+}
+
+// Each calls fn with the record of every trim matching the search filters,
+// in search order. The catalogue is read in one transaction, so the records
+// are consistent even while it is being edited.
+func Each(db repository.Querier, filters map[string]interface{}, fn func(r *Record) error) error {
+	return repository.Transact(db, func(tx repository.Querier) error {
+		trimRepo := repository.NewTrimRepository(tx)
+		provenanceRepo := repository.NewProvenanceRepository(tx)
+
+		for offset := 0; ; offset += pageSize {
+			trims, err := trimRepo.ListForExport(filters, offset, pageSize)
+			if err != nil {
+				return err
+			}
+
+			ids := make([]int64, len(trims))
+			for i, trim := range trims {
+				ids[i] = trim.ID
+			}
+			specs, err := provenanceRepo.ListSpecCitationsByTrims(ids)
+			if err != nil {
+				return err
+			}
+			sources, err := provenanceRepo.ListSourcesByTrims(ids)
+			if err != nil {
+				return err
+			}
+
+			for _, trim := range trims {
+				if err := fn(newRecord(trim, specs[trim.ID], sources[trim.ID])); err != nil {
+					return err
+				}
+			}
+			if len(trims) < pageSize {
+				return nil
+			}
+		}
+	})
+}
+
+type jsonlWriter struct {
+	encoder *json.Encoder
+}
+
+func (w *jsonlWriter) Write(r *Record) error {
+	return w.encoder.Encode(r)
+}
+
+func (w *jsonlWriter) Close() error {
+	return nil
+}
+
+type parquetWriter struct {
+	writer *parquet.GenericWriter[Record]
+}
+
+func (w *parquetWriter) Write(r *Record) error {
+	_, err := w.writer.Write([]Record{*r})
+	return err
+}
+
+func (w *parquetWriter) Close() error {
+	return w.writer.Close()
+}
@@ -0,0 +1,237 @@
+package export
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/emirh/car-specs/backend/internal/importer"
+	"github.com/emirh/car-specs/backend/migrations"
+	"github.com/parquet-go/parquet-go"
+	_ "modernc.org/sqlite"
+)
+
+func openTestDB(t *testing.T) *sql.DB {
+	t.Helper()
+	db, err := sql.Open("sqlite", filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("failed to open db: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	migrator, err := migrations.NewMigrator(db)
+	if err != nil {
+		t.Fatalf("failed to load migrations: %v", err)
+	}
+	if err := migrator.Up(); err != nil {
+		t.Fatalf("failed to migrate: %v", err)
+	}
+	return db
+}
+
+// importFile imports path into db with the bundled profile of that name
+func importFile(t *testing.T, db *sql.DB, profile, path string) {
+	t.Helper()
+	p, err := importer.LoadProfile(filepath.Join("../../data/import_profiles", profile+".yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := importer.Import(db, p, path, importer.Options{MaxErrors: 0})
+	if err != nil {
+		t.Fatalf("Import(%s) error = %v", path, err)
+	}
+	if n := result.Count(importer.RowCreated); n != len(result.Rows) {
+		t.Fatalf("Import(%s) created %d of %d rows: %+v", path, n, len(result.Rows), result.Rows)
+	}
+}
+
+// seedCatalogue stores an Audi and a Volkswagen trim, the Audi with a cited spec
+func seedCatalogue(t *testing.T) *sql.DB {
+	t.Helper()
+	db := openTestDB(t)
+	dataset := filepath.Join(t.TempDir(), "seed.jsonl")
+	content := `{"brand": {"name": "Audi", "country": "Germany"}, "model": {"name": "A3", "body_style": "sedan"}, "generation": {"code": "8Y", "start_year": 2020}, "name": "35 TFSI", "year": 2021, "market": "TR", "fuel_type": "petrol", "power_hp": 150, "acceleration_0_100": 8.4, "currency": "TRY"}
+{"brand": {"name": "Volkswagen", "country": "Germany"}, "model": {"name": "Golf", "body_style": "hatchback"}, "generation": {"code": "Mk8", "start_year": 2019}, "name": "2.0 TDI", "year": 2022, "market": "TR", "fuel_type": "diesel", "power_hp": 150, "is_facelift": true, "currency": "TRY"}
+`
+	if err := os.WriteFile(dataset, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	importFile(t, db, "export", dataset)
+
+	_, err := db.Exec(`INSERT INTO specs (trim_id, category, name, value)
+		SELECT id, 'Comfort', 'Heated seats', 'Standard' FROM trims WHERE name = '35 TFSI'`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = db.Exec(`INSERT INTO spec_sources (spec_id, source_document_id)
+		SELECT (SELECT id FROM specs), id FROM source_documents`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+func exportRecords(t *testing.T, db *sql.DB, format string, filters map[string]interface{}) ([]byte, []Record) {
+	t.Helper()
+	var buf bytes.Buffer
+	count, err := Export(db, &buf, format, filters)
+	if err != nil {
+		t.Fatalf("Export(%s) error = %v", format, err)
+	}
+
+	var records []Record
+	switch format {
+	case FormatJSONL:
+		decoder := json.NewDecoder(bytes.NewReader(buf.Bytes()))
+		for decoder.More() {
+			var r Record
+			if err := decoder.Decode(&r); err != nil {
+				t.Fatal(err)
+			}
+			records = append(records, r)
+		}
+	case FormatParquet:
+		if records, err = parquet.Read[Record](bytes.NewReader(buf.Bytes()), int64(buf.Len())); err != nil {
+			t.Fatalf("failed to read the parquet export: %v", err)
+		}
+	}
+	if records != nil && len(records) != count {
+		t.Errorf("Export(%s) = %d trims, read back %d", format, count, len(records))
+	}
+	return buf.Bytes(), records
+}
+
+func TestExport(t *testing.T) {
+	db := seedCatalogue(t)
+
+	data, records := exportRecords(t, db, FormatJSONL, nil)
+	if len(records) != 2 {
+		t.Fatalf("export has %d trims, want 2:\n%s", len(records), data)
+	}
+	audi := records[0]
+	if audi.Brand.Name != "Audi" || audi.Model.Name != "A3" || audi.Generation.Code != "8Y" || audi.Name != "35 TFSI" ||
+		audi.PowerHP == nil || *audi.PowerHP != 150 || audi.Acceleration0To100 == nil || *audi.Acceleration0To100 != 8.4 {
+		t.Errorf("first record = %s, want the Audi", data)
+	}
+	if len(audi.Specs) != 1 || audi.Specs[0].Name != "Heated seats" || len(audi.Specs[0].Sources) != 1 {
+		t.Errorf("specs = %+v, want the cited heated seats", audi.Specs)
+	}
+	if len(audi.Sources) != 1 || audi.Sources[0].Type != "csv" || !strings.Contains(strings.Join(audi.Sources[0].Fields, ","), "power_hp") {
+		t.Errorf("sources = %+v, want the seed file citing power_hp", audi.Sources)
+	}
+
+	// Filters are the search filters
+	if _, records := exportRecords(t, db, FormatJSONL, map[string]interface{}{"fuel_type": []string{"diesel"}}); len(records) != 1 || records[0].Brand.Name != "Volkswagen" {
+		t.Errorf("diesel export = %+v, want the Golf", records)
+	}
+
+	// Trashed trims are not exported
+	if _, err := db.Exec(`UPDATE trims SET deleted_at = CURRENT_TIMESTAMP WHERE name = '2.0 TDI'`); err != nil {
+		t.Fatal(err)
+	}
+	if _, records := exportRecords(t, db, FormatJSONL, nil); len(records) != 1 {
+		t.Errorf("export has %d trims, want the trashed one left out", len(records))
+	}
+}
+
+func TestExportCSV(t *testing.T) {
+	db := seedCatalogue(t)
+
+	data, _ := exportRecords(t, db, FormatCSV, nil)
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("CSV has %d lines, want a header and 2 trims:\n%s", len(lines), data)
+	}
+	if !strings.HasPrefix(lines[0], "id,brand.name,brand.country,model.name,model.body_style,model.segment,generation.code,") {
+		t.Errorf("header = %s", lines[0])
+	}
+	if !strings.Contains(lines[1], `"[{""category"":""Comfort"",""name"":""Heated seats""`) {
+		t.Errorf("Audi row = %s, want the specs as JSON", lines[1])
+	}
+
+	// An empty export is still a CSV with a header
+	data, _ = exportRecords(t, db, FormatCSV, map[string]interface{}{"brand": "Porsche"})
+	if got := strings.TrimSpace(string(data)); !strings.HasPrefix(got, "id,") || strings.Contains(got, "\n") {
+		t.Errorf("empty export = %q, want only the header", got)
+	}
+}
+
+func TestExportParquet(t *testing.T) {
+	db := seedCatalogue(t)
+
+	_, want := exportRecords(t, db, FormatJSONL, nil)
+	_, got := exportRecords(t, db, FormatParquet, nil)
+	for i := range got {
+		// Parquet keeps milliseconds; JSON keeps what SQLite stored
+		got[i].UpdatedAt = want[i].UpdatedAt
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("parquet export = %+v\nwant %+v", got, want)
+	}
+}
+
+// TestExportRoundTrip imports an export into an empty database and checks
+// that exporting that gives the same catalogue
+func TestExportRoundTrip(t *testing.T) {
+	source := seedCatalogue(t)
+
+	for _, format := range []string{FormatCSV, FormatJSONL} {
+		t.Run(format, func(t *testing.T) {
+			data, _ := exportRecords(t, source, format, nil)
+			path := filepath.Join(t.TempDir(), "catalogue."+format)
+			if err := os.WriteFile(path, data, 0o644); err != nil {
+				t.Fatal(err)
+			}
+
+			target := openTestDB(t)
+			importFile(t, target, "export", path)
+
+			_, want := exportRecords(t, source, FormatJSONL, nil)
+			_, got := exportRecords(t, target, FormatJSONL, nil)
+			if len(got) != len(want) {
+				t.Fatalf("round trip has %d trims, want %d", len(got), len(want))
+			}
+			for i := range got {
+				// IDs, timestamps, specs and sources belong to the database, not the import
+				for _, r := range []*Record{&got[i], &want[i]} {
+					r.ID, r.UpdatedAt, r.Specs, r.Sources = 0, want[i].UpdatedAt, nil, nil
+				}
+				if !reflect.DeepEqual(got[i], want[i]) {
+					g, _ := json.Marshal(got[i])
+					w, _ := json.Marshal(want[i])
+					t.Errorf("round trip record %d = %s\nwant %s", i, g, w)
+				}
+			}
+		})
+	}
+}
+
+// TestExportProfileCoversFields checks that every field the importer can set
+// is exported and read back by export.yaml
+func TestExportProfileCoversFields(t *testing.T) {
+	p, err := importer.LoadProfile("../../data/import_profiles/export.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	columns := make(map[string]bool)
+	for _, c := range CSVColumns {
+		columns[c.Name] = true
+	}
+	mapped := make(map[string]bool)
+	for _, c := range p.Columns {
+		if !columns[c.Column] {
+			t.Errorf("export.yaml reads %s, which is not exported", c.Column)
+		}
+		mapped[c.Field] = true
+	}
+	for _, field := range importer.Fields() {
+		if !mapped[field] {
+			t.Errorf("%s is not exported or not read back by export.yaml", field)
+		}
+	}
+}
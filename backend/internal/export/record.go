@@ -0,0 +1,209 @@
+package export
+
+import (
+	"time"
+
+	"github.com/emirh/car-specs/backend/internal/models"
+)
+
+// Record is one exported trim with its place in the catalogue. JSON Lines
+// and Parquet keep it nested; CSV flattens the brand, model and generation
+// into dotted columns (brand.name, generation.code, ...). Those names are
+// also the JSON paths, so data/import_profiles/export.yaml reads both back.
+type Record struct {
+	ID         int64            `json:"id" parquet:"id"`
+	Brand      BrandRecord      `json:"brand" parquet:"brand"`
+	Model      ModelRecord      `json:"model" parquet:"model"`
+	Generation GenerationRecord `json:"generation" parquet:"generation"`
+
+	// Identification
+	Name       string `json:"name" parquet:"name"`
+	Year       int    `json:"year" parquet:"year"`
+	StartYear  *int   `json:"start_year" parquet:"start_year"`
+	EndYear    *int   `json:"end_year" parquet:"end_year"`
+	IsFacelift bool   `json:"is_facelift" parquet:"is_facelift"`
+	Market     string `json:"market" parquet:"market"`
+
+	// Engine Specifications
+	EngineType     *string `json:"engine_type" parquet:"engine_type"`
+	FuelType       *string `json:"fuel_type" parquet:"fuel_type"`
+	DisplacementCC *int    `json:"displacement_cc" parquet:"displacement_cc"`
+	Cylinders      *int    `json:"cylinders" parquet:"cylinders"`
+	CylinderLayout *string `json:"cylinder_layout" parquet:"cylinder_layout"`
+	PowerHP        *int    `json:"power_hp" parquet:"power_hp"`
+	PowerKW        *int    `json:"power_kw" parquet:"power_kw"`
+	TorqueNM       *int    `json:"torque_nm" parquet:"torque_nm"`
+	EngineCode     *string `json:"engine_code" parquet:"engine_code"`
+
+	// Performance
+	Acceleration0To100  *float64 `json:"acceleration_0_100" parquet:"acceleration_0_100"`
+	TopSpeedKmh         *int     `json:"top_speed_kmh" parquet:"top_speed_kmh"`
+	FuelConsumptionCity *float64 `json:"fuel_consumption_city" parquet:"fuel_consumption_city"`
+	FuelConsumptionHwy  *float64 `json:"fuel_consumption_highway" parquet:"fuel_consumption_highway"`
+	FuelConsumptionComb *float64 `json:"fuel_consumption_combined" parquet:"fuel_consumption_combined"`
+	CO2Emissions        *int     `json:"co2_emissions" parquet:"co2_emissions"`
+	EmissionStandard    *string  `json:"emission_standard" parquet:"emission_standard"`
+
+	// Transmission & Drivetrain
+	TransmissionType *string `json:"transmission_type" parquet:"transmission_type"`
+	TransmissionCode *string `json:"transmission_code" parquet:"transmission_code"`
+	Gears            *int    `json:"gears" parquet:"gears"`
+	Drivetrain       *string `json:"drivetrain" parquet:"drivetrain"`
+
+	// Dimensions & Weight
+	LengthMM            *int `json:"length_mm" parquet:"length_mm"`
+	WidthMM             *int `json:"width_mm" parquet:"width_mm"`
+	HeightMM            *int `json:"height_mm" parquet:"height_mm"`
+	WheelbaseMM         *int `json:"wheelbase_mm" parquet:"wheelbase_mm"`
+	GroundClearanceMM   *int `json:"ground_clearance_mm" parquet:"ground_clearance_mm"`
+	CurbWeightKG        *int `json:"curb_weight_kg" parquet:"curb_weight_kg"`
+	GrossWeightKG       *int `json:"gross_weight_kg" parquet:"gross_weight_kg"`
+	LuggageCapacityL    *int `json:"luggage_capacity_l" parquet:"luggage_capacity_l"`
+	LuggageCapacityMaxL *int `json:"luggage_capacity_max_l" parquet:"luggage_capacity_max_l"`
+	FuelTankCapacityL   *int `json:"fuel_tank_capacity_l" parquet:"fuel_tank_capacity_l"`
+
+	// Wheels & Tires
+	TireSizeFront   *string  `json:"tire_size_front" parquet:"tire_size_front"`
+	TireSizeRear    *string  `json:"tire_size_rear" parquet:"tire_size_rear"`
+	WheelSizeInches *float64 `json:"wheel_size_inches" parquet:"wheel_size_inches"`
+
+	// Additional
+	SeatingCapacity int      `json:"seating_capacity" parquet:"seating_capacity"`
+	Doors           *int     `json:"doors" parquet:"doors"`
+	ImageURL        *string  `json:"image_url" parquet:"image_url"`
+	MSRPPrice       *float64 `json:"msrp_price" parquet:"msrp_price"`
+	Currency        string   `json:"currency" parquet:"currency"`
+
+	UpdatedAt time.Time `json:"updated_at" parquet:"updated_at,timestamp(millisecond)"`
+
+	// Key-value specs and features, with the URLs they are cited to
+	Specs []SpecRecord `json:"specs" parquet:"specs,list"`
+	// Documents cited for the trim's fields
+	Sources []SourceRecord `json:"sources" parquet:"sources,list"`
+}
+
+type BrandRecord struct {
+	Name    string  `json:"name" parquet:"name"`
+	Country *string `json:"country" parquet:"country"`
+}
+
+type ModelRecord struct {
+	Name      string  `json:"name" parquet:"name"`
+	BodyStyle *string `json:"body_style" parquet:"body_style"`
+	Segment   *string `json:"segment" parquet:"segment"`
+}
+
+type GenerationRecord struct {
+	Code      string  `json:"code" parquet:"code"`
+	Name      *string `json:"name" parquet:"name"`
+	StartYear *int    `json:"start_year" parquet:"start_year"`
+	EndYear   *int    `json:"end_year" parquet:"end_year"`
+	Platform  *string `json:"platform" parquet:"platform"`
+}
+
+type SpecRecord struct {
+	Category string   `json:"category" parquet:"category"`
+	Name     string   `json:"name" parquet:"name"`
+	Value    string   `json:"value" parquet:"value"`
+	Sources  []string `json:"sources" parquet:"sources,list"`
+}
+
+type SourceRecord struct {
+	URL    string   `json:"url" parquet:"url"`
+	Title  *string  `json:"title" parquet:"title"`
+	Type   string   `json:"type" parquet:"type"`
+	Fields []string `json:"fields" parquet:"fields,list"`
+}
+
+// newRecord builds the record of trim, which must come with its model,
+// brand and generation (see TrimRepository.ListForExport)
+func newRecord(trim *models.Trim, specs []models.SpecCitations, sources []models.TrimSource) *Record {
+	r := &Record{
+		ID:         trim.ID,
+		Name:       trim.Name,
+		Year:       trim.Year,
+		StartYear:  trim.StartYear,
+		EndYear:    trim.EndYear,
+		IsFacelift: trim.IsFacelift,
+		Market:     trim.Market,
+
+		EngineType:     trim.EngineType,
+		FuelType:       trim.FuelType,
+		DisplacementCC: trim.DisplacementCC,
+		Cylinders:      trim.Cylinders,
+		CylinderLayout: trim.CylinderLayout,
+		PowerHP:        trim.PowerHP,
+		PowerKW:        trim.PowerKW,
+		TorqueNM:       trim.TorqueNM,
+		EngineCode:     trim.EngineCode,
+
+		Acceleration0To100:  trim.Acceleration0To100,
+		TopSpeedKmh:         trim.TopSpeedKmh,
+		FuelConsumptionCity: trim.FuelConsumptionCity,
+		FuelConsumptionHwy:  trim.FuelConsumptionHwy,
+		FuelConsumptionComb: trim.FuelConsumptionComb,
+		CO2Emissions:        trim.CO2Emissions,
+		EmissionStandard:    trim.EmissionStandard,
+
+		TransmissionType: trim.TransmissionType,
+		TransmissionCode: trim.TransmissionCode,
+		Gears:            trim.Gears,
+		Drivetrain:       trim.Drivetrain,
+
+		LengthMM:            trim.LengthMM,
+		WidthMM:             trim.WidthMM,
+		HeightMM:            trim.HeightMM,
+		WheelbaseMM:         trim.WheelbaseMM,
+		GroundClearanceMM:   trim.GroundClearanceMM,
+		CurbWeightKG:        trim.CurbWeightKG,
+		GrossWeightKG:       trim.GrossWeightKG,
+		LuggageCapacityL:    trim.LuggageCapacityL,
+		LuggageCapacityMaxL: trim.LuggageCapacityMaxL,
+		FuelTankCapacityL:   trim.FuelTankCapacityL,
+
+		TireSizeFront:   trim.TireSizeFront,
+		TireSizeRear:    trim.TireSizeRear,
+		WheelSizeInches: trim.WheelSizeInches,
+
+		SeatingCapacity: trim.SeatingCapacity,
+		Doors:           trim.Doors,
+		ImageURL:        trim.ImageURL,
+		MSRPPrice:       trim.MSRPPrice,
+		Currency:        trim.Currency,
+
+		UpdatedAt: trim.UpdatedAt.UTC(),
+		Specs:     []SpecRecord{},
+		Sources:   []SourceRecord{},
+	}
+
+	if trim.Model != nil {
+		r.Model = ModelRecord{Name: trim.Model.Name, BodyStyle: trim.Model.BodyStyle, Segment: trim.Model.Segment}
+		if trim.Model.Brand != nil {
+			r.Brand = BrandRecord{Name: trim.Model.Brand.Name, Country: trim.Model.Brand.Country}
+		}
+	}
+	if g := trim.GenerationObj; g != nil {
+		r.Generation = GenerationRecord{Code: g.Code, Name: g.Name, EndYear: g.EndYear, Platform: g.Platform}
+		if g.StartYear != 0 {
+			startYear := g.StartYear
+			r.Generation.StartYear = &startYear
+		}
+	}
+
+	for _, spec := range specs {
+		s := SpecRecord{Category: spec.Category, Name: spec.Name, Value: spec.Value, Sources: []string{}}
+		for _, c := range spec.Sources {
+			s.Sources = append(s.Sources, c.Source.URL)
+		}
+		r.Specs = append(r.Specs, s)
+	}
+	for _, source := range sources {
+		r.Sources = append(r.Sources, SourceRecord{
+			URL:    source.URL,
+			Title:  source.Title,
+			Type:   source.SourceType,
+			Fields: source.Fields,
+		})
+	}
+	return r
+}
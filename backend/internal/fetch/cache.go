@@ -0,0 +1,96 @@
+package fetch
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/url"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// cache stores each page as <sha256 of URL>.html with a .json file holding
+// what is needed to revalidate it
+type cache struct {
+	dir string
+}
+
+type cacheEntry struct {
+	URL          string    `json:"url"`
+	FinalURL     string    `json:"final_url"`
+	ETag         string    `json:"etag,omitempty"`
+	LastModified string    `json:"last_modified,omitempty"`
+	FetchedAt    time.Time `json:"fetched_at"`
+}
+
+func (c *cache) path(rawURL, ext string) string {
+	sum := sha256.Sum256([]byte(rawURL))
+	key := hex.EncodeToString(sum[:])
+	return filepath.Join(c.dir, key[:2], key+ext)
+}
+
+// load returns the entry for a URL, or nil when it isn't cached or is unreadable
+func (c *cache) load(rawURL string) *cacheEntry {
+	data, err := os.ReadFile(c.path(rawURL, ".json"))
+	if err != nil {
+		return nil
+	}
+	var entry cacheEntry
+	if err := json.Unmarshal(data, &entry); err != nil || entry.URL != rawURL {
+		return nil
+	}
+	if _, err := os.Stat(c.path(rawURL, ".html")); err != nil {
+		return nil
+	}
+	return &entry
+}
+
+func (c *cache) response(entry *cacheEntry) (*Response, error) {
+	body, err := os.ReadFile(c.path(entry.URL, ".html"))
+	if err != nil {
+		return nil, err
+	}
+	final, err := url.Parse(entry.FinalURL)
+	if err != nil {
+		return nil, err
+	}
+	return &Response{URL: final, Body: body, FromCache: true}, nil
+}
+
+// store writes the body before the entry, so an entry never points at a missing body
+func (c *cache) store(entry *cacheEntry, body []byte) error {
+	if err := writeFile(c.path(entry.URL, ".html"), body); err != nil {
+		return err
+	}
+	return c.touch(entry)
+}
+
+func (c *cache) touch(entry *cacheEntry) error {
+	data, err := json.MarshalIndent(entry, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFile(c.path(entry.URL, ".json"), data)
+}
+
+// writeFile replaces a file atomically, so a crash never leaves half a page
+func writeFile(path string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".tmp-*")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,331 @@
+// Package fetch is the HTTP layer every scraper fetches pages through.
+//
+// A Client is polite to the sites it reads: requests to one host are spaced
+// by a delay (or the host's robots.txt Crawl-delay, if longer) and capped in
+// number, paths robots.txt disallows are refused, and failed requests are
+// retried with exponential backoff. Pages are kept in an on-disk cache and
+// revalidated with conditional requests, so a re-run mostly costs 304s.
+package fetch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/temoto/robotstxt"
+)
+
+// UserAgent identifies the crawler to the sites. Its first word is the token
+// robots.txt groups are matched against.
+const UserAgent = "CarSpecsBot/1.0 (+https://github.com/emirhanseker1/Car-Specs-Platform)"
+
+// robotsAgent is the product token of UserAgent
+const robotsAgent = "CarSpecsBot"
+
+// ErrDisallowed is returned for pages the host's robots.txt disallows
+var ErrDisallowed = errors.New("disallowed by robots.txt")
+
+// StatusError is returned for a response that is neither 200 nor retried to success
+type StatusError struct {
+	URL    string
+	Status int
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("GET %s: %d %s", e.URL, e.Status, http.StatusText(e.Status))
+}
+
+// Options configure a Client. The zero value fetches with no delay, cache,
+// retries or robots.txt check; DefaultOptions is what crawls should use.
+type Options struct {
+	UserAgent string
+	Timeout   time.Duration
+	// Delay is the minimum time between two requests to one host
+	Delay time.Duration
+	// MaxConcurrent caps the requests in flight to one host (0 means 1)
+	MaxConcurrent int
+	// RespectRobots checks every path against the host's robots.txt
+	RespectRobots bool
+	// CacheDir keeps fetched pages on disk ("" disables the cache)
+	CacheDir string
+	// MaxAge is how long a cached page is used without asking the site.
+	// Older pages are revalidated with If-None-Match / If-Modified-Since.
+	MaxAge time.Duration
+	// Retries is how many times a network error, 429 or 5xx is retried
+	Retries int
+	// Backoff is the wait before the first retry; it doubles after each one.
+	// A Retry-After header is honoured instead when the site sends one.
+	Backoff time.Duration
+	// Transport sends the requests (http.DefaultTransport when nil)
+	Transport http.RoundTripper
+}
+
+// DefaultOptions are polite settings for crawling spec sites
+func DefaultOptions() Options {
+	return Options{
+		UserAgent:     UserAgent,
+		Timeout:       2 * time.Minute,
+		Delay:         2 * time.Second,
+		MaxConcurrent: 2,
+		RespectRobots: true,
+		MaxAge:        24 * time.Hour,
+		Retries:       3,
+		Backoff:       5 * time.Second,
+	}
+}
+
+// Response is a fetched page
+type Response struct {
+	// URL is the address the page was finally served from, after redirects
+	URL  *url.URL
+	Body []byte
+	// FromCache is true when the body came from the cache (fresh or revalidated)
+	FromCache bool
+}
+
+// Client fetches pages. It is safe for concurrent use.
+type Client struct {
+	opts  Options
+	http  *http.Client
+	cache *cache
+
+	mu    sync.Mutex
+	hosts map[string]*host
+}
+
+// host is the per-domain state: the request slots, the time the next request
+// may start and the parsed robots.txt
+type host struct {
+	slots chan struct{}
+
+	mu    sync.Mutex
+	next  time.Time
+	delay time.Duration
+
+	// robotsMu is held while robots.txt is checked, so it is fetched once
+	robotsMu sync.Mutex
+	robots   *robotstxt.Group
+}
+
+// New returns a client with the given options
+func New(opts Options) *Client {
+	if opts.MaxConcurrent <= 0 {
+		opts.MaxConcurrent = 1
+	}
+	c := &Client{
+		opts:  opts,
+		http:  &http.Client{Timeout: opts.Timeout, Transport: opts.Transport},
+		hosts: make(map[string]*host),
+	}
+	if opts.CacheDir != "" {
+		c.cache = &cache{dir: opts.CacheDir}
+	}
+	return c
+}
+
+// Get fetches a page, from the cache when it is fresh enough
+func (c *Client) Get(ctx context.Context, rawURL string) (*Response, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	h := c.host(u.Host)
+
+	if c.opts.RespectRobots {
+		allowed, err := c.allowed(ctx, h, u)
+		if err != nil {
+			return nil, err
+		}
+		if !allowed {
+			return nil, fmt.Errorf("%s: %w", rawURL, ErrDisallowed)
+		}
+	}
+
+	var entry *cacheEntry
+	if c.cache != nil {
+		entry = c.cache.load(rawURL)
+		if entry != nil && c.opts.MaxAge > 0 && time.Since(entry.FetchedAt) < c.opts.MaxAge {
+			return c.cache.response(entry)
+		}
+	}
+
+	resp, body, err := c.do(ctx, h, u, entry)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode == http.StatusNotModified && entry != nil {
+		entry.FetchedAt = time.Now().UTC()
+		if err := c.cache.touch(entry); err != nil {
+			return nil, err
+		}
+		return c.cache.response(entry)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, &StatusError{URL: rawURL, Status: resp.StatusCode}
+	}
+
+	if c.cache != nil {
+		entry := &cacheEntry{
+			URL:          rawURL,
+			FinalURL:     resp.Request.URL.String(),
+			ETag:         resp.Header.Get("ETag"),
+			LastModified: resp.Header.Get("Last-Modified"),
+			FetchedAt:    time.Now().UTC(),
+		}
+		if err := c.cache.store(entry, body); err != nil {
+			return nil, err
+		}
+	}
+	return &Response{URL: resp.Request.URL, Body: body}, nil
+}
+
+// do sends a GET, conditional when a cached entry exists, retrying network
+// errors, 429 and 5xx. The response body is read and closed.
+func (c *Client) do(ctx context.Context, h *host, u *url.URL, entry *cacheEntry) (*http.Response, []byte, error) {
+	wait := c.opts.Backoff
+	for attempt := 0; ; attempt++ {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+		if err != nil {
+			return nil, nil, err
+		}
+		if c.opts.UserAgent != "" {
+			req.Header.Set("User-Agent", c.opts.UserAgent)
+		}
+		if entry != nil {
+			if entry.ETag != "" {
+				req.Header.Set("If-None-Match", entry.ETag)
+			}
+			if entry.LastModified != "" {
+				req.Header.Set("If-Modified-Since", entry.LastModified)
+			}
+		}
+
+		resp, body, err := c.send(ctx, h, req)
+		retry := err != nil || resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
+		if !retry || attempt >= c.opts.Retries || ctx.Err() != nil {
+			return resp, body, err
+		}
+
+		delay := wait
+		if resp != nil {
+			if after, ok := retryAfter(resp.Header.Get("Retry-After")); ok {
+				delay = after
+			}
+		}
+		if err := sleep(ctx, delay); err != nil {
+			return nil, nil, err
+		}
+		wait *= 2
+	}
+}
+
+// send waits for a slot on the host and for its delay to pass, then sends the request
+func (c *Client) send(ctx context.Context, h *host, req *http.Request) (*http.Response, []byte, error) {
+	select {
+	case h.slots <- struct{}{}:
+	case <-ctx.Done():
+		return nil, nil, ctx.Err()
+	}
+	defer func() { <-h.slots }()
+
+	h.mu.Lock()
+	now := time.Now()
+	start := h.next
+	if start.Before(now) {
+		start = now
+	}
+	h.next = start.Add(h.delay)
+	h.mu.Unlock()
+	if err := sleep(ctx, time.Until(start)); err != nil {
+		return nil, nil, err
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, nil, err
+	}
+	return resp, body, nil
+}
+
+func (c *Client) host(name string) *host {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	h, ok := c.hosts[name]
+	if !ok {
+		h = &host{slots: make(chan struct{}, c.opts.MaxConcurrent), delay: c.opts.Delay}
+		c.hosts[name] = h
+	}
+	return h
+}
+
+// allowed checks a path against the host's robots.txt, fetching it on first
+// use. Its Crawl-delay raises the host's delay. A robots.txt that is missing
+// allows everything; one that fails with 5xx disallows everything.
+func (c *Client) allowed(ctx context.Context, h *host, u *url.URL) (bool, error) {
+	h.robotsMu.Lock()
+	defer h.robotsMu.Unlock()
+
+	if h.robots == nil {
+		robotsURL := &url.URL{Scheme: u.Scheme, Host: u.Host, Path: "/robots.txt"}
+		resp, body, err := c.do(ctx, h, robotsURL, nil)
+		if err != nil {
+			return false, fmt.Errorf("failed to fetch %s: %w", robotsURL, err)
+		}
+		data, err := robotstxt.FromStatusAndBytes(resp.StatusCode, body)
+		if err != nil {
+			return false, fmt.Errorf("failed to parse %s: %w", robotsURL, err)
+		}
+		h.robots = data.FindGroup(robotsAgent)
+
+		h.mu.Lock()
+		if h.robots.CrawlDelay > h.delay {
+			h.delay = h.robots.CrawlDelay
+		}
+		h.mu.Unlock()
+	}
+
+	path := u.EscapedPath()
+	if u.RawQuery != "" {
+		path += "?" + u.RawQuery
+	}
+	return h.robots.Test(path), nil
+}
+
+// retryAfter reads a Retry-After header given in seconds or as an HTTP date
+func retryAfter(v string) (time.Duration, bool) {
+	if v == "" {
+		return 0, false
+	}
+	if secs, err := strconv.Atoi(v); err == nil && secs >= 0 {
+		return time.Duration(secs) * time.Second, true
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		return time.Until(t), true
+	}
+	return 0, false
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,150 @@
+package fetch
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRobots(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/robots.txt" {
+			w.Write([]byte("User-agent: *\nDisallow: /\n\nUser-agent: CarSpecsBot\nDisallow: /private\nCrawl-delay: 0.05\n"))
+			return
+		}
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	client := New(Options{UserAgent: UserAgent, RespectRobots: true})
+	if _, err := client.Get(context.Background(), server.URL+"/private/page"); !errors.Is(err, ErrDisallowed) {
+		t.Errorf("disallowed page: err = %v; want ErrDisallowed", err)
+	}
+
+	start := time.Now()
+	for i := 0; i < 2; i++ {
+		if _, err := client.Get(context.Background(), server.URL+"/public"); err != nil {
+			t.Fatalf("allowed page: %v", err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed < 50*time.Millisecond {
+		t.Errorf("two requests took %v; Crawl-delay should space them by 50ms", elapsed)
+	}
+}
+
+func TestCache(t *testing.T) {
+	var requests, conditional int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			atomic.AddInt32(&conditional, 1)
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", `"v1"`)
+		w.Write([]byte("<html>A3</html>"))
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	get := func(maxAge time.Duration) *Response {
+		t.Helper()
+		resp, err := New(Options{CacheDir: dir, MaxAge: maxAge}).Get(context.Background(), server.URL+"/a3")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(resp.Body) != "<html>A3</html>" {
+			t.Errorf("body = %q", resp.Body)
+		}
+		return resp
+	}
+
+	if resp := get(0); resp.FromCache {
+		t.Error("first fetch came from the cache")
+	}
+	// Stale: revalidated with If-None-Match and answered 304
+	if resp := get(0); !resp.FromCache {
+		t.Error("revalidated fetch did not use the cached body")
+	}
+	// Fresh: no request at all
+	get(time.Hour)
+
+	if requests != 2 || conditional != 1 {
+		t.Errorf("%d requests, %d conditional; want 2 and 1", requests, conditional)
+	}
+}
+
+func TestRetry(t *testing.T) {
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&requests, 1)
+		if n == 1 {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		if n < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	if _, err := New(Options{Retries: 3, Backoff: time.Millisecond}).Get(context.Background(), server.URL); err != nil {
+		t.Fatalf("expected success after retries: %v", err)
+	}
+	if requests != 3 {
+		t.Errorf("%d requests; want 3", requests)
+	}
+
+	atomic.StoreInt32(&requests, 0)
+	_, err := New(Options{Retries: 1, Backoff: time.Millisecond}).Get(context.Background(), server.URL)
+	var status *StatusError
+	if !errors.As(err, &status) || status.Status != http.StatusServiceUnavailable {
+		t.Errorf("err = %v; want a 503 StatusError", err)
+	}
+}
+
+func TestHostLimits(t *testing.T) {
+	var inFlight, maxInFlight int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&inFlight, 1)
+		defer atomic.AddInt32(&inFlight, -1)
+		for {
+			max := atomic.LoadInt32(&maxInFlight)
+			if n <= max || atomic.CompareAndSwapInt32(&maxInFlight, max, n) {
+				break
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	client := New(Options{MaxConcurrent: 2, Delay: 10 * time.Millisecond})
+	start := time.Now()
+	var wg sync.WaitGroup
+	for i := 0; i < 6; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := client.Get(context.Background(), server.URL); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if maxInFlight > 2 {
+		t.Errorf("%d requests in flight; want at most 2", maxInFlight)
+	}
+	if elapsed := time.Since(start); elapsed < 50*time.Millisecond {
+		t.Errorf("6 requests took %v; a 10ms delay needs at least 50ms", elapsed)
+	}
+}
@@ -1,78 +1,59 @@
 package formatter
 
 import (
-	"fmt"
 	"strings"
 
+	"github.com/emirh/car-specs/backend/internal/enums"
+	"github.com/emirh/car-specs/backend/internal/metrics"
 	"github.com/emirh/car-specs/backend/internal/models"
 )
 
-// FormatTransmission converts raw transmission codes to Turkish
-func FormatTransmission(raw string) string {
-	if raw == "" {
-		return ""
-	}
-
-	lower := strings.ToLower(strings.TrimSpace(raw))
+// FormatTransmission converts a transmission value to a localised label
+func FormatTransmission(raw string, locale Locale) string {
+	return formatEnum(enums.Transmission, raw, locale)
+}
 
-	switch {
-	case strings.Contains(lower, "auto") || lower == "a":
-		return "Otomatik"
-	case strings.Contains(lower, "manual") || lower == "m":
-		return "Manuel"
-	case strings.Contains(lower, "cvt") || lower == "cv":
-		return "CVT"
-	case strings.Contains(lower, "dct") || strings.Contains(lower, "dual"):
-		return "Çift Kavramalı"
-	default:
-		return TitleCase(raw)
-	}
+// FormatFuelType converts a fuel type value to a localised label
+func FormatFuelType(raw string, locale Locale) string {
+	return formatEnum(enums.FuelType, raw, locale)
 }
 
-// FormatFuelType converts raw fuel type codes to Turkish
-func FormatFuelType(raw string) string {
-	if raw == "" {
-		return ""
-	}
+// FormatDrivetrain converts a drivetrain value to a localised label
+func FormatDrivetrain(raw string, locale Locale) string {
+	return formatEnum(enums.Drivetrain, raw, locale)
+}
 
-	lower := strings.ToLower(strings.TrimSpace(raw))
+// FormatBodyStyle converts a body style value to a localised label
+func FormatBodyStyle(raw string, locale Locale) string {
+	return formatEnum(enums.BodyStyle, raw, locale)
+}
 
-	switch {
-	case lower == "gas" || lower == "gasoline" || lower == "petrol":
-		return "Benzin"
-	case lower == "diesel" || lower == "dizel":
-		return "Dizel"
-	case lower == "electric" || lower == "electricity" || lower == "elektrik":
-		return "Elektrik"
-	case lower == "hybrid":
-		return "Hibrit"
-	case lower == "plug-in hybrid" || lower == "phev":
-		return "Plug-in Hibrit"
-	default:
-		return TitleCase(raw)
+// FormatEmissionStandard turns "euro_6d_temp" into "Euro 6d-TEMP"
+func FormatEmissionStandard(raw string, locale Locale) string {
+	canonical, ok := enums.EmissionStandard.Normalize(raw)
+	if !ok || canonical == "" {
+		return raw
 	}
+	parts := strings.SplitN(strings.TrimPrefix(canonical, "euro_"), "_", 2)
+	label := "Euro " + parts[0]
+	if len(parts) == 2 {
+		label += "-" + strings.ToUpper(parts[1])
+	}
+	return label
 }
 
-// FormatDrivetrain converts raw drivetrain codes to Turkish
-func FormatDrivetrain(raw string) string {
+// formatEnum labels canonical values (and the aliases enums knows) and
+// title-cases anything else
+func formatEnum(e enums.Enum, raw string, locale Locale) string {
 	if raw == "" {
 		return ""
 	}
-
-	lower := strings.ToLower(strings.TrimSpace(raw))
-
-	switch lower {
-	case "fwd":
-		return "Önden Çekiş"
-	case "rwd":
-		return "Arkadan İtiş"
-	case "awd", "4wd":
-		return "Dört Tekerlekten Çekiş"
-	case "4x4":
-		return "4x4"
-	default:
-		return strings.ToUpper(raw)
+	if canonical, ok := e.Normalize(raw); ok {
+		if l, ok := label(locale, e.Field+"."+canonical); ok {
+			return l
+		}
 	}
+	return TitleCase(raw)
 }
 
 // TitleCase converts a string to title case
@@ -148,77 +129,126 @@ func FormatModelName(raw string) string {
 }
 
 // FormatPowerWithUnit formats power value with HP unit
-func FormatPowerWithUnit(hp *int) string {
+func FormatPowerWithUnit(hp *int, opts Options) string {
 	if hp == nil || *hp == 0 {
 		return ""
 	}
-	return fmt.Sprintf("%d HP", *hp)
+	return qPower.format(float64(*hp), opts)
 }
 
-// FormatTorqueWithUnit formats torque value with Nm unit
-func FormatTorqueWithUnit(nm *int) string {
+// FormatTorqueWithUnit formats torque value with Nm (or lb-ft) unit
+func FormatTorqueWithUnit(nm *int, opts Options) string {
 	if nm == nil || *nm == 0 {
 		return ""
 	}
-	return fmt.Sprintf("%d Nm", *nm)
+	return qTorque.format(float64(*nm), opts)
 }
 
 // FormatAccelerationWithUnit formats 0-100 km/h time with seconds unit
-func FormatAccelerationWithUnit(sec *float64) string {
+func FormatAccelerationWithUnit(sec *float64, opts Options) string {
 	if sec == nil || *sec == 0 {
 		return ""
 	}
-	return fmt.Sprintf("%.1f s", *sec)
+	return qSeconds.format(*sec, opts)
 }
 
-// FormatSpeedWithUnit formats top speed with km/h unit
-func FormatSpeedWithUnit(kmh *int) string {
+// FormatSpeedWithUnit formats top speed with km/h (or mph) unit
+func FormatSpeedWithUnit(kmh *int, opts Options) string {
 	if kmh == nil || *kmh == 0 {
 		return ""
 	}
-	return fmt.Sprintf("%d km/h", *kmh)
+	return qSpeed.format(float64(*kmh), opts)
+}
+
+// Options selects the language and unit system of display strings
+type Options struct {
+	Locale Locale
+	Units  UnitSystem
 }
 
-// FormatTrim formats a complete trim object with all human-readable values
-func FormatTrim(trim *models.Trim) {
+// DefaultOptions are used when the client does not ask for anything else
+var DefaultOptions = Options{Locale: DefaultLocale, Units: UnitsMetric}
+
+// FormatTrim fills trim.Display with human-readable values. Raw fields are left
+// untouched so integrators can keep using the machine-readable values.
+func FormatTrim(trim *models.Trim, opts Options) {
 	if trim == nil {
 		return
 	}
 
-	// Format transmission
-	if trim.TransmissionType != nil {
-		formatted := FormatTransmission(*trim.TransmissionType)
-		trim.TransmissionType = &formatted
+	// Derived metrics only read numeric specs; fill them in for trims that
+	// did not come through the service
+	if trim.Derived == nil {
+		metrics.Apply(trim)
 	}
 
-	// Format fuel type
-	if trim.FuelType != nil {
-		formatted := FormatFuelType(*trim.FuelType)
-		trim.FuelType = &formatted
+	display := make(map[string]string)
+	setText := func(key string, raw *string, format func(string, Locale) string) {
+		if raw != nil && *raw != "" {
+			display[key] = format(*raw, opts.Locale)
+		}
 	}
-
-	// Format drivetrain
-	if trim.Drivetrain != nil {
-		formatted := FormatDrivetrain(*trim.Drivetrain)
-		trim.Drivetrain = &formatted
+	setInt := func(key string, v *int, q quantity) {
+		if v != nil && *v != 0 {
+			display[key] = q.format(float64(*v), opts)
+		}
+	}
+	setFloat := func(key string, v *float64, q quantity) {
+		if v != nil && *v != 0 {
+			display[key] = q.format(*v, opts)
+		}
 	}
 
-	// Format trim name
-	trim.Name = TitleCase(trim.Name)
-
-	// Format related model and brand if present
+	display["name"] = TitleCase(trim.Name)
 	if trim.Model != nil {
-		trim.Model.Name = FormatModelName(trim.Model.Name)
-
+		display["model"] = FormatModelName(trim.Model.Name)
 		if trim.Model.Brand != nil {
-			trim.Model.Brand.Name = FormatBrandName(trim.Model.Brand.Name)
+			display["brand"] = FormatBrandName(trim.Model.Brand.Name)
 		}
 	}
+
+	setText("transmission_type", trim.TransmissionType, FormatTransmission)
+	setText("fuel_type", trim.FuelType, FormatFuelType)
+	setText("drivetrain", trim.Drivetrain, FormatDrivetrain)
+	setText("emission_standard", trim.EmissionStandard, FormatEmissionStandard)
+	if trim.Model != nil {
+		setText("body_style", trim.Model.BodyStyle, FormatBodyStyle)
+	}
+
+	setInt("displacement_cc", trim.DisplacementCC, qDisplacement)
+	setInt("power_hp", trim.PowerHP, qPower)
+	setInt("power_kw", trim.PowerKW, qPowerKW)
+	setInt("torque_nm", trim.TorqueNM, qTorque)
+
+	setFloat("acceleration_0_100", trim.Acceleration0To100, qSeconds)
+	setInt("top_speed_kmh", trim.TopSpeedKmh, qSpeed)
+	setFloat("fuel_consumption_city", trim.FuelConsumptionCity, qConsumption)
+	setFloat("fuel_consumption_highway", trim.FuelConsumptionHwy, qConsumption)
+	setFloat("fuel_consumption_combined", trim.FuelConsumptionComb, qConsumption)
+	setInt("co2_emissions", trim.CO2Emissions, qCO2)
+
+	setInt("length_mm", trim.LengthMM, qLength)
+	setInt("width_mm", trim.WidthMM, qLength)
+	setInt("height_mm", trim.HeightMM, qLength)
+	setInt("wheelbase_mm", trim.WheelbaseMM, qLength)
+	setInt("ground_clearance_mm", trim.GroundClearanceMM, qLength)
+	setInt("curb_weight_kg", trim.CurbWeightKG, qWeight)
+	setInt("gross_weight_kg", trim.GrossWeightKG, qWeight)
+	setInt("luggage_capacity_l", trim.LuggageCapacityL, qVolume)
+	setInt("luggage_capacity_max_l", trim.LuggageCapacityMaxL, qVolume)
+	setInt("fuel_tank_capacity_l", trim.FuelTankCapacityL, qFuelTank)
+	setFloat("wheel_size_inches", trim.WheelSizeInches, qWheel)
+
+	if trim.Derived != nil {
+		setFloat("range_km", trim.Derived.RangeKM, qDistance)
+	}
+
+	trim.Display = display
 }
 
 // FormatTrims formats a slice of trims
-func FormatTrims(trims []*models.Trim) {
+func FormatTrims(trims []*models.Trim, opts Options) {
 	for _, trim := range trims {
-		FormatTrim(trim)
+		FormatTrim(trim, opts)
 	}
 }
@@ -0,0 +1,90 @@
+package formatter
+
+import (
+	"testing"
+
+	"github.com/emirh/car-specs/backend/internal/models"
+)
+
+func TestNegotiateLocale(t *testing.T) {
+	tests := []struct {
+		header string
+		expect Locale
+	}{
+		{"", LocaleTR},
+		{"en-US,en;q=0.9", LocaleEN},
+		{"de-DE,en;q=0.5,tr;q=0.8", LocaleTR},
+		{"tr;q=0.2, en-GB;q=0.7", LocaleEN},
+		{"fr, de", LocaleTR},
+		{"en;q=0, tr", LocaleTR},
+	}
+
+	for _, tc := range tests {
+		if got := NegotiateLocale(tc.header); got != tc.expect {
+			t.Errorf("NegotiateLocale(%q) = %q; want %q", tc.header, got, tc.expect)
+		}
+	}
+}
+
+func TestFormatTrim(t *testing.T) {
+	intPtr := func(v int) *int { return &v }
+	floatPtr := func(v float64) *float64 { return &v }
+	strPtr := func(v string) *string { return &v }
+
+	newTrim := func() *models.Trim {
+		return &models.Trim{
+			Name:                "35 tfsi s tronic",
+			FuelType:            strPtr("petrol"),
+			TransmissionType:    strPtr("Automatic"),
+			Drivetrain:          strPtr("fwd"),
+			TorqueNM:            intPtr(250),
+			TopSpeedKmh:         intPtr(224),
+			Acceleration0To100:  floatPtr(8.4),
+			FuelConsumptionComb: floatPtr(5.6),
+			LengthMM:            intPtr(4343),
+			CurbWeightKG:        intPtr(1345),
+			WheelSizeInches:     floatPtr(17),
+		}
+	}
+
+	tests := []struct {
+		opts   Options
+		expect map[string]string
+	}{
+		{Options{LocaleTR, UnitsMetric}, map[string]string{
+			"fuel_type":                 "Benzin",
+			"transmission_type":         "Otomatik",
+			"drivetrain":                "Önden Çekiş",
+			"torque_nm":                 "250 Nm",
+			"acceleration_0_100":        "8,4 s",
+			"fuel_consumption_combined": "5,6 L/100 km",
+			"wheel_size_inches":         `17"`,
+		}},
+		{Options{LocaleEN, UnitsImperial}, map[string]string{
+			"fuel_type":                 "Petrol",
+			"transmission_type":         "Automatic",
+			"drivetrain":                "Front-Wheel Drive",
+			"torque_nm":                 "184 lb-ft",
+			"top_speed_kmh":             "139 mph",
+			"acceleration_0_100":        "8.4 s",
+			"fuel_consumption_combined": "42.0 mpg",
+			"length_mm":                 "171.0 in",
+			"curb_weight_kg":            "2965 lbs",
+		}},
+	}
+
+	for _, tc := range tests {
+		trim := newTrim()
+		FormatTrim(trim, tc.opts)
+		for key, expect := range tc.expect {
+			if got := trim.Display[key]; got != expect {
+				t.Errorf("%v: display[%s] = %q; want %q", tc.opts, key, got, expect)
+			}
+		}
+
+		// Raw values are never overwritten
+		if *trim.FuelType != "petrol" || *trim.TorqueNM != 250 || trim.Name != "35 tfsi s tronic" {
+			t.Errorf("%v: raw values were modified", tc.opts)
+		}
+	}
+}
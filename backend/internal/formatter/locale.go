@@ -0,0 +1,152 @@
+package formatter
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Locale selects the language of display strings
+type Locale string
+
+const (
+	LocaleTR Locale = "tr"
+	LocaleEN Locale = "en"
+
+	// DefaultLocale is used when the client does not ask for a supported locale
+	DefaultLocale = LocaleTR
+)
+
+// SupportedLocales lists the locales display strings are available in
+var SupportedLocales = []Locale{LocaleTR, LocaleEN}
+
+// ParseLocale accepts a language tag such as "en", "en-US" or "tr_TR"
+func ParseLocale(tag string) (Locale, error) {
+	lang := strings.ToLower(strings.TrimSpace(tag))
+	if i := strings.IndexAny(lang, "-_"); i >= 0 {
+		lang = lang[:i]
+	}
+	for _, l := range SupportedLocales {
+		if Locale(lang) == l {
+			return l, nil
+		}
+	}
+	return "", fmt.Errorf("unsupported locale: %s", tag)
+}
+
+// NegotiateLocale picks the best supported locale from an Accept-Language header,
+// honouring q-values. Falls back to DefaultLocale.
+func NegotiateLocale(acceptLanguage string) Locale {
+	type candidate struct {
+		locale Locale
+		q      float64
+	}
+	var candidates []candidate
+
+	for _, part := range strings.Split(acceptLanguage, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		locale, err := ParseLocale(tag)
+		if err != nil {
+			continue
+		}
+		q := 1.0
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			if parsed, err := strconv.ParseFloat(v, 64); err == nil {
+				q = parsed
+			}
+		}
+		if q > 0 {
+			candidates = append(candidates, candidate{locale, q})
+		}
+	}
+
+	if len(candidates) == 0 {
+		return DefaultLocale
+	}
+	sort.SliceStable(candidates, func(i, j int) bool { return candidates[i].q > candidates[j].q })
+	return candidates[0].locale
+}
+
+// labels translates canonical enum values, keyed "<field>.<value>"
+var labels = map[Locale]map[string]string{
+	LocaleTR: {
+		"transmission_type.automatic":    "Otomatik",
+		"transmission_type.manual":       "Manuel",
+		"transmission_type.cvt":          "CVT",
+		"transmission_type.dual_clutch":  "Çift Kavramalı",
+		"transmission_type.single_speed": "Tek Vitesli",
+		"fuel_type.petrol":               "Benzin",
+		"fuel_type.diesel":               "Dizel",
+		"fuel_type.mild_hybrid":          "Hafif Hibrit",
+		"fuel_type.hybrid":               "Hibrit",
+		"fuel_type.plug_in_hybrid":       "Plug-in Hibrit",
+		"fuel_type.electric":             "Elektrik",
+		"fuel_type.lpg":                  "LPG",
+		"fuel_type.cng":                  "CNG",
+		"fuel_type.hydrogen":             "Hidrojen",
+		"drivetrain.fwd":                 "Önden Çekiş",
+		"drivetrain.rwd":                 "Arkadan İtiş",
+		"drivetrain.awd":                 "Dört Tekerlekten Çekiş",
+		"drivetrain.4wd":                 "4x4",
+		"body_style.sedan":               "Sedan",
+		"body_style.hatchback":           "Hatchback",
+		"body_style.liftback":            "Liftback",
+		"body_style.wagon":               "Station Wagon",
+		"body_style.coupe":               "Coupe",
+		"body_style.convertible":         "Cabrio",
+		"body_style.roadster":            "Roadster",
+		"body_style.suv":                 "SUV",
+		"body_style.mpv":                 "MPV",
+		"body_style.pickup":              "Pick-up",
+		"body_style.van":                 "Panelvan",
+	},
+	LocaleEN: {
+		"transmission_type.automatic":    "Automatic",
+		"transmission_type.manual":       "Manual",
+		"transmission_type.cvt":          "CVT",
+		"transmission_type.dual_clutch":  "Dual-Clutch",
+		"transmission_type.single_speed": "Single-Speed",
+		"fuel_type.petrol":               "Petrol",
+		"fuel_type.diesel":               "Diesel",
+		"fuel_type.mild_hybrid":          "Mild Hybrid",
+		"fuel_type.hybrid":               "Hybrid",
+		"fuel_type.plug_in_hybrid":       "Plug-in Hybrid",
+		"fuel_type.electric":             "Electric",
+		"fuel_type.lpg":                  "LPG",
+		"fuel_type.cng":                  "CNG",
+		"fuel_type.hydrogen":             "Hydrogen",
+		"drivetrain.fwd":                 "Front-Wheel Drive",
+		"drivetrain.rwd":                 "Rear-Wheel Drive",
+		"drivetrain.awd":                 "All-Wheel Drive",
+		"drivetrain.4wd":                 "Four-Wheel Drive",
+		"body_style.sedan":               "Sedan",
+		"body_style.hatchback":           "Hatchback",
+		"body_style.liftback":            "Liftback",
+		"body_style.wagon":               "Wagon",
+		"body_style.coupe":               "Coupe",
+		"body_style.convertible":         "Convertible",
+		"body_style.roadster":            "Roadster",
+		"body_style.suv":                 "SUV",
+		"body_style.mpv":                 "MPV",
+		"body_style.pickup":              "Pickup",
+		"body_style.van":                 "Van",
+	},
+}
+
+func label(locale Locale, key string) (string, bool) {
+	if l, ok := labels[locale][key]; ok {
+		return l, true
+	}
+	l, ok := labels[DefaultLocale][key]
+	return l, ok
+}
+
+// formatNumber prints v with the locale's decimal separator
+func formatNumber(v float64, decimals int, locale Locale) string {
+	s := strconv.FormatFloat(v, 'f', decimals, 64)
+	if locale == LocaleTR {
+		s = strings.Replace(s, ".", ",", 1)
+	}
+	return s
+}
@@ -0,0 +1,86 @@
+package formatter
+
+import (
+	"fmt"
+	"strings"
+)
+
+// UnitSystem selects the units of display strings. Raw values are always metric.
+type UnitSystem string
+
+const (
+	UnitsMetric   UnitSystem = "metric"
+	UnitsImperial UnitSystem = "imperial"
+)
+
+// ParseUnits accepts "metric" or "imperial"; empty means metric
+func ParseUnits(s string) (UnitSystem, error) {
+	switch UnitSystem(strings.ToLower(strings.TrimSpace(s))) {
+	case "", UnitsMetric:
+		return UnitsMetric, nil
+	case UnitsImperial:
+		return UnitsImperial, nil
+	}
+	return "", fmt.Errorf("unsupported units: %s (use metric or imperial)", s)
+}
+
+// Conversion factors from the metric values stored in the database
+const (
+	nmToLbFt       = 0.737562
+	kmToMiles      = 0.621371
+	mmPerInch      = 25.4
+	kgToLbs        = 2.20462
+	litresPerCuFt  = 28.3168
+	litresPerGal   = 3.78541 // US gallon
+	l100kmToMPGNum = 235.215 // US mpg = 235.215 / (L/100km)
+)
+
+// quantity is a metric value with its unit in both systems
+type quantity struct {
+	metricUnit   string
+	metricDec    int
+	imperialUnit string
+	imperialDec  int
+	toImperial   func(v float64) float64
+}
+
+var (
+	qPower        = quantity{"HP", 0, "HP", 0, nil}
+	qPowerKW      = quantity{"kW", 0, "kW", 0, nil}
+	qDisplacement = quantity{"cc", 0, "cc", 0, nil}
+	qTorque       = quantity{"Nm", 0, "lb-ft", 0, func(v float64) float64 { return v * nmToLbFt }}
+	qSeconds      = quantity{"s", 1, "s", 1, nil}
+	qSpeed        = quantity{"km/h", 0, "mph", 0, func(v float64) float64 { return v * kmToMiles }}
+	qConsumption  = quantity{"L/100 km", 1, "mpg", 1, func(v float64) float64 { return l100kmToMPGNum / v }}
+	qCO2          = quantity{"g/km", 0, "g/mi", 0, func(v float64) float64 { return v / kmToMiles }}
+	qLength       = quantity{"mm", 0, "in", 1, func(v float64) float64 { return v / mmPerInch }}
+	qWeight       = quantity{"kg", 0, "lbs", 0, func(v float64) float64 { return v * kgToLbs }}
+	qVolume       = quantity{"L", 0, "cu ft", 1, func(v float64) float64 { return v / litresPerCuFt }}
+	qFuelTank     = quantity{"L", 0, "gal", 1, func(v float64) float64 { return v / litresPerGal }}
+	qDistance     = quantity{"km", 0, "mi", 0, func(v float64) float64 { return v * kmToMiles }}
+	qWheel        = quantity{`"`, 0, `"`, 0, nil}
+)
+
+// format renders a metric value in the requested unit system
+func (q quantity) format(v float64, opts Options) string {
+	unit, decimals := q.metricUnit, q.metricDec
+	if opts.Units == UnitsImperial && q.toImperial != nil {
+		if v == 0 {
+			return ""
+		}
+		v = q.toImperial(v)
+		unit, decimals = q.imperialUnit, q.imperialDec
+	}
+	if unit == `"` {
+		return formatNumber(v, decimalsFor(v), opts.Locale) + unit
+	}
+	return formatNumber(v, decimals, opts.Locale) + " " + unit
+}
+
+// decimalsFor drops the fraction of whole numbers (17" rather than 17.0")
+func decimalsFor(v float64) int {
+	if v == float64(int64(v)) {
+		return 0
+	}
+	return 1
+}
@@ -0,0 +1,119 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/emirh/car-specs/backend/internal/models"
+	"github.com/emirh/car-specs/backend/internal/repository"
+	"github.com/emirh/car-specs/backend/internal/service"
+)
+
+// actorHeader names the editor making an API write, for the audit log
+const actorHeader = "X-Actor"
+
+// requestActor is who the audit log records as making an API write
+func requestActor(r *http.Request) models.Actor {
+	name := r.Header.Get(actorHeader)
+	if name == "" {
+		name = "anonymous"
+	}
+	return models.Actor{Name: name, Source: models.ActorSourceAPI}
+}
+
+type AuditHandler struct {
+	service *service.AuditService
+}
+
+func NewAuditHandler(service *service.AuditService) *AuditHandler {
+	return &AuditHandler{service: service}
+}
+
+// HandleListAudit handles GET /api/audit
+// Filters: ?entity_type=brand|model|generation|trim, ?entity_id=, ?actor=, ?source=, ?limit= (default 100, max 1000).
+func (h *AuditHandler) HandleListAudit(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	filter := repository.AuditFilter{
+		EntityType: query.Get("entity_type"),
+		Actor:      query.Get("actor"),
+		Source:     query.Get("source"),
+	}
+
+	var err error
+	if filter.EntityID, err = optionalID(query.Get("entity_id")); err != nil {
+		http.Error(w, "Invalid entity ID", http.StatusBadRequest)
+		return
+	}
+	if raw := query.Get("limit"); raw != "" {
+		if filter.Limit, err = strconv.Atoi(raw); err != nil {
+			http.Error(w, "Invalid limit", http.StatusBadRequest)
+			return
+		}
+	}
+
+	entries, err := h.service.ListEntries(filter)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(entries)
+}
+
+// HandleGetTrimHistory handles GET /api/trims/{id}/history
+// Lists every recorded write of the trim, newest first, also after it was deleted.
+func (h *TrimHandler) HandleGetTrimHistory(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid trim ID", http.StatusBadRequest)
+		return
+	}
+
+	history, err := h.service.TrimHistory(id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(history)
+}
+
+// HandleRestoreTrimVersion handles POST /api/trims/{id}/history/{entryId}/restore
+// The restored values are cited like any API edit (see requestSource).
+func (h *TrimHandler) HandleRestoreTrimVersion(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid trim ID", http.StatusBadRequest)
+		return
+	}
+	entryID, err := strconv.ParseInt(r.PathValue("entryId"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid audit entry ID", http.StatusBadRequest)
+		return
+	}
+	opts, err := formatOptions(w, r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	source, err := requestSource(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	trim, err := h.service.As(requestActor(r)).RestoreTrimVersion(id, entryID, source)
+	if errors.Is(err, repository.ErrAuditEntryNotFound) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if errors.Is(err, repository.ErrParentDeleted) {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	writeTrimUpdate(w, trim, err, opts)
+}
@@ -32,7 +32,7 @@ func (h *BrandHandler) HandleCreateBrand(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	brand, err := h.service.CreateBrand(req.Name, req.Country, req.LogoURL)
+	brand, err := h.service.As(requestActor(r)).CreateBrand(req.Name, req.Country, req.LogoURL)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
@@ -91,7 +91,7 @@ func (h *BrandHandler) HandleUpdateBrand(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	brand, err := h.service.UpdateBrand(id, req.Name, req.Country, req.LogoURL)
+	brand, err := h.service.As(requestActor(r)).UpdateBrand(id, req.Name, req.Country, req.LogoURL)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
@@ -110,7 +110,7 @@ func (h *BrandHandler) HandleDeleteBrand(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	if err := h.service.DeleteBrand(id); err != nil {
+	if err := h.service.As(requestActor(r)).DeleteBrand(id); err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
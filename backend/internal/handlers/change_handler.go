@@ -0,0 +1,92 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/emirh/car-specs/backend/internal/models"
+	"github.com/emirh/car-specs/backend/internal/repository"
+	"github.com/emirh/car-specs/backend/internal/service"
+)
+
+type ChangeHandler struct {
+	service *service.ChangeService
+}
+
+func NewChangeHandler(service *service.ChangeService) *ChangeHandler {
+	return &ChangeHandler{service: service}
+}
+
+// ResolveChangeRequest is the body of PATCH /api/changes/{id}
+type ResolveChangeRequest struct {
+	Status string `json:"status"`
+	Note   string `json:"note"`
+}
+
+// HandleListChanges handles GET /api/changes
+// Filters: ?trim_id=, ?field=, ?status=pending|applied|rejected|superseded|all (default pending).
+func (h *ChangeHandler) HandleListChanges(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	filter := repository.ChangeFilter{
+		Field:  query.Get("field"),
+		Status: query.Get("status"),
+	}
+	switch filter.Status {
+	case "":
+		filter.Status = models.ChangePending
+	case "all":
+		filter.Status = ""
+	}
+
+	var err error
+	if filter.TrimID, err = optionalID(query.Get("trim_id")); err != nil {
+		http.Error(w, "Invalid trim ID", http.StatusBadRequest)
+		return
+	}
+
+	changes, err := h.service.ListChanges(filter)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(changes)
+}
+
+// HandleResolveChange handles PATCH /api/changes/{id}
+// Status applied writes the new value to the trim, cited like any API edit
+// (see requestSource); rejected keeps the stored value.
+func (h *ChangeHandler) HandleResolveChange(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid change ID", http.StatusBadRequest)
+		return
+	}
+
+	var req ResolveChangeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	source, err := requestSource(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	change, err := h.service.As(requestActor(r)).ResolveChange(id, req.Status, req.Note, source)
+	if errors.Is(err, repository.ErrTrimChangeNotFound) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(change)
+}
@@ -0,0 +1,64 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/emirh/car-specs/backend/internal/models"
+	"github.com/emirh/car-specs/backend/internal/repository"
+)
+
+// manualEditSource is cited for API edits that don't name a source document
+const manualEditSource = "manual://api"
+
+// HandleGetCitations handles GET /api/trims/{id}/citations
+// ?field=acceleration_0_100 limits the result to one trim field.
+func (h *TrimHandler) HandleGetCitations(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid trim ID", http.StatusBadRequest)
+		return
+	}
+
+	citations, err := h.service.GetCitations(id, r.URL.Query().Get("field"))
+	if errors.Is(err, repository.ErrTrimNotFound) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(citations)
+}
+
+// requestSource is the source cited for an API edit. Editors can name the
+// document they copied values from with ?source_url= (and ?source_title=);
+// otherwise the edit is cited as a manual override.
+func requestSource(r *http.Request) (*models.SourceDocument, error) {
+	query := r.URL.Query()
+	now := time.Now().UTC()
+
+	source := &models.SourceDocument{
+		URL:         manualEditSource,
+		SourceType:  models.SourceManual,
+		RetrievedAt: &now,
+	}
+	if rawURL := query.Get("source_url"); rawURL != "" {
+		parsed, err := url.Parse(rawURL)
+		if err != nil || parsed.Scheme == "" || parsed.Host == "" {
+			return nil, errors.New("source_url must be an absolute URL")
+		}
+		source.URL = parsed.String()
+	}
+	if title := query.Get("source_title"); title != "" {
+		source.Title = &title
+	}
+	return source, nil
+}
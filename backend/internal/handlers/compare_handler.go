@@ -0,0 +1,80 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/emirh/car-specs/backend/internal/repository"
+	"github.com/emirh/car-specs/backend/internal/service"
+)
+
+// HandleCompareTrims handles GET /api/compare?trims=1,2,3
+// The trims are returned aligned field by field; deltas are relative to
+// ?baseline=<id>, which defaults to the first trim.
+func (h *TrimHandler) HandleCompareTrims(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	ids, err := parseCompareIDs(multiValues(query["trims"]))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var baselineID int64
+	if baselineStr := query.Get("baseline"); baselineStr != "" {
+		baselineID, err = strconv.ParseInt(baselineStr, 10, 64)
+		if err != nil {
+			http.Error(w, "Invalid baseline ID", http.StatusBadRequest)
+			return
+		}
+		if !containsID(ids, baselineID) {
+			http.Error(w, "baseline must be one of the compared trims", http.StatusBadRequest)
+			return
+		}
+	}
+
+	comparison, err := h.service.CompareTrims(ids, baselineID)
+	if errors.Is(err, repository.ErrTrimNotFound) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(comparison)
+}
+
+// parseCompareIDs validates the trim IDs of a comparison
+func parseCompareIDs(values []string) ([]int64, error) {
+	if len(values) < service.MinCompareTrims || len(values) > service.MaxCompareTrims {
+		return nil, fmt.Errorf("trims must list between %d and %d trim IDs", service.MinCompareTrims, service.MaxCompareTrims)
+	}
+
+	ids := make([]int64, 0, len(values))
+	for _, v := range values {
+		id, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid trim ID: %s", v)
+		}
+		if containsID(ids, id) {
+			return nil, fmt.Errorf("trim %d is listed more than once", id)
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+func containsID(ids []int64, id int64) bool {
+	for _, existing := range ids {
+		if existing == id {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,50 @@
+package handlers
+
+import (
+	"database/sql"
+	"log"
+	"net/http"
+
+	"github.com/emirh/car-specs/backend/internal/export"
+)
+
+type ExportHandler struct {
+	db *sql.DB
+}
+
+func NewExportHandler(db *sql.DB) *ExportHandler {
+	return &ExportHandler{db: db}
+}
+
+// HandleExport handles GET /api/export
+// ?format=csv|jsonl|parquet (default jsonl); the other parameters are the
+// /api/search filters. The export is streamed as it is read.
+func (h *ExportHandler) HandleExport(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	format := query.Get("format")
+	if format == "" {
+		format = export.FormatJSONL
+	}
+	if !export.ValidFormat(format) {
+		http.Error(w, "Invalid format (csv, jsonl or parquet)", http.StatusBadRequest)
+		return
+	}
+	filters, err := ParseSearchFilters(query)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", export.ContentType(format))
+	w.Header().Set("Content-Disposition", `attachment; filename="catalogue.`+format+`"`)
+
+	// The status goes out with the first row, after which an error can only cut the export short
+	count, err := export.Export(h.db, w, format, filters)
+	if err != nil {
+		if count == 0 {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		log.Printf("❌ Export failed after %d trims: %v", count, err)
+	}
+}
@@ -2,9 +2,12 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
 	"strconv"
 
+	"github.com/emirh/car-specs/backend/internal/models"
+	"github.com/emirh/car-specs/backend/internal/repository"
 	"github.com/emirh/car-specs/backend/internal/service"
 )
 
@@ -33,6 +36,46 @@ type GenerationDTO struct {
 	TrimCount   int     `json:"trim_count,omitempty"`
 }
 
+// GenerationRequest is the body for creating or updating a generation
+type GenerationRequest struct {
+	Code        string  `json:"code"`
+	Name        *string `json:"name"`
+	StartYear   int     `json:"start_year"`
+	EndYear     *int    `json:"end_year"`
+	ImageURL    *string `json:"image_url"`
+	Description *string `json:"description"`
+	IsCurrent   bool    `json:"is_current"`
+	Platform    *string `json:"platform"`
+}
+
+func (req GenerationRequest) generation() *models.Generation {
+	return &models.Generation{
+		Code:        req.Code,
+		Name:        req.Name,
+		StartYear:   req.StartYear,
+		EndYear:     req.EndYear,
+		ImageURL:    req.ImageURL,
+		Description: req.Description,
+		IsCurrent:   req.IsCurrent,
+		Platform:    req.Platform,
+	}
+}
+
+func toGenerationDTO(gen *models.Generation) GenerationDTO {
+	return GenerationDTO{
+		ID:          gen.ID,
+		ModelID:     gen.ModelID,
+		Code:        gen.Code,
+		Name:        gen.Name,
+		StartYear:   gen.StartYear,
+		EndYear:     gen.EndYear,
+		ImageURL:    gen.ImageURL,
+		Description: gen.Description,
+		IsCurrent:   gen.IsCurrent,
+		Platform:    gen.Platform,
+	}
+}
+
 // HandleListByModel handles GET /api/models/{modelId}/generations
 func (h *GenerationHandler) HandleListByModel(w http.ResponseWriter, r *http.Request) {
 	modelIDStr := r.PathValue("modelId")
@@ -51,18 +94,7 @@ func (h *GenerationHandler) HandleListByModel(w http.ResponseWriter, r *http.Req
 	// Convert to DTOs and add trim counts
 	var dtos []GenerationDTO
 	for _, gen := range generations {
-		dto := GenerationDTO{
-			ID:          gen.ID,
-			ModelID:     gen.ModelID,
-			Code:        gen.Code,
-			Name:        gen.Name,
-			StartYear:   gen.StartYear,
-			EndYear:     gen.EndYear,
-			ImageURL:    gen.ImageURL,
-			Description: gen.Description,
-			IsCurrent:   gen.IsCurrent,
-			Platform:    gen.Platform,
-		}
+		dto := toGenerationDTO(gen)
 
 		// Get trim count for this generation
 		count, err := h.generationService.GetTrimCount(gen.ID)
@@ -99,18 +131,7 @@ func (h *GenerationHandler) HandleGetGeneration(w http.ResponseWriter, r *http.R
 	}
 
 	// Convert to DTO
-	dto := GenerationDTO{
-		ID:          generation.ID,
-		ModelID:     generation.ModelID,
-		Code:        generation.Code,
-		Name:        generation.Name,
-		StartYear:   generation.StartYear,
-		EndYear:     generation.EndYear,
-		ImageURL:    generation.ImageURL,
-		Description: generation.Description,
-		IsCurrent:   generation.IsCurrent,
-		Platform:    generation.Platform,
-	}
+	dto := toGenerationDTO(generation)
 
 	// Get trim count
 	count, err := h.generationService.GetTrimCount(generationID)
@@ -121,3 +142,89 @@ func (h *GenerationHandler) HandleGetGeneration(w http.ResponseWriter, r *http.R
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(dto)
 }
+
+// HandleCreateGeneration handles POST /api/models/{modelId}/generations
+func (h *GenerationHandler) HandleCreateGeneration(w http.ResponseWriter, r *http.Request) {
+	modelID, err := strconv.ParseInt(r.PathValue("modelId"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid model ID", http.StatusBadRequest)
+		return
+	}
+
+	var req GenerationRequest
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&req); err != nil {
+		http.Error(w, "Invalid request body: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	generation := req.generation()
+	if err := h.generationService.As(requestActor(r)).CreateGeneration(modelID, generation); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(toGenerationDTO(generation))
+}
+
+// HandleUpdateGeneration handles PUT /api/generations/{generationId}
+func (h *GenerationHandler) HandleUpdateGeneration(w http.ResponseWriter, r *http.Request) {
+	generationID, err := strconv.ParseInt(r.PathValue("generationId"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid generation ID", http.StatusBadRequest)
+		return
+	}
+
+	var req GenerationRequest
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&req); err != nil {
+		http.Error(w, "Invalid request body: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	generation, err := h.generationService.As(requestActor(r)).UpdateGeneration(generationID, req.generation())
+	if errors.Is(err, repository.ErrGenerationNotFound) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	dto := toGenerationDTO(generation)
+	if count, err := h.generationService.GetTrimCount(generationID); err == nil {
+		dto.TrimCount = count
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(dto)
+}
+
+// HandleDeleteGeneration handles DELETE /api/generations/{generationId}
+// Refuses with 409 while trims exist unless ?cascade=true is given.
+func (h *GenerationHandler) HandleDeleteGeneration(w http.ResponseWriter, r *http.Request) {
+	generationID, err := strconv.ParseInt(r.PathValue("generationId"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid generation ID", http.StatusBadRequest)
+		return
+	}
+
+	cascade := r.URL.Query().Get("cascade") == "true"
+
+	err = h.generationService.As(requestActor(r)).DeleteGeneration(generationID, cascade)
+	switch {
+	case errors.Is(err, repository.ErrGenerationNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, service.ErrGenerationHasTrims):
+		http.Error(w, err.Error(), http.StatusConflict)
+	case err != nil:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	default:
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
@@ -0,0 +1,74 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/emirh/car-specs/backend/internal/models"
+	"github.com/emirh/car-specs/backend/internal/repository"
+	"github.com/emirh/car-specs/backend/internal/service"
+)
+
+type MergeHandler struct {
+	service *service.MergeService
+}
+
+func NewMergeHandler(service *service.MergeService) *MergeHandler {
+	return &MergeHandler{service: service}
+}
+
+// HandleMergeTrim handles POST /api/trims/{id}/merge
+// ?dry_run=true reports the outcome without writing anything.
+func (h *MergeHandler) HandleMergeTrim(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid trim ID", http.StatusBadRequest)
+		return
+	}
+	dryRun, _ := strconv.ParseBool(r.URL.Query().Get("dry_run"))
+
+	result, err := h.service.As(requestActor(r)).MergeTrim(id, dryRun)
+	if errors.Is(err, repository.ErrTrimNotFound) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		// Merged values that fail trim validation
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
+
+// HandleListConflicts handles GET /api/conflicts
+// ?trim_id= limits the list to one trim, ?status=open|resolved|all (default open).
+func (h *MergeHandler) HandleListConflicts(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	var trimID int64
+	if raw := query.Get("trim_id"); raw != "" {
+		id, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil {
+			http.Error(w, "Invalid trim ID", http.StatusBadRequest)
+			return
+		}
+		trimID = id
+	}
+	status := query.Get("status")
+	if status == "" {
+		status = models.ConflictOpen
+	}
+
+	conflicts, err := h.service.ListConflicts(trimID, status)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(conflicts)
+}
@@ -6,6 +6,7 @@ import (
 	"net/http"
 	"strconv"
 
+	"github.com/emirh/car-specs/backend/internal/formatter"
 	"github.com/emirh/car-specs/backend/internal/service"
 )
 
@@ -39,7 +40,7 @@ func (h *ModelHandler) HandleCreateModel(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	model, err := h.service.CreateModel(req.BrandID, req.Name, req.BodyStyle, req.Segment)
+	model, err := h.service.As(requestActor(r)).CreateModel(req.BrandID, req.Name, req.BodyStyle, req.Segment)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
@@ -123,7 +124,7 @@ func (h *ModelHandler) HandleUpdateModel(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	model, err := h.service.UpdateModel(id, req.BrandID, req.Name, req.BodyStyle, req.Segment)
+	model, err := h.service.As(requestActor(r)).UpdateModel(id, req.BrandID, req.Name, req.BodyStyle, req.Segment)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
@@ -142,7 +143,7 @@ func (h *ModelHandler) HandleDeleteModel(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	if err := h.service.DeleteModel(id); err != nil {
+	if err := h.service.As(requestActor(r)).DeleteModel(id); err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
@@ -186,12 +187,19 @@ func (h *ModelHandler) HandleGetVehicleDetails(w http.ResponseWriter, r *http.Re
 		return
 	}
 
+	opts, err := formatOptions(w, r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
 	// 2. Fetch Trims for this Generation
 	trims, err := h.trimService.ListTrimsByGeneration(id)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
+	formatter.FormatTrims(trims, opts)
 
 	// 3. Construct Response
 	// Format Trims to include powertrain_meta and map to frontend key names if needed
@@ -214,6 +222,8 @@ func (h *ModelHandler) HandleGetVehicleDetails(w http.ResponseWriter, r *http.Re
 		ID             int64           `json:"id"`
 		Name           string          `json:"name"`
 		PowertrainMeta *PowertrainMeta `json:"powertrain_meta,omitempty"`
+		// Display holds readable labels for the raw powertrain_meta values
+		Display map[string]string `json:"display,omitempty"`
 	}
 
 	var trimList []TrimResponse
@@ -233,6 +243,7 @@ func (h *ModelHandler) HandleGetVehicleDetails(w http.ResponseWriter, r *http.Re
 			ID:             t.ID,
 			Name:           t.Name,
 			PowertrainMeta: meta,
+			Display:        t.Display,
 		})
 	}
 
@@ -0,0 +1,133 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/emirh/car-specs/backend/internal/models"
+	"github.com/emirh/car-specs/backend/internal/repository"
+	"github.com/emirh/car-specs/backend/internal/service"
+)
+
+type ReviewHandler struct {
+	service *service.ReviewService
+}
+
+func NewReviewHandler(service *service.ReviewService) *ReviewHandler {
+	return &ReviewHandler{service: service}
+}
+
+// ResolveIssueRequest is the body of PATCH /api/review/issues/{id}
+type ResolveIssueRequest struct {
+	Status string `json:"status"`
+	Note   string `json:"note"`
+}
+
+// ResolveIssuesRequest is the body of POST /api/review/issues/resolve.
+// One of TrimID and GenerationID is required; Rule and Field narrow it further.
+type ResolveIssuesRequest struct {
+	TrimID       int64  `json:"trim_id"`
+	GenerationID int64  `json:"generation_id"`
+	Rule         string `json:"rule"`
+	Field        string `json:"field"`
+	Status       string `json:"status"`
+	Note         string `json:"note"`
+}
+
+// HandleListIssues handles GET /api/review/issues
+// Filters: ?trim_id=, ?generation_id=, ?rule=, ?field=, ?status=open|accepted|fixed|ignored|all (default open).
+func (h *ReviewHandler) HandleListIssues(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	filter := repository.ReviewFilter{
+		Status: query.Get("status"),
+		Rule:   query.Get("rule"),
+		Field:  query.Get("field"),
+	}
+	switch filter.Status {
+	case "":
+		filter.Status = models.IssueOpen
+	case "all":
+		filter.Status = ""
+	}
+
+	var err error
+	if filter.TrimID, err = optionalID(query.Get("trim_id")); err != nil {
+		http.Error(w, "Invalid trim ID", http.StatusBadRequest)
+		return
+	}
+	if filter.GenerationID, err = optionalID(query.Get("generation_id")); err != nil {
+		http.Error(w, "Invalid generation ID", http.StatusBadRequest)
+		return
+	}
+
+	issues, err := h.service.ListIssues(filter)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(issues)
+}
+
+// HandleResolveIssue handles PATCH /api/review/issues/{id}
+func (h *ReviewHandler) HandleResolveIssue(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid issue ID", http.StatusBadRequest)
+		return
+	}
+
+	var req ResolveIssueRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	issue, err := h.service.ResolveIssue(id, req.Status, req.Note)
+	if errors.Is(err, repository.ErrReviewIssueNotFound) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(issue)
+}
+
+// HandleResolveIssues handles POST /api/review/issues/resolve
+func (h *ReviewHandler) HandleResolveIssues(w http.ResponseWriter, r *http.Request) {
+	var req ResolveIssuesRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	filter := repository.ReviewFilter{
+		TrimID:       req.TrimID,
+		GenerationID: req.GenerationID,
+		Rule:         req.Rule,
+		Field:        req.Field,
+	}
+	updated, err := h.service.ResolveIssues(filter, req.Status, req.Note)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]int64{"updated": updated})
+}
+
+// optionalID parses an optional ID query parameter; empty means 0
+func optionalID(raw string) (int64, error) {
+	if raw == "" {
+		return 0, nil
+	}
+	return strconv.ParseInt(raw, 10, 64)
+}
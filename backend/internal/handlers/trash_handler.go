@@ -0,0 +1,58 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/emirh/car-specs/backend/internal/repository"
+	"github.com/emirh/car-specs/backend/internal/service"
+)
+
+type TrashHandler struct {
+	service *service.TrashService
+}
+
+func NewTrashHandler(service *service.TrashService) *TrashHandler {
+	return &TrashHandler{service: service}
+}
+
+// HandleListTrash handles GET /api/trash
+// ?entity_type=brand|model|generation|trim narrows it to one type.
+func (h *TrashHandler) HandleListTrash(w http.ResponseWriter, r *http.Request) {
+	items, err := h.service.ListTrash(r.URL.Query().Get("entity_type"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(items)
+}
+
+// HandleRestore handles POST /api/trash/{entityType}/{id}/restore
+// Restores the row and everything deleted with it, and returns the row.
+func (h *TrashHandler) HandleRestore(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	restored, err := h.service.As(requestActor(r)).Restore(r.PathValue("entityType"), id)
+	switch {
+	case errors.Is(err, repository.ErrNotInTrash):
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	case errors.Is(err, repository.ErrParentDeleted):
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	case err != nil:
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(restored)
+}
@@ -1,12 +1,20 @@
 package handlers
 
 import (
+	"bytes"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
 	"net/http"
+	"net/url"
 	"strconv"
+	"strings"
 
+	"github.com/emirh/car-specs/backend/internal/enums"
 	"github.com/emirh/car-specs/backend/internal/formatter"
 	"github.com/emirh/car-specs/backend/internal/models"
+	"github.com/emirh/car-specs/backend/internal/repository"
 	"github.com/emirh/car-specs/backend/internal/service"
 )
 
@@ -20,13 +28,19 @@ func NewTrimHandler(service *service.TrimService) *TrimHandler {
 
 // HandleCreateTrim handles POST /api/trims
 func (h *TrimHandler) HandleCreateTrim(w http.ResponseWriter, r *http.Request) {
-	var trim models.Trim
-	if err := json.NewDecoder(r.Body).Decode(&trim); err != nil {
+	source, err := requestSource(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	trim, fields, err := decodeTrim(r, false)
+	if err != nil {
 		http.Error(w, "Invalid request body", http.StatusBadRequest)
 		return
 	}
 
-	if err := h.service.CreateTrim(&trim); err != nil {
+	if err := h.service.As(requestActor(r)).CreateTrim(trim, source, fields); err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
@@ -47,6 +61,12 @@ func (h *TrimHandler) HandleGetTrim(w http.ResponseWriter, r *http.Request) {
 
 	includeRelations := r.URL.Query().Get("include_relations") == "true"
 
+	opts, err := formatOptions(w, r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
 	trim, err := h.service.GetTrim(id, includeRelations)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusNotFound)
@@ -54,7 +74,7 @@ func (h *TrimHandler) HandleGetTrim(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Format data for professional display
-	formatter.FormatTrim(trim)
+	formatter.FormatTrim(trim, opts)
 
 	// Frontend expects a wrapper object: { vehicle: {...}, trims: [...] }
 
@@ -62,7 +82,7 @@ func (h *TrimHandler) HandleGetTrim(w http.ResponseWriter, r *http.Request) {
 	var siblingTrims []*models.Trim
 	if trim.ModelID != 0 {
 		siblingTrims, _ = h.service.ListTrimsByModel(trim.ModelID)
-		formatter.FormatTrims(siblingTrims)
+		formatter.FormatTrims(siblingTrims, opts)
 	} else {
 		// Fallback if no ModelID
 		siblingTrims = []*models.Trim{trim}
@@ -72,9 +92,9 @@ func (h *TrimHandler) HandleGetTrim(w http.ResponseWriter, r *http.Request) {
 	brandName := ""
 	modelName := ""
 	if trim.Model != nil {
-		modelName = trim.Model.Name
+		modelName = trim.Display["model"]
 		if trim.Model.Brand != nil {
-			brandName = trim.Model.Brand.Name
+			brandName = trim.Display["brand"]
 		}
 	}
 
@@ -111,50 +131,166 @@ func (h *TrimHandler) HandleGetTrim(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(response)
 }
 
+const (
+	defaultSearchLimit = 50
+	maxSearchLimit     = 200
+)
+
 // HandleSearchTrims handles GET /api/search
+//
+// q is a free-text query (e.g. "a3 35 tfsi") matched against the full-text index.
+// brand, fuel_type, transmission, drivetrain and body_style accept several values,
+// either repeated or comma-separated. Numeric ranges use <field>_min / <field>_max,
+// year_from / year_to match trims whose production years overlap the span.
+// sort takes a field name, prefixed with "-" for descending; page and limit paginate.
 func (h *TrimHandler) HandleSearchTrims(w http.ResponseWriter, r *http.Request) {
 	query := r.URL.Query()
 
-	filters := make(map[string]interface{})
-	if brand := query.Get("brand"); brand != "" {
-		filters["brand"] = brand
+	filters, err := ParseSearchFilters(query)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
 	}
-	if model := query.Get("model"); model != "" {
-		filters["model"] = model
+
+	display, err := formatOptions(w, r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
 	}
-	if fuelType := query.Get("fuel_type"); fuelType != "" {
-		filters["fuel_type"] = fuelType
+
+	page := 1
+	if pageStr := query.Get("page"); pageStr != "" {
+		page, err = strconv.Atoi(pageStr)
+		if err != nil || page < 1 {
+			http.Error(w, "Invalid page", http.StatusBadRequest)
+			return
+		}
 	}
-	if transmission := query.Get("transmission"); transmission != "" {
-		filters["transmission"] = transmission
+	limit := defaultSearchLimit
+	if limitStr := query.Get("limit"); limitStr != "" {
+		limit, err = strconv.Atoi(limitStr)
+		if err != nil || limit < 1 || limit > maxSearchLimit {
+			http.Error(w, "Invalid limit (1-"+strconv.Itoa(maxSearchLimit)+")", http.StatusBadRequest)
+			return
+		}
 	}
-	if yearStr := query.Get("year"); yearStr != "" {
-		if year, err := strconv.Atoi(yearStr); err == nil {
-			filters["year"] = year
+
+	opts := repository.SearchOptions{Limit: limit, Offset: (page - 1) * limit}
+	if sort := query.Get("sort"); sort != "" {
+		opts.Sort = strings.TrimPrefix(sort, "-")
+		opts.Desc = strings.HasPrefix(sort, "-")
+		if !repository.IsSearchSortField(opts.Sort) {
+			http.Error(w, "Invalid sort field: "+opts.Sort, http.StatusBadRequest)
+			return
 		}
 	}
 
-	trims, err := h.service.SearchTrims(filters)
+	trims, total, err := h.service.SearchTrims(filters, opts)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
 	// Format all trims for professional display
-	formatter.FormatTrims(trims)
+	formatter.FormatTrims(trims, display)
 
 	// Get facets for filters
-	facets, _ := h.service.GetSearchFacets()
+	facets, err := h.service.GetSearchFacets(filters)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if trims == nil {
+		trims = []*models.Trim{}
+	}
 
 	response := map[string]interface{}{
-		"results": trims,
-		"facets":  facets,
+		"results":     trims,
+		"facets":      facets,
+		"total":       total,
+		"page":        page,
+		"limit":       limit,
+		"total_pages": (total + limit - 1) / limit,
 	}
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(response)
 }
 
+// ParseSearchFilters reads the search filters from the query string, as
+// /api/search and /api/export take them
+func ParseSearchFilters(query url.Values) (map[string]interface{}, error) {
+	filters := make(map[string]interface{})
+	if q := strings.TrimSpace(query.Get("q")); q != "" {
+		if !repository.HasSearchTerms(q) {
+			return nil, fmt.Errorf("q has no searchable terms")
+		}
+		filters["q"] = q
+	}
+	if model := query.Get("model"); model != "" {
+		filters["model"] = model
+	}
+
+	if values := multiValues(query["brand"]); len(values) > 0 {
+		filters["brand"] = values
+	}
+	// Enum filters accept any known spelling ("Dizel", "4x4") and match the canonical value
+	for name, enum := range map[string]enums.Enum{
+		"fuel_type":    enums.FuelType,
+		"transmission": enums.Transmission,
+		"drivetrain":   enums.Drivetrain,
+		"body_style":   enums.BodyStyle,
+	} {
+		values := multiValues(query[name])
+		for i, v := range values {
+			if canonical, ok := enum.Normalize(v); ok {
+				values[i] = canonical
+			}
+		}
+		if len(values) > 0 {
+			filters[name] = values
+		}
+	}
+
+	for _, name := range []string{"year", "year_from", "year_to"} {
+		if v := query.Get(name); v != "" {
+			year, err := strconv.Atoi(v)
+			if err != nil {
+				return nil, fmt.Errorf("invalid %s: %s", name, v)
+			}
+			filters[name] = year
+		}
+	}
+
+	for _, rf := range repository.RangeFilters {
+		for _, key := range []string{rf.Name + "_min", rf.Name + "_max"} {
+			if v := query.Get(key); v != "" {
+				f, err := strconv.ParseFloat(v, 64)
+				if err != nil {
+					return nil, fmt.Errorf("invalid %s: %s", key, v)
+				}
+				filters[key] = f
+			}
+		}
+	}
+
+	return filters, nil
+}
+
+// multiValues flattens repeated and comma-separated query values
+func multiValues(raw []string) []string {
+	var values []string
+	for _, r := range raw {
+		for _, v := range strings.Split(r, ",") {
+			if v = strings.TrimSpace(v); v != "" {
+				values = append(values, v)
+			}
+		}
+	}
+	return values
+}
+
 // HandleListTrimsByModel handles GET /api/models/:modelId/trims
 func (h *TrimHandler) HandleListTrimsByModel(w http.ResponseWriter, r *http.Request) {
 	modelIDStr := r.PathValue("modelId")
@@ -164,6 +300,12 @@ func (h *TrimHandler) HandleListTrimsByModel(w http.ResponseWriter, r *http.Requ
 		return
 	}
 
+	opts, err := formatOptions(w, r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
 	trims, err := h.service.ListTrimsByModel(modelID)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
@@ -171,12 +313,117 @@ func (h *TrimHandler) HandleListTrimsByModel(w http.ResponseWriter, r *http.Requ
 	}
 
 	// Format all trims for professional display
-	formatter.FormatTrims(trims)
+	formatter.FormatTrims(trims, opts)
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(trims)
 }
 
+// HandleUpdateTrim handles PUT /api/trims/:id
+func (h *TrimHandler) HandleUpdateTrim(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid trim ID", http.StatusBadRequest)
+		return
+	}
+
+	opts, err := formatOptions(w, r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	source, err := requestSource(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	trim, fields, err := decodeTrim(r, true)
+	if err != nil {
+		http.Error(w, "Invalid request body: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	updated, err := h.service.As(requestActor(r)).UpdateTrim(id, trim, source, fields)
+	writeTrimUpdate(w, updated, err, opts)
+}
+
+// HandlePatchTrim handles PATCH /api/trims/:id
+// Only the fields present in the body change; a null value clears the field.
+func (h *TrimHandler) HandlePatchTrim(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid trim ID", http.StatusBadRequest)
+		return
+	}
+
+	opts, err := formatOptions(w, r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	source, err := requestSource(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var patch map[string]json.RawMessage
+	if err := json.NewDecoder(r.Body).Decode(&patch); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	updated, err := h.service.As(requestActor(r)).PatchTrim(id, patch, source)
+	writeTrimUpdate(w, updated, err, opts)
+}
+
+// decodeTrim reads a trim request body and the names of the fields it
+// contains, so only what the caller sent is cited. strict rejects unknown fields.
+func decodeTrim(r *http.Request, strict bool) (*models.Trim, []string, error) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return nil, nil, err
+	}
+	var sent map[string]json.RawMessage
+	if err := json.Unmarshal(body, &sent); err != nil {
+		return nil, nil, err
+	}
+
+	trim := &models.Trim{}
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	if strict {
+		decoder.DisallowUnknownFields()
+	}
+	if err := decoder.Decode(trim); err != nil {
+		return nil, nil, err
+	}
+
+	fields := make([]string, 0, len(sent))
+	for name := range sent {
+		fields = append(fields, name)
+	}
+	return trim, fields, nil
+}
+
+func writeTrimUpdate(w http.ResponseWriter, trim *models.Trim, err error, opts formatter.Options) {
+	if errors.Is(err, repository.ErrTrimNotFound) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	formatter.FormatTrim(trim, opts)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(trim)
+}
+
 // HandleDeleteTrim handles DELETE /api/trims/:id
 func (h *TrimHandler) HandleDeleteTrim(w http.ResponseWriter, r *http.Request) {
 	idStr := r.PathValue("id")
@@ -186,7 +433,12 @@ func (h *TrimHandler) HandleDeleteTrim(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := h.service.DeleteTrim(id); err != nil {
+	err = h.service.As(requestActor(r)).DeleteTrim(id)
+	if errors.Is(err, repository.ErrTrimNotFound) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
@@ -196,6 +448,12 @@ func (h *TrimHandler) HandleDeleteTrim(w http.ResponseWriter, r *http.Request) {
 
 // HandleGetFeaturedTrims handles GET /api/featured
 func (h *TrimHandler) HandleGetFeaturedTrims(w http.ResponseWriter, r *http.Request) {
+	opts, err := formatOptions(w, r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
 	// Get 4 random trims with images for homepage
 	trims, err := h.service.GetFeatured(4)
 	if err != nil {
@@ -204,7 +462,7 @@ func (h *TrimHandler) HandleGetFeaturedTrims(w http.ResponseWriter, r *http.Requ
 	}
 
 	// Format all trims for professional display
-	formatter.FormatTrims(trims)
+	formatter.FormatTrims(trims, opts)
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(trims)
@@ -219,6 +477,12 @@ func (h *TrimHandler) HandleListTrimsByGeneration(w http.ResponseWriter, r *http
 		return
 	}
 
+	opts, err := formatOptions(w, r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
 	trims, err := h.service.ListTrimsByGeneration(generationID)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
@@ -226,7 +490,7 @@ func (h *TrimHandler) HandleListTrimsByGeneration(w http.ResponseWriter, r *http
 	}
 
 	// Format all trims for professional display
-	formatter.FormatTrims(trims)
+	formatter.FormatTrims(trims, opts)
 
 	// Create simplified response for trim selection
 	type TrimListDTO struct {
@@ -242,6 +506,8 @@ func (h *TrimHandler) HandleListTrimsByGeneration(w http.ResponseWriter, r *http
 		Year               int      `json:"year"`
 		StartYear          *int     `json:"start_year,omitempty"`
 		EndYear            *int     `json:"end_year,omitempty"`
+
+		Display map[string]string `json:"display,omitempty"`
 	}
 
 	var trimDTOs []TrimListDTO
@@ -259,6 +525,7 @@ func (h *TrimHandler) HandleListTrimsByGeneration(w http.ResponseWriter, r *http
 			Year:               trim.Year,
 			StartYear:          trim.StartYear,
 			EndYear:            trim.EndYear,
+			Display:            trim.Display,
 		}
 		trimDTOs = append(trimDTOs, dto)
 	}
@@ -266,3 +533,30 @@ func (h *TrimHandler) HandleListTrimsByGeneration(w http.ResponseWriter, r *http
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(trimDTOs)
 }
+
+// formatOptions reads the display locale (?locale= or Accept-Language) and unit
+// system (?units=metric|imperial) of a request and announces the chosen locale
+func formatOptions(w http.ResponseWriter, r *http.Request) (formatter.Options, error) {
+	opts := formatter.DefaultOptions
+	query := r.URL.Query()
+
+	if locale := query.Get("locale"); locale != "" {
+		l, err := formatter.ParseLocale(locale)
+		if err != nil {
+			return opts, err
+		}
+		opts.Locale = l
+	} else if accept := r.Header.Get("Accept-Language"); accept != "" {
+		opts.Locale = formatter.NegotiateLocale(accept)
+	}
+
+	units, err := formatter.ParseUnits(query.Get("units"))
+	if err != nil {
+		return opts, err
+	}
+	opts.Units = units
+
+	w.Header().Set("Content-Language", string(opts.Locale))
+	w.Header().Add("Vary", "Accept-Language")
+	return opts, nil
+}
@@ -1,18 +1,23 @@
 package importer
 
 import (
+	"database/sql"
+	"errors"
 	"fmt"
 	"log"
 	"strconv"
 	"strings"
+	"time"
 
+	"github.com/emirh/car-specs/backend/internal/enums"
 	"github.com/emirh/car-specs/backend/internal/models"
+	"github.com/emirh/car-specs/backend/internal/repository"
+	"github.com/emirh/car-specs/backend/internal/service"
 	"github.com/emirh/car-specs/backend/pkg/apininjas"
-	"gorm.io/gorm"
 )
 
 // SyncApiNinjasData fetches data for provided makes and saves them ensuring relational integrity.
-func SyncApiNinjasData(db *gorm.DB, client *apininjas.Client, makes []string) error {
+func SyncApiNinjasData(db *sql.DB, client *apininjas.Client, makes []string) error {
 	for _, makeName := range makes {
 		log.Printf("Fetching cars for make: %s...", makeName)
 		// Use 0 for year to indicate "any year" (legacy behavior)
@@ -27,71 +32,11 @@ func SyncApiNinjasData(db *gorm.DB, client *apininjas.Client, makes []string) er
 			continue
 		}
 
-		// Transaction for each Make to ensure consistency
-		err = db.Transaction(func(tx *gorm.DB) error {
-			// 1. Find or Create Make
-			var makeModel models.Make
-			if err := tx.FirstOrCreate(&makeModel, models.Make{Name: makeName}).Error; err != nil {
-				return err
-			}
-
-			for _, car := range cars {
-				// 2. Find or Create Model
-				var modelModel models.Model
-				if err := tx.FirstOrCreate(&modelModel, models.Model{Name: car.Model, MakeID: makeModel.ID}).Error; err != nil {
-					return err
-				}
-
-				// 3. Create Trim
-				// Construct a descriptive Trim name
-				trimName := fmt.Sprintf("%d %s %s", car.Year, car.Transmission, car.Drive)
-
-				// Helper to get float from interface{}
-				dispFloat := toFloat(car.Displacement)
-
-				if dispFloat > 0 {
-					trimName = fmt.Sprintf("%s %.1fL", trimName, dispFloat)
-				}
-
-				var trimModel models.Trim
-				// Avoid duplicates based on Name + ModelID?
-				// Since we don't have unique constraints perfectly set up, we check manually or use FirstOrCreate
-				err := tx.Where(models.Trim{ModelID: modelModel.ID, Name: trimName, Year: car.Year}).
-					FirstOrCreate(&trimModel, models.Trim{
-						ModelID: modelModel.ID,
-						Name:    trimName,
-						Year:    car.Year,
-					}).Error
-				if err != nil {
-					return err
-				}
-
-				// 4. Add Specs (Technical Details)
-				specs := []models.Spec{
-					{TrimID: int64(trimModel.ID), Category: "General", Name: "Class", Value: car.Class},
-					{TrimID: int64(trimModel.ID), Category: "Engine", Name: "Cylinders", Value: toString(car.Cylinders)},
-					{TrimID: int64(trimModel.ID), Category: "Engine", Name: "Displacement", Value: toString(car.Displacement)},
-					{TrimID: int64(trimModel.ID), Category: "Engine", Name: "Fuel Type", Value: car.FuelType},
-					{TrimID: int64(trimModel.ID), Category: "Transmission", Name: "Transmission", Value: car.Transmission},
-					{TrimID: int64(trimModel.ID), Category: "Drivetrain", Name: "Drive", Value: car.Drive},
-					{TrimID: int64(trimModel.ID), Category: "Consumption", Name: "City MPG", Value: toString(car.CityMPG)},
-					{TrimID: int64(trimModel.ID), Category: "Consumption", Name: "Highway MPG", Value: toString(car.HighwayMPG)},
-				}
-
-				for _, spec := range specs {
-					// Upsert Spec? Or just Create (can lead to dupes if run multiple times)
-					// Let's use FirstOrCreate
-					if err := tx.Where(&models.Spec{TrimID: spec.TrimID, Category: spec.Category, Name: spec.Name}).
-						Assign(models.Spec{Value: spec.Value}). // Update value if exists
-						FirstOrCreate(&spec).Error; err != nil {
-						return err
-					}
-				}
-			}
-			return nil
-		})
-
-		if err != nil {
+		// Store under the requested make name; saveCars uses one transaction per make
+		for i := range cars {
+			cars[i].Make = makeName
+		}
+		if err := saveCars(db, cars, apininjas.QueryURL(makeName, 0)); err != nil {
 			log.Printf("Failed to save data for %s: %v", makeName, err)
 		} else {
 			log.Printf("Successfully imported %d cars for %s", len(cars), makeName)
@@ -101,7 +46,7 @@ func SyncApiNinjasData(db *gorm.DB, client *apininjas.Client, makes []string) er
 }
 
 // SyncDetailedYears fetches data for specific years (e.g. 2024, 2025) to showcase detailed new models.
-func SyncDetailedYears(db *gorm.DB, client *apininjas.Client, makes []string, years []int) error {
+func SyncDetailedYears(db *sql.DB, client *apininjas.Client, makes []string, years []int) error {
 	for _, makeName := range makes {
 		for _, year := range years {
 			log.Printf(">>> Demo Mode: Fetching %s models for %d...", makeName, year)
@@ -116,11 +61,7 @@ func SyncDetailedYears(db *gorm.DB, client *apininjas.Client, makes []string, ye
 			}
 			log.Printf("   Found %d models! Importing...", len(cars))
 
-			// Reuse the same transaction logic?
-			// We can extract the saving logic to a helper "importCars(tx, cars)" to avoid code duplication.
-			// For now, to keep it simple and robust, I'll inline the save logic again or call a helper.
-			// Let's refactor the save logic into `saveCars` private helper.
-			if err := saveCars(db, cars); err != nil {
+			if err := saveCars(db, cars, apininjas.QueryURL(makeName, year)); err != nil {
 				log.Printf("Failed to save batch: %v", err)
 			}
 		}
@@ -128,64 +69,283 @@ func SyncDetailedYears(db *gorm.DB, client *apininjas.Client, makes []string, ye
 	return nil
 }
 
-// saveCars handles the DB transaction for a list of cars
-func saveCars(db *gorm.DB, cars []apininjas.Car) error {
-	return db.Transaction(func(tx *gorm.DB) error {
-		for _, car := range cars {
-			// 1. Find or Create Make
-			var makeModel models.Make
-			if err := tx.FirstOrCreate(&makeModel, models.Make{Name: car.Make}).Error; err != nil {
-				return err
-			}
+// saveCars handles the DB transaction for a list of cars. Every spec is
+// cited to sourceURL, the API Ninjas query the cars came from.
+func saveCars(db *sql.DB, cars []apininjas.Car, sourceURL string) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
 
-			// 2. Find or Create Model
-			var modelModel models.Model
-			if err := tx.FirstOrCreate(&modelModel, models.Model{Name: car.Model, MakeID: makeModel.ID}).Error; err != nil {
-				return err
-			}
+	provenance := repository.NewProvenanceRepository(tx)
+	title := "API Ninjas cars"
+	market := "US"
+	retrieved := time.Now().UTC()
+	source := &models.SourceDocument{
+		URL:         sourceURL,
+		Title:       &title,
+		SourceType:  models.SourceAPINinjas,
+		MarketScope: &market,
+		RetrievedAt: &retrieved,
+	}
+	if err := provenance.EnsureSource(source); err != nil {
+		return err
+	}
 
-			// 3. Create Trim
-			trimName := fmt.Sprintf("%d %s %s", car.Year, car.Transmission, car.Drive)
-			dispFloat := toFloat(car.Displacement)
-			if dispFloat > 0 {
-				trimName = fmt.Sprintf("%s %.1fL", trimName, dispFloat)
-			}
+	var trimIDs []int64
+	for _, car := range cars {
+		// 1. Find or Create Make
+		brandID, err := ensureBrand(tx, car.Make)
+		if skipTrashed(err) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		// 2. Find or Create Model
+		modelID, err := ensureModel(tx, brandID, car.Model)
+		if skipTrashed(err) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		// API Ninjas has no generation data, so trims are grouped per model year
+		generationID, err := ensureYearGeneration(tx, modelID, car.Year)
+		if skipTrashed(err) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
 
-			var trimModel models.Trim
-			err := tx.Where(models.Trim{ModelID: modelModel.ID, Name: trimName, Year: car.Year}).
-				FirstOrCreate(&trimModel, models.Trim{
-					ModelID: modelModel.ID,
-					Name:    trimName,
-					Year:    car.Year,
-				}).Error
+		// 3. Create Trim
+		trimName := fmt.Sprintf("%d %s %s", car.Year, car.Transmission, car.Drive)
+		dispFloat := toFloat(car.Displacement)
+		if dispFloat > 0 {
+			trimName = fmt.Sprintf("%s %.1fL", trimName, dispFloat)
+		}
+
+		trimID, err := ensureTrim(tx, modelID, generationID, trimName, car.Year)
+		if skipTrashed(err) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		trimIDs = append(trimIDs, trimID)
+		if err := provenance.CiteField(trimID, "year", car.Year, source.ID, ""); err != nil {
+			return err
+		}
+
+		// 4. Specs
+		specs := []models.Spec{
+			{TrimID: trimID, Category: "General", Name: "Class", Value: normalizeSpecValue("General", "Class", car.Class)},
+			{TrimID: trimID, Category: "Engine", Name: "Cylinders", Value: toString(car.Cylinders)},
+			{TrimID: trimID, Category: "Engine", Name: "Displacement", Value: toString(car.Displacement)},
+			{TrimID: trimID, Category: "Engine", Name: "Fuel Type", Value: normalizeSpecValue("Engine", "Fuel Type", car.FuelType)},
+			{TrimID: trimID, Category: "Transmission", Name: "Transmission", Value: normalizeSpecValue("Transmission", "Transmission", car.Transmission)},
+			{TrimID: trimID, Category: "Drivetrain", Name: "Drive", Value: normalizeSpecValue("Drivetrain", "Drive", car.Drive)},
+			{TrimID: trimID, Category: "Consumption", Name: "City MPG", Value: toString(car.CityMPG)},
+			{TrimID: trimID, Category: "Consumption", Name: "Highway MPG", Value: toString(car.HighwayMPG)},
+		}
+
+		for _, spec := range specs {
+			specID, err := upsertTrimSpec(tx, spec)
 			if err != nil {
 				return err
 			}
-
-			// 4. Specs
-			specs := []models.Spec{
-				{TrimID: int64(trimModel.ID), Category: "General", Name: "Class", Value: normalizeSpecValue("General", "Class", car.Class)},
-				{TrimID: int64(trimModel.ID), Category: "Engine", Name: "Cylinders", Value: toString(car.Cylinders)},
-				{TrimID: int64(trimModel.ID), Category: "Engine", Name: "Displacement", Value: toString(car.Displacement)},
-				{TrimID: int64(trimModel.ID), Category: "Engine", Name: "Fuel Type", Value: normalizeSpecValue("Engine", "Fuel Type", car.FuelType)},
-				{TrimID: int64(trimModel.ID), Category: "Transmission", Name: "Transmission", Value: normalizeSpecValue("Transmission", "Transmission", car.Transmission)},
-				{TrimID: int64(trimModel.ID), Category: "Drivetrain", Name: "Drive", Value: normalizeSpecValue("Drivetrain", "Drive", car.Drive)},
-				{TrimID: int64(trimModel.ID), Category: "Consumption", Name: "City MPG", Value: toString(car.CityMPG)},
-				{TrimID: int64(trimModel.ID), Category: "Consumption", Name: "Highway MPG", Value: toString(car.HighwayMPG)},
+			if err := provenance.CiteSpec(specID, source.ID, "", ""); err != nil {
+				return err
 			}
+		}
+	}
 
-			for _, spec := range specs {
-				if err := tx.Where(&models.Spec{TrimID: spec.TrimID, Category: spec.Category, Name: spec.Name}).
-					Assign(models.Spec{Value: spec.Value}).
-					FirstOrCreate(&spec).Error; err != nil {
-					return err
-				}
-			}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	// Reviewed after the commit: the review queue has its own transaction
+	reviews := service.NewReviewService(repository.NewReviewRepository(db), repository.NewTrimRepository(db), repository.NewGenerationRepository(db))
+	for _, id := range trimIDs {
+		if _, err := reviews.ReviewTrimByID(id, models.SourceAPINinjas); err != nil {
+			return err
 		}
-		return nil
+	}
+	return nil
+}
+
+// errInTrash is returned by the ensure helpers for a row in the trash. The
+// sync skips cars that would be stored in or under a deleted row.
+var errInTrash = errors.New("in the trash")
+
+// skipTrashed logs and reports whether err is errInTrash
+func skipTrashed(err error) bool {
+	if !errors.Is(err, errInTrash) {
+		return false
+	}
+	log.Printf("Skipping car: %v", err)
+	return true
+}
+
+// ensureBrand returns the id of the brand with the given name, creating it if needed
+func ensureBrand(tx *sql.Tx, name string) (int64, error) {
+	var id int64
+	var deleted bool
+	err := tx.QueryRow("SELECT id, deleted_at IS NOT NULL FROM brands WHERE LOWER(name) = LOWER(?)", name).Scan(&id, &deleted)
+	if err == nil && deleted {
+		return 0, fmt.Errorf("brand %s is %w", name, errInTrash)
+	}
+	if err == nil {
+		return id, nil
+	}
+	if err != sql.ErrNoRows {
+		return 0, fmt.Errorf("failed to find brand %s: %w", name, err)
+	}
+
+	res, err := tx.Exec("INSERT INTO brands (name) VALUES (?)", name)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create brand %s: %w", name, err)
+	}
+	return auditCreated(tx, res, models.EntityBrand, func(id int64) (interface{}, error) {
+		return &models.Brand{ID: id, Name: name}, nil
+	})
+}
+
+// ensureModel returns the id of the brand's model with the given name, creating it if needed
+func ensureModel(tx *sql.Tx, brandID int64, name string) (int64, error) {
+	var id int64
+	var deleted bool
+	err := tx.QueryRow("SELECT id, deleted_at IS NOT NULL FROM models WHERE brand_id = ? AND LOWER(name) = LOWER(?)", brandID, name).Scan(&id, &deleted)
+	if err == nil && deleted {
+		return 0, fmt.Errorf("model %s is %w", name, errInTrash)
+	}
+	if err == nil {
+		return id, nil
+	}
+	if err != sql.ErrNoRows {
+		return 0, fmt.Errorf("failed to find model %s: %w", name, err)
+	}
+
+	res, err := tx.Exec("INSERT INTO models (brand_id, name) VALUES (?, ?)", brandID, name)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create model %s: %w", name, err)
+	}
+	return auditCreated(tx, res, models.EntityModel, func(id int64) (interface{}, error) {
+		return &models.Model{ID: id, BrandID: brandID, Name: name}, nil
+	})
+}
+
+// ensureYearGeneration returns a generation coded by model year, creating it if needed
+func ensureYearGeneration(tx *sql.Tx, modelID int64, year int) (int64, error) {
+	code := strconv.Itoa(year)
+
+	var id int64
+	var deleted bool
+	err := tx.QueryRow("SELECT id, deleted_at IS NOT NULL FROM generations WHERE model_id = ? AND code = ?", modelID, code).Scan(&id, &deleted)
+	if err == nil && deleted {
+		return 0, fmt.Errorf("generation %s is %w", code, errInTrash)
+	}
+	if err == nil {
+		return id, nil
+	}
+	if err != sql.ErrNoRows {
+		return 0, fmt.Errorf("failed to find generation %s: %w", code, err)
+	}
+
+	res, err := tx.Exec(
+		"INSERT INTO generations (model_id, code, name, start_year, end_year) VALUES (?, ?, ?, ?, ?)",
+		modelID, code, code, year, year,
+	)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create generation %s: %w", code, err)
+	}
+	return auditCreated(tx, res, models.EntityGeneration, func(id int64) (interface{}, error) {
+		return repository.NewGenerationRepository(tx).GetByID(id)
+	})
+}
+
+// ensureTrim returns the id of the trim with the given name and year, creating it if needed
+func ensureTrim(tx *sql.Tx, modelID, generationID int64, name string, year int) (int64, error) {
+	var id int64
+	var deleted bool
+	err := tx.QueryRow(
+		"SELECT id, deleted_at IS NOT NULL FROM trims WHERE model_id = ? AND name = ? AND year = ?",
+		modelID, name, year,
+	).Scan(&id, &deleted)
+	if err == nil && deleted {
+		return 0, fmt.Errorf("trim %s is %w", name, errInTrash)
+	}
+	if err == nil {
+		return id, nil
+	}
+	if err != sql.ErrNoRows {
+		return 0, fmt.Errorf("failed to find trim %s: %w", name, err)
+	}
+
+	res, err := tx.Exec(
+		"INSERT INTO trims (model_id, generation_id, name, year) VALUES (?, ?, ?, ?)",
+		modelID, generationID, name, year,
+	)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create trim %s: %w", name, err)
+	}
+	return auditCreated(tx, res, models.EntityTrim, func(id int64) (interface{}, error) {
+		return repository.NewTrimRepository(tx).GetByID(id, false)
 	})
 }
 
+// apiNinjasActor is who the audit log records as creating the synced rows
+var apiNinjasActor = service.CommandActor("importer:" + models.SourceAPINinjas)
+
+// auditCreated records the row res inserted in the audit log and returns its
+// id. row loads (or builds) the row for the log.
+func auditCreated(tx *sql.Tx, res sql.Result, entityType string, row func(id int64) (interface{}, error)) (int64, error) {
+	id, err := res.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+	created, err := row(id)
+	if err != nil {
+		return 0, err
+	}
+	if err := repository.NewAuditRepository(tx).Record(apiNinjasActor, entityType, id, models.AuditCreate, nil, created); err != nil {
+		return 0, err
+	}
+	return id, nil
+}
+
+// upsertTrimSpec updates the spec's value if it exists, otherwise inserts it,
+// and returns the spec's id
+func upsertTrimSpec(tx *sql.Tx, spec models.Spec) (int64, error) {
+	var id int64
+	err := tx.QueryRow(
+		"SELECT id FROM specs WHERE trim_id = ? AND category = ? AND name = ?",
+		spec.TrimID, spec.Category, spec.Name,
+	).Scan(&id)
+	if err == nil {
+		if _, err := tx.Exec("UPDATE specs SET value = ? WHERE id = ?", spec.Value, id); err != nil {
+			return 0, fmt.Errorf("failed to update spec %s: %w", spec.Name, err)
+		}
+		return id, nil
+	}
+	if err != sql.ErrNoRows {
+		return 0, fmt.Errorf("failed to find spec %s: %w", spec.Name, err)
+	}
+
+	res, err := tx.Exec(
+		"INSERT INTO specs (trim_id, category, name, value) VALUES (?, ?, ?, ?)",
+		spec.TrimID, spec.Category, spec.Name, spec.Value,
+	)
+	if err != nil {
+		return 0, fmt.Errorf("failed to insert spec %s: %w", spec.Name, err)
+	}
+	return res.LastInsertId()
+}
+
 // Helper to safely get float64 from interface{} (which might be float64, int, string)
 func toFloat(v interface{}) float64 {
 	if v == nil {
@@ -225,65 +385,38 @@ func normalizeSpecValue(category, name, value string) string {
 		return "-"
 	}
 
-	if name == "Transmission" {
-		if v == "a" || v == "automatic" {
-			return "Otomatik"
-		}
-		if v == "m" || v == "manual" {
-			return "Manuel"
-		}
-		if v == "cvt" {
-			return "Otomatik (CVT)"
-		}
-	}
-
-	if name == "Drive" {
-		if v == "fwd" {
-			return "Önden Çekiş"
-		}
-		if v == "rwd" {
-			return "Arkadan İtiş"
-		}
-		if v == "awd" || v == "4wd" {
-			return "4 Çeker"
-		}
+	// Enum-like specs are stored canonically; display strings come from the formatter
+	var enum *enums.Enum
+	switch name {
+	case "Transmission":
+		enum = &enums.Transmission
+	case "Drive":
+		enum = &enums.Drivetrain
+	case "Fuel Type":
+		enum = &enums.FuelType
 	}
-
-	if name == "Fuel Type" {
-		switch v {
-		case "gas", "gasoline":
-			return "Benzin"
-		case "diesel":
-			return "Dizel"
-		case "electricity", "electric":
-			return "Elektrik"
-		case "hybrid":
-			return "Hibrit"
+	if enum != nil {
+		if canonical, ok := enum.Normalize(v); ok && canonical != "" {
+			return canonical
 		}
 	}
 
 	if name == "Class" {
-		// Translate common classes
-		if strings.Contains(v, "sedan") {
-			return "Sedan"
-		}
-		if strings.Contains(v, "coupe") {
-			return "Coupe"
-		}
-		if strings.Contains(v, "wagon") {
-			return "Station Wagon"
-		}
-		if strings.Contains(v, "suv") {
-			return "SUV"
-		}
-		if strings.Contains(v, "pickup") {
-			return "Pickup"
-		}
-		if strings.Contains(v, "convertible") {
-			return "Cabrio"
-		}
-		if strings.Contains(v, "minivan") {
-			return "Minivan"
+		// API Ninjas classes read like "small station wagon" or "standard sport utility vehicle"
+		for _, style := range []struct{ match, value string }{
+			{"sedan", enums.BodySedan},
+			{"coupe", enums.BodyCoupe},
+			{"wagon", enums.BodyWagon},
+			{"sport utility", enums.BodySUV},
+			{"suv", enums.BodySUV},
+			{"pickup", enums.BodyPickup},
+			{"convertible", enums.BodyConvertible},
+			{"minivan", enums.BodyMPV},
+			{"van", enums.BodyVan},
+		} {
+			if strings.Contains(v, style.match) {
+				return style.value
+			}
 		}
 	}
 
@@ -1,17 +1,17 @@
 package importer
 
 import (
+	"database/sql"
 	"log"
 	"strconv"
 	"time"
 
 	"github.com/emirh/car-specs/backend/internal/service"
 	"github.com/emirh/car-specs/backend/pkg/carquery"
-	"gorm.io/gorm"
 )
 
 // SyncCarQueryData fetches data from CarQuery API for the given year range and saves it to the DB.
-func SyncCarQueryData(db *gorm.DB, client *carquery.Client, startYear, endYear int) error {
+func SyncCarQueryData(db *sql.DB, client *carquery.Client, startYear, endYear int) error {
 	log.Printf("Starting CarQuery Sync for years %d-%d...", startYear, endYear)
 
 	for year := startYear; year <= endYear; year++ {
@@ -0,0 +1,391 @@
+package importer
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/emirh/car-specs/backend/internal/formatter"
+)
+
+// Dataset formats
+const (
+	FormatCSV  = "csv"
+	FormatJSON = "json"
+)
+
+// Row is one record of a dataset mapped through a profile
+type Row struct {
+	// Line is the row's line in a CSV file, or its position in a JSON dataset
+	Line int
+	// Values holds the row's fields with a value, in the fields' units
+	Values map[string]string
+	// SourceURL is the page the row came from, if the profile has a url_column
+	SourceURL string
+	// Err is why the row cannot be imported
+	Err error
+}
+
+// ReadRows reads the dataset at path and maps its rows with p
+func (p *Profile) ReadRows(path string) ([]*Row, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	format := p.Format
+	if format == "" {
+		format = strings.TrimPrefix(strings.ToLower(filepath.Ext(path)), ".")
+		if format == "jsonl" || format == "ndjson" {
+			format = FormatJSON
+		}
+	}
+	switch format {
+	case FormatCSV:
+		return p.readCSV(f)
+	case FormatJSON:
+		return p.readJSON(f)
+	}
+	return nil, fmt.Errorf("cannot tell the format of %s, set format in the profile", path)
+}
+
+// readCSV maps the rows of a CSV file with a header row
+func (p *Profile) readCSV(r io.Reader) ([]*Row, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+	reader.FieldsPerRecord = -1
+	if p.Delimiter != "" {
+		reader.Comma = []rune(p.Delimiter)[0]
+	}
+
+	headers, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the header row: %w", err)
+	}
+	headerIndex := make(map[string]int)
+	for i, h := range headers {
+		h = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(h, "\ufeff")))
+		if _, ok := headerIndex[h]; !ok {
+			headerIndex[h] = i
+		}
+	}
+	index := func(c *Column) (int, error) {
+		if c.Index > 0 {
+			return c.Index - 1, nil
+		}
+		i, ok := headerIndex[strings.ToLower(strings.TrimSpace(c.Column))]
+		if !ok {
+			return 0, fmt.Errorf("column %q is not in the file", c.Column)
+		}
+		return i, nil
+	}
+
+	// Resolve every column up front, so a typo fails the import instead of every row
+	positions := make([]int, len(p.Columns))
+	for i := range p.Columns {
+		if positions[i], err = index(&p.Columns[i]); err != nil {
+			return nil, err
+		}
+	}
+	urlPosition := -1
+	if p.Source.URLColumn != "" {
+		if urlPosition, err = index(&Column{Column: p.Source.URLColumn}); err != nil {
+			return nil, err
+		}
+	}
+
+	var rows []*Row
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if blank(record) {
+			continue
+		}
+		line, _ := reader.FieldPos(0)
+
+		get := func(i int) string {
+			if i < 0 || i >= len(record) {
+				return ""
+			}
+			return record[i]
+		}
+		row := p.mapRow(line, func(i int, _ *Column) (string, error) {
+			return get(positions[i]), nil
+		})
+		if row.Err == nil && urlPosition >= 0 {
+			row.SourceURL = strings.TrimSpace(get(urlPosition))
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+// readJSON maps the objects of a JSON array, or of JSON Lines
+func (p *Profile) readJSON(r io.Reader) ([]*Row, error) {
+	for i := range p.Columns {
+		if p.Columns[i].Index > 0 {
+			return nil, fmt.Errorf("column #%d: index only works for CSV files", p.Columns[i].Index)
+		}
+	}
+
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+
+	// An array of objects, or one object per line
+	inArray := bytes.HasPrefix(bytes.TrimSpace(data), []byte("["))
+	if inArray {
+		if _, err := decoder.Token(); err != nil {
+			return nil, err
+		}
+	}
+
+	var rows []*Row
+	for n := 1; ; n++ {
+		if inArray && !decoder.More() {
+			break
+		}
+		var object map[string]interface{}
+		if err := decoder.Decode(&object); err != nil {
+			if !inArray && errors.Is(err, io.EOF) {
+				break
+			}
+			return nil, fmt.Errorf("record %d: %w", n, err)
+		}
+
+		row := p.mapRow(n, func(_ int, c *Column) (string, error) {
+			return jsonValue(object, c.Column)
+		})
+		if row.Err == nil && p.Source.URLColumn != "" {
+			url, err := jsonValue(object, p.Source.URLColumn)
+			if err != nil {
+				row.Err = err
+			}
+			row.SourceURL = strings.TrimSpace(url)
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+// jsonValue returns the scalar at key in object. A key that isn't in object
+// is split at its dots and looked up in nested objects.
+func jsonValue(object map[string]interface{}, key string) (string, error) {
+	var value interface{} = object
+	if v, ok := object[key]; ok {
+		value = v
+	} else {
+		for _, part := range strings.Split(key, ".") {
+			nested, ok := value.(map[string]interface{})
+			if !ok {
+				return "", nil
+			}
+			if value, ok = nested[part]; !ok {
+				return "", nil
+			}
+		}
+	}
+
+	switch v := value.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return v, nil
+	case json.Number:
+		return v.String(), nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	}
+	return "", fmt.Errorf("%s is not a single value", key)
+}
+
+// mapRow maps one record. value returns the raw value of column i.
+func (p *Profile) mapRow(line int, value func(i int, c *Column) (string, error)) *Row {
+	row := &Row{Line: line, Values: make(map[string]string)}
+	for i := range p.Columns {
+		c := &p.Columns[i]
+		raw, err := value(i, c)
+		if err != nil {
+			row.Err = err
+			return row
+		}
+		v, err := p.normalize(c, raw)
+		if err != nil {
+			row.Err = fmt.Errorf("%s: %w", c.Field, err)
+			return row
+		}
+		if v != "" && row.Values[c.Field] == "" {
+			row.Values[c.Field] = v
+		}
+	}
+
+	// Plain defaults first, so templates can use them
+	fields := make([]string, 0, len(p.Defaults))
+	for field := range p.Defaults {
+		fields = append(fields, field)
+	}
+	sort.Slice(fields, func(i, j int) bool {
+		ti, tj := strings.Contains(p.Defaults[fields[i]], "{"), strings.Contains(p.Defaults[fields[j]], "{")
+		if ti != tj {
+			return tj
+		}
+		return fields[i] < fields[j]
+	})
+	for _, field := range fields {
+		template := p.Defaults[field]
+		if row.Values[field] != "" {
+			continue
+		}
+		raw, ok := expandDefault(template, row.Values)
+		if !ok {
+			continue
+		}
+		v, err := p.normalize(&Column{Field: field}, raw)
+		if err != nil {
+			row.Err = fmt.Errorf("default %s: %w", field, err)
+			return row
+		}
+		if v != "" {
+			row.Values[field] = v
+		}
+	}
+	return row
+}
+
+var templateField = regexp.MustCompile(`\{([a-z0-9_.]+)\}`)
+
+// expandDefault replaces "{field}" in template with the row's value. It
+// reports false when a field it refers to has no value.
+func expandDefault(template string, values map[string]string) (string, bool) {
+	ok := true
+	expanded := templateField.ReplaceAllStringFunc(template, func(m string) string {
+		v := values[m[1:len(m)-1]]
+		if v == "" {
+			ok = false
+		}
+		return v
+	})
+	return expanded, ok
+}
+
+// normalize cleans up a raw value of column c and parses it for c's field.
+// Numbers come back in the field's unit.
+func (p *Profile) normalize(c *Column, raw string) (string, error) {
+	v := strings.TrimSpace(raw)
+	for _, null := range p.NullValues {
+		if strings.EqualFold(v, null) {
+			return "", nil
+		}
+	}
+	for from, to := range c.Replace {
+		if strings.EqualFold(v, strings.TrimSpace(from)) {
+			v = to
+			break
+		}
+	}
+	if c.pattern != nil {
+		if m := c.pattern.FindStringSubmatch(v); m != nil {
+			v = m[0]
+			if len(m) > 1 {
+				v = m[1]
+			}
+		}
+	}
+	v = strings.TrimSpace(v)
+	switch c.Case {
+	case "upper":
+		v = strings.ToUpper(v)
+	case "lower":
+		v = strings.ToLower(v)
+	case "title":
+		v = formatter.TitleCase(v)
+	}
+	if v == "" {
+		return "", nil
+	}
+
+	kind, _ := fieldKindOf(c.Field)
+	switch kind {
+	case kindInt, kindFloat:
+		n, err := p.parseNumber(v)
+		if err != nil {
+			return "", err
+		}
+		if c.Unit != "" {
+			if n, err = convertUnit(n, c.Unit, c.Field); err != nil {
+				return "", err
+			}
+		}
+		if kind == kindInt {
+			return strconv.Itoa(int(math.Round(n))), nil
+		}
+		return strconv.FormatFloat(math.Round(n*100)/100, 'f', -1, 64), nil
+	case kindBool:
+		b, err := parseBool(v)
+		if err != nil {
+			return "", err
+		}
+		return strconv.FormatBool(b), nil
+	}
+	return v, nil
+}
+
+var numberPattern = regexp.MustCompile(`[-+]?\d+(?:[.,]\d+)*`)
+
+// parseNumber reads the first number in s ("150 PS", "7,4 s") using the
+// profile's decimal separator
+func (p *Profile) parseNumber(s string) (float64, error) {
+	m := numberPattern.FindString(s)
+	if m == "" {
+		return 0, fmt.Errorf("%q is not a number", s)
+	}
+	decimal, thousands := ".", ","
+	if p.DecimalSeparator == "," {
+		decimal, thousands = ",", "."
+	}
+	m = strings.ReplaceAll(m, thousands, "")
+	m = strings.Replace(m, decimal, ".", 1)
+	n, err := strconv.ParseFloat(m, 64)
+	if err != nil {
+		return 0, fmt.Errorf("%q is not a number", s)
+	}
+	return n, nil
+}
+
+func parseBool(s string) (bool, error) {
+	switch strings.ToLower(s) {
+	case "1", "true", "yes", "y":
+		return true, nil
+	case "0", "false", "no", "n":
+		return false, nil
+	}
+	return false, fmt.Errorf("%q is not a yes/no value", s)
+}
+
+func blank(record []string) bool {
+	for _, v := range record {
+		if strings.TrimSpace(v) != "" {
+			return false
+		}
+	}
+	return true
+}
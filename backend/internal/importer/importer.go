@@ -1,741 +1,588 @@
 package importer
 
 import (
-	"bytes"
 	"database/sql"
-	"encoding/csv"
+	"encoding/json"
 	"errors"
 	"fmt"
-	"io"
-	"net/url"
-	"net/http"
-	"os"
+	"path/filepath"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
-)
 
-type importRow struct {
-	Brand            string
-	Model            string
-	Generation       string
-	StartYear        *int64
-	EndYear          *int64
-	IsFacelift       *bool
-	Market           string
-	TrimName         string
-	EngineCode       string
-	FuelType         string
-	DisplacementCC   *int64
-	PowerHP          *int64
-	TorqueNM         *int64
-	TransmissionType string
-	Gears            *int64
-	Drive            string
-	MarketScope      string
-	SpecCategory     string
-	SpecName         string
-	SpecValue        string
-	SourceURL        string
-	SourceTitle      string
-	SourceType       string
-	SourceMarket     string
-	SourceRetrieved  string
-	SourcePage       string
-	SourceNote       string
-}
+	"github.com/emirh/car-specs/backend/internal/enums"
+	"github.com/emirh/car-specs/backend/internal/merge"
+	"github.com/emirh/car-specs/backend/internal/models"
+	"github.com/emirh/car-specs/backend/internal/repository"
+	"github.com/emirh/car-specs/backend/internal/service"
+)
 
-func ImportCSV(db *sql.DB, csvPath string) error {
-	f, err := os.Open(csvPath)
-	if err != nil {
-		return err
-	}
-	defer f.Close()
+// What an import did with a row
+const (
+	RowCreated   = "created"
+	RowUpdated   = "updated"
+	RowUnchanged = "unchanged"
+	RowSkipped   = "skipped" // it, or the brand, model or generation it belongs to, is in the trash
+	RowInvalid   = "invalid"
+)
 
-	r := csv.NewReader(f)
-	r.TrimLeadingSpace = true
+// RowResult is what an import did with one row
+type RowResult struct {
+	Line   int    `json:"line"`
+	Action string `json:"action"`
+	// TrimID is the trim the row was stored as (0 unless created, updated or unchanged)
+	TrimID int64 `json:"trim_id,omitempty"`
+	// Trim names the row's trim, as far as it could be read
+	Trim string `json:"trim,omitempty"`
+	// Reason is why the row was skipped or is invalid
+	Reason string `json:"reason,omitempty"`
+	// Created lists the brand, model and generation created for the row
+	Created []string `json:"created,omitempty"`
+	// Changes lists the trim fields the row set (created) or changed (updated)
+	Changes []models.FieldChange `json:"changes,omitempty"`
+}
 
-	headers, err := r.Read()
-	if err != nil {
-		return err
-	}
+// Result lists what an import did with every row, in file order
+type Result struct {
+	// DryRun is set when nothing was stored
+	DryRun bool        `json:"dry_run"`
+	Rows   []RowResult `json:"rows"`
+}
 
-	headerIndex := map[string]int{}
-	for i, h := range headers {
-		headerIndex[strings.ToLower(strings.TrimSpace(h))] = i
+// Count returns the number of rows with the given action
+func (r *Result) Count(action string) int {
+	n := 0
+	for _, row := range r.Rows {
+		if row.Action == action {
+			n++
+		}
 	}
+	return n
+}
 
-	// Auto-detect dataset formats
-	if _, ok := headerIndex["model_year"]; ok {
-		return importVWGolfEpeyCSV(db, r, headerIndex)
-	}
+// Options control an import
+type Options struct {
+	// DryRun runs the whole import, then rolls it back
+	DryRun bool
+	// MaxErrors aborts the import, storing nothing, once more rows than this
+	// are invalid. Negative means no limit. A dry run always reads every row.
+	MaxErrors int
+	// MergePolicy settles the values of existing trims (nil for merge.DefaultPolicy)
+	MergePolicy *merge.Policy
+}
 
-	get := func(cols []string, key string) string {
-		i, ok := headerIndex[key]
-		if !ok || i < 0 || i >= len(cols) {
-			return ""
-		}
-		return strings.TrimSpace(cols[i])
+// ErrTooManyErrors is returned when an import is aborted by Options.MaxErrors
+var ErrTooManyErrors = errors.New("too many invalid rows")
+
+// errDryRun rolls back the transaction of a dry run
+var errDryRun = errors.New("import dry run")
+
+// Import reads the dataset at path with p and stores its rows in one
+// transaction. For a trim that exists in the row's generation (same name,
+// year and market) the row's values are cited and merged in under the merge
+// policy, so values from a more trusted source are kept, as are values the
+// row leaves empty. A row that cannot be stored is rolled back on its own and
+// reported, the others are kept. When the import is aborted the rows read so
+// far are returned with the error.
+func Import(db *sql.DB, p *Profile, path string, opts Options) (*Result, error) {
+	rows, err := p.ReadRows(path)
+	if err != nil {
+		return nil, err
 	}
-
-	tx, err := db.Begin()
+	source, err := p.fileSource(path)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	defer func() {
-		if err != nil {
-			_ = tx.Rollback()
-		}
-	}()
-
-	for {
-		cols, readErr := r.Read()
-		if readErr != nil {
-			if errors.Is(readErr, io.EOF) {
-				break
-			}
-			err = readErr
-			return err
-		}
-
-		row, parseErr := parseRow(cols, get)
-		if parseErr != nil {
-			err = parseErr
-			return err
-		}
-		if row.Brand == "" || row.Model == "" || row.Generation == "" {
-			continue
-		}
 
-		vehicleID, vErr := upsertVehicle(tx, row.Brand, row.Model, row.Generation)
-		if vErr != nil {
-			err = vErr
-			return err
-		}
-
-		if row.StartYear != nil || row.EndYear != nil || row.IsFacelift != nil || row.Market != "" {
-			if gmErr := upsertGenerationMeta(tx, vehicleID, row); gmErr != nil {
-				err = gmErr
-				return err
-			}
-		}
+	policy := merge.DefaultPolicy()
+	if opts.MergePolicy != nil {
+		policy = *opts.MergePolicy
+	}
 
-		if row.TrimName != "" {
-			trimID, tErr := upsertTrim(tx, vehicleID, row.TrimName)
-			if tErr != nil {
-				err = tErr
+	result := &Result{DryRun: opts.DryRun}
+	invalid := 0
+	err = repository.Transact(db, func(tx repository.Querier) error {
+		w := newRowWriter(tx, p, source, policy)
+		for _, row := range rows {
+			if _, err := tx.Exec(`SAVEPOINT import_row`); err != nil {
 				return err
 			}
-
-			if row.EngineCode != "" || row.FuelType != "" || row.DisplacementCC != nil || row.PowerHP != nil || row.TorqueNM != nil || row.TransmissionType != "" || row.Gears != nil || row.Drive != "" || row.MarketScope != "" {
-				if pmErr := upsertPowertrainMeta(tx, trimID, row); pmErr != nil {
-					err = pmErr
+			res := w.write(row)
+			if res.Action == RowInvalid {
+				if _, err := tx.Exec(`ROLLBACK TO import_row`); err != nil {
 					return err
 				}
 			}
+			if _, err := tx.Exec(`RELEASE import_row`); err != nil {
+				return err
+			}
+			if opts.DryRun && res.Action == RowCreated {
+				// The ID is rolled back with the trim
+				res.TrimID = 0
+			}
+			result.Rows = append(result.Rows, res)
 
-			if row.SpecCategory != "" && row.SpecName != "" && row.SpecValue != "" {
-				specID, sErr := insertSpec(tx, trimID, row.SpecCategory, row.SpecName, row.SpecValue)
-				if sErr != nil {
-					err = sErr
-					return err
-				}
-
-				if row.SourceURL != "" {
-					sourceID, sdErr := upsertSourceDocument(tx, row)
-					if sdErr != nil {
-						err = sdErr
-						return err
-					}
-					if ssErr := upsertSpecSource(tx, specID, sourceID, row.SourcePage, row.SourceNote); ssErr != nil {
-						err = ssErr
-						return err
-					}
+			if res.Action == RowInvalid {
+				invalid++
+				if !opts.DryRun && opts.MaxErrors >= 0 && invalid > opts.MaxErrors {
+					return fmt.Errorf("%w: more than %d, stopped at line %d", ErrTooManyErrors, opts.MaxErrors, row.Line)
 				}
 			}
 		}
+		if opts.DryRun {
+			return errDryRun
+		}
+		return nil
+	})
+	if errors.Is(err, ErrTooManyErrors) {
+		return result, err
 	}
-
-	if cErr := tx.Commit(); cErr != nil {
-		return cErr
+	if err != nil && !errors.Is(err, errDryRun) {
+		return nil, err
 	}
-	return nil
+	return result, nil
 }
 
-func importVWGolfEpeyCSV(db *sql.DB, r *csv.Reader, headerIndex map[string]int) error {
-	get := func(cols []string, key string) string {
-		i, ok := headerIndex[key]
-		if !ok || i < 0 || i >= len(cols) {
-			return ""
-		}
-		return strings.TrimSpace(cols[i])
-	}
-
-	tx, err := db.Begin()
+// fileSource is the source document of rows without a url_column value
+func (p *Profile) fileSource(path string) (*models.SourceDocument, error) {
+	abs, err := filepath.Abs(path)
 	if err != nil {
-		return err
-	}
-	defer func() {
-		if err != nil {
-			_ = tx.Rollback()
-		}
-	}()
-
-	brand := "Volkswagen"
-	model := "Golf"
-	defaultSourceType := "trusted"
-	defaultMarketScope := "TR"
-	imageCache := make(map[string]string)
-
-	for {
-		cols, readErr := r.Read()
-		if readErr != nil {
-			if errors.Is(readErr, io.EOF) {
-				break
-			}
-			err = readErr
-			return err
-		}
-
-		yearStr := get(cols, "model_year")
-		if yearStr == "" {
-			continue
-		}
-		year, pErr := strconv.ParseInt(yearStr, 10, 64)
-		if pErr != nil {
-			continue
-		}
-
-		generation := yearStr
-		vehicleID, vErr := upsertVehicle(tx, brand, model, generation)
-		if vErr != nil {
-			err = vErr
-			return err
-		}
-
-		// Set generation meta as single-year range for now.
-		startYear := year
-		endYear := year
-		gm := importRow{StartYear: &startYear, EndYear: &endYear, Market: defaultMarketScope}
-		if gmErr := upsertGenerationMeta(tx, vehicleID, gm); gmErr != nil {
-			err = gmErr
-			return err
-		}
-
-		fullModelName := get(cols, "model_name")
-		imageURL := ""
-		modelNameCitation := get(cols, "model_name_citation")
-		if modelNameCitation != "" {
-			if cached, ok := imageCache[modelNameCitation]; ok {
-				imageURL = cached
-			} else {
-				img, _ := fetchOpenGraphImage(modelNameCitation)
-				imageCache[modelNameCitation] = img
-				imageURL = img
-			}
-		}
-		if imageURL != "" {
-			if uErr := updateVehicleImage(tx, vehicleID, imageURL); uErr != nil {
-				err = uErr
-				return err
-			}
-		}
-		trimName := normalizeVWTrimName(yearStr, fullModelName)
-		if trimName == "" {
-			trimName = fullModelName
-		}
-		if trimName == "" {
-			trimName = "Variant " + yearStr
-		}
-
-		trimID, tErr := upsertTrim(tx, vehicleID, trimName)
-		if tErr != nil {
-			err = tErr
-			return err
-		}
-
-		// Powertrain meta (optional)
-		disp, _ := parseInt64Ptr(get(cols, "engine_capacity_cc"))
-		power, _ := parseInt64Ptr(get(cols, "engine_power_hp"))
-		fuelType := get(cols, "fuel_type")
-		transmission := get(cols, "epey_com_transmission_type")
-
-		pm := importRow{
-			FuelType:         fuelType,
-			DisplacementCC:   disp,
-			PowerHP:          power,
-			TransmissionType: transmission,
-			MarketScope:      defaultMarketScope,
-		}
-		if pmErr := upsertPowertrainMeta(tx, trimID, pm); pmErr != nil {
-			err = pmErr
-			return err
-		}
-
-		// Helper: insert spec + link citation as a source
-		addSpec := func(category, name, value, citationURL string) error {
-			if value == "" {
-				return nil
-			}
-			specID, sErr := insertSpec(tx, trimID, category, name, value)
-			if sErr != nil {
-				return sErr
-			}
-			if citationURL == "" {
-				return nil
-			}
-
-			sourceRow := importRow{
-				SourceURL:       citationURL,
-				SourceTitle:     fullModelName,
-				SourceType:      defaultSourceType,
-				SourceMarket:    defaultMarketScope,
-				SourceRetrieved: time.Now().Format(time.RFC3339),
-			}
-			sourceID, sdErr := upsertSourceDocument(tx, sourceRow)
-			if sdErr != nil {
-				return sdErr
-			}
-			if ssErr := upsertSpecSource(tx, specID, sourceID, "", name); ssErr != nil {
-				return ssErr
-			}
-			return nil
-		}
-
-		if err := addSpec("General", "Model year", yearStr, get(cols, "model_year_citation")); err != nil {
-			return err
-		}
-		if err := addSpec("General", "Body type", get(cols, "epey_com_body_type"), get(cols, "epey_com_body_type_citation")); err != nil {
-			return err
-		}
-		if err := addSpec("Transmission", "Transmission", transmission, get(cols, "epey_com_transmission_type_citation")); err != nil {
-			return err
-		}
-
-		if disp != nil {
-			if err := addSpec("Engine", "Engine capacity", fmt.Sprintf("%d cc", *disp), get(cols, "engine_capacity_cc_citation")); err != nil {
-				return err
-			}
-		}
-		if power != nil {
-			if err := addSpec("Engine", "Power", fmt.Sprintf("%d hp", *power), get(cols, "engine_power_hp_citation")); err != nil {
-				return err
-			}
-		}
-		if err := addSpec("Engine", "Fuel type", fuelType, get(cols, "fuel_type_citation")); err != nil {
-			return err
-		}
-
-		if err := addSpec("Consumption", "Fuel consumption (city)", appendUnit(get(cols, "epey_com_fuel_consumption_l_100km_city"), "L/100km"), get(cols, "epey_com_fuel_consumption_l_100km_city_citation")); err != nil {
-			return err
-		}
-		if err := addSpec("Consumption", "Fuel consumption (highway)", appendUnit(get(cols, "epey_com_fuel_consumption_l_100km_highway"), "L/100km"), get(cols, "epey_com_fuel_consumption_l_100km_highway_citation")); err != nil {
-			return err
-		}
-
-		if err := addSpec("Performance", "0-100 km/h", appendUnit(get(cols, "epey_com_acceleration_0_100kmh_seconds"), "s"), get(cols, "epey_com_acceleration_0_100kmh_seconds_citation")); err != nil {
-			return err
-		}
-		if err := addSpec("Performance", "Top speed", appendUnit(get(cols, "epey_com_top_speed_kmh"), "km/h"), get(cols, "epey_com_top_speed_kmh_citation")); err != nil {
-			return err
-		}
+		return nil, err
 	}
-
-	if cErr := tx.Commit(); cErr != nil {
-		return cErr
+	source := p.source("file://" + filepath.ToSlash(abs))
+	if source.Title == nil {
+		title := filepath.Base(path)
+		source.Title = &title
 	}
-	return nil
+	return source, nil
 }
 
-func normalizeVWTrimName(yearStr, fullName string) string {
-	name := strings.TrimSpace(fullName)
-	if name == "" {
-		return ""
+// source is the source document at url, described as the profile says
+func (p *Profile) source(url string) *models.SourceDocument {
+	now := time.Now().UTC()
+	source := &models.SourceDocument{
+		URL:         url,
+		SourceType:  p.Source.Type,
+		RetrievedAt: &now,
 	}
-	// Remove leading year if present
-	if strings.HasPrefix(name, yearStr) {
-		name = strings.TrimSpace(strings.TrimPrefix(name, yearStr))
+	if source.SourceType == "" {
+		source.SourceType = models.SourceCSV
 	}
-	// Remove leading brand/model if present
-	for _, p := range []string{"Volkswagen", "VW"} {
-		if strings.HasPrefix(strings.ToLower(name), strings.ToLower(p)) {
-			name = strings.TrimSpace(name[len(p):])
-		}
+	if p.Source.Title != "" {
+		source.Title = &p.Source.Title
 	}
-	if strings.HasPrefix(strings.ToLower(name), "golf") {
-		name = strings.TrimSpace(name[len("golf"):])
+	if p.Source.MarketScope != "" {
+		source.MarketScope = &p.Source.MarketScope
 	}
-	return strings.TrimSpace(name)
+	return source
 }
 
-func appendUnit(v string, unit string) string {
-	v = strings.TrimSpace(v)
-	if v == "" {
-		return ""
-	}
-	if unit == "" {
-		return v
-	}
-	return v + " " + unit
+// rowWriter stores rows through the catalogue services, so they are
+// validated, cited, reviewed and audited like any other write
+type rowWriter struct {
+	profile        *Profile
+	source         *models.SourceDocument
+	brands         *service.BrandService
+	models         *service.ModelService
+	generations    *service.GenerationService
+	trims          *service.TrimService
+	merger         *service.MergeService
+	brandRepo      *repository.BrandRepository
+	modelRepo      *repository.ModelRepository
+	generationRepo *repository.GenerationRepository
+	trimRepo       *repository.TrimRepository
+	// created collects the parents created for the row being written
+	created []string
 }
 
-func updateVehicleImage(tx *sql.Tx, vehicleID int64, imageURL string) error {
-	imageURL = strings.TrimSpace(imageURL)
-	if imageURL == "" {
-		return nil
+func newRowWriter(tx repository.Querier, p *Profile, source *models.SourceDocument, policy merge.Policy) *rowWriter {
+	brandRepo := repository.NewBrandRepository(tx)
+	modelRepo := repository.NewModelRepository(tx)
+	generationRepo := repository.NewGenerationRepository(tx)
+	trimRepo := repository.NewTrimRepository(tx)
+	auditRepo := repository.NewAuditRepository(tx)
+	provenanceRepo := repository.NewProvenanceRepository(tx)
+	reviews := service.NewReviewService(repository.NewReviewRepository(tx), trimRepo, generationRepo)
+
+	// Writes are audited as this import, e.g. importer:csv
+	actor := service.CommandActor("importer:" + source.SourceType)
+	trims := service.NewTrimService(trimRepo, modelRepo, generationRepo, provenanceRepo, reviews, auditRepo).As(actor)
+	return &rowWriter{
+		profile:        p,
+		source:         source,
+		brands:         service.NewBrandService(brandRepo, auditRepo).As(actor),
+		models:         service.NewModelService(modelRepo, brandRepo, auditRepo).As(actor),
+		generations:    service.NewGenerationService(generationRepo, modelRepo, auditRepo).As(actor),
+		trims:          trims,
+		merger:         service.NewMergeService(trims, provenanceRepo, repository.NewMergeConflictRepository(tx), policy),
+		brandRepo:      brandRepo,
+		modelRepo:      modelRepo,
+		generationRepo: generationRepo,
+		trimRepo:       trimRepo,
 	}
-	_, err := tx.Exec(
-		"UPDATE vehicles SET image_url = CASE WHEN image_url IS NULL OR image_url = '' THEN ? ELSE image_url END WHERE id = ?",
-		imageURL,
-		vehicleID,
-	)
-	return err
 }
 
-func fetchOpenGraphImage(pageURL string) (string, error) {
-	pageURL = strings.TrimSpace(pageURL)
-	if pageURL == "" {
-		return "", nil
-	}
+// errSkipped reports a row that belongs under a row in the trash
+type errSkipped struct{ reason string }
 
-	client := &http.Client{Timeout: 20 * time.Second}
-	req, err := http.NewRequest("GET", pageURL, nil)
-	if err != nil {
-		return "", err
-	}
-	req.Header.Set("User-Agent", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36")
+func (e errSkipped) Error() string { return e.reason }
 
-	resp, err := client.Do(req)
-	if err != nil {
-		return "", err
+// write stores one row and reports what it did
+func (w *rowWriter) write(row *Row) RowResult {
+	v := row.Values
+	res := RowResult{Line: row.Line, Trim: strings.TrimSpace(strings.Join([]string{v[FieldBrand], v[FieldModel], v["name"], v["year"]}, " "))}
+	if row.Err != nil {
+		res.Action, res.Reason = RowInvalid, row.Err.Error()
+		return res
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		return "", fmt.Errorf("unexpected status: %s", resp.Status)
+	w.created = nil
+	trimID, action, changes, err := w.store(row)
+	if skipped, ok := err.(errSkipped); ok {
+		res.Action, res.Reason = RowSkipped, skipped.reason
+		return res
 	}
-
-	// Limit read to avoid huge pages
-	const maxBytes = 1024 * 1024
-	buf, err := io.ReadAll(io.LimitReader(resp.Body, maxBytes))
 	if err != nil {
-		return "", err
-	}
-
-	img := extractOGImage(buf)
-	if img == "" {
-		return "", nil
+		// Parents created for the row are rolled back with it
+		res.Action, res.Reason = RowInvalid, err.Error()
+		return res
 	}
+	res.Action, res.TrimID, res.Created, res.Changes = action, trimID, w.created, changes
+	return res
+}
 
-	// Normalize to absolute URL when possible
-	parsedPage, err := url.Parse(pageURL)
-	if err == nil {
-		parsedImg, err := url.Parse(img)
-		if err == nil {
-			img = parsedPage.ResolveReference(parsedImg).String()
+// store finds or creates the row's brand, model and generation, then creates,
+// updates or leaves its trim. It returns the trim fields it set or changed.
+func (w *rowWriter) store(row *Row) (int64, string, []models.FieldChange, error) {
+	v := row.Values
+	for _, required := range []string{FieldBrand, FieldModel, "name", "year"} {
+		if v[required] == "" {
+			return 0, "", nil, fmt.Errorf("%s is empty", required)
 		}
 	}
+	year, _ := strconv.Atoi(v["year"])
 
-	return img, nil
-}
-
-func extractOGImage(html []byte) string {
-	// Look for: <meta property="og:image" content="...">
-	lower := bytes.ToLower(html)
-	needle := []byte("og:image")
-	idx := bytes.Index(lower, needle)
-	if idx < 0 {
-		return ""
+	brandID, err := w.brand(v)
+	if err != nil {
+		return 0, "", nil, err
 	}
-
-	// Search forward from where og:image appears for content=
-	windowStart := idx
-	windowEnd := idx + 2000
-	if windowEnd > len(lower) {
-		windowEnd = len(lower)
+	modelID, err := w.model(brandID, v)
+	if err != nil {
+		return 0, "", nil, err
 	}
-	window := lower[windowStart:windowEnd]
-
-	contentIdx := bytes.Index(window, []byte("content="))
-	if contentIdx < 0 {
-		return ""
+	generationID, err := w.generation(modelID, year, v)
+	if err != nil {
+		return 0, "", nil, err
 	}
 
-	// Find quote char after content=
-	qPos := contentIdx + len("content=")
-	if qPos >= len(window) {
-		return ""
-	}
-	quote := window[qPos]
-	if quote != '"' && quote != '\'' {
-		return ""
+	patch, fields, err := trimPatch(v)
+	if err != nil {
+		return 0, "", nil, err
 	}
-
-	start := qPos + 1
-	endRel := bytes.IndexByte(window[start:], quote)
-	if endRel < 0 {
-		return ""
+	source := w.source
+	if row.SourceURL != "" {
+		source = w.profile.source(row.SourceURL)
 	}
-	end := start + endRel
 
-	// Return from original html slice to preserve casing
-	origWindow := html[windowStart:windowEnd]
-	return strings.TrimSpace(string(origWindow[start:end]))
-}
-
-func parseRow(cols []string, get func([]string, string) string) (importRow, error) {
-	row := importRow{
-		Brand:      get(cols, "brand"),
-		Model:      get(cols, "model"),
-		Generation: get(cols, "generation"),
-		Market:     get(cols, "market"),
-		TrimName:   get(cols, "trim_name"),
-
-		EngineCode:       get(cols, "engine_code"),
-		FuelType:         get(cols, "fuel_type"),
-		TransmissionType: get(cols, "transmission_type"),
-		Drive:            get(cols, "drive"),
-		MarketScope:      get(cols, "market_scope"),
-
-		SpecCategory: get(cols, "spec_category"),
-		SpecName:     get(cols, "spec_name"),
-		SpecValue:    get(cols, "spec_value"),
-
-		SourceURL:       get(cols, "source_url"),
-		SourceTitle:     get(cols, "source_title"),
-		SourceType:      get(cols, "source_type"),
-		SourceMarket:    get(cols, "source_market_scope"),
-		SourceRetrieved: get(cols, "source_retrieved_at"),
-		SourcePage:      get(cols, "source_page"),
-		SourceNote:      get(cols, "source_note"),
-	}
-
-	startYear, err := parseInt64Ptr(get(cols, "start_year"))
+	existing, err := w.findTrim(generationID, v["name"], year, v["market"])
 	if err != nil {
-		return importRow{}, fmt.Errorf("start_year: %w", err)
+		return 0, "", nil, err
 	}
-	row.StartYear = startYear
+	if existing == nil {
+		if deleted, err := w.trimRepo.NameInTrash(generationID, v["name"], year); err != nil {
+			return 0, "", nil, err
+		} else if deleted {
+			return 0, "", nil, errSkipped{fmt.Sprintf("trim %s is in the trash", v["name"])}
+		}
 
-	endYear, err := parseInt64Ptr(get(cols, "end_year"))
-	if err != nil {
-		return importRow{}, fmt.Errorf("end_year: %w", err)
+		trim := &models.Trim{}
+		if err := decodeTrim(patch, trim); err != nil {
+			return 0, "", nil, err
+		}
+		trim.GenerationID = generationID
+		if err := w.trims.CreateTrim(trim, source, fields); err != nil {
+			return 0, "", nil, err
+		}
+		changes, err := fieldChanges(nil, trim, fields)
+		if err != nil {
+			return 0, "", nil, err
+		}
+		return trim.ID, RowCreated, changes, nil
 	}
-	row.EndYear = endYear
 
-	isFacelift, err := parseBoolPtr(get(cols, "is_facelift"))
+	changed, err := changedFields(existing, patch)
 	if err != nil {
-		return importRow{}, fmt.Errorf("is_facelift: %w", err)
+		return 0, "", nil, err
 	}
-	row.IsFacelift = isFacelift
-
-	displacement, err := parseInt64Ptr(get(cols, "displacement_cc"))
-	if err != nil {
-		return importRow{}, fmt.Errorf("displacement_cc: %w", err)
+	if len(changed) == 0 {
+		return existing.ID, RowUnchanged, nil, nil
 	}
-	row.DisplacementCC = displacement
 
-	powerHP, err := parseInt64Ptr(get(cols, "power_hp"))
-	if err != nil {
-		return importRow{}, fmt.Errorf("power_hp: %w", err)
+	// The row is one more source for the trim: cite what it reports, then
+	// merge, which only takes the values its source outranks the others on
+	candidate := &models.Trim{}
+	if err := decodeTrim(patch, candidate); err != nil {
+		return 0, "", nil, err
 	}
-	row.PowerHP = powerHP
-
-	torqueNM, err := parseInt64Ptr(get(cols, "torque_nm"))
+	merged, err := w.merger.Ingest(existing.ID, candidate, source)
 	if err != nil {
-		return importRow{}, fmt.Errorf("torque_nm: %w", err)
+		return 0, "", nil, err
 	}
-	row.TorqueNM = torqueNM
-
-	gears, err := parseInt64Ptr(get(cols, "gears"))
+	var names []string
+	for _, f := range merged.Fields {
+		if _, ok := changed[f.Field]; ok && f.Status == models.MergeApplied {
+			names = append(names, f.Field)
+		}
+	}
+	if len(names) == 0 {
+		return existing.ID, RowUnchanged, nil, nil
+	}
+	updated, err := w.trimRepo.GetByID(existing.ID, false)
 	if err != nil {
-		return importRow{}, fmt.Errorf("gears: %w", err)
+		return 0, "", nil, err
 	}
-	row.Gears = gears
-
-	if row.SourceRetrieved == "" {
-		row.SourceRetrieved = time.Now().Format(time.RFC3339)
+	changes, err := fieldChanges(existing, updated, names)
+	if err != nil {
+		return 0, "", nil, err
 	}
-
-	return row, nil
+	return existing.ID, RowUpdated, changes, nil
 }
 
-func parseInt64Ptr(s string) (*int64, error) {
-	s = strings.TrimSpace(s)
-	if s == "" {
-		return nil, nil
+// brand returns the id of the row's brand, creating it if needed
+func (w *rowWriter) brand(v map[string]string) (int64, error) {
+	name := v[FieldBrand]
+	brand, err := w.brandRepo.GetByName(name)
+	if err == nil {
+		return brand.ID, nil
 	}
-	n, err := strconv.ParseInt(s, 10, 64)
+	if !errors.Is(err, repository.ErrBrandNotFound) {
+		return 0, err
+	}
+	if deleted, err := w.brandRepo.InTrash(name); err != nil {
+		return 0, err
+	} else if deleted {
+		return 0, errSkipped{fmt.Sprintf("brand %s is in the trash", name)}
+	}
+
+	brand, err = w.brands.CreateBrand(name, optional(v[FieldBrandCountry]), nil)
 	if err != nil {
-		return nil, err
+		return 0, err
 	}
-	return &n, nil
+	w.created = append(w.created, "brand "+name)
+	return brand.ID, nil
 }
 
-func parseBoolPtr(s string) (*bool, error) {
-	s = strings.TrimSpace(strings.ToLower(s))
-	if s == "" {
-		return nil, nil
+// model returns the id of the row's model, creating it if needed
+func (w *rowWriter) model(brandID int64, v map[string]string) (int64, error) {
+	name := v[FieldModel]
+	existing, err := w.modelRepo.ListByBrand(brandID)
+	if err != nil {
+		return 0, err
 	}
-	if s == "1" || s == "true" || s == "yes" {
-		b := true
-		return &b, nil
+	for _, m := range existing {
+		if strings.EqualFold(m.Name, name) {
+			return m.ID, nil
+		}
 	}
-	if s == "0" || s == "false" || s == "no" {
-		b := false
-		return &b, nil
+	if deleted, err := w.modelRepo.InTrash(brandID, name); err != nil {
+		return 0, err
+	} else if deleted {
+		return 0, errSkipped{fmt.Sprintf("model %s is in the trash", name)}
 	}
-	return nil, fmt.Errorf("invalid bool: %s", s)
-}
 
-func vehicleLink(brand, model, generation string) string {
-	slug := strings.ToLower(strings.TrimSpace(brand)) + "/" + strings.ToLower(strings.TrimSpace(model)) + "/" + strings.ToLower(strings.TrimSpace(generation))
-	slug = strings.ReplaceAll(slug, " ", "-")
-	slug = strings.ReplaceAll(slug, "_", "-")
-	return "manual://" + slug
+	model, err := w.models.CreateModel(brandID, name, optional(v[FieldModelBodyStyle]), optional(v[FieldModelSegment]))
+	if err != nil {
+		return 0, err
+	}
+	w.created = append(w.created, "model "+name)
+	return model.ID, nil
 }
 
-func trimLink(brand, model, generation, trimName string) string {
-	slug := strings.ToLower(strings.TrimSpace(trimName))
-	slug = strings.ReplaceAll(slug, " ", "-")
-	slug = strings.ReplaceAll(slug, "_", "-")
-	return vehicleLink(brand, model, generation) + "/trim/" + slug
-}
+// generation returns the id of the row's generation, creating it if needed.
+// Rows without one are grouped per model year, like the API Ninjas sync does.
+func (w *rowWriter) generation(modelID int64, year int, v map[string]string) (int64, error) {
+	code := v[FieldGeneration]
+	byYear := code == ""
+	if byYear {
+		code = strconv.Itoa(year)
+	}
 
-func upsertVehicle(tx *sql.Tx, brand, model, generation string) (int64, error) {
-	var id int64
-	err := tx.QueryRow("SELECT id FROM vehicles WHERE brand = ? AND model = ? AND generation = ?", brand, model, generation).Scan(&id)
-	if err == nil {
-		return id, nil
+	existing, err := w.generationRepo.ListByModel(modelID)
+	if err != nil {
+		return 0, err
 	}
-	if err != sql.ErrNoRows {
+	for _, g := range existing {
+		if strings.EqualFold(g.Code, code) {
+			return g.ID, nil
+		}
+	}
+	if deleted, err := w.generationRepo.InTrash(modelID, code); err != nil {
 		return 0, err
+	} else if deleted {
+		return 0, errSkipped{fmt.Sprintf("generation %s is in the trash", code)}
 	}
 
-	link := vehicleLink(brand, model, generation)
-	res, err := tx.Exec("INSERT INTO vehicles (brand, model, generation, image_url, link) VALUES (?, ?, ?, ?, ?)", brand, model, generation, "", link)
-	if err != nil {
+	g := &models.Generation{
+		Code:     code,
+		Name:     optional(v[FieldGenerationName]),
+		Platform: optional(v[FieldGenerationPlatform]),
+	}
+	g.StartYear = firstYear(v[FieldGenerationStartYear], v["start_year"], v["year"])
+	if end := firstYear(v[FieldGenerationEndYear]); end != 0 {
+		g.EndYear = &end
+	} else if byYear {
+		g.EndYear = &g.StartYear
+	}
+	g.IsCurrent = g.EndYear == nil
+	if err := w.generations.CreateGeneration(modelID, g); err != nil {
 		return 0, err
 	}
-	return res.LastInsertId()
+	w.created = append(w.created, "generation "+code)
+	return g.ID, nil
 }
 
-func upsertGenerationMeta(tx *sql.Tx, vehicleID int64, row importRow) error {
-	var start interface{} = nil
-	var end interface{} = nil
-	var isFacelift interface{} = nil
-	if row.StartYear != nil {
-		start = *row.StartYear
-	}
-	if row.EndYear != nil {
-		end = *row.EndYear
-	}
-	if row.IsFacelift != nil {
-		if *row.IsFacelift {
-			isFacelift = 1
-		} else {
-			isFacelift = 0
+// findTrim returns the live trim of the generation with the name and year,
+// and the market when the row has one, or nil
+func (w *rowWriter) findTrim(generationID int64, name string, year int, market string) (*models.Trim, error) {
+	trims, err := w.trimRepo.ListByGeneration(generationID)
+	if err != nil {
+		return nil, err
+	}
+	for _, t := range trims {
+		if strings.EqualFold(t.Name, name) && t.Year == year && (market == "" || strings.EqualFold(t.Market, market)) {
+			return t, nil
 		}
 	}
+	return nil, nil
+}
 
-	_, err := tx.Exec(
-		"INSERT INTO vehicle_generation_meta (vehicle_id, start_year, end_year, is_facelift, market) VALUES (?, ?, ?, COALESCE(?, 0), ?) ON CONFLICT(vehicle_id) DO UPDATE SET start_year=excluded.start_year, end_year=excluded.end_year, is_facelift=excluded.is_facelift, market=excluded.market",
-		vehicleID,
-		start,
-		end,
-		isFacelift,
-		row.Market,
-	)
-	return err
+// enumFields are normalised before comparing, so a row spelling a stored
+// value differently ("Benzin" for "petrol") is not a change
+var enumFields = map[string]enums.Enum{
+	"fuel_type":         enums.FuelType,
+	"transmission_type": enums.Transmission,
+	"drivetrain":        enums.Drivetrain,
+	"emission_standard": enums.EmissionStandard,
 }
 
-func upsertTrim(tx *sql.Tx, vehicleID int64, trimName string) (int64, error) {
-	var id int64
-	err := tx.QueryRow("SELECT id FROM trims WHERE vehicle_id = ? AND name = ?", vehicleID, trimName).Scan(&id)
-	if err == nil {
-		return id, nil
-	}
-	if err != sql.ErrNoRows {
-		return 0, err
-	}
+// trimPatch encodes the row's trim fields as a JSON merge patch, and returns
+// the fields it sets
+func trimPatch(v map[string]string) (map[string]json.RawMessage, []string, error) {
+	patch := make(map[string]json.RawMessage)
+	var fields []string
+	for field, value := range v {
+		kind, ok := trimFieldKinds[field]
+		if !ok {
+			continue
+		}
+		if enum, ok := enumFields[field]; ok {
+			if canonical, ok := enum.Normalize(value); ok {
+				value = canonical
+			}
+		}
 
-	var brand, model, generation string
-	if err := tx.QueryRow("SELECT brand, model, generation FROM vehicles WHERE id = ?", vehicleID).Scan(&brand, &model, &generation); err != nil {
-		return 0, err
+		var encoded json.RawMessage
+		switch kind {
+		case kindInt, kindFloat, kindBool:
+			// normalize has already written these as JSON literals
+			encoded = json.RawMessage(value)
+		default:
+			var err error
+			if encoded, err = json.Marshal(value); err != nil {
+				return nil, nil, err
+			}
+		}
+		patch[field] = encoded
+		fields = append(fields, field)
 	}
+	return patch, fields, nil
+}
 
-	link := trimLink(brand, model, generation, trimName)
-	res, err := tx.Exec("INSERT INTO trims (vehicle_id, name, link) VALUES (?, ?, ?)", vehicleID, trimName, link)
+// decodeTrim sets the fields in patch on trim
+func decodeTrim(patch map[string]json.RawMessage, trim *models.Trim) error {
+	body, err := json.Marshal(patch)
 	if err != nil {
-		return 0, err
+		return err
 	}
-	return res.LastInsertId()
+	if err := json.Unmarshal(body, trim); err != nil {
+		return fmt.Errorf("invalid values: %w", err)
+	}
+	return nil
 }
 
-func upsertPowertrainMeta(tx *sql.Tx, trimID int64, row importRow) error {
-	var displacement interface{} = nil
-	var power interface{} = nil
-	var torque interface{} = nil
-	var gears interface{} = nil
-	if row.DisplacementCC != nil {
-		displacement = *row.DisplacementCC
-	}
-	if row.PowerHP != nil {
-		power = *row.PowerHP
-	}
-	if row.TorqueNM != nil {
-		torque = *row.TorqueNM
-	}
-	if row.Gears != nil {
-		gears = *row.Gears
-	}
-
-	_, err := tx.Exec(
-		"INSERT INTO trim_powertrain_meta (trim_id, engine_code, fuel_type, displacement_cc, power_hp, torque_nm, transmission_type, gears, drive, market_scope) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?) ON CONFLICT(trim_id) DO UPDATE SET engine_code=excluded.engine_code, fuel_type=excluded.fuel_type, displacement_cc=excluded.displacement_cc, power_hp=excluded.power_hp, torque_nm=excluded.torque_nm, transmission_type=excluded.transmission_type, gears=excluded.gears, drive=excluded.drive, market_scope=excluded.market_scope",
-		trimID,
-		row.EngineCode,
-		row.FuelType,
-		displacement,
-		power,
-		torque,
-		row.TransmissionType,
-		gears,
-		row.Drive,
-		row.MarketScope,
-	)
-	return err
+// changedFields returns the part of patch that differs from existing
+func changedFields(existing *models.Trim, patch map[string]json.RawMessage) (map[string]json.RawMessage, error) {
+	current, err := encodeTrim(existing)
+	if err != nil {
+		return nil, err
+	}
+
+	changed := make(map[string]json.RawMessage)
+	for field, value := range patch {
+		if string(current[field]) != string(value) {
+			changed[field] = value
+		}
+	}
+	return changed, nil
 }
 
-func insertSpec(tx *sql.Tx, trimID int64, category, name, value string) (int64, error) {
-	var id int64
-	err := tx.QueryRow("SELECT id FROM specs WHERE trim_id = ? AND category = ? AND name = ? AND value = ?", trimID, category, name, value).Scan(&id)
-	if err == nil {
-		return id, nil
+// fieldChanges lists fields of before (nil for a create) and after as stored,
+// sorted by name
+func fieldChanges(before, after *models.Trim, fields []string) ([]models.FieldChange, error) {
+	old := map[string]json.RawMessage{}
+	if before != nil {
+		var err error
+		if old, err = encodeTrim(before); err != nil {
+			return nil, err
+		}
 	}
-	if err != sql.ErrNoRows {
-		return 0, err
+	updated, err := encodeTrim(after)
+	if err != nil {
+		return nil, err
 	}
 
-	res, err := tx.Exec("INSERT INTO specs (trim_id, category, name, value) VALUES (?, ?, ?, ?)", trimID, category, name, value)
-	if err != nil {
-		return 0, err
+	changes := make([]models.FieldChange, 0, len(fields))
+	for _, field := range fields {
+		change := models.FieldChange{Field: field, Before: old[field], After: updated[field]}
+		if change.Before == nil {
+			change.Before = json.RawMessage("null")
+		}
+		if change.After == nil {
+			change.After = json.RawMessage("null")
+		}
+		changes = append(changes, change)
 	}
-	return res.LastInsertId()
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Field < changes[j].Field })
+	return changes, nil
 }
 
-func upsertSourceDocument(tx *sql.Tx, row importRow) (int64, error) {
-	_, err := tx.Exec("INSERT OR IGNORE INTO source_documents (url, title, source_type, market_scope, retrieved_at) VALUES (?, ?, ?, ?, ?)", row.SourceURL, row.SourceTitle, row.SourceType, row.SourceMarket, row.SourceRetrieved)
+// encodeTrim returns the JSON encoding of trim's fields, keyed by name
+func encodeTrim(trim *models.Trim) (map[string]json.RawMessage, error) {
+	encoded, err := json.Marshal(trim)
 	if err != nil {
-		return 0, err
+		return nil, fmt.Errorf("failed to encode trim: %w", err)
+	}
+	fields := make(map[string]json.RawMessage)
+	if err := json.Unmarshal(encoded, &fields); err != nil {
+		return nil, fmt.Errorf("failed to encode trim: %w", err)
 	}
+	return fields, nil
+}
 
-	var id int64
-	if err := tx.QueryRow("SELECT id FROM source_documents WHERE url = ?", row.SourceURL).Scan(&id); err != nil {
-		return 0, err
+// firstYear returns the first of values that is a year, or 0
+func firstYear(values ...string) int {
+	for _, v := range values {
+		if year, err := strconv.Atoi(v); err == nil && year != 0 {
+			return year
+		}
 	}
-	return id, nil
+	return 0
 }
 
-func upsertSpecSource(tx *sql.Tx, specID, sourceID int64, page, note string) error {
-	_, err := tx.Exec("INSERT OR IGNORE INTO spec_sources (spec_id, source_document_id, page, note) VALUES (?, ?, ?, ?)", specID, sourceID, page, note)
-	return err
+func optional(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
 }
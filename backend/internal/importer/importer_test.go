@@ -0,0 +1,393 @@
+package importer
+
+import (
+	"database/sql"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/emirh/car-specs/backend/internal/models"
+	"github.com/emirh/car-specs/backend/internal/repository"
+	"github.com/emirh/car-specs/backend/migrations"
+
+	_ "modernc.org/sqlite"
+)
+
+func openTestDB(t *testing.T) *sql.DB {
+	t.Helper()
+	db, err := sql.Open("sqlite", filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("failed to open db: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	migrator, err := migrations.NewMigrator(db)
+	if err != nil {
+		t.Fatalf("failed to load migrations: %v", err)
+	}
+	if err := migrator.Up(); err != nil {
+		t.Fatalf("failed to migrate: %v", err)
+	}
+	return db
+}
+
+// writeFile writes content to name in a temporary directory and returns its path
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestBundledProfiles(t *testing.T) {
+	paths, err := filepath.Glob("../../data/import_profiles/*")
+	if err != nil || len(paths) == 0 {
+		t.Fatalf("no bundled profiles found (%v)", err)
+	}
+	for _, path := range paths {
+		if _, err := LoadProfile(path); err != nil {
+			t.Errorf("LoadProfile(%s) error = %v", path, err)
+		}
+	}
+}
+
+func TestProfileValidation(t *testing.T) {
+	tests := []struct {
+		name    string
+		profile string
+		wantErr string
+	}{
+		{"unknown field", `{"columns": [{"column": "a", "field": "horsepower"}]}`, `unknown field "horsepower"`},
+		{"bad unit", `{"columns": [{"column": "a", "field": "power_hp", "unit": "mph"}]}`, "cannot convert mph"},
+		{"unit on text", `{"columns": [{"column": "a", "field": "name", "unit": "mm"}]}`, "name has no unit"},
+		{"column and index", `{"columns": [{"column": "a", "index": 1, "field": "name"}]}`, "set either column or index"},
+		{"missing brand", `{"columns": [{"column": "a", "field": "name"}], "defaults": {"model": "Golf", "year": "2020"}}`, "brand must be mapped"},
+		{"unknown key", `{"colums": []}`, "unknown field"},
+	}
+	for _, tc := range tests {
+		path := writeFile(t, "profile.json", tc.profile)
+		if _, err := LoadProfile(path); err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+			t.Errorf("%s: LoadProfile() error = %v, want %q", tc.name, err, tc.wantErr)
+		}
+	}
+}
+
+func TestReadRows(t *testing.T) {
+	profile := writeFile(t, "profile.yaml", `
+decimal_separator: ","
+null_values: ["-"]
+defaults:
+  brand: Volkswagen
+  model: Golf
+  name: "Variant {year}"
+columns:
+  - {column: Yıl, field: year}
+  - {column: Name, field: name, pattern: '(?i)^golf\s+(.+)$'}
+  - {column: Power, field: power_hp, unit: kw}
+  - {column: Torque, field: torque_nm}
+  - {column: Speed, field: top_speed_kmh, unit: mph}
+  - {column: Accel, field: acceleration_0_100}
+  - {column: Body, field: model.body_style, case: lower}
+`)
+	p, err := LoadProfile(profile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dataset := writeFile(t, "golf.csv", "yıl,name,power,torque,speed,accel,body\n"+
+		"2021,Golf 1.5 eTSI,110 kW,250,\"139,8 mph\",\"8,5\",HATCHBACK\n"+
+		"2022,,-,-,,,\n"+
+		"2023,GTI,fast,,,,\n")
+
+	rows, err := p.ReadRows(dataset)
+	if err != nil {
+		t.Fatalf("ReadRows() error = %v", err)
+	}
+	if len(rows) != 3 {
+		t.Fatalf("ReadRows() = %d rows, want 3", len(rows))
+	}
+
+	want := map[string]string{
+		"brand": "Volkswagen", "model": "Golf", "year": "2021", "name": "1.5 eTSI",
+		"power_hp": "150", "torque_nm": "250", "top_speed_kmh": "225", "acceleration_0_100": "8.5",
+		"model.body_style": "hatchback",
+	}
+	for field, value := range want {
+		if got := rows[0].Values[field]; got != value {
+			t.Errorf("row 1 %s = %q, want %q", field, got, value)
+		}
+	}
+	if rows[0].Line != 2 {
+		t.Errorf("row 1 line = %d, want 2", rows[0].Line)
+	}
+	if got := rows[1].Values["name"]; got != "Variant 2022" {
+		t.Errorf("row 2 name = %q, want the default", got)
+	}
+	if _, ok := rows[1].Values["power_hp"]; ok {
+		t.Errorf("row 2 power_hp = %q, want none for a null value", rows[1].Values["power_hp"])
+	}
+	if rows[2].Err == nil || !strings.Contains(rows[2].Err.Error(), "power_hp") {
+		t.Errorf("row 3 error = %v, want power_hp not a number", rows[2].Err)
+	}
+}
+
+func TestReadRowsJSON(t *testing.T) {
+	p := &Profile{
+		Columns: []Column{
+			{Column: "make", Field: FieldBrand},
+			{Column: "model", Field: FieldModel},
+			{Column: "trim", Field: "name"},
+			{Column: "year", Field: "year"},
+			{Column: "engine.torque", Field: "torque_nm", Unit: "lb-ft"},
+			{Column: "engine.awd", Field: "drivetrain", Replace: map[string]string{"true": "awd", "false": "fwd"}},
+		},
+	}
+	if err := p.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	lines := `{"make": "Audi", "model": "A3", "trim": "S3", "year": 2021, "engine": {"torque": 295, "awd": true}}
+{"make": "Audi", "model": "A3", "trim": "35 TFSI", "year": 2021, "engine": {"torque": null}}
+`
+	rows, err := p.ReadRows(writeFile(t, "feed.jsonl", lines))
+	if err != nil {
+		t.Fatalf("ReadRows() error = %v", err)
+	}
+	if len(rows) != 2 {
+		t.Fatalf("ReadRows() = %d rows, want 2", len(rows))
+	}
+	if got := rows[0].Values["torque_nm"]; got != "400" {
+		t.Errorf("torque_nm = %q, want 400", got)
+	}
+	if got := rows[0].Values["drivetrain"]; got != "awd" {
+		t.Errorf("drivetrain = %q, want awd", got)
+	}
+	if _, ok := rows[1].Values["torque_nm"]; ok {
+		t.Error("null torque was mapped")
+	}
+}
+
+func TestImport(t *testing.T) {
+	db := openTestDB(t)
+	p := &Profile{
+		Defaults: map[string]string{"brand": "Audi", "market": "DE"},
+		Columns: []Column{
+			{Column: "model", Field: FieldModel},
+			{Column: "generation", Field: FieldGeneration},
+			{Column: "from", Field: FieldGenerationStartYear},
+			{Column: "trim", Field: "name"},
+			{Column: "year", Field: "year"},
+			{Column: "hp", Field: "power_hp"},
+			{Column: "fuel", Field: "fuel_type"},
+		},
+	}
+	if err := p.Validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	first := writeFile(t, "a3.csv", "model,generation,from,trim,year,hp,fuel\n"+
+		"A3,8Y,2020,35 TFSI,2021,150,Benzin\n"+
+		"A3,8Y,2020,40 TDI,2021,200,Dizel\n"+
+		"A3,8Y,2020,30 TFSI,2021,110,steam\n")
+	result, err := Import(db, p, first, Options{MaxErrors: -1})
+	if err != nil {
+		t.Fatalf("Import() error = %v", err)
+	}
+	if result.Count(RowCreated) != 2 || result.Count(RowInvalid) != 1 {
+		t.Fatalf("Import() = %+v, want 2 created and 1 invalid", result.Rows)
+	}
+	if bad := result.Rows[2]; bad.Line != 4 || !strings.Contains(bad.Reason, "fuel_type") {
+		t.Errorf("invalid row = %+v, want line 4 with a fuel_type reason", bad)
+	}
+	var generations int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM generations WHERE code = '8Y'`).Scan(&generations); err != nil || generations != 1 {
+		t.Errorf("%d 8Y generations (%v), want 1", generations, err)
+	}
+
+	// Importing again updates what changed and leaves the rest
+	mustExec(t, db, `UPDATE trims SET deleted_at = CURRENT_TIMESTAMP WHERE name = '40 TDI'`)
+	second := writeFile(t, "a3.csv", "model,generation,from,trim,year,hp,fuel\n"+
+		"A3,8Y,2020,35 TFSI,2021,150,petrol\n"+
+		"A3,8Y,2020,35 TFSI,2022,163,\n"+
+		"A3,8Y,2020,40 TDI,2021,204,diesel\n")
+	mustExec(t, db, `UPDATE trims SET power_hp = 140 WHERE name = '35 TFSI'`)
+	if result, err = Import(db, p, second, Options{MaxErrors: -1}); err != nil {
+		t.Fatalf("second Import() error = %v", err)
+	}
+	actions := []string{}
+	for _, row := range result.Rows {
+		actions = append(actions, row.Action)
+	}
+	if strings.Join(actions, ",") != "updated,created,skipped" {
+		t.Errorf("second import actions = %v, want updated, created, skipped", actions)
+	}
+
+	var hp int
+	var market string
+	if err := db.QueryRow(`SELECT power_hp, market FROM trims WHERE name = '35 TFSI' AND year = 2021`).Scan(&hp, &market); err != nil {
+		t.Fatal(err)
+	}
+	if hp != 150 || market != "DE" {
+		t.Errorf("35 TFSI power_hp = %d, market = %s; want 150 and DE", hp, market)
+	}
+	var entries int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM audit_log WHERE source = 'importer:csv'`).Scan(&entries); err != nil || entries == 0 {
+		t.Errorf("%d audit entries by the import (%v)", entries, err)
+	}
+}
+
+func mustExec(t *testing.T, db *sql.DB, query string) {
+	t.Helper()
+	if _, err := db.Exec(query); err != nil {
+		t.Fatalf("%s: %v", query, err)
+	}
+}
+
+func TestImportDryRun(t *testing.T) {
+	db := openTestDB(t)
+	p := &Profile{
+		Defaults: map[string]string{"brand": "Audi", "model": "A3"},
+		Columns: []Column{
+			{Column: "trim", Field: "name"},
+			{Column: "year", Field: "year"},
+			{Column: "hp", Field: "power_hp"},
+		},
+	}
+	if err := p.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	dataset := writeFile(t, "a3.csv", "trim,year,hp\n35 TFSI,2021,150\n40 TDI,2021,-5\n")
+
+	result, err := Import(db, p, dataset, Options{DryRun: true, MaxErrors: 0})
+	if err != nil {
+		t.Fatalf("Import(dry run) error = %v", err)
+	}
+	if !result.DryRun || len(result.Rows) != 2 {
+		t.Fatalf("Import(dry run) = %+v, want both rows reported", result)
+	}
+	created := result.Rows[0]
+	if created.Action != RowCreated || created.TrimID != 0 || strings.Join(created.Created, ",") != "brand Audi,model A3,generation 2021" {
+		t.Errorf("created row = %+v", created)
+	}
+	if len(created.Changes) != 3 || created.Changes[1].Field != "power_hp" || string(created.Changes[1].After) != "150" {
+		t.Errorf("created row changes = %+v, want name, power_hp and year", created.Changes)
+	}
+	if result.Rows[1].Action != RowInvalid || !strings.Contains(result.Rows[1].Reason, "power_hp must not be negative") {
+		t.Errorf("invalid row = %+v", result.Rows[1])
+	}
+	var brands int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM brands`).Scan(&brands); err != nil || brands != 0 {
+		t.Errorf("dry run stored %d brands (%v)", brands, err)
+	}
+
+	// The diff of an update shows the stored value before and after. The
+	// dataset is imported again from the same path, so its new value replaces
+	// its old one rather than disputing it.
+	dataset = writeFile(t, "a3.csv", "trim,year,hp\n35 TFSI,2021,150\n")
+	if _, err := Import(db, p, dataset, Options{MaxErrors: -1}); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(dataset, []byte("trim,year,hp\n35 TFSI,2021,163\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	result, err = Import(db, p, dataset, Options{DryRun: true, MaxErrors: -1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	updated := result.Rows[0]
+	if updated.Action != RowUpdated || updated.TrimID == 0 || len(updated.Changes) != 1 ||
+		string(updated.Changes[0].Before) != "150" || string(updated.Changes[0].After) != "163" {
+		t.Errorf("updated row = %+v, want power_hp 150 -> 163", updated)
+	}
+	var hp int
+	if err := db.QueryRow(`SELECT power_hp FROM trims`).Scan(&hp); err != nil || hp != 150 {
+		t.Errorf("power_hp after a dry run = %d (%v), want 150", hp, err)
+	}
+}
+
+// TestImportKeepsTrustedValues checks that a row is merged in like any other
+// source: it cannot overwrite a value an editor set
+func TestImportKeepsTrustedValues(t *testing.T) {
+	db := openTestDB(t)
+	p := &Profile{
+		Defaults: map[string]string{"brand": "Audi", "model": "A3"},
+		Columns: []Column{
+			{Column: "trim", Field: "name"},
+			{Column: "year", Field: "year"},
+			{Column: "hp", Field: "power_hp"},
+		},
+	}
+	if err := p.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	dataset := writeFile(t, "a3.csv", "trim,year,hp\n35 TFSI,2021,150\n")
+	result, err := Import(db, p, dataset, Options{MaxErrors: -1})
+	if err != nil || result.Count(RowCreated) != 1 {
+		t.Fatalf("Import() = %+v, %v", result, err)
+	}
+	trimID := result.Rows[0].TrimID
+
+	// An editor corrects the power
+	provenance := repository.NewProvenanceRepository(db)
+	editor := &models.SourceDocument{URL: "manual://editor", SourceType: models.SourceManual}
+	if err := provenance.EnsureSource(editor); err != nil {
+		t.Fatal(err)
+	}
+	if err := provenance.CiteField(trimID, "power_hp", 160, editor.ID, ""); err != nil {
+		t.Fatal(err)
+	}
+	mustExec(t, db, `UPDATE trims SET power_hp = 160`)
+	// The edit is a minute old when the dataset is imported again
+	mustExec(t, db, `UPDATE trim_field_sources SET recorded_at = datetime('now', '-1 minute') WHERE value = '160'`)
+
+	if err := os.WriteFile(dataset, []byte("trim,year,hp\n35 TFSI,2021,170\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if result, err = Import(db, p, dataset, Options{MaxErrors: -1}); err != nil {
+		t.Fatalf("second Import() error = %v", err)
+	}
+	if row := result.Rows[0]; row.Action != RowUnchanged {
+		t.Errorf("row = %+v, want unchanged", row)
+	}
+	var hp int
+	if err := db.QueryRow(`SELECT power_hp FROM trims WHERE id = ?`, trimID).Scan(&hp); err != nil || hp != 160 {
+		t.Errorf("power_hp = %d (%v), want the editor's 160", hp, err)
+	}
+	var conflicts int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM merge_conflicts WHERE trim_id = ? AND field = 'power_hp'`, trimID).Scan(&conflicts); err != nil || conflicts != 1 {
+		t.Errorf("%d power_hp conflicts (%v), want 1", conflicts, err)
+	}
+}
+
+func TestImportMaxErrors(t *testing.T) {
+	db := openTestDB(t)
+	p := &Profile{
+		Defaults: map[string]string{"brand": "Audi", "model": "A3"},
+		Columns: []Column{
+			{Column: "trim", Field: "name"},
+			{Column: "year", Field: "year"},
+		},
+	}
+	if err := p.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	dataset := writeFile(t, "a3.csv", "trim,year\n35 TFSI,2021\n,2021\n,2022\n40 TDI,2021\n")
+
+	result, err := Import(db, p, dataset, Options{MaxErrors: 1})
+	if !errors.Is(err, ErrTooManyErrors) {
+		t.Fatalf("Import() error = %v, want ErrTooManyErrors", err)
+	}
+	if len(result.Rows) != 3 {
+		t.Errorf("Import() reported %d rows, want the 3 read before aborting", len(result.Rows))
+	}
+	var trims int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM trims`).Scan(&trims); err != nil || trims != 0 {
+		t.Errorf("aborted import stored %d trims (%v)", trims, err)
+	}
+
+	if result, err = Import(db, p, dataset, Options{MaxErrors: 2}); err != nil || result.Count(RowCreated) != 2 {
+		t.Errorf("Import(max 2 errors) = %d created, %v; want 2", result.Count(RowCreated), err)
+	}
+}
@@ -0,0 +1,272 @@
+package importer
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"strings"
+
+	"github.com/emirh/car-specs/backend/internal/models"
+	"gopkg.in/yaml.v3"
+)
+
+// Profile describes how the rows of one kind of CSV or JSON dataset map onto
+// the catalogue, so a new dealer or spec-site export can be imported by
+// writing a profile instead of Go code. Profiles are YAML or JSON files, see
+// data/import_profiles.
+type Profile struct {
+	Name string `yaml:"name" json:"name"`
+	// Format is csv or json. Empty picks it from the dataset's extension.
+	Format string `yaml:"format" json:"format"`
+	// Delimiter separates CSV columns (default ",")
+	Delimiter string `yaml:"delimiter" json:"delimiter"`
+	// DecimalSeparator is "." (default) or ","; the other one is read as a
+	// thousands separator
+	DecimalSeparator string `yaml:"decimal_separator" json:"decimal_separator"`
+	// NullValues are read as empty, e.g. "-" or "n/a" (case-insensitive)
+	NullValues []string `yaml:"null_values" json:"null_values"`
+	Columns    []Column `yaml:"columns" json:"columns"`
+	// Defaults fill fields no column gave a value, e.g. brand, model or
+	// market. "{field}" is replaced with that field's value in the row.
+	Defaults map[string]string `yaml:"defaults" json:"defaults"`
+	Source   ProfileSource     `yaml:"source" json:"source"`
+}
+
+// Column maps one column of the dataset onto a field. When several columns
+// map onto the same field the first one with a value wins.
+type Column struct {
+	// Column is the CSV header (case-insensitive) or JSON key. Dots descend
+	// into nested JSON objects ("engine.power").
+	Column string `yaml:"column" json:"column"`
+	// Index is the 1-based position of a CSV column, for files whose headers
+	// are missing or unreliable. Used instead of Column.
+	Index int `yaml:"index" json:"index"`
+	// Field is what the value is stored as, see Fields
+	Field string `yaml:"field" json:"field"`
+	// Unit is the unit values are given in, converted to the field's unit
+	// (e.g. "kw" for power_hp, "mph" for top_speed_kmh)
+	Unit string `yaml:"unit" json:"unit"`
+	// Replace maps whole values (case-insensitive) onto others, e.g. the
+	// site's "Otomatik" onto "automatic"
+	Replace map[string]string `yaml:"replace" json:"replace"`
+	// Pattern extracts the first capture group (or the whole match) of a
+	// regular expression; values it doesn't match are kept as they are
+	Pattern string `yaml:"pattern" json:"pattern"`
+	// Case is upper, lower or title
+	Case string `yaml:"case" json:"case"`
+
+	pattern *regexp.Regexp
+}
+
+// ProfileSource is the source document imported values are cited to
+type ProfileSource struct {
+	// Type is the source type (default csv)
+	Type        string `yaml:"type" json:"type"`
+	Title       string `yaml:"title" json:"title"`
+	MarketScope string `yaml:"market_scope" json:"market_scope"`
+	// URLColumn holds the page a row came from. Rows without one are cited
+	// to the dataset file.
+	URLColumn string `yaml:"url_column" json:"url_column"`
+}
+
+// Fields that place a trim in the catalogue rather than describe it. The
+// brand, model and generation are created when missing; their other fields
+// are only used then.
+const (
+	FieldBrand               = "brand"
+	FieldBrandCountry        = "brand.country"
+	FieldModel               = "model"
+	FieldModelBodyStyle      = "model.body_style"
+	FieldModelSegment        = "model.segment"
+	FieldGeneration          = "generation"
+	FieldGenerationName      = "generation.name"
+	FieldGenerationStartYear = "generation.start_year"
+	FieldGenerationEndYear   = "generation.end_year"
+	FieldGenerationPlatform  = "generation.platform"
+)
+
+var catalogueFields = []string{
+	FieldBrand, FieldBrandCountry,
+	FieldModel, FieldModelBodyStyle, FieldModelSegment,
+	FieldGeneration, FieldGenerationName, FieldGenerationStartYear, FieldGenerationEndYear, FieldGenerationPlatform,
+}
+
+// fieldKind is how a field's values are parsed
+type fieldKind int
+
+const (
+	kindString fieldKind = iota
+	kindInt
+	kindFloat
+	kindBool
+)
+
+// unmappedTrimFields are Trim fields a profile cannot set: IDs, timestamps,
+// computed or joined values, and the legacy generation string (the
+// generation field names the generation row instead)
+var unmappedTrimFields = map[string]bool{
+	"id": true, "model_id": true, "generation_id": true, "generation": true,
+	"created_at": true, "updated_at": true,
+	"derived": true, "display": true, "generation_obj": true, "model": true, "specs": true,
+}
+
+// trimFieldKinds holds the kind of every Trim field a profile can map, keyed by JSON name
+var trimFieldKinds = func() map[string]fieldKind {
+	kinds := make(map[string]fieldKind)
+	t := reflect.TypeOf(models.Trim{})
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if name == "" || name == "-" || unmappedTrimFields[name] {
+			continue
+		}
+		typ := f.Type
+		if typ.Kind() == reflect.Pointer {
+			typ = typ.Elem()
+		}
+		switch typ.Kind() {
+		case reflect.Int, reflect.Int64:
+			kinds[name] = kindInt
+		case reflect.Float64:
+			kinds[name] = kindFloat
+		case reflect.Bool:
+			kinds[name] = kindBool
+		case reflect.String:
+			kinds[name] = kindString
+		}
+	}
+	return kinds
+}()
+
+// fieldKindOf returns the kind of a profile field and whether it exists
+func fieldKindOf(field string) (fieldKind, bool) {
+	switch field {
+	case FieldGenerationStartYear, FieldGenerationEndYear:
+		return kindInt, true
+	}
+	for _, f := range catalogueFields {
+		if f == field {
+			return kindString, true
+		}
+	}
+	kind, ok := trimFieldKinds[field]
+	return kind, ok
+}
+
+// Fields returns every field a profile can map a column onto
+func Fields() []string {
+	fields := append([]string{}, catalogueFields...)
+	for name := range trimFieldKinds {
+		fields = append(fields, name)
+	}
+	return fields
+}
+
+// LoadProfile reads a YAML (.yaml, .yml) or JSON (.json) profile and checks it
+func LoadProfile(path string) (*Profile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read profile: %w", err)
+	}
+
+	p := &Profile{}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		decoder := yaml.NewDecoder(bytes.NewReader(data))
+		decoder.KnownFields(true)
+		err = decoder.Decode(p)
+	case ".json":
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.DisallowUnknownFields()
+		err = decoder.Decode(p)
+	default:
+		return nil, fmt.Errorf("profile %s must be .yaml, .yml or .json", path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid profile %s: %w", path, err)
+	}
+	if p.Name == "" {
+		p.Name = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	}
+
+	if err := p.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid profile %s: %w", path, err)
+	}
+	return p, nil
+}
+
+// Validate checks the profile and compiles its patterns
+func (p *Profile) Validate() error {
+	switch p.Format {
+	case "", FormatCSV, FormatJSON:
+	default:
+		return fmt.Errorf("format must be csv or json, not %q", p.Format)
+	}
+	if len([]rune(p.Delimiter)) > 1 {
+		return fmt.Errorf("delimiter must be a single character")
+	}
+	switch p.DecimalSeparator {
+	case "", ".", ",":
+	default:
+		return fmt.Errorf("decimal_separator must be \".\" or \",\"")
+	}
+	if len(p.Columns) == 0 {
+		return fmt.Errorf("no columns are mapped")
+	}
+
+	mapped := make(map[string]bool)
+	for i := range p.Columns {
+		c := &p.Columns[i]
+		if (c.Column == "") == (c.Index == 0) {
+			return fmt.Errorf("column %d: set either column or index", i+1)
+		}
+		if c.Index < 0 {
+			return fmt.Errorf("column %d: index must be positive", i+1)
+		}
+		if _, ok := fieldKindOf(c.Field); !ok {
+			return fmt.Errorf("column %s: unknown field %q", c.name(), c.Field)
+		}
+		if c.Unit != "" {
+			if _, err := convertUnit(1, c.Unit, c.Field); err != nil {
+				return fmt.Errorf("column %s: %w", c.name(), err)
+			}
+		}
+		switch c.Case {
+		case "", "upper", "lower", "title":
+		default:
+			return fmt.Errorf("column %s: case must be upper, lower or title", c.name())
+		}
+		if c.Pattern != "" {
+			var err error
+			if c.pattern, err = regexp.Compile(c.Pattern); err != nil {
+				return fmt.Errorf("column %s: invalid pattern: %w", c.name(), err)
+			}
+		}
+		mapped[c.Field] = true
+	}
+	for field := range p.Defaults {
+		if _, ok := fieldKindOf(field); !ok {
+			return fmt.Errorf("defaults: unknown field %q", field)
+		}
+		mapped[field] = true
+	}
+
+	for _, required := range []string{FieldBrand, FieldModel, "name", "year"} {
+		if !mapped[required] {
+			return fmt.Errorf("%s must be mapped to a column or given a default", required)
+		}
+	}
+	return nil
+}
+
+// name identifies c in messages
+func (c *Column) name() string {
+	if c.Column != "" {
+		return fmt.Sprintf("%q", c.Column)
+	}
+	return fmt.Sprintf("#%d", c.Index)
+}
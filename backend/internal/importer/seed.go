@@ -1,13 +1,13 @@
 package importer
 
 import (
+	"database/sql"
 	"fmt"
 
 	"github.com/emirh/car-specs/backend/internal/service"
-	"gorm.io/gorm"
 )
 
-func SeedDemoData(db *gorm.DB) error {
+func SeedDemoData(db *sql.DB) error {
 	fmt.Println("Seeding demo data...")
 
 	demoData := []service.CarJSON{
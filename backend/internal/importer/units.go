@@ -0,0 +1,116 @@
+package importer
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+// fieldUnits is the unit each numeric trim field is stored in
+var fieldUnits = map[string]string{
+	"power_hp":                  "hp",
+	"power_kw":                  "kw",
+	"torque_nm":                 "nm",
+	"displacement_cc":           "cc",
+	"acceleration_0_100":        "s",
+	"top_speed_kmh":             "km/h",
+	"fuel_consumption_city":     "l/100km",
+	"fuel_consumption_highway":  "l/100km",
+	"fuel_consumption_combined": "l/100km",
+	"co2_emissions":             "g/km",
+	"length_mm":                 "mm",
+	"width_mm":                  "mm",
+	"height_mm":                 "mm",
+	"wheelbase_mm":              "mm",
+	"ground_clearance_mm":       "mm",
+	"curb_weight_kg":            "kg",
+	"gross_weight_kg":           "kg",
+	"luggage_capacity_l":        "l",
+	"luggage_capacity_max_l":    "l",
+	"fuel_tank_capacity_l":      "l",
+	"wheel_size_inches":         "in",
+}
+
+// unitConversions converts a value given in a unit (inner key) to a stored
+// unit (outer key). Horsepower is metric (PS), as sites and brochures give it.
+var unitConversions = map[string]map[string]func(v float64) float64{
+	"hp": {
+		"hp":  same,
+		"ps":  same,
+		"kw":  func(v float64) float64 { return v * 1.35962 },
+		"bhp": func(v float64) float64 { return v * 1.01387 },
+	},
+	"kw": {
+		"kw":  same,
+		"hp":  func(v float64) float64 { return v / 1.35962 },
+		"ps":  func(v float64) float64 { return v / 1.35962 },
+		"bhp": func(v float64) float64 { return v * 0.745700 },
+	},
+	"nm": {
+		"nm":    same,
+		"lb-ft": func(v float64) float64 { return v * 1.35582 },
+		"kgm":   func(v float64) float64 { return v * 9.80665 },
+	},
+	"cc": {
+		"cc":    same,
+		"l":     func(v float64) float64 { return v * 1000 },
+		"cu in": func(v float64) float64 { return v * 16.3871 },
+	},
+	"s": {
+		"s": same,
+	},
+	"km/h": {
+		"km/h": same,
+		"mph":  func(v float64) float64 { return v * 1.609344 },
+	},
+	"l/100km": {
+		"l/100km": same,
+		"mpg":     func(v float64) float64 { return 235.215 / v }, // US gallons
+		"mpg-uk":  func(v float64) float64 { return 282.481 / v },
+		"km/l":    func(v float64) float64 { return 100 / v },
+	},
+	"g/km": {
+		"g/km": same,
+		"g/mi": func(v float64) float64 { return v / 1.609344 },
+	},
+	"mm": {
+		"mm": same,
+		"cm": func(v float64) float64 { return v * 10 },
+		"m":  func(v float64) float64 { return v * 1000 },
+		"in": func(v float64) float64 { return v * 25.4 },
+	},
+	"kg": {
+		"kg": same,
+		"lb": func(v float64) float64 { return v * 0.453592 },
+		"t":  func(v float64) float64 { return v * 1000 },
+	},
+	"l": {
+		"l":     same,
+		"cu ft": func(v float64) float64 { return v * 28.3168 },
+		"gal":   func(v float64) float64 { return v * 3.78541 }, // US gallons
+	},
+	"in": {
+		"in": same,
+		"mm": func(v float64) float64 { return v / 25.4 },
+		"cm": func(v float64) float64 { return v / 2.54 },
+	},
+}
+
+func same(v float64) float64 { return v }
+
+// convertUnit converts v, given in unit, to the unit field is stored in
+func convertUnit(v float64, unit, field string) (float64, error) {
+	stored, ok := fieldUnits[field]
+	if !ok {
+		return 0, fmt.Errorf("%s has no unit", field)
+	}
+	convert, ok := unitConversions[stored][strings.ToLower(strings.TrimSpace(unit))]
+	if !ok {
+		return 0, fmt.Errorf("cannot convert %s to %s for %s", unit, stored, field)
+	}
+	converted := convert(v)
+	if math.IsInf(converted, 0) || math.IsNaN(converted) {
+		return 0, fmt.Errorf("cannot convert %g %s to %s", v, unit, stored)
+	}
+	return converted, nil
+}
@@ -0,0 +1,211 @@
+// Package merge picks the value a trim field should hold when several sources
+// (ultimatespecs, API Ninjas, CSV imports, editors, ...) report one.
+//
+// Every source's candidate is kept in trim_field_sources; merging only decides
+// which one wins. The winner is the freshest candidate from the most trusted
+// source. When another candidate disagrees with it beyond the field's tolerance
+// the field is flagged as a conflict and left unchanged, so an editor decides
+// instead of the last importer to run.
+package merge
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/emirh/car-specs/backend/internal/models"
+)
+
+// Policy configures how candidates are ranked and compared
+type Policy struct {
+	// Priority lists source types, most trusted first. Unlisted types rank last.
+	Priority []string `json:"priority"`
+	// MaxAgeDays makes candidates recorded longer ago than this rank below every
+	// fresher one, whatever their source. 0 disables the cut-off.
+	MaxAgeDays int `json:"max_age_days"`
+	// Tolerances is the relative difference (percent) up to which two numbers
+	// agree, per field. DefaultTolerance applies to numeric fields not listed.
+	// Strings must match exactly (ignoring case).
+	Tolerances       map[string]float64 `json:"tolerances"`
+	DefaultTolerance float64            `json:"default_tolerance"`
+	// Ignore lists fields that are never merged, such as the trim name, which
+	// every source spells its own way
+	Ignore []string `json:"ignore"`
+}
+
+// DefaultPolicy trusts editors first, then curated CSV data, then the
+// European spec sheets the catalogue is built on, then US API data
+func DefaultPolicy() Policy {
+	return Policy{
+		Priority: []string{
+			models.SourceManual,
+			models.SourceCSV,
+			models.SourceUltimateSpecs,
+			models.SourceCarsData,
+			models.SourceAPINinjas,
+			models.SourceCarQuery,
+			models.SourceImageSearch,
+			models.SourceDefault,
+		},
+		Tolerances: map[string]float64{
+			"power_hp":  5,
+			"power_kw":  5,
+			"torque_nm": 5,
+		},
+		DefaultTolerance: 2,
+		Ignore:           []string{"name"},
+	}
+}
+
+// LoadPolicy reads a policy from a JSON file. Settings missing from the file
+// keep their DefaultPolicy values; an empty path returns DefaultPolicy.
+func LoadPolicy(path string) (Policy, error) {
+	policy := DefaultPolicy()
+	if path == "" {
+		return policy, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return policy, fmt.Errorf("failed to read merge policy: %w", err)
+	}
+	if err := json.Unmarshal(data, &policy); err != nil {
+		return policy, fmt.Errorf("failed to parse merge policy %s: %w", path, err)
+	}
+	return policy, nil
+}
+
+// Decision is the outcome of merging one field
+type Decision struct {
+	Field string
+	// Winner is nil when no source reported a usable value
+	Winner *models.Citation
+	// Conflict is true when a candidate disagrees with Winner beyond tolerance
+	Conflict bool
+	// Disagreeing holds the candidates that caused the conflict
+	Disagreeing []models.Citation
+}
+
+// Ignored reports whether field is excluded from merging
+func (p Policy) Ignored(field string) bool {
+	for _, f := range p.Ignore {
+		if f == field {
+			return true
+		}
+	}
+	return false
+}
+
+// Resolve picks the winning candidate for field. now decides which candidates
+// are stale under MaxAgeDays.
+//
+// A null candidate means the source does not know the value and is skipped,
+// except from manual sources, where it is an editor clearing the field.
+func (p Policy) Resolve(field string, candidates []models.Citation, now time.Time) Decision {
+	decision := Decision{Field: field}
+
+	var usable []models.Citation
+	for _, c := range candidates {
+		if isNull(c.Value) && c.Source.SourceType != models.SourceManual {
+			continue
+		}
+		usable = append(usable, c)
+	}
+	if len(usable) == 0 {
+		return decision
+	}
+
+	sort.SliceStable(usable, func(i, j int) bool {
+		a, b := usable[i], usable[j]
+		if staleA, staleB := p.stale(a, now), p.stale(b, now); staleA != staleB {
+			return !staleA
+		}
+		if rankA, rankB := p.rank(a.Source.SourceType), p.rank(b.Source.SourceType); rankA != rankB {
+			return rankA < rankB
+		}
+		return a.RecordedAt.After(b.RecordedAt)
+	})
+
+	winner := usable[0]
+	decision.Winner = &winner
+	for _, c := range usable[1:] {
+		// Stale values that lost to a fresh one are outdated, not disputed
+		if p.stale(c, now) && !p.stale(winner, now) {
+			continue
+		}
+		// An editor's value settles everything reported before it
+		if winner.Source.SourceType == models.SourceManual && !c.RecordedAt.After(winner.RecordedAt) {
+			continue
+		}
+		if !p.agree(field, winner.Value, c.Value) {
+			decision.Disagreeing = append(decision.Disagreeing, c)
+		}
+	}
+	decision.Conflict = len(decision.Disagreeing) > 0
+	return decision
+}
+
+func (p Policy) rank(sourceType string) int {
+	for i, t := range p.Priority {
+		if t == sourceType {
+			return i
+		}
+	}
+	return len(p.Priority)
+}
+
+func (p Policy) stale(c models.Citation, now time.Time) bool {
+	if p.MaxAgeDays <= 0 {
+		return false
+	}
+	return now.Sub(c.RecordedAt) > time.Duration(p.MaxAgeDays)*24*time.Hour
+}
+
+// agree reports whether two JSON values are the same within field's tolerance
+func (p Policy) agree(field string, a, b json.RawMessage) bool {
+	if isNull(a) || isNull(b) {
+		return isNull(a) && isNull(b)
+	}
+
+	var x, y interface{}
+	if json.Unmarshal(a, &x) != nil || json.Unmarshal(b, &y) != nil {
+		return bytes.Equal(a, b)
+	}
+
+	switch xv := x.(type) {
+	case float64:
+		yv, ok := y.(float64)
+		if !ok {
+			return false
+		}
+		tolerance, ok := p.Tolerances[field]
+		if !ok {
+			tolerance = p.DefaultTolerance
+		}
+		return RelativeDifference(xv, yv) <= tolerance
+	case string:
+		yv, ok := y.(string)
+		return ok && strings.EqualFold(strings.TrimSpace(xv), strings.TrimSpace(yv))
+	default:
+		ca, _ := json.Marshal(x)
+		cb, _ := json.Marshal(y)
+		return bytes.Equal(ca, cb)
+	}
+}
+
+// RelativeDifference is |a-b| as a percentage of the larger magnitude
+func RelativeDifference(a, b float64) float64 {
+	if a == b {
+		return 0
+	}
+	return math.Abs(a-b) / math.Max(math.Abs(a), math.Abs(b)) * 100
+}
+
+func isNull(v json.RawMessage) bool {
+	return len(v) == 0 || string(bytes.TrimSpace(v)) == "null"
+}
@@ -0,0 +1,188 @@
+package merge
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/emirh/car-specs/backend/internal/models"
+)
+
+var now = time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+
+func candidate(sourceType, value string, daysAgo int) models.Citation {
+	return models.Citation{
+		Source:     models.SourceDocument{URL: sourceType + "://test", SourceType: sourceType},
+		Value:      json.RawMessage(value),
+		RecordedAt: now.AddDate(0, 0, -daysAgo),
+	}
+}
+
+func TestResolve(t *testing.T) {
+	tests := []struct {
+		name         string
+		field        string
+		policy       func(p *Policy)
+		candidates   []models.Citation
+		wantValue    string // "" when there is no winner
+		wantConflict bool
+	}{
+		{
+			name:  "priority beats freshness",
+			field: "power_hp",
+			candidates: []models.Citation{
+				candidate(models.SourceAPINinjas, "150", 1),
+				candidate(models.SourceUltimateSpecs, "148", 30),
+			},
+			wantValue: "148",
+		},
+		{
+			name:  "newest wins within a source type",
+			field: "power_hp",
+			candidates: []models.Citation{
+				candidate(models.SourceUltimateSpecs, "148", 30),
+				candidate(models.SourceUltimateSpecs, "150", 2),
+			},
+			wantValue: "150",
+		},
+		{
+			name:  "hp within 5% agrees",
+			field: "power_hp",
+			candidates: []models.Citation{
+				candidate(models.SourceUltimateSpecs, "150", 1),
+				candidate(models.SourceAPINinjas, "145", 1),
+			},
+			wantValue: "150",
+		},
+		{
+			name:  "hp beyond 5% conflicts",
+			field: "power_hp",
+			candidates: []models.Citation{
+				candidate(models.SourceUltimateSpecs, "150", 1),
+				candidate(models.SourceAPINinjas, "130", 1),
+			},
+			wantValue:    "150",
+			wantConflict: true,
+		},
+		{
+			name:  "default tolerance applies to other fields",
+			field: "curb_weight_kg",
+			candidates: []models.Citation{
+				candidate(models.SourceCSV, "1300", 1),
+				candidate(models.SourceAPINinjas, "1360", 1),
+			},
+			wantValue:    "1300",
+			wantConflict: true,
+		},
+		{
+			name:  "strings compare ignoring case",
+			field: "fuel_type",
+			candidates: []models.Citation{
+				candidate(models.SourceUltimateSpecs, `"petrol"`, 1),
+				candidate(models.SourceAPINinjas, `"Petrol"`, 1),
+			},
+			wantValue: `"petrol"`,
+		},
+		{
+			name:  "unknown values are skipped",
+			field: "torque_nm",
+			candidates: []models.Citation{
+				candidate(models.SourceUltimateSpecs, "null", 1),
+				candidate(models.SourceAPINinjas, "250", 1),
+			},
+			wantValue: "250",
+		},
+		{
+			name:  "nothing usable",
+			field: "torque_nm",
+			candidates: []models.Citation{
+				candidate(models.SourceUltimateSpecs, "null", 1),
+			},
+		},
+		{
+			name:  "manual clear wins",
+			field: "torque_nm",
+			candidates: []models.Citation{
+				candidate(models.SourceUltimateSpecs, "250", 5),
+				candidate(models.SourceManual, "null", 1),
+			},
+			wantValue: "null",
+		},
+		{
+			name:  "manual edit settles earlier values",
+			field: "power_hp",
+			candidates: []models.Citation{
+				candidate(models.SourceAPINinjas, "130", 5),
+				candidate(models.SourceManual, "150", 1),
+			},
+			wantValue: "150",
+		},
+		{
+			name:  "data newer than a manual edit is disputed",
+			field: "power_hp",
+			candidates: []models.Citation{
+				candidate(models.SourceManual, "150", 5),
+				candidate(models.SourceUltimateSpecs, "130", 1),
+			},
+			wantValue:    "150",
+			wantConflict: true,
+		},
+		{
+			name:   "stale candidates rank last",
+			field:  "power_hp",
+			policy: func(p *Policy) { p.MaxAgeDays = 365 },
+			candidates: []models.Citation{
+				candidate(models.SourceUltimateSpecs, "130", 400),
+				candidate(models.SourceAPINinjas, "150", 10),
+			},
+			wantValue: "150",
+		},
+		{
+			name:   "custom priority",
+			field:  "power_hp",
+			policy: func(p *Policy) { p.Priority = []string{models.SourceAPINinjas} },
+			candidates: []models.Citation{
+				candidate(models.SourceUltimateSpecs, "148", 1),
+				candidate(models.SourceAPINinjas, "150", 1),
+			},
+			wantValue: "150",
+		},
+	}
+
+	for _, tt := range tests {
+		policy := DefaultPolicy()
+		if tt.policy != nil {
+			tt.policy(&policy)
+		}
+
+		got := policy.Resolve(tt.field, tt.candidates, now)
+		gotValue := ""
+		if got.Winner != nil {
+			gotValue = string(got.Winner.Value)
+		}
+		if gotValue != tt.wantValue {
+			t.Errorf("%s: winner = %q, want %q", tt.name, gotValue, tt.wantValue)
+		}
+		if got.Conflict != tt.wantConflict {
+			t.Errorf("%s: conflict = %v, want %v (disagreeing: %d)", tt.name, got.Conflict, tt.wantConflict, len(got.Disagreeing))
+		}
+	}
+}
+
+func TestRelativeDifference(t *testing.T) {
+	tests := []struct {
+		a, b, want float64
+	}{
+		{100, 100, 0},
+		{100, 95, 5},
+		{95, 100, 5},
+		{0, 0, 0},
+		{0, 10, 100},
+	}
+
+	for _, tt := range tests {
+		if got := RelativeDifference(tt.a, tt.b); got != tt.want {
+			t.Errorf("RelativeDifference(%v, %v) = %v, want %v", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
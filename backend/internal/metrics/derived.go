@@ -0,0 +1,145 @@
+// Package metrics computes the performance figures derived from a trim's specs
+// (power-to-weight, specific output, range, ...).
+//
+// Every metric is defined twice: as Go code for the API payload and as a SQL
+// expression over trims (alias t) for search filters and sort keys. Both round
+// the same way so a trim shown with 150.2 hp/t is found by power_to_weight_min=150.2.
+package metrics
+
+import (
+	"math"
+
+	"github.com/emirh/car-specs/backend/internal/models"
+)
+
+// hpToKW converts metric horsepower (PS), which most European spec sheets use, to kW
+const hpToKW = 0.7355
+
+// PowerKWTolerance is the deviation (percent) up to which power_kw and power_hp
+// are considered consistent. It covers rounding and mechanical vs metric horsepower.
+const PowerKWTolerance = 3.0
+
+// Metric is a derived value usable in the payload, as a range filter and as a sort key
+type Metric struct {
+	Name     string // JSON field, filter prefix (<name>_min / <name>_max) and sort key
+	Unit     string
+	Decimals int
+	SQL      string // Expression over trims t; NULL when an input is missing or zero
+	value    func(t *models.Trim) *float64
+}
+
+// Value computes the metric for a trim, nil when an input is missing or zero
+func (m Metric) Value(t *models.Trim) *float64 {
+	v := m.value(t)
+	if v == nil {
+		return nil
+	}
+	rounded := round(*v, m.Decimals)
+	return &rounded
+}
+
+var (
+	PowerToWeight = Metric{
+		Name: "power_to_weight", Unit: "hp/t", Decimals: 1,
+		SQL: "ROUND(t.power_hp * 1000.0 / NULLIF(t.curb_weight_kg, 0), 1)",
+		value: func(t *models.Trim) *float64 {
+			return ratio(intValue(t.PowerHP), intValue(t.CurbWeightKG), 1000)
+		},
+	}
+	TorqueToWeight = Metric{
+		Name: "torque_to_weight", Unit: "Nm/t", Decimals: 1,
+		SQL: "ROUND(t.torque_nm * 1000.0 / NULLIF(t.curb_weight_kg, 0), 1)",
+		value: func(t *models.Trim) *float64 {
+			return ratio(intValue(t.TorqueNM), intValue(t.CurbWeightKG), 1000)
+		},
+	}
+	SpecificOutput = Metric{
+		Name: "specific_output", Unit: "hp/L", Decimals: 1,
+		SQL: "ROUND(t.power_hp * 1000.0 / NULLIF(t.displacement_cc, 0), 1)",
+		value: func(t *models.Trim) *float64 {
+			return ratio(intValue(t.PowerHP), intValue(t.DisplacementCC), 1000)
+		},
+	}
+	PowerKWDeviation = Metric{
+		Name: "power_kw_deviation", Unit: "%", Decimals: 1,
+		SQL: "ROUND(ABS(t.power_kw - t.power_hp * 0.7355) * 100.0 / NULLIF(t.power_hp * 0.7355, 0), 1)",
+		value: func(t *models.Trim) *float64 {
+			if t.PowerKW == nil || t.PowerHP == nil {
+				return nil
+			}
+			expected := float64(*t.PowerHP) * hpToKW
+			diff := math.Abs(float64(*t.PowerKW) - expected)
+			return ratio(&diff, &expected, 100)
+		},
+	}
+	RangeKM = Metric{
+		Name: "range_km", Unit: "km", Decimals: 0,
+		SQL: "ROUND(t.fuel_tank_capacity_l * 100.0 / NULLIF(t.fuel_consumption_combined, 0), 0)",
+		value: func(t *models.Trim) *float64 {
+			return ratio(intValue(t.FuelTankCapacityL), t.FuelConsumptionComb, 100)
+		},
+	}
+	CargoPerFootprint = Metric{
+		Name: "cargo_per_footprint", Unit: "L/m²", Decimals: 1,
+		SQL: "ROUND(t.luggage_capacity_l * 1000000.0 / NULLIF(t.length_mm * t.width_mm, 0), 1)",
+		value: func(t *models.Trim) *float64 {
+			if t.LengthMM == nil || t.WidthMM == nil {
+				return nil
+			}
+			footprint := float64(*t.LengthMM) * float64(*t.WidthMM)
+			return ratio(intValue(t.LuggageCapacityL), &footprint, 1000000)
+		},
+	}
+)
+
+// All lists the derived metrics in payload order
+var All = []Metric{PowerToWeight, TorqueToWeight, SpecificOutput, PowerKWDeviation, RangeKM, CargoPerFootprint}
+
+// Derive computes the derived metrics of a trim
+func Derive(t *models.Trim) *models.DerivedMetrics {
+	d := &models.DerivedMetrics{
+		PowerToWeight:     PowerToWeight.Value(t),
+		TorqueToWeight:    TorqueToWeight.Value(t),
+		SpecificOutput:    SpecificOutput.Value(t),
+		PowerKWDeviation:  PowerKWDeviation.Value(t),
+		RangeKM:           RangeKM.Value(t),
+		CargoPerFootprint: CargoPerFootprint.Value(t),
+	}
+	if d.PowerKWDeviation != nil {
+		consistent := *d.PowerKWDeviation <= PowerKWTolerance
+		d.PowerKWConsistent = &consistent
+	}
+	return d
+}
+
+// Apply sets Derived on every trim
+func Apply(trims ...*models.Trim) {
+	for _, t := range trims {
+		if t != nil {
+			t.Derived = Derive(t)
+		}
+	}
+}
+
+// ratio returns num / den * scale, nil when either is missing or den is zero
+func ratio(num, den *float64, scale float64) *float64 {
+	if num == nil || den == nil || *den == 0 {
+		return nil
+	}
+	v := *num * scale / *den
+	return &v
+}
+
+func intValue(v *int) *float64 {
+	if v == nil {
+		return nil
+	}
+	f := float64(*v)
+	return &f
+}
+
+// round matches SQLite's ROUND (half away from zero)
+func round(v float64, decimals int) float64 {
+	p := math.Pow(10, float64(decimals))
+	return math.Round(v*p) / p
+}
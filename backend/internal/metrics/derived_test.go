@@ -0,0 +1,120 @@
+package metrics
+
+import (
+	"database/sql"
+	"path/filepath"
+	"testing"
+
+	"github.com/emirh/car-specs/backend/internal/models"
+
+	_ "modernc.org/sqlite"
+)
+
+func intPtr(v int) *int           { return &v }
+func floatPtr(v float64) *float64 { return &v }
+
+var testTrims = []*models.Trim{
+	{
+		ID: 1, PowerHP: intPtr(150), PowerKW: intPtr(110), TorqueNM: intPtr(250),
+		CurbWeightKG: intPtr(1345), DisplacementCC: intPtr(1498),
+		FuelTankCapacityL: intPtr(50), FuelConsumptionComb: floatPtr(5.3),
+		LuggageCapacityL: intPtr(380), LengthMM: intPtr(4343), WidthMM: intPtr(1816),
+	},
+	// power_kw given in mechanical horsepower, still consistent
+	{ID: 2, PowerHP: intPtr(300), PowerKW: intPtr(224)},
+	// power_kw copied from power_hp by mistake
+	{ID: 3, PowerHP: intPtr(150), PowerKW: intPtr(150)},
+	// zero and missing inputs
+	{ID: 4, PowerHP: intPtr(150), CurbWeightKG: intPtr(0), FuelConsumptionComb: floatPtr(0), FuelTankCapacityL: intPtr(50)},
+}
+
+func TestDerive(t *testing.T) {
+	tests := []struct {
+		name   string
+		got    *float64
+		expect *float64
+	}{
+		{"power_to_weight", Derive(testTrims[0]).PowerToWeight, floatPtr(111.5)},
+		{"torque_to_weight", Derive(testTrims[0]).TorqueToWeight, floatPtr(185.9)},
+		{"specific_output", Derive(testTrims[0]).SpecificOutput, floatPtr(100.1)},
+		{"power_kw_deviation", Derive(testTrims[0]).PowerKWDeviation, floatPtr(0.3)},
+		{"range_km", Derive(testTrims[0]).RangeKM, floatPtr(943)},
+		{"cargo_per_footprint", Derive(testTrims[0]).CargoPerFootprint, floatPtr(48.2)},
+		{"power_to_weight zero weight", Derive(testTrims[3]).PowerToWeight, nil},
+		{"range_km zero consumption", Derive(testTrims[3]).RangeKM, nil},
+		{"specific_output missing", Derive(testTrims[3]).SpecificOutput, nil},
+	}
+
+	for _, tc := range tests {
+		if !equal(tc.got, tc.expect) {
+			t.Errorf("%s = %v; want %v", tc.name, show(tc.got), show(tc.expect))
+		}
+	}
+
+	consistent := []bool{true, true, false}
+	for i, expect := range consistent {
+		d := Derive(testTrims[i])
+		if d.PowerKWConsistent == nil || *d.PowerKWConsistent != expect {
+			t.Errorf("trim %d: power_kw_consistent = %v; want %v", testTrims[i].ID, d.PowerKWConsistent, expect)
+		}
+	}
+	if Derive(testTrims[3]).PowerKWConsistent != nil {
+		t.Errorf("trim 4: power_kw_consistent should be nil without power_kw")
+	}
+}
+
+// TestSQLMatchesGo checks that filtering and sorting in SQL sees the same values as the payload
+func TestSQLMatchesGo(t *testing.T) {
+	db, err := sql.Open("sqlite", filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("failed to open db: %v", err)
+	}
+	defer db.Close()
+
+	_, err = db.Exec(`CREATE TABLE trims (
+		id INTEGER PRIMARY KEY, power_hp INTEGER, power_kw INTEGER, torque_nm INTEGER,
+		curb_weight_kg INTEGER, displacement_cc INTEGER, fuel_tank_capacity_l INTEGER,
+		fuel_consumption_combined REAL, luggage_capacity_l INTEGER, length_mm INTEGER, width_mm INTEGER
+	)`)
+	if err != nil {
+		t.Fatalf("failed to create table: %v", err)
+	}
+	for _, tr := range testTrims {
+		_, err := db.Exec(`INSERT INTO trims VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			tr.ID, tr.PowerHP, tr.PowerKW, tr.TorqueNM, tr.CurbWeightKG, tr.DisplacementCC,
+			tr.FuelTankCapacityL, tr.FuelConsumptionComb, tr.LuggageCapacityL, tr.LengthMM, tr.WidthMM)
+		if err != nil {
+			t.Fatalf("failed to insert trim: %v", err)
+		}
+	}
+
+	for _, m := range All {
+		for _, tr := range testTrims {
+			var got sql.NullFloat64
+			if err := db.QueryRow("SELECT "+m.SQL+" FROM trims t WHERE t.id = ?", tr.ID).Scan(&got); err != nil {
+				t.Fatalf("%s: query failed: %v", m.Name, err)
+			}
+			var sqlValue *float64
+			if got.Valid {
+				sqlValue = &got.Float64
+			}
+			if goValue := m.Value(tr); !equal(sqlValue, goValue) {
+				t.Errorf("%s trim %d: SQL = %v; Go = %v", m.Name, tr.ID, show(sqlValue), show(goValue))
+			}
+		}
+	}
+}
+
+func equal(a, b *float64) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+func show(v *float64) interface{} {
+	if v == nil {
+		return nil
+	}
+	return *v
+}
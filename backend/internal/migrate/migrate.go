@@ -0,0 +1,471 @@
+package migrate
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrChecksumMismatch is returned when an applied migration no longer matches its source
+var ErrChecksumMismatch = errors.New("migration checksum mismatch")
+
+// Migration is a single versioned schema change.
+// SQL migrations are loaded from NNNN_name.up.sql / NNNN_name.down.sql files,
+// Go migrations set UpFunc/DownFunc for changes SQLite cannot express idempotently.
+type Migration struct {
+	Version int
+	Name    string
+
+	UpSQL   string
+	DownSQL string
+
+	UpFunc   func(tx *sql.Tx) error
+	DownFunc func(tx *sql.Tx) error
+	// Revision stands in for the source of UpFunc/DownFunc in the checksum.
+	// Go migrations must set it and bump it whenever either function changes.
+	Revision string
+}
+
+// Checksum returns a stable hash of the migration source
+func (m Migration) Checksum() string {
+	h := sha256.New()
+	io.WriteString(h, m.UpSQL)
+	h.Write([]byte{0})
+	io.WriteString(h, m.DownSQL)
+	if m.Revision != "" {
+		h.Write([]byte{0})
+		io.WriteString(h, m.Revision)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func (m Migration) isGo() bool {
+	return m.UpFunc != nil || m.DownFunc != nil
+}
+
+func (m Migration) hasUp() bool {
+	return strings.TrimSpace(m.UpSQL) != "" || m.UpFunc != nil
+}
+
+func (m Migration) hasDown() bool {
+	return strings.TrimSpace(m.DownSQL) != "" || m.DownFunc != nil
+}
+
+// Status describes the state of one migration in a database
+type Status struct {
+	Version   int        `json:"version"`
+	Name      string     `json:"name"`
+	Applied   bool       `json:"applied"`
+	AppliedAt *time.Time `json:"applied_at,omitempty"`
+	Modified  bool       `json:"modified"` // Applied checksum differs from the source
+	Missing   bool       `json:"missing"`  // Applied in the database but no longer in the source tree
+}
+
+type appliedRow struct {
+	Name      string
+	Checksum  string
+	AppliedAt time.Time
+}
+
+var fileNamePattern = regexp.MustCompile(`^(\d+)_([a-z0-9_]+)\.(up|down)\.sql$`)
+
+// Load reads SQL migrations from fsys. Files that do not follow the
+// NNNN_name.up.sql / NNNN_name.down.sql convention are ignored.
+func Load(fsys fs.FS) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read migrations: %w", err)
+	}
+
+	byVersion := make(map[int]*Migration)
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		matches := fileNamePattern.FindStringSubmatch(entry.Name())
+		if matches == nil {
+			continue
+		}
+
+		version, _ := strconv.Atoi(matches[1])
+		name := matches[2]
+
+		content, err := fs.ReadFile(fsys, entry.Name())
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", entry.Name(), err)
+		}
+
+		m, ok := byVersion[version]
+		if !ok {
+			m = &Migration{Version: version, Name: name}
+			byVersion[version] = m
+		} else if m.Name != name {
+			return nil, fmt.Errorf("duplicate migration version %d (%s, %s)", version, m.Name, name)
+		}
+
+		if matches[3] == "up" {
+			m.UpSQL = string(content)
+		} else {
+			m.DownSQL = string(content)
+		}
+	}
+
+	var migrations []Migration
+	for _, m := range byVersion {
+		if !m.hasUp() {
+			return nil, fmt.Errorf("migration %04d_%s has no up file", m.Version, m.Name)
+		}
+		migrations = append(migrations, *m)
+	}
+	sortMigrations(migrations)
+
+	return migrations, nil
+}
+
+func sortMigrations(migrations []Migration) {
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+}
+
+// Migrator applies migrations to a database and records them in schema_migrations
+type Migrator struct {
+	db         *sql.DB
+	migrations []Migration
+
+	// DryRun runs the planned migrations inside one transaction that is always rolled back
+	DryRun bool
+	// Log receives progress output; nil discards it
+	Log io.Writer
+}
+
+// New creates a Migrator. Migration versions must be unique.
+func New(db *sql.DB, migrations []Migration) (*Migrator, error) {
+	sorted := append([]Migration(nil), migrations...)
+	sortMigrations(sorted)
+
+	for i := 1; i < len(sorted); i++ {
+		if sorted[i].Version == sorted[i-1].Version {
+			return nil, fmt.Errorf("duplicate migration version %d (%s, %s)", sorted[i].Version, sorted[i-1].Name, sorted[i].Name)
+		}
+	}
+	for _, mig := range sorted {
+		if mig.isGo() && mig.Revision == "" {
+			return nil, fmt.Errorf("go migration %04d_%s has no revision", mig.Version, mig.Name)
+		}
+	}
+
+	return &Migrator{db: db, migrations: sorted}, nil
+}
+
+// Latest returns the highest known migration version
+func (m *Migrator) Latest() int {
+	if len(m.migrations) == 0 {
+		return 0
+	}
+	return m.migrations[len(m.migrations)-1].Version
+}
+
+func (m *Migrator) logf(format string, args ...any) {
+	if m.Log != nil {
+		fmt.Fprintf(m.Log, format+"\n", args...)
+	}
+}
+
+func (m *Migrator) ensureTable() error {
+	_, err := m.db.Exec(`
+		CREATE TABLE IF NOT EXISTS schema_migrations (
+			version INTEGER PRIMARY KEY,
+			name TEXT NOT NULL,
+			checksum TEXT NOT NULL,
+			applied_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		)
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to create schema_migrations: %w", err)
+	}
+	return nil
+}
+
+func (m *Migrator) applied() (map[int]appliedRow, error) {
+	if err := m.ensureTable(); err != nil {
+		return nil, err
+	}
+
+	rows, err := m.db.Query(`SELECT version, name, checksum, applied_at FROM schema_migrations`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read schema_migrations: %w", err)
+	}
+	defer rows.Close()
+
+	applied := make(map[int]appliedRow)
+	for rows.Next() {
+		var version int
+		var row appliedRow
+		if err := rows.Scan(&version, &row.Name, &row.Checksum, &row.AppliedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan schema_migrations: %w", err)
+		}
+		applied[version] = row
+	}
+
+	return applied, rows.Err()
+}
+
+// CurrentVersion returns the highest applied migration version (0 for an empty database)
+func (m *Migrator) CurrentVersion() (int, error) {
+	applied, err := m.applied()
+	if err != nil {
+		return 0, err
+	}
+
+	current := 0
+	for version := range applied {
+		if version > current {
+			current = version
+		}
+	}
+	return current, nil
+}
+
+// Status reports every known and applied migration in version order
+func (m *Migrator) Status() ([]Status, error) {
+	applied, err := m.applied()
+	if err != nil {
+		return nil, err
+	}
+
+	var statuses []Status
+	seen := make(map[int]bool)
+	for _, mig := range m.migrations {
+		st := Status{Version: mig.Version, Name: mig.Name}
+		if row, ok := applied[mig.Version]; ok {
+			appliedAt := row.AppliedAt
+			st.Applied = true
+			st.AppliedAt = &appliedAt
+			st.Modified = row.Checksum != mig.Checksum()
+		}
+		statuses = append(statuses, st)
+		seen[mig.Version] = true
+	}
+
+	for version, row := range applied {
+		if seen[version] {
+			continue
+		}
+		appliedAt := row.AppliedAt
+		statuses = append(statuses, Status{
+			Version:   version,
+			Name:      row.Name,
+			Applied:   true,
+			AppliedAt: &appliedAt,
+			Missing:   true,
+		})
+	}
+
+	sort.Slice(statuses, func(i, j int) bool {
+		return statuses[i].Version < statuses[j].Version
+	})
+	return statuses, nil
+}
+
+// Verify fails if an applied migration was edited after it ran
+func (m *Migrator) Verify() error {
+	statuses, err := m.Status()
+	if err != nil {
+		return err
+	}
+	for _, st := range statuses {
+		if st.Modified {
+			return fmt.Errorf("%w: %04d_%s was edited after it was applied", ErrChecksumMismatch, st.Version, st.Name)
+		}
+	}
+	return nil
+}
+
+// Up applies all pending migrations
+func (m *Migrator) Up() error {
+	return m.To(m.Latest())
+}
+
+// Down rolls back the most recently applied migration
+func (m *Migrator) Down() error {
+	current, err := m.CurrentVersion()
+	if err != nil {
+		return err
+	}
+	if current == 0 {
+		m.logf("Nothing to roll back")
+		return nil
+	}
+
+	target := 0
+	for _, mig := range m.migrations {
+		if mig.Version < current && mig.Version > target {
+			target = mig.Version
+		}
+	}
+	return m.To(target)
+}
+
+// To migrates up or down until exactly the migrations up to target are applied
+func (m *Migrator) To(target int) error {
+	if target < 0 {
+		return fmt.Errorf("invalid target version %d", target)
+	}
+	if target > 0 && !m.known(target) {
+		return fmt.Errorf("unknown migration version %d", target)
+	}
+
+	if err := m.Verify(); err != nil {
+		return err
+	}
+
+	applied, err := m.applied()
+	if err != nil {
+		return err
+	}
+
+	var plan []step
+
+	// Roll back newest first
+	for i := len(m.migrations) - 1; i >= 0; i-- {
+		mig := m.migrations[i]
+		if mig.Version <= target {
+			break
+		}
+		if _, ok := applied[mig.Version]; ok {
+			plan = append(plan, step{migration: mig, up: false})
+		}
+	}
+
+	for _, mig := range m.migrations {
+		if mig.Version > target {
+			break
+		}
+		if _, ok := applied[mig.Version]; !ok {
+			plan = append(plan, step{migration: mig, up: true})
+		}
+	}
+
+	if len(plan) == 0 {
+		m.logf("Already at version %d", target)
+		return nil
+	}
+
+	if m.DryRun {
+		return m.dryRun(plan)
+	}
+
+	for _, s := range plan {
+		if err := m.run(s); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type step struct {
+	migration Migration
+	up        bool
+}
+
+func (s step) direction() string {
+	if s.up {
+		return "up"
+	}
+	return "down"
+}
+
+// dryRun executes the whole plan in a single transaction so later steps see
+// the effect of earlier ones, then rolls everything back
+func (m *Migrator) dryRun(plan []step) error {
+	tx, err := m.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	for _, s := range plan {
+		m.logf("[dry-run] %s %04d_%s", s.direction(), s.migration.Version, s.migration.Name)
+		if err := apply(tx, s); err != nil {
+			return err
+		}
+	}
+
+	m.logf("[dry-run] rolled back %d migration step(s)", len(plan))
+	return nil
+}
+
+func (m *Migrator) known(version int) bool {
+	for _, mig := range m.migrations {
+		if mig.Version == version {
+			return true
+		}
+	}
+	return false
+}
+
+func (m *Migrator) run(s step) error {
+	m.logf("%s %04d_%s", s.direction(), s.migration.Version, s.migration.Name)
+
+	tx, err := m.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := apply(tx, s); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit migration %04d_%s: %w", s.migration.Version, s.migration.Name, err)
+	}
+	return nil
+}
+
+// apply runs one migration step and records it in schema_migrations
+func apply(tx *sql.Tx, s step) error {
+	mig := s.migration
+	direction := s.direction()
+
+	stmt, fn := mig.UpSQL, mig.UpFunc
+	if !s.up {
+		if !mig.hasDown() {
+			return fmt.Errorf("migration %04d_%s cannot be rolled back (no down step)", mig.Version, mig.Name)
+		}
+		stmt, fn = mig.DownSQL, mig.DownFunc
+	}
+
+	if strings.TrimSpace(stmt) != "" {
+		if _, err := tx.Exec(stmt); err != nil {
+			return fmt.Errorf("migration %04d_%s %s failed: %w", mig.Version, mig.Name, direction, err)
+		}
+	}
+	if fn != nil {
+		if err := fn(tx); err != nil {
+			return fmt.Errorf("migration %04d_%s %s failed: %w", mig.Version, mig.Name, direction, err)
+		}
+	}
+
+	var err error
+	if s.up {
+		_, err = tx.Exec(`INSERT INTO schema_migrations (version, name, checksum) VALUES (?, ?, ?)`,
+			mig.Version, mig.Name, mig.Checksum())
+	} else {
+		_, err = tx.Exec(`DELETE FROM schema_migrations WHERE version = ?`, mig.Version)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to record migration %04d_%s: %w", mig.Version, mig.Name, err)
+	}
+	return nil
+}
@@ -0,0 +1,227 @@
+package migrate
+
+import (
+	"database/sql"
+	"errors"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+
+	_ "modernc.org/sqlite"
+)
+
+func openTestDB(t *testing.T) *sql.DB {
+	t.Helper()
+	db, err := sql.Open("sqlite", filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("failed to open db: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func testFS() fstest.MapFS {
+	return fstest.MapFS{
+		"0001_brands.up.sql":   {Data: []byte("CREATE TABLE brands (id INTEGER PRIMARY KEY, name TEXT);")},
+		"0001_brands.down.sql": {Data: []byte("DROP TABLE brands;")},
+		"0002_models.up.sql":   {Data: []byte("CREATE TABLE models (id INTEGER PRIMARY KEY, brand_id INTEGER);")},
+		"0002_models.down.sql": {Data: []byte("DROP TABLE models;")},
+		"README.md":            {Data: []byte("ignored")},
+	}
+}
+
+func tableExists(t *testing.T, db *sql.DB, name string) bool {
+	t.Helper()
+	var count int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = ?`, name).Scan(&count); err != nil {
+		t.Fatalf("failed to query sqlite_master: %v", err)
+	}
+	return count > 0
+}
+
+func newTestMigrator(t *testing.T, db *sql.DB, fsys fstest.MapFS) *Migrator {
+	t.Helper()
+	migrations, err := Load(fsys)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	m, err := New(db, migrations)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	return m
+}
+
+func TestLoad(t *testing.T) {
+	migrations, err := Load(testFS())
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if len(migrations) != 2 {
+		t.Fatalf("Load() returned %d migrations, want 2", len(migrations))
+	}
+	if migrations[0].Version != 1 || migrations[0].Name != "brands" {
+		t.Errorf("first migration = %d_%s, want 1_brands", migrations[0].Version, migrations[0].Name)
+	}
+	if migrations[1].DownSQL == "" {
+		t.Errorf("second migration has no down SQL")
+	}
+}
+
+func TestLoadRejectsDuplicateVersions(t *testing.T) {
+	fsys := testFS()
+	fsys["0002_other.up.sql"] = &fstest.MapFile{Data: []byte("SELECT 1;")}
+
+	if _, err := Load(fsys); err == nil {
+		t.Errorf("Load() expected error for duplicate version")
+	}
+}
+
+func TestUpDownTo(t *testing.T) {
+	db := openTestDB(t)
+	m := newTestMigrator(t, db, testFS())
+
+	if err := m.Up(); err != nil {
+		t.Fatalf("Up() error = %v", err)
+	}
+	if v, _ := m.CurrentVersion(); v != 2 {
+		t.Errorf("CurrentVersion() after Up = %d, want 2", v)
+	}
+	if !tableExists(t, db, "models") {
+		t.Errorf("models table missing after Up")
+	}
+
+	if err := m.Down(); err != nil {
+		t.Fatalf("Down() error = %v", err)
+	}
+	if v, _ := m.CurrentVersion(); v != 1 {
+		t.Errorf("CurrentVersion() after Down = %d, want 1", v)
+	}
+	if tableExists(t, db, "models") {
+		t.Errorf("models table still exists after Down")
+	}
+
+	if err := m.To(0); err != nil {
+		t.Fatalf("To(0) error = %v", err)
+	}
+	if tableExists(t, db, "brands") {
+		t.Errorf("brands table still exists after To(0)")
+	}
+
+	if err := m.To(7); err == nil {
+		t.Errorf("To(7) expected error for unknown version")
+	}
+}
+
+func TestDryRun(t *testing.T) {
+	db := openTestDB(t)
+	m := newTestMigrator(t, db, testFS())
+	m.DryRun = true
+
+	if err := m.Up(); err != nil {
+		t.Fatalf("Up() error = %v", err)
+	}
+	if tableExists(t, db, "brands") {
+		t.Errorf("dry run created brands table")
+	}
+	if v, _ := m.CurrentVersion(); v != 0 {
+		t.Errorf("CurrentVersion() after dry run = %d, want 0", v)
+	}
+}
+
+func TestChecksumMismatch(t *testing.T) {
+	db := openTestDB(t)
+	if err := newTestMigrator(t, db, testFS()).Up(); err != nil {
+		t.Fatalf("Up() error = %v", err)
+	}
+
+	edited := testFS()
+	edited["0001_brands.up.sql"] = &fstest.MapFile{Data: []byte("CREATE TABLE brands (id INTEGER PRIMARY KEY, name TEXT, country TEXT);")}
+	m := newTestMigrator(t, db, edited)
+
+	statuses, err := m.Status()
+	if err != nil {
+		t.Fatalf("Status() error = %v", err)
+	}
+	if !statuses[0].Modified {
+		t.Errorf("Status() did not flag edited migration")
+	}
+	if err := m.Up(); !errors.Is(err, ErrChecksumMismatch) {
+		t.Errorf("Up() error = %v, want ErrChecksumMismatch", err)
+	}
+}
+
+func TestGoMigration(t *testing.T) {
+	db := openTestDB(t)
+	fsys := testFS()
+	migrations, err := Load(fsys)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	migrations = append(migrations, Migration{
+		Version:  3,
+		Name:     "seed",
+		Revision: "1",
+		UpFunc: func(tx *sql.Tx) error {
+			_, err := tx.Exec(`INSERT INTO brands (name) VALUES ('Audi')`)
+			return err
+		},
+	})
+	m, err := New(db, migrations)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	if err := m.Up(); err != nil {
+		t.Fatalf("Up() error = %v", err)
+	}
+	if err := m.Down(); err == nil {
+		t.Errorf("Down() expected error for migration without down step")
+	}
+	if v, _ := m.CurrentVersion(); v != 3 {
+		t.Errorf("CurrentVersion() = %d, want 3", v)
+	}
+}
+
+func seedMigration(revision string) Migration {
+	return Migration{
+		Version:  3,
+		Name:     "seed",
+		Revision: revision,
+		UpFunc: func(tx *sql.Tx) error {
+			_, err := tx.Exec(`INSERT INTO brands (name) VALUES ('Audi')`)
+			return err
+		},
+	}
+}
+
+func newGoTestMigrator(t *testing.T, db *sql.DB, mig Migration) *Migrator {
+	t.Helper()
+	migrations, err := Load(testFS())
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	m, err := New(db, append(migrations, mig))
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	return m
+}
+
+func TestGoMigrationRequiresRevision(t *testing.T) {
+	if _, err := New(openTestDB(t), []Migration{seedMigration("")}); err == nil {
+		t.Errorf("New() accepted a Go migration without a revision")
+	}
+}
+
+func TestGoMigrationRevisionChange(t *testing.T) {
+	db := openTestDB(t)
+	if err := newGoTestMigrator(t, db, seedMigration("1")).Up(); err != nil {
+		t.Fatalf("Up() error = %v", err)
+	}
+
+	if err := newGoTestMigrator(t, db, seedMigration("2")).Verify(); !errors.Is(err, ErrChecksumMismatch) {
+		t.Errorf("Verify() after revision bump = %v, want ErrChecksumMismatch", err)
+	}
+}
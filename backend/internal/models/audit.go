@@ -0,0 +1,57 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Audited entity types
+const (
+	EntityBrand      = "brand"
+	EntityModel      = "model"
+	EntityGeneration = "generation"
+	EntityTrim       = "trim"
+)
+
+// Audit actions
+const (
+	AuditCreate  = "create"
+	AuditUpdate  = "update"
+	AuditDelete  = "delete"
+	AuditRestore = "restore" // An earlier version was written back
+)
+
+// ActorSourceAPI is the Actor.Source of writes made through the HTTP API
+const ActorSourceAPI = "api"
+
+// Actor is who made a catalogue write and through what, for the audit log
+type Actor struct {
+	// Name is the editor (the X-Actor header of API calls) or the command
+	Name string `json:"name"`
+	// Source is the channel: "api", "importer:<name>", "scraper:<adapter>", ...
+	Source string `json:"source"`
+}
+
+// AuditEntry is one recorded write of a brand, model, generation or trim
+type AuditEntry struct {
+	ID         int64  `db:"id" json:"id"`
+	EntityType string `db:"entity_type" json:"entity_type"`
+	EntityID   int64  `db:"entity_id" json:"entity_id"`
+	Action     string `db:"action" json:"action"`
+	Actor      string `db:"actor" json:"actor"`
+	Source     string `db:"source" json:"source"`
+	// Before and After are the row as JSON (null for the side of a create or
+	// delete that has no row)
+	Before json.RawMessage `db:"before_data" json:"before"`
+	After  json.RawMessage `db:"after_data" json:"after"`
+	// Changes lists the fields that differ between Before and After
+	Changes   []FieldChange `db:"-" json:"changes"`
+	CreatedAt time.Time     `db:"created_at" json:"created_at"`
+}
+
+// FieldChange is one field of an audited write
+type FieldChange struct {
+	Field  string          `json:"field"`
+	Before json.RawMessage `json:"before"`
+	After  json.RawMessage `json:"after"`
+}
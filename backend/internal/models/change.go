@@ -0,0 +1,42 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Trim change statuses
+const (
+	ChangePending    = "pending"
+	ChangeApplied    = "applied"    // The trim holds the new value
+	ChangeRejected   = "rejected"   // An editor kept the old value
+	ChangeSuperseded = "superseded" // A later change to the field from the same source replaced it
+)
+
+// TrimChange is a difference a re-scraped page showed against a stored trim field
+type TrimChange struct {
+	ID       int64  `db:"id" json:"id"`
+	TrimID   int64  `db:"trim_id" json:"trim_id"`
+	TrimName string `db:"-" json:"trim_name"`
+	Field    string `db:"field" json:"field"`
+	// OldValue is what the trim held when the change was detected (null when unset)
+	OldValue   json.RawMessage `db:"old_value" json:"old_value"`
+	NewValue   json.RawMessage `db:"new_value" json:"new_value"`
+	Source     SourceDocument  `db:"-" json:"source"`
+	Status     string          `db:"status" json:"status"`
+	Note       *string         `db:"note" json:"note,omitempty"`
+	DetectedAt time.Time       `db:"detected_at" json:"detected_at"`
+	ResolvedAt *time.Time      `db:"resolved_at" json:"resolved_at,omitempty"`
+}
+
+// PageFingerprint is the hash of what was last extracted from a scraped page
+type PageFingerprint struct {
+	URL         string    `db:"url" json:"url"`
+	TrimID      int64     `db:"trim_id" json:"trim_id"`
+	SourceType  string    `db:"source_type" json:"source_type"`
+	Fingerprint string    `db:"fingerprint" json:"fingerprint"`
+	FirstSeenAt time.Time `db:"first_seen_at" json:"first_seen_at"`
+	CheckedAt   time.Time `db:"checked_at" json:"checked_at"`
+	// ChangedAt is when the fingerprint last differed from the one before
+	ChangedAt time.Time `db:"changed_at" json:"changed_at"`
+}
@@ -0,0 +1,46 @@
+package models
+
+// Comparison lines up several trims field by field
+type Comparison struct {
+	BaselineID int64               `json:"baseline_id"`
+	Trims      []ComparisonTrim    `json:"trims"`
+	Sections   []ComparisonSection `json:"sections"`
+}
+
+// ComparisonTrim identifies one compared trim (column headers)
+type ComparisonTrim struct {
+	ID         int64   `json:"id"`
+	Name       string  `json:"name"`
+	Brand      string  `json:"brand"`
+	Model      string  `json:"model"`
+	Generation string  `json:"generation,omitempty"`
+	Year       int     `json:"year"`
+	ImageURL   *string `json:"image_url,omitempty"`
+}
+
+// ComparisonSection groups fields the same way the Trim struct does
+// (engine, performance, transmission, dimensions, wheels)
+type ComparisonSection struct {
+	Key    string            `json:"key"`
+	Fields []ComparisonField `json:"fields"`
+}
+
+// ComparisonField is one row of the comparison
+type ComparisonField struct {
+	Key  string `json:"key"` // JSON name of the Trim field
+	Unit string `json:"unit,omitempty"`
+	// Better is "higher" or "lower" for ranked metrics, empty otherwise
+	Better  string            `json:"better,omitempty"`
+	Differs bool              `json:"differs"`
+	Values  []ComparisonValue `json:"values"` // Same order as Comparison.Trims
+}
+
+// ComparisonValue is one trim's value for a field
+type ComparisonValue struct {
+	TrimID int64       `json:"trim_id"`
+	Value  interface{} `json:"value"` // nil when unknown
+	// Delta and DeltaPercent are relative to the baseline trim (numeric fields only)
+	Delta        *float64 `json:"delta,omitempty"`
+	DeltaPercent *float64 `json:"delta_percent,omitempty"`
+	Best         bool     `json:"best"`
+}
@@ -0,0 +1,50 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Merge conflict statuses
+const (
+	ConflictOpen     = "open"
+	ConflictResolved = "resolved"
+)
+
+// Outcomes of merging a trim field
+const (
+	MergeApplied    = "applied"   // The winning value replaced the stored one
+	MergeUnchanged  = "unchanged" // The stored value already is the winning one
+	MergeConflicted = "conflict"  // Sources disagree; the stored value was kept
+)
+
+// MergeConflict is a trim field whose sources disagree beyond tolerance
+type MergeConflict struct {
+	ID     int64  `db:"id" json:"id"`
+	TrimID int64  `db:"trim_id" json:"trim_id"`
+	Field  string `db:"field" json:"field"`
+	// StoredValue is what the trim held when the conflict was last seen
+	StoredValue json.RawMessage `db:"stored_value" json:"stored_value"`
+	Candidates  []Citation      `db:"candidates" json:"candidates"`
+	Status      string          `db:"status" json:"status"`
+	CreatedAt   time.Time       `db:"created_at" json:"created_at"`
+	UpdatedAt   time.Time       `db:"updated_at" json:"updated_at"`
+	ResolvedAt  *time.Time      `db:"resolved_at" json:"resolved_at,omitempty"`
+}
+
+// FieldMerge is the merge outcome of one trim field
+type FieldMerge struct {
+	Field  string          `json:"field"`
+	Status string          `json:"status"`
+	Value  json.RawMessage `json:"value"` // Winning candidate's value
+	Source SourceDocument  `json:"source"`
+	// Previous is the stored value before the merge
+	Previous json.RawMessage `json:"previous"`
+}
+
+// MergeResult lists the outcome for every trim field that has candidates
+type MergeResult struct {
+	TrimID int64        `json:"trim_id"`
+	DryRun bool         `json:"dry_run"`
+	Fields []FieldMerge `json:"fields"`
+}
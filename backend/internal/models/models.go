@@ -95,12 +95,30 @@ type Trim struct {
 	CreatedAt time.Time `db:"created_at" json:"created_at"`
 	UpdatedAt time.Time `db:"updated_at" json:"updated_at"`
 
+	// Computed from the specs above, see internal/metrics
+	Derived *DerivedMetrics `db:"-" json:"derived,omitempty"`
+
+	// Localised, unit-converted strings keyed by the raw field's JSON name
+	// (e.g. "torque_nm": "184 lb-ft"); raw fields always stay metric
+	Display map[string]string `db:"-" json:"display,omitempty"`
+
 	// Relationships
 	GenerationObj *Generation `db:"-" json:"generation_obj,omitempty"` // Changed from Model
 	Model         *Model      `db:"-" json:"model,omitempty"`          // Kept via Generation
 	Specs         []Spec      `db:"-" json:"specs,omitempty"`
 }
 
+// DerivedMetrics are figures computed from a trim's specs (nil when an input is missing)
+type DerivedMetrics struct {
+	PowerToWeight     *float64 `json:"power_to_weight,omitempty"`     // hp per tonne of curb weight
+	TorqueToWeight    *float64 `json:"torque_to_weight,omitempty"`    // Nm per tonne of curb weight
+	SpecificOutput    *float64 `json:"specific_output,omitempty"`     // hp per litre of displacement
+	PowerKWDeviation  *float64 `json:"power_kw_deviation,omitempty"`  // % difference between power_kw and power_hp converted to kW
+	PowerKWConsistent *bool    `json:"power_kw_consistent,omitempty"` // power_kw_deviation within tolerance
+	RangeKM           *float64 `json:"range_km,omitempty"`            // Fuel tank / combined consumption
+	CargoPerFootprint *float64 `json:"cargo_per_footprint,omitempty"` // Luggage litres per m² of length x width
+}
+
 // Spec represents a Key-Value specification
 type Spec struct {
 	ID       int64  `db:"id" json:"id"`
@@ -137,3 +155,28 @@ type GenerationMeta struct {
 	IsFacelift *bool   `json:"is_facelift,omitempty"`
 	Market     *string `json:"market,omitempty"`
 }
+
+// FacetCount is one option of a search facet and how many trims it would return
+type FacetCount struct {
+	Value string `json:"value"`
+	Count int    `json:"count"`
+}
+
+// NumericRange is the min/max of a numeric field across the matching trims (nil when no data)
+type NumericRange struct {
+	Min *float64 `json:"min"`
+	Max *float64 `json:"max"`
+}
+
+// SearchFacets are the filter options for the current search
+type SearchFacets struct {
+	Brands        []FacetCount `json:"brands"`
+	FuelTypes     []FacetCount `json:"fuel_types"`
+	Transmissions []FacetCount `json:"transmissions"`
+	Drivetrains   []FacetCount `json:"drivetrains"`
+	BodyStyles    []FacetCount `json:"body_styles"`
+	Year          NumericRange `json:"year"`
+	PowerHP       NumericRange `json:"power_hp"`
+	TorqueNM      NumericRange `json:"torque_nm"`
+	Price         NumericRange `json:"price"`
+}
@@ -0,0 +1,61 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Source types stored in SourceDocument.SourceType
+const (
+	SourceUltimateSpecs = "ultimatespecs"
+	SourceCarsData      = "cars_data"
+	SourceCarQuery      = "carquery"
+	SourceAPINinjas     = "api_ninjas"
+	SourceCSV           = "csv"
+	SourceImageSearch   = "image_search" // Image URLs found through SerpApi / Google image search
+	SourceManual        = "manual"       // API edits and data/manual_overrides.json
+	SourceDefault       = "default"      // Placeholder values written without any source
+)
+
+// SourceDocument is one page, API query or file that spec values came from
+type SourceDocument struct {
+	ID          int64      `db:"id" json:"id"`
+	URL         string     `db:"url" json:"url"`
+	Title       *string    `db:"title" json:"title,omitempty"`
+	SourceType  string     `db:"source_type" json:"source_type"`
+	MarketScope *string    `db:"market_scope" json:"market_scope,omitempty"`
+	RetrievedAt *time.Time `db:"retrieved_at" json:"retrieved_at,omitempty"`
+}
+
+// Citation links a value to the document it came from
+type Citation struct {
+	Source SourceDocument `json:"source"`
+	// Value as reported by the source (trim fields only; null when the source says unknown)
+	Value json.RawMessage `json:"value,omitempty"`
+	// Current is true when Value equals what the trim stores now
+	Current    bool      `json:"current"`
+	Page       *string   `json:"page,omitempty"`
+	Note       *string   `json:"note,omitempty"`
+	RecordedAt time.Time `json:"recorded_at"`
+}
+
+// SpecCitations lists the sources of one key-value spec
+type SpecCitations struct {
+	Spec
+	Sources []Citation `json:"sources"`
+}
+
+// TrimCitations is the provenance of every cited value of a trim
+type TrimCitations struct {
+	TrimID int64 `json:"trim_id"`
+	// Keyed by the JSON name of the Trim field (e.g. "acceleration_0_100")
+	Fields map[string][]Citation `json:"fields"`
+	Specs  []SpecCitations       `json:"specs"`
+}
+
+// TrimSource is a document cited for some of a trim's fields
+type TrimSource struct {
+	SourceDocument
+	// JSON names of the cited Trim fields
+	Fields []string `json:"fields"`
+}
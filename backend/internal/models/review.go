@@ -0,0 +1,35 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Review issue statuses
+const (
+	IssueOpen     = "open"
+	IssueAccepted = "accepted" // The value is unusual but correct
+	IssueFixed    = "fixed"    // The value was corrected (set automatically when the issue goes away)
+	IssueIgnored  = "ignored"
+)
+
+// ReviewIssue is a data-quality issue of a trim waiting for (or settled by) an editor
+type ReviewIssue struct {
+	ID           int64  `db:"id" json:"id"`
+	TrimID       int64  `db:"trim_id" json:"trim_id"`
+	TrimName     string `db:"-" json:"trim_name"`
+	GenerationID int64  `db:"-" json:"generation_id"`
+	Rule         string `db:"rule" json:"rule"`
+	Field        string `db:"field" json:"field"`
+	// Value is the flagged value as JSON (null when it is missing)
+	Value      json.RawMessage `db:"value" json:"value"`
+	Confidence string          `db:"confidence" json:"confidence"`
+	Message    string          `db:"message" json:"message"`
+	// Source is the source type of the write that raised the issue
+	Source     *string    `db:"source" json:"source,omitempty"`
+	Status     string     `db:"status" json:"status"`
+	Note       *string    `db:"note" json:"note,omitempty"`
+	CreatedAt  time.Time  `db:"created_at" json:"created_at"`
+	UpdatedAt  time.Time  `db:"updated_at" json:"updated_at"`
+	ResolvedAt *time.Time `db:"resolved_at" json:"resolved_at,omitempty"`
+}
@@ -0,0 +1,21 @@
+package models
+
+import "time"
+
+// TrashItem is a deleted brand, model, generation or trim that can still be
+// restored. Rows deleted along with their parent are not listed on their own;
+// Cascaded counts them.
+type TrashItem struct {
+	EntityType string `json:"entity_type"`
+	ID         int64  `json:"id"`
+	// Name is the brand, model or trim name, or the generation code
+	Name string `json:"name"`
+	// ParentID is the brand, model or generation the row belongs to (0 for brands)
+	ParentID  int64     `json:"parent_id,omitempty"`
+	DeletedAt time.Time `json:"deleted_at"`
+	// Cascaded is the number of rows below it deleted at the same time
+	Cascaded int `json:"cascaded"`
+}
+
+// PurgeCounts is the number of rows a purge removed (or would remove), per entity type
+type PurgeCounts map[string]int64
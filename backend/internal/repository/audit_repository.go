@@ -0,0 +1,234 @@
+package repository
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/emirh/car-specs/backend/internal/models"
+)
+
+// ErrAuditEntryNotFound is returned when no audit entry has the requested ID
+var ErrAuditEntryNotFound = errors.New("audit entry not found")
+
+// AuditRepository records and reads the audit log of catalogue writes
+type AuditRepository struct {
+	db Querier
+}
+
+func NewAuditRepository(db Querier) *AuditRepository {
+	return &AuditRepository{db: db}
+}
+
+// AuditFilter narrows List. Zero values match everything.
+type AuditFilter struct {
+	EntityType string
+	EntityID   int64
+	Actor      string
+	Source     string
+	Limit      int
+}
+
+func (f AuditFilter) where() (string, []interface{}) {
+	var conditions []string
+	var args []interface{}
+	if f.EntityType != "" {
+		conditions = append(conditions, "entity_type = ?")
+		args = append(args, f.EntityType)
+	}
+	if f.EntityID != 0 {
+		conditions = append(conditions, "entity_id = ?")
+		args = append(args, f.EntityID)
+	}
+	if f.Actor != "" {
+		conditions = append(conditions, "actor = ?")
+		args = append(args, f.Actor)
+	}
+	if f.Source != "" {
+		conditions = append(conditions, "source = ?")
+		args = append(args, f.Source)
+	}
+	if len(conditions) == 0 {
+		return "", nil
+	}
+	return " WHERE " + strings.Join(conditions, " AND "), args
+}
+
+// auditSkippedFields are left out of snapshots: timestamps change on every
+// write and the rest are joined or computed, not stored in the row
+var auditSkippedFields = []string{
+	"created_at", "updated_at", "derived", "display", "brand", "model", "generation_obj", "specs", "trims",
+}
+
+// Record stores a write of an entity made by actor. before and after are the
+// entity (e.g. a *models.Trim) before and after the write; either may be nil.
+func (r *AuditRepository) Record(actor models.Actor, entityType string, entityID int64, action string, before, after interface{}) error {
+	beforeData, err := snapshot(before)
+	if err != nil {
+		return err
+	}
+	afterData, err := snapshot(after)
+	if err != nil {
+		return err
+	}
+	if actor.Name == "" {
+		actor.Name = "system"
+	}
+	if actor.Source == "" {
+		actor.Source = "unknown"
+	}
+
+	_, err = r.db.Exec(`
+		INSERT INTO audit_log (entity_type, entity_id, action, actor, source, before_data, after_data)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+	`, entityType, entityID, action, actor.Name, actor.Source, beforeData, afterData)
+	if err != nil {
+		return fmt.Errorf("failed to record audit entry: %w", err)
+	}
+	return nil
+}
+
+// snapshot encodes v as a JSON object without auditSkippedFields, or nil for a nil v
+func snapshot(v interface{}) (*string, error) {
+	if v == nil {
+		return nil, nil
+	}
+	if rv := reflect.ValueOf(v); rv.Kind() == reflect.Ptr && rv.IsNil() {
+		return nil, nil
+	}
+
+	encoded, err := json.Marshal(v)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode audit snapshot: %w", err)
+	}
+	fields := make(map[string]json.RawMessage)
+	if err := json.Unmarshal(encoded, &fields); err != nil {
+		return nil, fmt.Errorf("failed to encode audit snapshot: %w", err)
+	}
+	for _, name := range auditSkippedFields {
+		delete(fields, name)
+	}
+	encoded, err = json.Marshal(fields)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode audit snapshot: %w", err)
+	}
+	s := string(encoded)
+	return &s, nil
+}
+
+const auditEntryColumns = `id, entity_type, entity_id, action, actor, source, before_data, after_data, created_at`
+
+// List returns entries matching filter, newest first
+func (r *AuditRepository) List(filter AuditFilter) ([]*models.AuditEntry, error) {
+	where, args := filter.where()
+	query := `SELECT ` + auditEntryColumns + ` FROM audit_log` + where + ` ORDER BY id DESC`
+	if filter.Limit > 0 {
+		query += " LIMIT ?"
+		args = append(args, filter.Limit)
+	}
+
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list audit entries: %w", err)
+	}
+	defer rows.Close()
+
+	entries := []*models.AuditEntry{}
+	for rows.Next() {
+		entry, err := scanAuditEntry(rows)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list audit entries: %w", err)
+	}
+	return entries, nil
+}
+
+// GetByID returns an audit entry, or ErrAuditEntryNotFound
+func (r *AuditRepository) GetByID(id int64) (*models.AuditEntry, error) {
+	rows, err := r.db.Query(`SELECT `+auditEntryColumns+` FROM audit_log WHERE id = ?`, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get audit entry: %w", err)
+	}
+	defer rows.Close()
+
+	if !rows.Next() {
+		if err := rows.Err(); err != nil {
+			return nil, fmt.Errorf("failed to get audit entry: %w", err)
+		}
+		return nil, ErrAuditEntryNotFound
+	}
+	return scanAuditEntry(rows)
+}
+
+func scanAuditEntry(rows *sql.Rows) (*models.AuditEntry, error) {
+	entry := &models.AuditEntry{}
+	var before, after sql.NullString
+
+	err := rows.Scan(
+		&entry.ID, &entry.EntityType, &entry.EntityID, &entry.Action, &entry.Actor, &entry.Source,
+		&before, &after, &entry.CreatedAt,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan audit entry: %w", err)
+	}
+
+	entry.Before = json.RawMessage("null")
+	if before.Valid {
+		entry.Before = json.RawMessage(before.String)
+	}
+	entry.After = json.RawMessage("null")
+	if after.Valid {
+		entry.After = json.RawMessage(after.String)
+	}
+	if entry.Changes, err = diffSnapshots(entry.Before, entry.After); err != nil {
+		return nil, fmt.Errorf("failed to decode audit entry %d: %w", entry.ID, err)
+	}
+	return entry, nil
+}
+
+// diffSnapshots lists the fields whose values differ between two snapshots,
+// by name. A field missing from one side counts as null there.
+func diffSnapshots(before, after json.RawMessage) ([]models.FieldChange, error) {
+	var b, a map[string]json.RawMessage
+	if err := json.Unmarshal(before, &b); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(after, &a); err != nil {
+		return nil, err
+	}
+
+	names := make(map[string]bool)
+	for name := range b {
+		names[name] = true
+	}
+	for name := range a {
+		names[name] = true
+	}
+
+	changes := []models.FieldChange{}
+	for name := range names {
+		old, ok := b[name]
+		if !ok {
+			old = json.RawMessage("null")
+		}
+		updated, ok := a[name]
+		if !ok {
+			updated = json.RawMessage("null")
+		}
+		if bytes.Equal(old, updated) {
+			continue
+		}
+		changes = append(changes, models.FieldChange{Field: name, Before: old, After: updated})
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Field < changes[j].Field })
+	return changes, nil
+}
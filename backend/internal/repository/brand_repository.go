@@ -2,19 +2,28 @@ package repository
 
 import (
 	"database/sql"
+	"errors"
 	"fmt"
 
 	"github.com/emirh/car-specs/backend/internal/models"
 )
 
+// ErrBrandNotFound is returned when no live brand has the requested ID or name
+var ErrBrandNotFound = errors.New("brand not found")
+
 type BrandRepository struct {
-	db *sql.DB
+	db Querier
 }
 
-func NewBrandRepository(db *sql.DB) *BrandRepository {
+func NewBrandRepository(db Querier) *BrandRepository {
 	return &BrandRepository{db: db}
 }
 
+// Transact runs fn in a transaction on the brand repository's database
+func (r *BrandRepository) Transact(fn func(tx Querier) error) error {
+	return Transact(r.db, fn)
+}
+
 // Create inserts a new brand
 func (r *BrandRepository) Create(brand *models.Brand) error {
 	query := `
@@ -40,7 +49,7 @@ func (r *BrandRepository) GetByID(id int64) (*models.Brand, error) {
 	query := `
 		SELECT id, name, country, logo_url, created_at, updated_at
 		FROM brands
-		WHERE id = ?
+		WHERE id = ? AND deleted_at IS NULL
 	`
 	brand := &models.Brand{}
 	err := r.db.QueryRow(query, id).Scan(
@@ -53,7 +62,7 @@ func (r *BrandRepository) GetByID(id int64) (*models.Brand, error) {
 	)
 	if err != nil {
 		if err == sql.ErrNoRows {
-			return nil, fmt.Errorf("brand not found")
+			return nil, ErrBrandNotFound
 		}
 		return nil, fmt.Errorf("failed to get brand: %w", err)
 	}
@@ -66,7 +75,7 @@ func (r *BrandRepository) GetByName(name string) (*models.Brand, error) {
 	query := `
 		SELECT id, name, country, logo_url, created_at, updated_at
 		FROM brands
-		WHERE LOWER(name) = LOWER(?)
+		WHERE LOWER(name) = LOWER(?) AND deleted_at IS NULL
 	`
 	brand := &models.Brand{}
 	err := r.db.QueryRow(query, name).Scan(
@@ -79,7 +88,7 @@ func (r *BrandRepository) GetByName(name string) (*models.Brand, error) {
 	)
 	if err != nil {
 		if err == sql.ErrNoRows {
-			return nil, fmt.Errorf("brand not found")
+			return nil, ErrBrandNotFound
 		}
 		return nil, fmt.Errorf("failed to get brand: %w", err)
 	}
@@ -92,6 +101,7 @@ func (r *BrandRepository) List() ([]*models.Brand, error) {
 	query := `
 		SELECT id, name, country, logo_url, created_at, updated_at
 		FROM brands
+		WHERE deleted_at IS NULL
 		ORDER BY name
 	`
 	rows, err := r.db.Query(query)
@@ -125,7 +135,7 @@ func (r *BrandRepository) Update(brand *models.Brand) error {
 	query := `
 		UPDATE brands
 		SET name = ?, country = ?, logo_url = ?, updated_at = CURRENT_TIMESTAMP
-		WHERE id = ?
+		WHERE id = ? AND deleted_at IS NULL
 	`
 	_, err := r.db.Exec(query, brand.Name, brand.Country, brand.LogoURL, brand.ID)
 	if err != nil {
@@ -135,13 +145,18 @@ func (r *BrandRepository) Update(brand *models.Brand) error {
 	return nil
 }
 
-// Delete deletes a brand
+// Delete moves a brand to the trash with its models, generations and trims
 func (r *BrandRepository) Delete(id int64) error {
-	query := `DELETE FROM brands WHERE id = ?`
-	_, err := r.db.Exec(query, id)
+	return softDelete(r.db, models.EntityBrand, id, ErrBrandNotFound)
+}
+
+// InTrash reports whether a deleted brand has the name (case-insensitive).
+// Brand names stay unique while a brand is in the trash.
+func (r *BrandRepository) InTrash(name string) (bool, error) {
+	var count int
+	err := r.db.QueryRow(`SELECT COUNT(*) FROM brands WHERE LOWER(name) = LOWER(?) AND deleted_at IS NOT NULL`, name).Scan(&count)
 	if err != nil {
-		return fmt.Errorf("failed to delete brand: %w", err)
+		return false, fmt.Errorf("failed to get brand: %w", err)
 	}
-
-	return nil
+	return count > 0, nil
 }
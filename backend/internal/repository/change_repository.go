@@ -0,0 +1,238 @@
+package repository
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/emirh/car-specs/backend/internal/models"
+)
+
+// ErrTrimChangeNotFound is returned when no trim change has the requested ID
+var ErrTrimChangeNotFound = errors.New("trim change not found")
+
+// ChangeRepository stores scraped page fingerprints and the trim changes
+// re-scraped pages showed
+type ChangeRepository struct {
+	db Querier
+}
+
+func NewChangeRepository(db Querier) *ChangeRepository {
+	return &ChangeRepository{db: db}
+}
+
+// ChangeFilter narrows List. Zero values match everything.
+type ChangeFilter struct {
+	TrimID int64
+	Field  string
+	Status string
+}
+
+func (f ChangeFilter) where() (string, []interface{}) {
+	var conditions []string
+	var args []interface{}
+	if f.TrimID != 0 {
+		conditions = append(conditions, "c.trim_id = ?")
+		args = append(args, f.TrimID)
+	}
+	if f.Field != "" {
+		conditions = append(conditions, "c.field = ?")
+		args = append(args, f.Field)
+	}
+	if f.Status != "" {
+		conditions = append(conditions, "c.status = ?")
+		args = append(args, f.Status)
+	}
+	if len(conditions) == 0 {
+		return "", nil
+	}
+	return " WHERE " + strings.Join(conditions, " AND "), args
+}
+
+// GetFingerprint returns the fingerprint stored for a page, or nil if the page
+// was never scraped
+func (r *ChangeRepository) GetFingerprint(url string) (*models.PageFingerprint, error) {
+	fp := &models.PageFingerprint{}
+	err := r.db.QueryRow(`
+		SELECT url, trim_id, source_type, fingerprint, first_seen_at, checked_at, changed_at
+		FROM page_fingerprints
+		WHERE url = ?
+	`, url).Scan(&fp.URL, &fp.TrimID, &fp.SourceType, &fp.Fingerprint, &fp.FirstSeenAt, &fp.CheckedAt, &fp.ChangedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get page fingerprint: %w", err)
+	}
+	return fp, nil
+}
+
+// SaveFingerprint records the fingerprint a page has now. changed_at only
+// moves when it differs from the stored one.
+func (r *ChangeRepository) SaveFingerprint(url string, trimID int64, sourceType, fingerprint string) error {
+	_, err := r.db.Exec(`
+		INSERT INTO page_fingerprints (url, trim_id, source_type, fingerprint)
+		VALUES (?, ?, ?, ?)
+		ON CONFLICT(url) DO UPDATE SET
+			trim_id = excluded.trim_id,
+			source_type = excluded.source_type,
+			changed_at = CASE WHEN fingerprint = excluded.fingerprint THEN changed_at ELSE CURRENT_TIMESTAMP END,
+			fingerprint = excluded.fingerprint,
+			checked_at = CURRENT_TIMESTAMP
+	`, url, trimID, sourceType, fingerprint)
+	if err != nil {
+		return fmt.Errorf("failed to save page fingerprint: %w", err)
+	}
+	return nil
+}
+
+// TouchFingerprint records that a page was checked and found unchanged
+func (r *ChangeRepository) TouchFingerprint(url string) error {
+	_, err := r.db.Exec(`UPDATE page_fingerprints SET checked_at = CURRENT_TIMESTAMP WHERE url = ?`, url)
+	if err != nil {
+		return fmt.Errorf("failed to update page fingerprint: %w", err)
+	}
+	return nil
+}
+
+// Record stores a pending change and sets change.ID. A pending change of the
+// same trim field from the same source is superseded by it.
+func (r *ChangeRepository) Record(change *models.TrimChange) error {
+	_, err := r.db.Exec(`
+		UPDATE trim_changes
+		SET status = ?, resolved_at = CURRENT_TIMESTAMP
+		WHERE trim_id = ? AND field = ? AND source_document_id = ? AND status = ?
+	`, models.ChangeSuperseded, change.TrimID, change.Field, change.Source.ID, models.ChangePending)
+	if err != nil {
+		return fmt.Errorf("failed to supersede trim changes: %w", err)
+	}
+
+	res, err := r.db.Exec(`
+		INSERT INTO trim_changes (trim_id, field, old_value, new_value, source_document_id, status)
+		VALUES (?, ?, ?, ?, ?, ?)
+	`, change.TrimID, change.Field, jsonOrNull(change.OldValue), jsonOrNull(change.NewValue), change.Source.ID, models.ChangePending)
+	if err != nil {
+		return fmt.Errorf("failed to record trim change: %w", err)
+	}
+	change.ID, _ = res.LastInsertId()
+	change.Status = models.ChangePending
+	return nil
+}
+
+const trimChangeColumns = `
+	c.id, c.trim_id, t.name, c.field, c.old_value, c.new_value, c.status, c.note, c.detected_at, c.resolved_at,
+	d.id, d.url, d.title, d.source_type, d.market_scope, d.retrieved_at`
+
+const trimChangeFrom = `
+	FROM trim_changes c
+	JOIN trims t ON t.id = c.trim_id AND t.deleted_at IS NULL
+	JOIN source_documents d ON d.id = c.source_document_id`
+
+// List returns changes matching filter, most recently detected first
+func (r *ChangeRepository) List(filter ChangeFilter) ([]*models.TrimChange, error) {
+	where, args := filter.where()
+	rows, err := r.db.Query(`SELECT`+trimChangeColumns+trimChangeFrom+where+`
+		ORDER BY c.detected_at DESC, c.id DESC`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list trim changes: %w", err)
+	}
+	defer rows.Close()
+
+	changes := []*models.TrimChange{}
+	for rows.Next() {
+		change, err := scanTrimChange(rows)
+		if err != nil {
+			return nil, err
+		}
+		changes = append(changes, change)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list trim changes: %w", err)
+	}
+	return changes, nil
+}
+
+// GetByID returns a trim change, or ErrTrimChangeNotFound
+func (r *ChangeRepository) GetByID(id int64) (*models.TrimChange, error) {
+	rows, err := r.db.Query(`SELECT`+trimChangeColumns+trimChangeFrom+`
+		WHERE c.id = ?`, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get trim change: %w", err)
+	}
+	defer rows.Close()
+
+	if !rows.Next() {
+		if err := rows.Err(); err != nil {
+			return nil, fmt.Errorf("failed to get trim change: %w", err)
+		}
+		return nil, ErrTrimChangeNotFound
+	}
+	return scanTrimChange(rows)
+}
+
+// Resolve sets the status (and note) of one change
+func (r *ChangeRepository) Resolve(id int64, status, note string) error {
+	res, err := r.db.Exec(`
+		UPDATE trim_changes
+		SET status = ?, note = COALESCE(?, note), resolved_at = CURRENT_TIMESTAMP
+		WHERE id = ?
+	`, status, nullIfEmpty(note), id)
+	if err != nil {
+		return fmt.Errorf("failed to resolve trim change: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrTrimChangeNotFound
+	}
+	return nil
+}
+
+func scanTrimChange(rows *sql.Rows) (*models.TrimChange, error) {
+	change := &models.TrimChange{}
+	var oldValue, newValue, note, title, market sql.NullString
+	var resolvedAt, retrievedAt sql.NullTime
+
+	err := rows.Scan(
+		&change.ID, &change.TrimID, &change.TrimName, &change.Field, &oldValue, &newValue,
+		&change.Status, &note, &change.DetectedAt, &resolvedAt,
+		&change.Source.ID, &change.Source.URL, &title, &change.Source.SourceType, &market, &retrievedAt,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan trim change: %w", err)
+	}
+
+	change.OldValue = json.RawMessage("null")
+	if oldValue.Valid {
+		change.OldValue = json.RawMessage(oldValue.String)
+	}
+	change.NewValue = json.RawMessage("null")
+	if newValue.Valid {
+		change.NewValue = json.RawMessage(newValue.String)
+	}
+	if note.Valid {
+		change.Note = &note.String
+	}
+	if resolvedAt.Valid {
+		change.ResolvedAt = &resolvedAt.Time
+	}
+	if title.Valid {
+		change.Source.Title = &title.String
+	}
+	if market.Valid {
+		change.Source.MarketScope = &market.String
+	}
+	if retrievedAt.Valid {
+		change.Source.RetrievedAt = &retrievedAt.Time
+	}
+	return change, nil
+}
+
+// jsonOrNull stores a JSON value as text, with JSON null (or nothing) as SQL NULL
+func jsonOrNull(v json.RawMessage) *string {
+	if len(v) == 0 || string(v) == "null" {
+		return nil
+	}
+	s := string(v)
+	return &s
+}
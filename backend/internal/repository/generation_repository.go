@@ -2,19 +2,28 @@ package repository
 
 import (
 	"database/sql"
+	"errors"
 	"fmt"
 
 	"github.com/emirh/car-specs/backend/internal/models"
 )
 
+// ErrGenerationNotFound is returned when no generation has the requested ID
+var ErrGenerationNotFound = errors.New("generation not found")
+
 type GenerationRepository struct {
-	db *sql.DB
+	db Querier
 }
 
-func NewGenerationRepository(db *sql.DB) *GenerationRepository {
+func NewGenerationRepository(db Querier) *GenerationRepository {
 	return &GenerationRepository{db: db}
 }
 
+// Transact runs fn in a transaction on the generation repository's database
+func (r *GenerationRepository) Transact(fn func(tx Querier) error) error {
+	return Transact(r.db, fn)
+}
+
 // ListByModel retrieves all generations for a specific model
 func (r *GenerationRepository) ListByModel(modelID int64) ([]*models.Generation, error) {
 	query := `
@@ -26,12 +35,15 @@ func (r *GenerationRepository) ListByModel(modelID int64) ([]*models.Generation,
 			g.start_year, 
 			g.end_year,
 			g.image_url,
+			g.description,
+			g.is_current,
+			g.platform,
 			g.created_at,
 			g.updated_at,
 			COUNT(t.id) as trim_count
 		FROM generations g
-		LEFT JOIN trims t ON t.generation_id = g.id
-		WHERE g.model_id = ?
+		LEFT JOIN trims t ON t.generation_id = g.id AND t.deleted_at IS NULL
+		WHERE g.model_id = ? AND g.deleted_at IS NULL
 		GROUP BY g.id
 		ORDER BY g.start_year DESC
 	`
@@ -48,7 +60,8 @@ func (r *GenerationRepository) ListByModel(modelID int64) ([]*models.Generation,
 		var trimCount int
 		var endYear sql.NullInt64
 		var name sql.NullString
-		var imageURL sql.NullString
+		var imageURL, description, platform sql.NullString
+		var isCurrent sql.NullBool
 
 		err := rows.Scan(
 			&g.ID,
@@ -58,6 +71,9 @@ func (r *GenerationRepository) ListByModel(modelID int64) ([]*models.Generation,
 			&g.StartYear,
 			&endYear,
 			&imageURL,
+			&description,
+			&isCurrent,
+			&platform,
 			&g.CreatedAt,
 			&g.UpdatedAt,
 			&trimCount,
@@ -77,6 +93,13 @@ func (r *GenerationRepository) ListByModel(modelID int64) ([]*models.Generation,
 		if imageURL.Valid {
 			g.ImageURL = &imageURL.String
 		}
+		if description.Valid {
+			g.Description = &description.String
+		}
+		if platform.Valid {
+			g.Platform = &platform.String
+		}
+		g.IsCurrent = isCurrent.Bool
 
 		generations = append(generations, g)
 	}
@@ -84,6 +107,7 @@ func (r *GenerationRepository) ListByModel(modelID int64) ([]*models.Generation,
 	return generations, nil
 }
 
+// GetByID retrieves a generation by ID
 func (r *GenerationRepository) GetByID(id int64) (*models.Generation, error) {
 	query := `
 		SELECT 
@@ -93,15 +117,20 @@ func (r *GenerationRepository) GetByID(id int64) (*models.Generation, error) {
 			name, 
 			start_year, 
 			end_year,
+			image_url,
+			description,
+			is_current,
+			platform,
 			created_at,
 			updated_at
 		FROM generations
-		WHERE id = ?
+		WHERE id = ? AND deleted_at IS NULL
 	`
 
 	g := &models.Generation{}
 	var endYear sql.NullInt64
-	var name sql.NullString
+	var name, imageURL, description, platform sql.NullString
+	var isCurrent sql.NullBool
 
 	err := r.db.QueryRow(query, id).Scan(
 		&g.ID,
@@ -110,13 +139,17 @@ func (r *GenerationRepository) GetByID(id int64) (*models.Generation, error) {
 		&name,
 		&g.StartYear,
 		&endYear,
+		&imageURL,
+		&description,
+		&isCurrent,
+		&platform,
 		&g.CreatedAt,
 		&g.UpdatedAt,
 	)
 
 	if err != nil {
 		if err == sql.ErrNoRows {
-			return nil, fmt.Errorf("generation not found")
+			return nil, ErrGenerationNotFound
 		}
 		return nil, fmt.Errorf("failed to get generation: %w", err)
 	}
@@ -129,13 +162,97 @@ func (r *GenerationRepository) GetByID(id int64) (*models.Generation, error) {
 		y := int(endYear.Int64)
 		g.EndYear = &y
 	}
+	if imageURL.Valid {
+		g.ImageURL = &imageURL.String
+	}
+	if description.Valid {
+		g.Description = &description.String
+	}
+	if platform.Valid {
+		g.Platform = &platform.String
+	}
+	g.IsCurrent = isCurrent.Bool
 
 	return g, nil
 }
 
+// Create inserts a new generation
+func (r *GenerationRepository) Create(g *models.Generation) error {
+	query := `
+		INSERT INTO generations (
+			model_id, code, name, start_year, end_year,
+			image_url, description, is_current, platform
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`
+	result, err := r.db.Exec(query,
+		g.ModelID, g.Code, g.Name, g.StartYear, g.EndYear,
+		g.ImageURL, g.Description, g.IsCurrent, g.Platform,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create generation: %w", err)
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("failed to get last insert id: %w", err)
+	}
+
+	g.ID = id
+	return nil
+}
+
+// Update writes every editable column of a generation
+func (r *GenerationRepository) Update(g *models.Generation) error {
+	query := `
+		UPDATE generations
+		SET code = ?, name = ?, start_year = ?, end_year = ?,
+			image_url = ?, description = ?, is_current = ?, platform = ?,
+			updated_at = CURRENT_TIMESTAMP
+		WHERE id = ? AND deleted_at IS NULL
+	`
+	result, err := r.db.Exec(query,
+		g.Code, g.Name, g.StartYear, g.EndYear,
+		g.ImageURL, g.Description, g.IsCurrent, g.Platform,
+		g.ID,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update generation: %w", err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get affected rows: %w", err)
+	}
+	if affected == 0 {
+		return ErrGenerationNotFound
+	}
+
+	return nil
+}
+
+// Delete moves a generation and its trims to the trash. Callers check for
+// trims first when the delete must not cascade.
+func (r *GenerationRepository) Delete(id int64) error {
+	return softDelete(r.db, models.EntityGeneration, id, ErrGenerationNotFound)
+}
+
+// InTrash reports whether a deleted generation of the model has the code
+// (case-insensitive)
+func (r *GenerationRepository) InTrash(modelID int64, code string) (bool, error) {
+	var count int
+	err := r.db.QueryRow(
+		`SELECT COUNT(*) FROM generations WHERE model_id = ? AND LOWER(code) = LOWER(?) AND deleted_at IS NOT NULL`,
+		modelID, code,
+	).Scan(&count)
+	if err != nil {
+		return false, fmt.Errorf("failed to get generation: %w", err)
+	}
+	return count > 0, nil
+}
+
 // GetTrimCount returns the number of trims for a generation
 func (r *GenerationRepository) GetTrimCount(generationID int64) (int, error) {
-	query := `SELECT COUNT(*) FROM trims WHERE generation_id = ?`
+	query := `SELECT COUNT(*) FROM trims WHERE generation_id = ? AND deleted_at IS NULL`
 	var count int
 	err := r.db.QueryRow(query, generationID).Scan(&count)
 	if err != nil {
@@ -0,0 +1,128 @@
+package repository
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/emirh/car-specs/backend/internal/models"
+)
+
+type MergeConflictRepository struct {
+	db Querier
+}
+
+func NewMergeConflictRepository(db Querier) *MergeConflictRepository {
+	return &MergeConflictRepository{db: db}
+}
+
+// ConflictFilter narrows ListConflicts. Zero values match everything.
+type ConflictFilter struct {
+	TrimID int64
+	Status string
+}
+
+// Open records a conflict for a trim field, refreshing the open one if there is one
+func (r *MergeConflictRepository) Open(trimID int64, field string, stored json.RawMessage, candidates []models.Citation) error {
+	encoded, err := json.Marshal(candidates)
+	if err != nil {
+		return fmt.Errorf("failed to encode candidates: %w", err)
+	}
+	var storedValue *string
+	if len(stored) > 0 {
+		s := string(stored)
+		storedValue = &s
+	}
+
+	res, err := r.db.Exec(`
+		UPDATE merge_conflicts
+		SET stored_value = ?, candidates = ?, updated_at = CURRENT_TIMESTAMP
+		WHERE trim_id = ? AND field = ? AND status = ?
+	`, storedValue, string(encoded), trimID, field, models.ConflictOpen)
+	if err != nil {
+		return fmt.Errorf("failed to update merge conflict: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n > 0 {
+		return nil
+	}
+
+	_, err = r.db.Exec(`
+		INSERT INTO merge_conflicts (trim_id, field, stored_value, candidates, status)
+		VALUES (?, ?, ?, ?, ?)
+	`, trimID, field, storedValue, string(encoded), models.ConflictOpen)
+	if err != nil {
+		return fmt.Errorf("failed to create merge conflict: %w", err)
+	}
+	return nil
+}
+
+// Resolve closes the open conflict of a trim field, if any
+func (r *MergeConflictRepository) Resolve(trimID int64, field string) error {
+	_, err := r.db.Exec(`
+		UPDATE merge_conflicts
+		SET status = ?, resolved_at = CURRENT_TIMESTAMP, updated_at = CURRENT_TIMESTAMP
+		WHERE trim_id = ? AND field = ? AND status = ?
+	`, models.ConflictResolved, trimID, field, models.ConflictOpen)
+	if err != nil {
+		return fmt.Errorf("failed to resolve merge conflict: %w", err)
+	}
+	return nil
+}
+
+// List returns conflicts matching filter, most recently seen first
+func (r *MergeConflictRepository) List(filter ConflictFilter) ([]*models.MergeConflict, error) {
+	// Conflicts of trims in the trash are not listed
+	where := []string{"trim_id IN (SELECT id FROM trims WHERE deleted_at IS NULL)"}
+	var args []interface{}
+	if filter.TrimID != 0 {
+		where = append(where, "trim_id = ?")
+		args = append(args, filter.TrimID)
+	}
+	if filter.Status != "" {
+		where = append(where, "status = ?")
+		args = append(args, filter.Status)
+	}
+
+	query := `
+		SELECT id, trim_id, field, stored_value, candidates, status, created_at, updated_at, resolved_at
+		FROM merge_conflicts
+		WHERE ` + strings.Join(where, " AND ")
+	query += " ORDER BY updated_at DESC, id DESC"
+
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list merge conflicts: %w", err)
+	}
+	defer rows.Close()
+
+	conflicts := []*models.MergeConflict{}
+	for rows.Next() {
+		c := &models.MergeConflict{}
+		var stored sql.NullString
+		var candidates string
+		var resolvedAt sql.NullTime
+
+		err := rows.Scan(&c.ID, &c.TrimID, &c.Field, &stored, &candidates, &c.Status, &c.CreatedAt, &c.UpdatedAt, &resolvedAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan merge conflict: %w", err)
+		}
+		if stored.Valid {
+			c.StoredValue = json.RawMessage(stored.String)
+		} else {
+			c.StoredValue = json.RawMessage("null")
+		}
+		if err := json.Unmarshal([]byte(candidates), &c.Candidates); err != nil {
+			return nil, fmt.Errorf("failed to decode candidates of conflict %d: %w", c.ID, err)
+		}
+		if resolvedAt.Valid {
+			c.ResolvedAt = &resolvedAt.Time
+		}
+		conflicts = append(conflicts, c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list merge conflicts: %w", err)
+	}
+
+	return conflicts, nil
+}
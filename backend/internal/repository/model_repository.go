@@ -9,13 +9,18 @@ import (
 )
 
 type ModelRepository struct {
-	db *sql.DB
+	db Querier
 }
 
-func NewModelRepository(db *sql.DB) *ModelRepository {
+func NewModelRepository(db Querier) *ModelRepository {
 	return &ModelRepository{db: db}
 }
 
+// Transact runs fn in a transaction on the model repository's database
+func (r *ModelRepository) Transact(fn func(tx Querier) error) error {
+	return Transact(r.db, fn)
+}
+
 // Create inserts a new model
 func (r *ModelRepository) Create(model *models.Model) error {
 	query := `
@@ -46,13 +51,13 @@ func (r *ModelRepository) GetByID(id int64, includeBrand bool) (*models.Model, e
 				b.id, b.name, b.country, b.logo_url, b.created_at, b.updated_at
 			FROM models m
 			LEFT JOIN brands b ON m.brand_id = b.id
-			WHERE m.id = ?
+			WHERE m.id = ? AND m.deleted_at IS NULL
 		`
 	} else {
 		query = `
 			SELECT id, brand_id, name, body_style, segment, created_at, updated_at
 			FROM models
-			WHERE id = ?
+			WHERE id = ? AND deleted_at IS NULL
 		`
 	}
 
@@ -92,7 +97,7 @@ func (r *ModelRepository) ListByBrand(brandID int64) ([]*models.Model, error) {
 	query := `
 		SELECT id, brand_id, name, body_style, segment, created_at, updated_at
 		FROM models
-		WHERE brand_id = ?
+		WHERE brand_id = ? AND deleted_at IS NULL
 		ORDER BY name
 	`
 	rows, err := r.db.Query(query, brandID)
@@ -122,7 +127,7 @@ func (r *ModelRepository) Update(model *models.Model) error {
 	query := `
 		UPDATE models
 		SET brand_id = ?, name = ?, body_style = ?, segment = ?, updated_at = CURRENT_TIMESTAMP
-		WHERE id = ?
+		WHERE id = ? AND deleted_at IS NULL
 	`
 	_, err := r.db.Exec(query, model.BrandID, model.Name, model.BodyStyle, model.Segment, model.ID)
 	if err != nil {
@@ -132,15 +137,23 @@ func (r *ModelRepository) Update(model *models.Model) error {
 	return nil
 }
 
-// Delete deletes a model
+// Delete moves a model to the trash with its generations and trims
 func (r *ModelRepository) Delete(id int64) error {
-	query := `DELETE FROM models WHERE id = ?`
-	_, err := r.db.Exec(query, id)
+	return softDelete(r.db, models.EntityModel, id, fmt.Errorf("model not found"))
+}
+
+// InTrash reports whether a deleted model of the brand has the name
+// (case-insensitive). Model names stay unique while a model is in the trash.
+func (r *ModelRepository) InTrash(brandID int64, name string) (bool, error) {
+	var count int
+	err := r.db.QueryRow(
+		`SELECT COUNT(*) FROM models WHERE brand_id = ? AND LOWER(name) = LOWER(?) AND deleted_at IS NOT NULL`,
+		brandID, name,
+	).Scan(&count)
 	if err != nil {
-		return fmt.Errorf("failed to delete model: %w", err)
+		return false, fmt.Errorf("failed to get model: %w", err)
 	}
-
-	return nil
+	return count > 0, nil
 }
 
 // ListVehiclesByName retrieves aggregated vehicle list (Generation focused)
@@ -156,9 +169,9 @@ func (r *ModelRepository) ListVehiclesByName(brandName string) ([]*models.Vehicl
 			GROUP_CONCAT(t.name)
 		FROM models m
 		JOIN brands b ON m.brand_id = b.id
-		LEFT JOIN generations g ON g.model_id = m.id
-		LEFT JOIN trims t ON t.generation_id = g.id
-		WHERE b.name = ?
+		LEFT JOIN generations g ON g.model_id = m.id AND g.deleted_at IS NULL
+		LEFT JOIN trims t ON t.generation_id = g.id AND t.deleted_at IS NULL
+		WHERE b.name = ? AND b.deleted_at IS NULL AND m.deleted_at IS NULL
 		GROUP BY m.id, g.id
 		ORDER BY MAX(m.name), g.start_year DESC
 	`
@@ -228,7 +241,7 @@ func (r *ModelRepository) GetGeneration(id int64) (*models.Generation, *models.M
 		FROM models m
 		JOIN generations g ON g.model_id = m.id
 		JOIN brands b ON m.brand_id = b.id
-		WHERE g.id = ?
+		WHERE g.id = ? AND g.deleted_at IS NULL
 	`
 
 	g := &models.Generation{}
@@ -0,0 +1,338 @@
+package repository
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/emirh/car-specs/backend/internal/models"
+)
+
+// ProvenanceRepository records and reads where spec values came from
+type ProvenanceRepository struct {
+	db Querier
+}
+
+func NewProvenanceRepository(db Querier) *ProvenanceRepository {
+	return &ProvenanceRepository{db: db}
+}
+
+// EnsureSource stores doc keyed by URL (refreshing its details if it exists) and sets doc.ID
+func (r *ProvenanceRepository) EnsureSource(doc *models.SourceDocument) error {
+	if doc.URL == "" {
+		return fmt.Errorf("source url is required")
+	}
+	if doc.SourceType == "" {
+		return fmt.Errorf("source type is required")
+	}
+
+	_, err := r.db.Exec(`
+		INSERT INTO source_documents (url, title, source_type, market_scope, retrieved_at)
+		VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT(url) DO UPDATE SET
+			title = COALESCE(excluded.title, title),
+			source_type = excluded.source_type,
+			market_scope = COALESCE(excluded.market_scope, market_scope),
+			retrieved_at = COALESCE(excluded.retrieved_at, retrieved_at)
+	`, doc.URL, doc.Title, doc.SourceType, doc.MarketScope, doc.RetrievedAt)
+	if err != nil {
+		return fmt.Errorf("failed to save source document: %w", err)
+	}
+
+	if err := r.db.QueryRow("SELECT id FROM source_documents WHERE url = ?", doc.URL).Scan(&doc.ID); err != nil {
+		return fmt.Errorf("failed to get source document id: %w", err)
+	}
+	return nil
+}
+
+// CiteField records that sourceID reported value for a trim field (JSON name).
+// Citing the same field from the same source again replaces the earlier value.
+func (r *ProvenanceRepository) CiteField(trimID int64, field string, value interface{}, sourceID int64, note string) error {
+	var encoded *string
+	if value != nil {
+		raw, err := json.Marshal(value)
+		if err != nil {
+			return fmt.Errorf("failed to encode %s: %w", field, err)
+		}
+		if string(raw) != "null" {
+			s := string(raw)
+			encoded = &s
+		}
+	}
+
+	_, err := r.db.Exec(`
+		INSERT INTO trim_field_sources (trim_id, field, source_document_id, value, note)
+		VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT(trim_id, field, source_document_id) DO UPDATE SET
+			value = excluded.value,
+			note = excluded.note,
+			recorded_at = CURRENT_TIMESTAMP
+	`, trimID, field, sourceID, encoded, nullIfEmpty(note))
+	if err != nil {
+		return fmt.Errorf("failed to cite %s: %w", field, err)
+	}
+	return nil
+}
+
+// CiteFields cites every entry of values (field JSON name -> value) with the same source
+func (r *ProvenanceRepository) CiteFields(trimID int64, values map[string]interface{}, sourceID int64, note string) error {
+	for field, value := range values {
+		if err := r.CiteField(trimID, field, value, sourceID, note); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// CiteSpec records that sourceID backs a key-value spec
+func (r *ProvenanceRepository) CiteSpec(specID, sourceID int64, page, note string) error {
+	_, err := r.db.Exec(`
+		INSERT INTO spec_sources (spec_id, source_document_id, page, note)
+		VALUES (?, ?, ?, ?)
+		ON CONFLICT(spec_id, source_document_id) DO UPDATE SET
+			page = COALESCE(excluded.page, page),
+			note = COALESCE(excluded.note, note)
+	`, specID, sourceID, nullIfEmpty(page), nullIfEmpty(note))
+	if err != nil {
+		return fmt.Errorf("failed to cite spec %d: %w", specID, err)
+	}
+	return nil
+}
+
+// ListFieldCitations returns a trim's field citations keyed by field, newest first
+func (r *ProvenanceRepository) ListFieldCitations(trimID int64) (map[string][]models.Citation, error) {
+	rows, err := r.db.Query(`
+		SELECT
+			fs.field, fs.value, fs.note, fs.recorded_at,
+			d.id, d.url, d.title, d.source_type, d.market_scope, d.retrieved_at
+		FROM trim_field_sources fs
+		JOIN source_documents d ON d.id = fs.source_document_id
+		WHERE fs.trim_id = ?
+		ORDER BY fs.field, fs.recorded_at DESC, fs.id DESC
+	`, trimID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list field citations: %w", err)
+	}
+	defer rows.Close()
+
+	citations := make(map[string][]models.Citation)
+	for rows.Next() {
+		var field string
+		var value, note sql.NullString
+		c := models.Citation{}
+		if err := scanCitation(rows, &c, &field, &value, &note); err != nil {
+			return nil, err
+		}
+		if value.Valid {
+			c.Value = json.RawMessage(value.String)
+		} else {
+			c.Value = json.RawMessage("null")
+		}
+		if note.Valid {
+			c.Note = &note.String
+		}
+		citations[field] = append(citations[field], c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list field citations: %w", err)
+	}
+
+	return citations, nil
+}
+
+// ListSpecCitations returns every spec of a trim with its sources (possibly none)
+func (r *ProvenanceRepository) ListSpecCitations(trimID int64) ([]models.SpecCitations, error) {
+	return r.listSpecCitations("s.trim_id = ?", trimID)
+}
+
+// ListSpecCitationsByTrims is ListSpecCitations for several trims, keyed by trim
+func (r *ProvenanceRepository) ListSpecCitationsByTrims(trimIDs []int64) (map[int64][]models.SpecCitations, error) {
+	byTrim := make(map[int64][]models.SpecCitations)
+	if len(trimIDs) == 0 {
+		return byTrim, nil
+	}
+	specs, err := r.listSpecCitations("s.trim_id IN ("+placeholders(len(trimIDs))+")", int64Args(trimIDs)...)
+	if err != nil {
+		return nil, err
+	}
+	for _, spec := range specs {
+		byTrim[spec.TrimID] = append(byTrim[spec.TrimID], spec)
+	}
+	return byTrim, nil
+}
+
+// listSpecCitations returns the specs matching where, with their sources
+func (r *ProvenanceRepository) listSpecCitations(where string, args ...interface{}) ([]models.SpecCitations, error) {
+	rows, err := r.db.Query(`
+		SELECT
+			s.id, s.trim_id, s.category, s.name, s.value,
+			ss.page, ss.note, ss.created_at,
+			d.id, d.url, d.title, d.source_type, d.market_scope, d.retrieved_at
+		FROM specs s
+		LEFT JOIN spec_sources ss ON ss.spec_id = s.id
+		LEFT JOIN source_documents d ON d.id = ss.source_document_id
+		WHERE `+where+`
+		ORDER BY s.trim_id, s.category, s.name, s.id, ss.id
+	`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list spec citations: %w", err)
+	}
+	defer rows.Close()
+
+	specs := []models.SpecCitations{}
+	for rows.Next() {
+		var spec models.Spec
+		var page, note, url, sourceType sql.NullString
+		var title, market sql.NullString
+		var recordedAt, retrievedAt sql.NullTime
+		var sourceID sql.NullInt64
+
+		err := rows.Scan(
+			&spec.ID, &spec.TrimID, &spec.Category, &spec.Name, &spec.Value,
+			&page, &note, &recordedAt,
+			&sourceID, &url, &title, &sourceType, &market, &retrievedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan spec citation: %w", err)
+		}
+
+		if len(specs) == 0 || specs[len(specs)-1].ID != spec.ID {
+			specs = append(specs, models.SpecCitations{Spec: spec, Sources: []models.Citation{}})
+		}
+		if !sourceID.Valid {
+			continue
+		}
+
+		c := models.Citation{
+			Source: models.SourceDocument{
+				ID:         sourceID.Int64,
+				URL:        url.String,
+				SourceType: sourceType.String,
+			},
+			Current:    true, // A spec citation always refers to the stored value
+			RecordedAt: recordedAt.Time,
+		}
+		if title.Valid {
+			c.Source.Title = &title.String
+		}
+		if market.Valid {
+			c.Source.MarketScope = &market.String
+		}
+		if retrievedAt.Valid {
+			c.Source.RetrievedAt = &retrievedAt.Time
+		}
+		if page.Valid {
+			c.Page = &page.String
+		}
+		if note.Valid {
+			c.Note = &note.String
+		}
+		last := &specs[len(specs)-1]
+		last.Sources = append(last.Sources, c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list spec citations: %w", err)
+	}
+
+	return specs, nil
+}
+
+// ListSourcesByTrims returns the documents cited for the fields of each
+// trim, keyed by trim
+func (r *ProvenanceRepository) ListSourcesByTrims(trimIDs []int64) (map[int64][]models.TrimSource, error) {
+	byTrim := make(map[int64][]models.TrimSource)
+	if len(trimIDs) == 0 {
+		return byTrim, nil
+	}
+	rows, err := r.db.Query(`
+		SELECT
+			fs.trim_id, fs.field,
+			d.id, d.url, d.title, d.source_type, d.market_scope, d.retrieved_at
+		FROM trim_field_sources fs
+		JOIN source_documents d ON d.id = fs.source_document_id
+		WHERE fs.trim_id IN (`+placeholders(len(trimIDs))+`)
+		ORDER BY fs.trim_id, d.id, fs.field
+	`, int64Args(trimIDs)...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list trim sources: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var trimID int64
+		var field string
+		var doc models.SourceDocument
+		var title, market sql.NullString
+		var retrievedAt sql.NullTime
+		err := rows.Scan(&trimID, &field, &doc.ID, &doc.URL, &title, &doc.SourceType, &market, &retrievedAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan trim source: %w", err)
+		}
+
+		sources := byTrim[trimID]
+		if len(sources) == 0 || sources[len(sources)-1].ID != doc.ID {
+			if title.Valid {
+				doc.Title = &title.String
+			}
+			if market.Valid {
+				doc.MarketScope = &market.String
+			}
+			if retrievedAt.Valid {
+				doc.RetrievedAt = &retrievedAt.Time
+			}
+			sources = append(sources, models.TrimSource{SourceDocument: doc})
+		}
+		last := &sources[len(sources)-1]
+		last.Fields = append(last.Fields, field)
+		byTrim[trimID] = sources
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list trim sources: %w", err)
+	}
+	return byTrim, nil
+}
+
+func scanCitation(rows *sql.Rows, c *models.Citation, field *string, value, note *sql.NullString) error {
+	var title, market sql.NullString
+	var retrievedAt sql.NullTime
+
+	err := rows.Scan(
+		field, value, note, &c.RecordedAt,
+		&c.Source.ID, &c.Source.URL, &title, &c.Source.SourceType, &market, &retrievedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to scan citation: %w", err)
+	}
+
+	if title.Valid {
+		c.Source.Title = &title.String
+	}
+	if market.Valid {
+		c.Source.MarketScope = &market.String
+	}
+	if retrievedAt.Valid {
+		c.Source.RetrievedAt = &retrievedAt.Time
+	}
+	return nil
+}
+
+// placeholders returns "?, ?, ?" for n values of an IN list
+func placeholders(n int) string {
+	return strings.TrimSuffix(strings.Repeat("?, ", n), ", ")
+}
+
+func int64Args(ids []int64) []interface{} {
+	args := make([]interface{}, len(ids))
+	for i, id := range ids {
+		args[i] = id
+	}
+	return args
+}
+
+func nullIfEmpty(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
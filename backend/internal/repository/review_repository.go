@@ -0,0 +1,271 @@
+package repository
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/emirh/car-specs/backend/internal/models"
+)
+
+// ErrReviewIssueNotFound is returned when no review issue has the requested ID
+var ErrReviewIssueNotFound = errors.New("review issue not found")
+
+type ReviewRepository struct {
+	db Querier
+}
+
+func NewReviewRepository(db Querier) *ReviewRepository {
+	return &ReviewRepository{db: db}
+}
+
+// ReviewFilter narrows List and ResolveMatching. Zero values match everything.
+type ReviewFilter struct {
+	TrimID       int64
+	GenerationID int64
+	Status       string
+	Rule         string
+	Field        string
+}
+
+func (f ReviewFilter) where() (string, []interface{}) {
+	var conditions []string
+	var args []interface{}
+	if f.TrimID != 0 {
+		conditions = append(conditions, "ri.trim_id = ?")
+		args = append(args, f.TrimID)
+	}
+	if f.GenerationID != 0 {
+		conditions = append(conditions, "ri.trim_id IN (SELECT id FROM trims WHERE generation_id = ?)")
+		args = append(args, f.GenerationID)
+	}
+	if f.Status != "" {
+		conditions = append(conditions, "ri.status = ?")
+		args = append(args, f.Status)
+	}
+	if f.Rule != "" {
+		conditions = append(conditions, "ri.rule = ?")
+		args = append(args, f.Rule)
+	}
+	if f.Field != "" {
+		conditions = append(conditions, "ri.field = ?")
+		args = append(args, f.Field)
+	}
+	if len(conditions) == 0 {
+		return "", nil
+	}
+	return " WHERE " + strings.Join(conditions, " AND "), args
+}
+
+// Sync stores the issues currently found in a trim. New issues are opened,
+// open issues no longer found are marked fixed, fixed ones found again are
+// reopened, and accepted or ignored ones reopen only when their value changed.
+func (r *ReviewRepository) Sync(trimID int64, source string, found []*models.ReviewIssue) error {
+	return Transact(r.db, func(tx Querier) error {
+		type stored struct {
+			id     int64
+			status string
+			value  sql.NullString
+		}
+		rows, err := tx.Query("SELECT id, rule, field, status, value FROM review_issues WHERE trim_id = ?", trimID)
+		if err != nil {
+			return fmt.Errorf("failed to load review issues: %w", err)
+		}
+		existing := make(map[string]stored)
+		for rows.Next() {
+			var s stored
+			var rule, field string
+			if err := rows.Scan(&s.id, &rule, &field, &s.status, &s.value); err != nil {
+				rows.Close()
+				return fmt.Errorf("failed to scan review issue: %w", err)
+			}
+			existing[rule+"|"+field] = s
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return fmt.Errorf("failed to load review issues: %w", err)
+		}
+
+		seen := make(map[string]bool)
+		for _, issue := range found {
+			key := issue.Rule + "|" + issue.Field
+			seen[key] = true
+			value := nullIfEmpty(string(issue.Value))
+			if value != nil && *value == "null" {
+				value = nil
+			}
+
+			prev, ok := existing[key]
+			if !ok {
+				_, err := tx.Exec(`
+					INSERT INTO review_issues (trim_id, rule, field, value, confidence, message, source, status)
+					VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+				`, trimID, issue.Rule, issue.Field, value, issue.Confidence, issue.Message, nullIfEmpty(source), models.IssueOpen)
+				if err != nil {
+					return fmt.Errorf("failed to create review issue: %w", err)
+				}
+				continue
+			}
+
+			valueChanged := prev.value.Valid != (value != nil) || (value != nil && prev.value.String != *value)
+			status := prev.status
+			switch prev.status {
+			case models.IssueFixed:
+				status = models.IssueOpen
+			case models.IssueAccepted, models.IssueIgnored:
+				if valueChanged {
+					status = models.IssueOpen
+				}
+			}
+			if status == prev.status && !valueChanged {
+				continue
+			}
+
+			_, err := tx.Exec(`
+				UPDATE review_issues
+				SET value = ?, confidence = ?, message = ?, source = COALESCE(?, source), status = ?,
+					resolved_at = CASE WHEN ? = 'open' THEN NULL ELSE resolved_at END,
+					updated_at = CURRENT_TIMESTAMP
+				WHERE id = ?
+			`, value, issue.Confidence, issue.Message, nullIfEmpty(source), status, status, prev.id)
+			if err != nil {
+				return fmt.Errorf("failed to update review issue %d: %w", prev.id, err)
+			}
+		}
+
+		for key, prev := range existing {
+			if seen[key] || prev.status != models.IssueOpen {
+				continue
+			}
+			_, err := tx.Exec(`
+				UPDATE review_issues
+				SET status = ?, resolved_at = CURRENT_TIMESTAMP, updated_at = CURRENT_TIMESTAMP
+				WHERE id = ?
+			`, models.IssueFixed, prev.id)
+			if err != nil {
+				return fmt.Errorf("failed to close review issue %d: %w", prev.id, err)
+			}
+		}
+
+		return nil
+	})
+}
+
+const reviewIssueColumns = `
+	ri.id, ri.trim_id, t.name, t.generation_id, ri.rule, ri.field, ri.value, ri.confidence,
+	ri.message, ri.source, ri.status, ri.note, ri.created_at, ri.updated_at, ri.resolved_at`
+
+// List returns issues matching filter, ordered by generation, trim and rule
+func (r *ReviewRepository) List(filter ReviewFilter) ([]*models.ReviewIssue, error) {
+	where, args := filter.where()
+	rows, err := r.db.Query(`
+		SELECT`+reviewIssueColumns+`
+		FROM review_issues ri
+		JOIN trims t ON t.id = ri.trim_id AND t.deleted_at IS NULL`+where+`
+		ORDER BY t.generation_id, ri.trim_id, ri.rule, ri.field`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list review issues: %w", err)
+	}
+	defer rows.Close()
+
+	issues := []*models.ReviewIssue{}
+	for rows.Next() {
+		issue, err := scanReviewIssue(rows)
+		if err != nil {
+			return nil, err
+		}
+		issues = append(issues, issue)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list review issues: %w", err)
+	}
+	return issues, nil
+}
+
+// GetByID returns a review issue, or ErrReviewIssueNotFound
+func (r *ReviewRepository) GetByID(id int64) (*models.ReviewIssue, error) {
+	rows, err := r.db.Query(`
+		SELECT`+reviewIssueColumns+`
+		FROM review_issues ri
+		JOIN trims t ON t.id = ri.trim_id
+		WHERE ri.id = ?`, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get review issue: %w", err)
+	}
+	defer rows.Close()
+
+	if !rows.Next() {
+		if err := rows.Err(); err != nil {
+			return nil, fmt.Errorf("failed to get review issue: %w", err)
+		}
+		return nil, ErrReviewIssueNotFound
+	}
+	return scanReviewIssue(rows)
+}
+
+// Resolve sets the status (and note) of one issue
+func (r *ReviewRepository) Resolve(id int64, status, note string) error {
+	res, err := r.db.Exec(`
+		UPDATE review_issues
+		SET status = ?, note = COALESCE(?, note),
+			resolved_at = CASE WHEN ? = 'open' THEN NULL ELSE CURRENT_TIMESTAMP END,
+			updated_at = CURRENT_TIMESTAMP
+		WHERE id = ?
+	`, status, nullIfEmpty(note), status, id)
+	if err != nil {
+		return fmt.Errorf("failed to resolve review issue: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrReviewIssueNotFound
+	}
+	return nil
+}
+
+// ResolveMatching sets the status (and note) of every open issue matching
+// filter and returns how many changed
+func (r *ReviewRepository) ResolveMatching(filter ReviewFilter, status, note string) (int64, error) {
+	filter.Status = models.IssueOpen
+	where, args := filter.where()
+	args = append([]interface{}{status, nullIfEmpty(note)}, args...)
+
+	res, err := r.db.Exec(`
+		UPDATE review_issues AS ri
+		SET status = ?, note = COALESCE(?, note), resolved_at = CURRENT_TIMESTAMP, updated_at = CURRENT_TIMESTAMP`+where, args...)
+	if err != nil {
+		return 0, fmt.Errorf("failed to resolve review issues: %w", err)
+	}
+	n, _ := res.RowsAffected()
+	return n, nil
+}
+
+func scanReviewIssue(rows *sql.Rows) (*models.ReviewIssue, error) {
+	issue := &models.ReviewIssue{}
+	var value, source, note sql.NullString
+	var resolvedAt sql.NullTime
+
+	err := rows.Scan(
+		&issue.ID, &issue.TrimID, &issue.TrimName, &issue.GenerationID, &issue.Rule, &issue.Field,
+		&value, &issue.Confidence, &issue.Message, &source, &issue.Status, &note,
+		&issue.CreatedAt, &issue.UpdatedAt, &resolvedAt,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan review issue: %w", err)
+	}
+
+	issue.Value = json.RawMessage("null")
+	if value.Valid {
+		issue.Value = json.RawMessage(value.String)
+	}
+	if source.Valid {
+		issue.Source = &source.String
+	}
+	if note.Valid {
+		issue.Note = &note.String
+	}
+	if resolvedAt.Valid {
+		issue.ResolvedAt = &resolvedAt.Time
+	}
+	return issue, nil
+}
@@ -0,0 +1,254 @@
+package repository
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/emirh/car-specs/backend/internal/metrics"
+)
+
+// ftsRank orders trims_fts matches with bm25. Weights follow the column order of
+// trims_fts (trim_id, brand, model, generation_code, generation_name, trim,
+// engine_code, transmission_code): codes and trim names are the most specific terms.
+const ftsRank = "bm25(trims_fts, 0, 2.0, 3.0, 4.0, 1.0, 5.0, 4.0, 4.0)"
+
+// valueFilters maps the exact-match filters accepted by Search to their columns.
+// A filter value may be a string or a []string (any of the values matches).
+var valueFilters = []struct {
+	name   string
+	column string
+}{
+	{"brand", "b.name"},
+	{"fuel_type", "t.fuel_type"},
+	{"transmission", "t.transmission_type"},
+	{"drivetrain", "t.drivetrain"},
+	{"body_style", "m.body_style"},
+}
+
+// RangeFilter is a numeric filter over a column or a derived metric expression
+type RangeFilter struct {
+	Name   string
+	Column string
+}
+
+// RangeFilters maps the numeric range filters accepted by Search to their columns.
+// Filters are passed as "<name>_min" / "<name>_max" with float64 values.
+// Every derived metric (power_to_weight, range_km, ...) is a range filter as well.
+var RangeFilters = append([]RangeFilter{
+	{"power_hp", "t.power_hp"},
+	{"torque_nm", "t.torque_nm"},
+	{"acceleration_0_100", "t.acceleration_0_100"},
+	{"price", "t.msrp_price"},
+	{"curb_weight", "t.curb_weight_kg"},
+	{"luggage", "t.luggage_capacity_l"},
+}, derivedRangeFilters()...)
+
+func derivedRangeFilters() []RangeFilter {
+	var filters []RangeFilter
+	for _, m := range metrics.All {
+		filters = append(filters, RangeFilter{m.Name, m.SQL})
+	}
+	return filters
+}
+
+// sortColumns whitelists the sort keys accepted by Search (derived metrics are added in init)
+var sortColumns = map[string]string{
+	"name":               "t.name",
+	"brand":              "b.name",
+	"model":              "m.name",
+	"year":               "COALESCE(t.start_year, t.year)",
+	"power_hp":           "t.power_hp",
+	"torque_nm":          "t.torque_nm",
+	"acceleration_0_100": "t.acceleration_0_100",
+	"top_speed_kmh":      "t.top_speed_kmh",
+	"price":              "t.msrp_price",
+	"curb_weight":        "t.curb_weight_kg",
+	"luggage":            "t.luggage_capacity_l",
+	"fuel_consumption":   "t.fuel_consumption_combined",
+}
+
+func init() {
+	for _, m := range metrics.All {
+		sortColumns[m.Name] = m.SQL
+	}
+}
+
+// SortRelevance orders free-text matches by rank
+const SortRelevance = "relevance"
+
+// IsSearchSortField reports whether Search accepts name as a sort key
+func IsSearchSortField(name string) bool {
+	_, ok := sortColumns[name]
+	return ok || name == SortRelevance
+}
+
+// trimStartYear and trimEndYear give every trim a production span for overlap filtering.
+// A missing end_year on a trim with a start_year means it is still in production.
+const (
+	trimStartYear = "COALESCE(t.start_year, t.year, 0)"
+	trimEndYear   = "COALESCE(t.end_year, CASE WHEN t.start_year IS NULL THEN t.year END, 9999)"
+)
+
+// SearchOptions controls ordering and paging of Search results
+type SearchOptions struct {
+	// Sort is a key of sortColumns or SortRelevance. Empty sorts by relevance when
+	// there is a free-text query and by brand, model and year otherwise.
+	Sort string
+	Desc bool
+
+	// Limit of 0 returns every match
+	Limit  int
+	Offset int
+}
+
+// orderBy builds the ORDER BY clause. Missing values always sort last and
+// t.id breaks ties so pages are stable.
+func (o SearchOptions) orderBy(match string) string {
+	direction := "ASC"
+	if o.Desc {
+		direction = "DESC"
+	}
+
+	if column, ok := sortColumns[o.Sort]; ok {
+		return fmt.Sprintf(" ORDER BY %[1]s IS NULL, %[1]s %[2]s, t.id", column, direction)
+	}
+	if match != "" {
+		return " ORDER BY " + ftsRank + ", b.name, m.name, t.year DESC, t.name, t.id"
+	}
+	return " ORDER BY b.name, m.name, t.year DESC, t.name, t.id"
+}
+
+// searchFilter is the FROM/WHERE part shared by Search and SearchFacets.
+// joins follows "FROM trims t"; where is a list of " AND ..." conditions.
+type searchFilter struct {
+	joins string
+	where string
+	args  []interface{}
+	match string
+}
+
+// buildSearchFilter builds the joins and conditions for the search filters.
+// The filter named by skip is left out so a facet can count the other options
+// of its own dimension (selecting "diesel" still shows how many petrol trims exist).
+func buildSearchFilter(filters map[string]interface{}, skip string) searchFilter {
+	f := searchFilter{}
+
+	// Free-text search goes through the FTS5 index and is ranked by relevance.
+	// A query with no searchable terms matches nothing rather than everything.
+	noTerms := false
+	if q, ok := filters["q"].(string); ok && skip != "q" {
+		f.match = buildMatchQuery(q)
+		noTerms = f.match == ""
+	}
+	if f.match != "" {
+		f.joins += " JOIN trims_fts ON trims_fts.trim_id = t.id"
+	}
+
+	f.joins += `
+		LEFT JOIN generations g ON t.generation_id = g.id
+		LEFT JOIN models m ON g.model_id = m.id
+		LEFT JOIN brands b ON m.brand_id = b.id`
+
+	f.where += " AND t.deleted_at IS NULL"
+	if f.match != "" {
+		f.where += " AND trims_fts MATCH ?"
+		f.args = append(f.args, f.match)
+	}
+	if noTerms {
+		f.where += " AND 0"
+	}
+
+	for _, vf := range valueFilters {
+		if vf.name == skip {
+			continue
+		}
+		values := stringValues(filters[vf.name])
+		if len(values) == 0 {
+			continue
+		}
+		placeholders := strings.TrimSuffix(strings.Repeat("LOWER(?), ", len(values)), ", ")
+		f.where += fmt.Sprintf(" AND LOWER(%s) IN (%s)", vf.column, placeholders)
+		for _, v := range values {
+			f.args = append(f.args, v)
+		}
+	}
+
+	if modelName, ok := filters["model"].(string); ok && skip != "model" {
+		f.where += " AND LOWER(m.name) LIKE LOWER(?)"
+		f.args = append(f.args, "%"+modelName+"%")
+	}
+	if year, ok := filters["year"]; ok && skip != "year" {
+		f.where += " AND t.year = ?"
+		f.args = append(f.args, year)
+	}
+
+	// Year span: trims whose production years overlap [year_from, year_to]
+	if from, ok := filters["year_from"]; ok {
+		f.where += " AND " + trimEndYear + " >= ?"
+		f.args = append(f.args, from)
+	}
+	if to, ok := filters["year_to"]; ok {
+		f.where += " AND " + trimStartYear + " <= ?"
+		f.args = append(f.args, to)
+	}
+
+	for _, rf := range RangeFilters {
+		if min, ok := filters[rf.Name+"_min"]; ok {
+			f.where += fmt.Sprintf(" AND %s >= ?", rf.Column)
+			f.args = append(f.args, min)
+		}
+		if max, ok := filters[rf.Name+"_max"]; ok {
+			f.where += fmt.Sprintf(" AND %s <= ?", rf.Column)
+			f.args = append(f.args, max)
+		}
+	}
+
+	return f
+}
+
+// stringValues accepts a filter value given as string or []string
+func stringValues(v interface{}) []string {
+	switch val := v.(type) {
+	case string:
+		if val != "" {
+			return []string{val}
+		}
+	case []string:
+		return val
+	}
+	return nil
+}
+
+// buildMatchQuery turns free text such as `a3 35 tfsi` into an FTS5 MATCH expression.
+// Every term must match (implicit AND) and is prefix-matched, so "tfs" finds "TFSI".
+// Terms are quoted, which keeps user input from being parsed as FTS5 syntax.
+// Returns "" when the input has no searchable terms.
+func buildMatchQuery(q string) string {
+	var terms []string
+	for _, term := range tokenize(q) {
+		terms = append(terms, `"`+term+`"*`)
+	}
+	return strings.Join(terms, " ")
+}
+
+// HasSearchTerms reports whether q contains anything the full-text index can match
+func HasSearchTerms(q string) bool {
+	return len(tokenize(q)) > 0
+}
+
+// tokenize mirrors the trims_fts tokenizer (unicode61 with '.' as a token character)
+func tokenize(q string) []string {
+	fields := strings.FieldsFunc(strings.ToLower(q), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '.'
+	})
+
+	var terms []string
+	for _, f := range fields {
+		f = strings.Trim(f, ".")
+		if f != "" {
+			terms = append(terms, f)
+		}
+	}
+	return terms
+}
@@ -0,0 +1,26 @@
+package repository
+
+import "testing"
+
+func TestBuildMatchQuery(t *testing.T) {
+	tests := []struct {
+		input  string
+		expect string
+	}{
+		{"a3 35 tfsi", `"a3"* "35"* "tfsi"*`},
+		{"8V 1.5 TFSI", `"8v"* "1.5"* "tfsi"*`},
+		{"  Golf,  GTI ", `"golf"* "gti"*`},
+		{`"DQ200" OR -NEAR(`, `"dq200"* "or"* "near"*`},
+		{"1.5.", `"1.5"*`},
+		{"Škoda", `"škoda"*`},
+		{"", ""},
+		{"...", ""},
+	}
+
+	for _, tc := range tests {
+		got := buildMatchQuery(tc.input)
+		if got != tc.expect {
+			t.Errorf("buildMatchQuery(%q) = %q; want %q", tc.input, got, tc.expect)
+		}
+	}
+}
@@ -0,0 +1,38 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+// Querier is satisfied by both *sql.DB and *sql.Tx, so a repository can be
+// bound to a transaction and write together with other repositories
+type Querier interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+// Transact runs fn in a transaction and commits it if fn succeeds. When db
+// is already a transaction, fn joins it and the caller's commit decides.
+func Transact(db Querier, fn func(tx Querier) error) error {
+	switch d := db.(type) {
+	case *sql.Tx:
+		return fn(d)
+	case *sql.DB:
+		tx, err := d.Begin()
+		if err != nil {
+			return fmt.Errorf("failed to begin transaction: %w", err)
+		}
+		defer tx.Rollback()
+
+		if err := fn(tx); err != nil {
+			return err
+		}
+		if err := tx.Commit(); err != nil {
+			return fmt.Errorf("failed to commit transaction: %w", err)
+		}
+		return nil
+	}
+	return fmt.Errorf("cannot begin a transaction on %T", db)
+}
@@ -0,0 +1,279 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/emirh/car-specs/backend/internal/models"
+)
+
+// Errors returned by TrashRepository
+var (
+	ErrNotInTrash    = errors.New("not in the trash")
+	ErrParentDeleted = errors.New("parent is deleted, restore it first")
+	ErrUnknownEntity = errors.New("unknown entity type")
+)
+
+// errPurgeDryRun rolls back the transaction of a dry-run purge
+var errPurgeDryRun = errors.New("purge dry run")
+
+// trashLevel is one level of the brand → model → generation → trim hierarchy
+type trashLevel struct {
+	entityType string
+	table      string
+	name       string // column listed as the row's name
+	parent     string // column referencing the row one level up
+}
+
+var trashLevels = []trashLevel{
+	{models.EntityBrand, "brands", "name", ""},
+	{models.EntityModel, "models", "name", "brand_id"},
+	{models.EntityGeneration, "generations", "code", "model_id"},
+	{models.EntityTrim, "trims", "name", "generation_id"},
+}
+
+// deletedNow is the deleted_at of a delete. It has milliseconds, so two
+// deletes made in the same second are still restored separately.
+const deletedNow = `strftime('%Y-%m-%d %H:%M:%f', 'now')`
+
+func trashLevelOf(entityType string) (int, error) {
+	for i, l := range trashLevels {
+		if l.entityType == entityType {
+			return i, nil
+		}
+	}
+	return 0, fmt.Errorf("%w: %s", ErrUnknownEntity, entityType)
+}
+
+// descendants returns one subquery per level below level, selecting the ids
+// of the rows under the row whose id is root (a placeholder or a column)
+func descendants(level int, root string) []string {
+	var queries []string
+	ids := root
+	for _, l := range trashLevels[level+1:] {
+		ids = fmt.Sprintf("SELECT id FROM %s WHERE %s IN (%s)", l.table, l.parent, ids)
+		queries = append(queries, ids)
+	}
+	return queries
+}
+
+// softDelete sets deleted_at on row id of the entity type and on every live
+// row below it, all to the same time. notFound is returned when the row does
+// not exist or is already deleted.
+func softDelete(db Querier, entityType string, id int64, notFound error) error {
+	level, err := trashLevelOf(entityType)
+	if err != nil {
+		return err
+	}
+	l := trashLevels[level]
+
+	return Transact(db, func(tx Querier) error {
+		result, err := tx.Exec(`UPDATE `+l.table+` SET deleted_at = `+deletedNow+` WHERE id = ? AND deleted_at IS NULL`, id)
+		if err != nil {
+			return fmt.Errorf("failed to delete %s: %w", l.entityType, err)
+		}
+		affected, err := result.RowsAffected()
+		if err != nil {
+			return fmt.Errorf("failed to get affected rows: %w", err)
+		}
+		if affected == 0 {
+			return notFound
+		}
+
+		stamp := `(SELECT deleted_at FROM ` + l.table + ` WHERE id = ?)`
+		for i, ids := range descendants(level, "?") {
+			child := trashLevels[level+1+i]
+			query := `UPDATE ` + child.table + ` SET deleted_at = ` + stamp + ` WHERE deleted_at IS NULL AND id IN (` + ids + `)`
+			if _, err := tx.Exec(query, id, id); err != nil {
+				return fmt.Errorf("failed to delete %ss of %s %d: %w", child.entityType, l.entityType, id, err)
+			}
+		}
+		return nil
+	})
+}
+
+// TrashRepository lists, restores and purges soft-deleted rows
+type TrashRepository struct {
+	db Querier
+}
+
+func NewTrashRepository(db Querier) *TrashRepository {
+	return &TrashRepository{db: db}
+}
+
+// Transact runs fn in a transaction on the trash repository's database
+func (r *TrashRepository) Transact(fn func(tx Querier) error) error {
+	return Transact(r.db, fn)
+}
+
+// List returns the deleted rows of entityType ("" for every type), most
+// recently deleted first. Rows deleted along with their parent are counted
+// in the parent's Cascaded instead of listed.
+func (r *TrashRepository) List(entityType string) ([]*models.TrashItem, error) {
+	items := []*models.TrashItem{}
+	for level, l := range trashLevels {
+		if entityType != "" && entityType != l.entityType {
+			continue
+		}
+
+		parent, join, notCascaded := "0", "", ""
+		if l.parent != "" {
+			parent = "r." + l.parent
+			join = " LEFT JOIN " + trashLevels[level-1].table + " p ON p.id = r." + l.parent
+			notCascaded = " AND (p.deleted_at IS NULL OR p.deleted_at != r.deleted_at)"
+		}
+		cascaded := "0"
+		for i, ids := range descendants(level, "r.id") {
+			child := trashLevels[level+1+i]
+			cascaded += fmt.Sprintf(" + (SELECT COUNT(*) FROM %s WHERE deleted_at = r.deleted_at AND id IN (%s))", child.table, ids)
+		}
+
+		rows, err := r.db.Query(`
+			SELECT r.id, COALESCE(r.` + l.name + `, ''), ` + parent + `, r.deleted_at, ` + cascaded + `
+			FROM ` + l.table + ` r` + join + `
+			WHERE r.deleted_at IS NOT NULL` + notCascaded)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list deleted %ss: %w", l.entityType, err)
+		}
+		for rows.Next() {
+			item := &models.TrashItem{EntityType: l.entityType}
+			if err := rows.Scan(&item.ID, &item.Name, &item.ParentID, &item.DeletedAt, &item.Cascaded); err != nil {
+				rows.Close()
+				return nil, fmt.Errorf("failed to scan deleted %s: %w", l.entityType, err)
+			}
+			items = append(items, item)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return nil, fmt.Errorf("failed to list deleted %ss: %w", l.entityType, err)
+		}
+	}
+
+	sort.SliceStable(items, func(i, j int) bool {
+		return items[i].DeletedAt.After(items[j].DeletedAt)
+	})
+	return items, nil
+}
+
+// IsDeleted reports whether row id of entityType exists and is deleted
+func (r *TrashRepository) IsDeleted(entityType string, id int64) (bool, error) {
+	level, err := trashLevelOf(entityType)
+	if err != nil {
+		return false, err
+	}
+	var deleted bool
+	err = r.db.QueryRow(`SELECT deleted_at IS NOT NULL FROM `+trashLevels[level].table+` WHERE id = ?`, id).Scan(&deleted)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to get %s: %w", entityType, err)
+	}
+	return deleted, nil
+}
+
+// Restore undeletes row id of entityType and the rows deleted along with it.
+// A row whose parent is still deleted cannot be restored.
+func (r *TrashRepository) Restore(entityType string, id int64) error {
+	level, err := trashLevelOf(entityType)
+	if err != nil {
+		return err
+	}
+	l := trashLevels[level]
+
+	return Transact(r.db, func(tx Querier) error {
+		deleted, err := NewTrashRepository(tx).IsDeleted(entityType, id)
+		if err != nil {
+			return err
+		}
+		if !deleted {
+			return ErrNotInTrash
+		}
+
+		if l.parent != "" {
+			var parentDeleted bool
+			err := tx.QueryRow(`
+				SELECT p.deleted_at IS NOT NULL
+				FROM `+l.table+` r JOIN `+trashLevels[level-1].table+` p ON p.id = r.`+l.parent+`
+				WHERE r.id = ?`, id).Scan(&parentDeleted)
+			if err != nil && err != sql.ErrNoRows {
+				return fmt.Errorf("failed to get parent of %s %d: %w", entityType, id, err)
+			}
+			if parentDeleted {
+				return ErrParentDeleted
+			}
+		}
+
+		// Rows below first: they are matched by the deleted_at the row still has
+		stamp := `(SELECT deleted_at FROM ` + l.table + ` WHERE id = ?)`
+		for i, ids := range descendants(level, "?") {
+			child := trashLevels[level+1+i]
+			query := `UPDATE ` + child.table + ` SET deleted_at = NULL WHERE deleted_at = ` + stamp + ` AND id IN (` + ids + `)`
+			if _, err := tx.Exec(query, id, id); err != nil {
+				return fmt.Errorf("failed to restore %ss of %s %d: %w", child.entityType, entityType, id, err)
+			}
+		}
+		if _, err := tx.Exec(`UPDATE `+l.table+` SET deleted_at = NULL WHERE id = ?`, id); err != nil {
+			return fmt.Errorf("failed to restore %s %d: %w", entityType, id, err)
+		}
+		return nil
+	})
+}
+
+// Purge permanently removes rows deleted before cutoff, trims first. A
+// deleted row that still has rows below it (e.g. a brand whose models were
+// deleted later) stays until they are purged too. With dryRun nothing is
+// removed, but the counts are those a purge would report.
+func (r *TrashRepository) Purge(cutoff time.Time, dryRun bool) (models.PurgeCounts, error) {
+	before := cutoff.UTC().Format("2006-01-02 15:04:05.000")
+	expired := `deleted_at IS NOT NULL AND deleted_at < ?`
+	counts := models.PurgeCounts{}
+
+	// purges lists the statements per level, children first. Each takes before
+	// once. Children are matched with NOT EXISTS: NOT IN matches nothing once a
+	// legacy row has a NULL parent ID.
+	purges := []struct {
+		entityType string
+		prepare    []string
+		delete     string
+	}{
+		{models.EntityTrim, []string{
+			`DELETE FROM specs WHERE trim_id IN (SELECT id FROM trims WHERE ` + expired + `)`,
+		}, `DELETE FROM trims WHERE ` + expired},
+		{models.EntityGeneration, nil,
+			`DELETE FROM generations WHERE ` + expired + ` AND NOT EXISTS (SELECT 1 FROM trims t WHERE t.generation_id = generations.id)`},
+		{models.EntityModel, nil,
+			`DELETE FROM models WHERE ` + expired + ` AND NOT EXISTS (SELECT 1 FROM generations g WHERE g.model_id = models.id)
+				AND NOT EXISTS (SELECT 1 FROM trims t WHERE t.model_id = models.id)`},
+		{models.EntityBrand, nil,
+			`DELETE FROM brands WHERE ` + expired + ` AND NOT EXISTS (SELECT 1 FROM models m WHERE m.brand_id = brands.id)`},
+	}
+
+	err := Transact(r.db, func(tx Querier) error {
+		for _, p := range purges {
+			for _, query := range p.prepare {
+				if _, err := tx.Exec(query, before); err != nil {
+					return fmt.Errorf("failed to purge %ss: %w", p.entityType, err)
+				}
+			}
+			result, err := tx.Exec(p.delete, before)
+			if err != nil {
+				return fmt.Errorf("failed to purge %ss: %w", p.entityType, err)
+			}
+			if counts[p.entityType], err = result.RowsAffected(); err != nil {
+				return fmt.Errorf("failed to get affected rows: %w", err)
+			}
+		}
+		if dryRun {
+			return errPurgeDryRun
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, errPurgeDryRun) {
+		return nil, err
+	}
+	return counts, nil
+}
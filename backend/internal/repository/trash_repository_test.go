@@ -0,0 +1,94 @@
+package repository
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/emirh/car-specs/backend/internal/models"
+)
+
+func TestTrashCascade(t *testing.T) {
+	db := openTestDB(t)
+	trash := NewTrashRepository(db)
+
+	mustExec(t, db, `INSERT INTO brands (id, name) VALUES (1, 'Volkswagen')`)
+	mustExec(t, db, `INSERT INTO models (id, brand_id, name) VALUES (1, 1, 'Golf')`)
+	mustExec(t, db, `INSERT INTO generations (id, model_id, code) VALUES (1, 1, 'Mk8')`)
+	mustExec(t, db, `INSERT INTO trims (id, generation_id, model_id, name, year) VALUES (1, 1, 1, '1.5 eTSI', 2021), (2, 1, 1, '2.0 TDI', 2021)`)
+
+	// A trim deleted on its own stays deleted when its brand is restored
+	if err := NewTrimRepository(db).Delete(2); err != nil {
+		t.Fatalf("Delete(trim 2) error = %v", err)
+	}
+	time.Sleep(5 * time.Millisecond)
+	if err := NewBrandRepository(db).Delete(1); err != nil {
+		t.Fatalf("Delete(brand 1) error = %v", err)
+	}
+
+	if _, err := NewTrimRepository(db).GetByID(1, false); err == nil {
+		t.Error("trim of a deleted brand is still found")
+	}
+	items, err := trash.List("")
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	if len(items) != 2 || items[0].EntityType != models.EntityBrand || items[0].Cascaded != 3 || items[1].ID != 2 {
+		t.Fatalf("List() = %+v, want the brand with 3 cascaded rows, then trim 2", items)
+	}
+
+	if err := trash.Restore(models.EntityTrim, 1); !errors.Is(err, ErrParentDeleted) {
+		t.Errorf("Restore(trim 1) error = %v, want ErrParentDeleted", err)
+	}
+	if err := trash.Restore(models.EntityBrand, 1); err != nil {
+		t.Fatalf("Restore(brand 1) error = %v", err)
+	}
+	if _, err := NewTrimRepository(db).GetByID(1, false); err != nil {
+		t.Errorf("trim 1 not restored with its brand: %v", err)
+	}
+	if deleted, _ := trash.IsDeleted(models.EntityTrim, 2); !deleted {
+		t.Error("trim 2 was restored with the brand it was not deleted with")
+	}
+	if err := trash.Restore(models.EntityBrand, 1); !errors.Is(err, ErrNotInTrash) {
+		t.Errorf("second Restore(brand 1) error = %v, want ErrNotInTrash", err)
+	}
+}
+
+func TestTrashPurge(t *testing.T) {
+	db := openTestDB(t)
+	trash := NewTrashRepository(db)
+
+	mustExec(t, db, `INSERT INTO brands (id, name) VALUES (1, 'Audi')`)
+	mustExec(t, db, `INSERT INTO models (id, brand_id, name) VALUES (1, 1, 'A3')`)
+	mustExec(t, db, `INSERT INTO generations (id, model_id, code) VALUES (1, 1, '8Y')`)
+	mustExec(t, db, `INSERT INTO trims (id, generation_id, model_id, name, year) VALUES (1, 1, 1, '35 TFSI', 2021), (2, 1, 1, '40 TDI', 2021)`)
+	mustExec(t, db, `UPDATE trims SET deleted_at = '2020-01-01 00:00:00.000' WHERE id = 1`)
+	mustExec(t, db, `UPDATE generations SET deleted_at = '2020-01-01 00:00:00.000' WHERE id = 1`)
+
+	// The generation still has trim 2, so only trim 1 can go
+	counts, err := trash.Purge(time.Now(), true)
+	if err != nil {
+		t.Fatalf("Purge(dry run) error = %v", err)
+	}
+	if counts[models.EntityTrim] != 1 || counts[models.EntityGeneration] != 0 {
+		t.Errorf("Purge(dry run) = %v, want 1 trim", counts)
+	}
+	if deleted, _ := trash.IsDeleted(models.EntityTrim, 1); !deleted {
+		t.Error("dry run removed trim 1")
+	}
+
+	mustExec(t, db, `UPDATE trims SET deleted_at = '2020-01-01 00:00:00.000' WHERE id = 2`)
+	if counts, err = trash.Purge(time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC), false); err != nil || counts[models.EntityTrim] != 0 {
+		t.Errorf("Purge(before deletes) = %v, %v; want nothing purged", counts, err)
+	}
+	if counts, err = trash.Purge(time.Now(), false); err != nil {
+		t.Fatalf("Purge() error = %v", err)
+	}
+	if counts[models.EntityTrim] != 2 || counts[models.EntityGeneration] != 1 || counts[models.EntityModel] != 0 {
+		t.Errorf("Purge() = %v, want 2 trims and 1 generation", counts)
+	}
+	var left int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM trims`).Scan(&left); err != nil || left != 0 {
+		t.Errorf("%d trims left after purge (%v)", left, err)
+	}
+}
@@ -2,43 +2,54 @@ package repository
 
 import (
 	"database/sql"
+	"errors"
 	"fmt"
 
 	"github.com/emirh/car-specs/backend/internal/models"
 )
 
+// ErrTrimNotFound is returned when no trim has the requested ID
+var ErrTrimNotFound = errors.New("trim not found")
+
 type TrimRepository struct {
-	db *sql.DB
+	db Querier
 }
 
-func NewTrimRepository(db *sql.DB) *TrimRepository {
+func NewTrimRepository(db Querier) *TrimRepository {
 	return &TrimRepository{db: db}
 }
 
-// Create inserts a new trim
+// Transact runs fn in a transaction on the trim repository's database, so
+// services can bind the repositories a trim write touches to one transaction
+func (r *TrimRepository) Transact(fn func(tx Querier) error) error {
+	return Transact(r.db, fn)
+}
+
+// Create inserts a new trim. A non-zero trim.ID is kept, so a deleted trim
+// can be restored under its old ID; otherwise the database assigns one.
 func (r *TrimRepository) Create(trim *models.Trim) error {
 	query := `
 		INSERT INTO trims (
-			generation_id, name, year, generation, is_facelift, market,
+			id, generation_id, model_id, name, year, start_year, end_year, generation, is_facelift, market,
 			engine_type, fuel_type, displacement_cc, cylinders, cylinder_layout,
 			power_hp, power_kw, torque_nm, engine_code,
 			acceleration_0_100, top_speed_kmh,
 			fuel_consumption_city, fuel_consumption_highway, fuel_consumption_combined,
 			co2_emissions, emission_standard,
-			transmission_type, gears, drivetrain,
+			transmission_type, transmission_code, gears, drivetrain,
 			length_mm, width_mm, height_mm, wheelbase_mm, ground_clearance_mm,
 			curb_weight_kg, gross_weight_kg,
 			luggage_capacity_l, luggage_capacity_max_l, fuel_tank_capacity_l,
 			tire_size_front, tire_size_rear, wheel_size_inches,
 			seating_capacity, doors, image_url, msrp_price, currency
 		) VALUES (
-			?, ?, ?, ?, ?, ?,
+			?, ?, ?, ?, ?, ?, ?, ?, ?, ?,
 			?, ?, ?, ?, ?,
 			?, ?, ?, ?,
 			?, ?,
 			?, ?, ?,
 			?, ?,
-			?, ?, ?,
+			?, ?, ?, ?,
 			?, ?, ?, ?, ?,
 			?, ?,
 			?, ?, ?,
@@ -47,13 +58,13 @@ func (r *TrimRepository) Create(trim *models.Trim) error {
 		)
 	`
 	result, err := r.db.Exec(query,
-		trim.GenerationID, trim.Name, trim.Year, trim.Generation, trim.IsFacelift, trim.Market,
+		sql.NullInt64{Int64: trim.ID, Valid: trim.ID != 0}, trim.GenerationID, trim.ModelID, trim.Name, trim.Year, trim.StartYear, trim.EndYear, trim.Generation, trim.IsFacelift, trim.Market,
 		trim.EngineType, trim.FuelType, trim.DisplacementCC, trim.Cylinders, trim.CylinderLayout,
 		trim.PowerHP, trim.PowerKW, trim.TorqueNM, trim.EngineCode,
 		trim.Acceleration0To100, trim.TopSpeedKmh,
 		trim.FuelConsumptionCity, trim.FuelConsumptionHwy, trim.FuelConsumptionComb,
 		trim.CO2Emissions, trim.EmissionStandard,
-		trim.TransmissionType, trim.Gears, trim.Drivetrain,
+		trim.TransmissionType, trim.TransmissionCode, trim.Gears, trim.Drivetrain,
 		trim.LengthMM, trim.WidthMM, trim.HeightMM, trim.WheelbaseMM, trim.GroundClearanceMM,
 		trim.CurbWeightKG, trim.GrossWeightKG,
 		trim.LuggageCapacityL, trim.LuggageCapacityMaxL, trim.FuelTankCapacityL,
@@ -93,12 +104,13 @@ func (r *TrimRepository) GetByID(id int64, includeRelations bool) (*models.Trim,
 				t.seating_capacity, t.doors, t.image_url, t.msrp_price, t.currency,
 				t.created_at, t.updated_at,
 				m.id, m.brand_id, m.name, m.body_style, m.segment, m.created_at, m.updated_at,
-				b.id, b.name, b.country, b.logo_url, b.created_at, b.updated_at
+				b.id, b.name, b.country, b.logo_url, b.created_at, b.updated_at,
+				g.id, g.code, g.name
 			FROM trims t
 			LEFT JOIN generations g ON t.generation_id = g.id
 			LEFT JOIN models m ON g.model_id = m.id
 			LEFT JOIN brands b ON m.brand_id = b.id
-			WHERE t.id = ?
+			WHERE t.id = ? AND t.deleted_at IS NULL
 		`
 	} else {
 		query = `
@@ -116,7 +128,7 @@ func (r *TrimRepository) GetByID(id int64, includeRelations bool) (*models.Trim,
 				tire_size_front, tire_size_rear, wheel_size_inches,
 				seating_capacity, doors, image_url, msrp_price, currency,
 				created_at, updated_at
-			FROM trims WHERE id = ?
+			FROM trims WHERE id = ? AND deleted_at IS NULL
 		`
 	}
 
@@ -126,6 +138,8 @@ func (r *TrimRepository) GetByID(id int64, includeRelations bool) (*models.Trim,
 	if includeRelations {
 		model := &models.Model{}
 		brand := &models.Brand{}
+		var genID sql.NullInt64
+		var genCode, genName sql.NullString
 		err = r.db.QueryRow(query, id).Scan(
 			&trim.ID, &trim.GenerationID, &trim.ModelID, &trim.Name, &trim.Year, &trim.StartYear, &trim.EndYear, &trim.Generation, &trim.IsFacelift, &trim.Market,
 			&trim.EngineType, &trim.FuelType, &trim.DisplacementCC, &trim.Cylinders, &trim.CylinderLayout,
@@ -142,10 +156,17 @@ func (r *TrimRepository) GetByID(id int64, includeRelations bool) (*models.Trim,
 			&trim.CreatedAt, &trim.UpdatedAt,
 			&model.ID, &model.BrandID, &model.Name, &model.BodyStyle, &model.Segment, &model.CreatedAt, &model.UpdatedAt,
 			&brand.ID, &brand.Name, &brand.Country, &brand.LogoURL, &brand.CreatedAt, &brand.UpdatedAt,
+			&genID, &genCode, &genName,
 		)
 		if err == nil {
 			model.Brand = brand
 			trim.Model = model
+			if genID.Valid {
+				trim.GenerationObj = &models.Generation{ID: genID.Int64, ModelID: model.ID, Code: genCode.String}
+				if genName.Valid {
+					trim.GenerationObj.Name = &genName.String
+				}
+			}
 		}
 	} else {
 		// Simplified scan for trim-only query matching ListByGeneration
@@ -168,7 +189,7 @@ func (r *TrimRepository) GetByID(id int64, includeRelations bool) (*models.Trim,
 
 	if err != nil {
 		if err == sql.ErrNoRows {
-			return nil, fmt.Errorf("trim not found")
+			return nil, ErrTrimNotFound
 		}
 		return nil, fmt.Errorf("failed to get trim: %w", err)
 	}
@@ -176,8 +197,9 @@ func (r *TrimRepository) GetByID(id int64, includeRelations bool) (*models.Trim,
 	return trim, nil
 }
 
-// Search searches trims with filters and includes brand/model data
-func (r *TrimRepository) Search(filters map[string]interface{}) ([]*models.Trim, error) {
+// Search searches trims with filters and includes brand/model data.
+// It returns one page of results and the total number of matches.
+func (r *TrimRepository) Search(filters map[string]interface{}, opts SearchOptions) ([]*models.Trim, int, error) {
 	query := `
 		SELECT 
 			t.id, t.generation_id, t.name, t.year, t.start_year, t.end_year, t.generation, t.is_facelift, t.market,
@@ -198,40 +220,25 @@ func (r *TrimRepository) Search(filters map[string]interface{}) ([]*models.Trim,
 			b.id as brand_id, b.name as brand_name, b.country, b.logo_url,
 			b.created_at as brand_created_at, b.updated_at as brand_updated_at
 		FROM trims t
-		LEFT JOIN generations g ON t.generation_id = g.id
-		LEFT JOIN models m ON g.model_id = m.id
-		LEFT JOIN brands b ON m.brand_id = b.id
-		WHERE 1=1
 	`
-	args := []interface{}{}
+	filter := buildSearchFilter(filters, "")
+	query += filter.joins + " WHERE 1=1" + filter.where + opts.orderBy(filter.match)
+	args := append([]interface{}{}, filter.args...)
 
-	// Build dynamic WHERE clause
-	if brandName, ok := filters["brand"]; ok {
-		query += " AND LOWER(b.name) = LOWER(?)"
-		args = append(args, brandName)
-	}
-	if modelName, ok := filters["model"]; ok {
-		query += " AND LOWER(m.name) LIKE LOWER(?)"
-		args = append(args, "%"+modelName.(string)+"%")
-	}
-	if fuelType, ok := filters["fuel_type"]; ok {
-		query += " AND LOWER(t.fuel_type) = LOWER(?)"
-		args = append(args, fuelType)
-	}
-	if transmission, ok := filters["transmission"]; ok {
-		query += " AND LOWER(t.transmission_type) = LOWER(?)"
-		args = append(args, transmission)
-	}
-	if year, ok := filters["year"]; ok {
-		query += " AND t.year = ?"
-		args = append(args, year)
+	if opts.Limit > 0 {
+		query += " LIMIT ? OFFSET ?"
+		args = append(args, opts.Limit, opts.Offset)
 	}
 
-	query += " ORDER BY b.name, m.name, t.year DESC, t.name"
+	var total int
+	countQuery := "SELECT COUNT(*) FROM trims t" + filter.joins + " WHERE 1=1" + filter.where
+	if err := r.db.QueryRow(countQuery, filter.args...).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to count trims: %w", err)
+	}
 
 	rows, err := r.db.Query(query, args...)
 	if err != nil {
-		return nil, fmt.Errorf("failed to search trims: %w", err)
+		return nil, 0, fmt.Errorf("failed to search trims: %w", err)
 	}
 	defer rows.Close()
 
@@ -261,7 +268,7 @@ func (r *TrimRepository) Search(filters map[string]interface{}) ([]*models.Trim,
 			&brand.CreatedAt, &brand.UpdatedAt,
 		)
 		if err != nil {
-			return nil, fmt.Errorf("failed to scan trim: %w", err)
+			return nil, 0, fmt.Errorf("failed to scan trim: %w", err)
 		}
 
 		// Populate relationships
@@ -271,7 +278,7 @@ func (r *TrimRepository) Search(filters map[string]interface{}) ([]*models.Trim,
 		trims = append(trims, trim)
 	}
 
-	return trims, nil
+	return trims, total, nil
 }
 
 // ListByModel retrieves all trims for a model (via generations)
@@ -293,7 +300,7 @@ func (r *TrimRepository) ListByModel(modelID int64) ([]*models.Trim, error) {
 			t.created_at, t.updated_at
 		FROM trims t
 		LEFT JOIN generations g ON t.generation_id = g.id
-		WHERE g.model_id = ?
+		WHERE g.model_id = ? AND t.deleted_at IS NULL
 		ORDER BY t.year DESC, t.name
 	`
 	rows, err := r.db.Query(query, modelID)
@@ -347,7 +354,7 @@ func (r *TrimRepository) ListByGeneration(genID int64) ([]*models.Trim, error) {
 			seating_capacity, doors, image_url, msrp_price, currency,
 			created_at, updated_at
 		FROM trims
-		WHERE generation_id = ?
+		WHERE generation_id = ? AND deleted_at IS NULL
 		ORDER BY year DESC, name
 	`
 	rows, err := r.db.Query(query, genID)
@@ -383,13 +390,81 @@ func (r *TrimRepository) ListByGeneration(genID int64) ([]*models.Trim, error) {
 	return trims, nil
 }
 
-// Delete deletes a trim
-func (r *TrimRepository) Delete(id int64) error {
-	query := `DELETE FROM trims WHERE id = ?`
-	_, err := r.db.Exec(query, id)
+// Update writes every editable column of the trim (and its model_id) and bumps updated_at.
+// Nil pointer fields are stored as NULL.
+func (r *TrimRepository) Update(trim *models.Trim) error {
+	query := `
+		UPDATE trims SET
+			generation_id = ?, model_id = ?, name = ?, year = ?, start_year = ?, end_year = ?, generation = ?, is_facelift = ?, market = ?,
+			engine_type = ?, fuel_type = ?, displacement_cc = ?, cylinders = ?, cylinder_layout = ?,
+			power_hp = ?, power_kw = ?, torque_nm = ?, engine_code = ?,
+			acceleration_0_100 = ?, top_speed_kmh = ?,
+			fuel_consumption_city = ?, fuel_consumption_highway = ?, fuel_consumption_combined = ?,
+			co2_emissions = ?, emission_standard = ?,
+			transmission_type = ?, transmission_code = ?, gears = ?, drivetrain = ?,
+			length_mm = ?, width_mm = ?, height_mm = ?, wheelbase_mm = ?, ground_clearance_mm = ?,
+			curb_weight_kg = ?, gross_weight_kg = ?,
+			luggage_capacity_l = ?, luggage_capacity_max_l = ?, fuel_tank_capacity_l = ?,
+			tire_size_front = ?, tire_size_rear = ?, wheel_size_inches = ?,
+			seating_capacity = ?, doors = ?, image_url = ?, msrp_price = ?, currency = ?,
+			updated_at = CURRENT_TIMESTAMP
+		WHERE id = ? AND deleted_at IS NULL
+	`
+	result, err := r.db.Exec(query,
+		trim.GenerationID, trim.ModelID, trim.Name, trim.Year, trim.StartYear, trim.EndYear, trim.Generation, trim.IsFacelift, trim.Market,
+		trim.EngineType, trim.FuelType, trim.DisplacementCC, trim.Cylinders, trim.CylinderLayout,
+		trim.PowerHP, trim.PowerKW, trim.TorqueNM, trim.EngineCode,
+		trim.Acceleration0To100, trim.TopSpeedKmh,
+		trim.FuelConsumptionCity, trim.FuelConsumptionHwy, trim.FuelConsumptionComb,
+		trim.CO2Emissions, trim.EmissionStandard,
+		trim.TransmissionType, trim.TransmissionCode, trim.Gears, trim.Drivetrain,
+		trim.LengthMM, trim.WidthMM, trim.HeightMM, trim.WheelbaseMM, trim.GroundClearanceMM,
+		trim.CurbWeightKG, trim.GrossWeightKG,
+		trim.LuggageCapacityL, trim.LuggageCapacityMaxL, trim.FuelTankCapacityL,
+		trim.TireSizeFront, trim.TireSizeRear, trim.WheelSizeInches,
+		trim.SeatingCapacity, trim.Doors, trim.ImageURL, trim.MSRPPrice, trim.Currency,
+		trim.ID,
+	)
 	if err != nil {
-		return fmt.Errorf("failed to delete trim: %w", err)
+		return fmt.Errorf("failed to update trim: %w", err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to update trim: %w", err)
+	}
+	if affected == 0 {
+		return ErrTrimNotFound
 	}
 
 	return nil
 }
+
+// Delete moves a trim to the trash
+func (r *TrimRepository) Delete(id int64) error {
+	return softDelete(r.db, models.EntityTrim, id, ErrTrimNotFound)
+}
+
+// InTrash reports whether trim id is deleted and not yet purged
+func (r *TrimRepository) InTrash(id int64) (bool, error) {
+	return NewTrashRepository(r.db).IsDeleted(models.EntityTrim, id)
+}
+
+// NameInTrash reports whether a deleted trim of the generation has the name
+// (case-insensitive) and year
+func (r *TrimRepository) NameInTrash(generationID int64, name string, year int) (bool, error) {
+	var count int
+	err := r.db.QueryRow(
+		`SELECT COUNT(*) FROM trims WHERE generation_id = ? AND LOWER(name) = LOWER(?) AND year = ? AND deleted_at IS NOT NULL`,
+		generationID, name, year,
+	).Scan(&count)
+	if err != nil {
+		return false, fmt.Errorf("failed to get trim: %w", err)
+	}
+	return count > 0, nil
+}
+
+// Undelete takes trim id out of the trash
+func (r *TrimRepository) Undelete(id int64) error {
+	return NewTrashRepository(r.db).Restore(models.EntityTrim, id)
+}
@@ -0,0 +1,90 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/emirh/car-specs/backend/internal/models"
+)
+
+// ListForExport returns up to limit trims matching the search filters,
+// starting at offset, in the order Search returns them. Every trim comes
+// with its model, brand and generation.
+func (r *TrimRepository) ListForExport(filters map[string]interface{}, offset, limit int) ([]*models.Trim, error) {
+	query := `
+		SELECT
+			t.id, t.generation_id, t.model_id, t.name, t.year, t.start_year, t.end_year, t.is_facelift, t.market,
+			t.engine_type, t.fuel_type, t.displacement_cc, t.cylinders, t.cylinder_layout,
+			t.power_hp, t.power_kw, t.torque_nm, t.engine_code,
+			t.acceleration_0_100, t.top_speed_kmh,
+			t.fuel_consumption_city, t.fuel_consumption_highway, t.fuel_consumption_combined,
+			t.co2_emissions, t.emission_standard,
+			t.transmission_type, t.transmission_code, t.gears, t.drivetrain,
+			t.length_mm, t.width_mm, t.height_mm, t.wheelbase_mm, t.ground_clearance_mm,
+			t.curb_weight_kg, t.gross_weight_kg,
+			t.luggage_capacity_l, t.luggage_capacity_max_l, t.fuel_tank_capacity_l,
+			t.tire_size_front, t.tire_size_rear, t.wheel_size_inches,
+			t.seating_capacity, t.doors, t.image_url, t.msrp_price, t.currency,
+			t.created_at, t.updated_at,
+			m.id, m.brand_id, m.name, m.body_style, m.segment,
+			b.id, b.name, b.country,
+			g.id, g.code, g.name, g.start_year, g.end_year, g.platform
+		FROM trims t
+	`
+	filter := buildSearchFilter(filters, "")
+	query += filter.joins + " WHERE 1=1" + filter.where + SearchOptions{}.orderBy(filter.match) + " LIMIT ? OFFSET ?"
+	args := append(append([]interface{}{}, filter.args...), limit, offset)
+
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list trims for export: %w", err)
+	}
+	defer rows.Close()
+
+	var trims []*models.Trim
+	for rows.Next() {
+		trim := &models.Trim{}
+		model := &models.Model{}
+		brand := &models.Brand{}
+		var genID, genStartYear sql.NullInt64
+		var genCode sql.NullString
+		gen := &models.Generation{}
+
+		err := rows.Scan(
+			&trim.ID, &trim.GenerationID, &trim.ModelID, &trim.Name, &trim.Year, &trim.StartYear, &trim.EndYear, &trim.IsFacelift, &trim.Market,
+			&trim.EngineType, &trim.FuelType, &trim.DisplacementCC, &trim.Cylinders, &trim.CylinderLayout,
+			&trim.PowerHP, &trim.PowerKW, &trim.TorqueNM, &trim.EngineCode,
+			&trim.Acceleration0To100, &trim.TopSpeedKmh,
+			&trim.FuelConsumptionCity, &trim.FuelConsumptionHwy, &trim.FuelConsumptionComb,
+			&trim.CO2Emissions, &trim.EmissionStandard,
+			&trim.TransmissionType, &trim.TransmissionCode, &trim.Gears, &trim.Drivetrain,
+			&trim.LengthMM, &trim.WidthMM, &trim.HeightMM, &trim.WheelbaseMM, &trim.GroundClearanceMM,
+			&trim.CurbWeightKG, &trim.GrossWeightKG,
+			&trim.LuggageCapacityL, &trim.LuggageCapacityMaxL, &trim.FuelTankCapacityL,
+			&trim.TireSizeFront, &trim.TireSizeRear, &trim.WheelSizeInches,
+			&trim.SeatingCapacity, &trim.Doors, &trim.ImageURL, &trim.MSRPPrice, &trim.Currency,
+			&trim.CreatedAt, &trim.UpdatedAt,
+			&model.ID, &model.BrandID, &model.Name, &model.BodyStyle, &model.Segment,
+			&brand.ID, &brand.Name, &brand.Country,
+			&genID, &genCode, &gen.Name, &genStartYear, &gen.EndYear, &gen.Platform,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan trim: %w", err)
+		}
+
+		model.Brand = brand
+		trim.Model = model
+		if genID.Valid {
+			gen.ID = genID.Int64
+			gen.ModelID = model.ID
+			gen.Code = genCode.String
+			gen.StartYear = int(genStartYear.Int64)
+			trim.GenerationObj = gen
+		}
+		trims = append(trims, trim)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list trims for export: %w", err)
+	}
+	return trims, nil
+}
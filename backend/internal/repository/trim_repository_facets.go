@@ -0,0 +1,87 @@
+package repository
+
+import (
+	"fmt"
+
+	"github.com/emirh/car-specs/backend/internal/models"
+)
+
+// facetDimension maps a search filter to the column its facet counts
+type facetDimension struct {
+	filter string
+	column string
+}
+
+// SearchFacets computes the filter options for a search.
+// Each count facet applies every filter except its own, so the sidebar keeps
+// showing alternatives to the selected value; ranges apply all filters.
+func (r *TrimRepository) SearchFacets(filters map[string]interface{}) (*models.SearchFacets, error) {
+	facets := &models.SearchFacets{}
+
+	counts := []struct {
+		dim    facetDimension
+		target *[]models.FacetCount
+	}{
+		{facetDimension{"brand", "b.name"}, &facets.Brands},
+		{facetDimension{"fuel_type", "t.fuel_type"}, &facets.FuelTypes},
+		{facetDimension{"transmission", "t.transmission_type"}, &facets.Transmissions},
+		{facetDimension{"drivetrain", "t.drivetrain"}, &facets.Drivetrains},
+		{facetDimension{"body_style", "m.body_style"}, &facets.BodyStyles},
+	}
+	for _, c := range counts {
+		values, err := r.facetCounts(filters, c.dim)
+		if err != nil {
+			return nil, err
+		}
+		*c.target = values
+	}
+
+	filter := buildSearchFilter(filters, "")
+	query := `
+		SELECT
+			MIN(COALESCE(t.start_year, t.year)), MAX(COALESCE(t.end_year, t.year)),
+			MIN(t.power_hp), MAX(t.power_hp),
+			MIN(t.torque_nm), MAX(t.torque_nm),
+			MIN(t.msrp_price), MAX(t.msrp_price)
+		FROM trims t` + filter.joins + " WHERE 1=1" + filter.where
+
+	err := r.db.QueryRow(query, filter.args...).Scan(
+		&facets.Year.Min, &facets.Year.Max,
+		&facets.PowerHP.Min, &facets.PowerHP.Max,
+		&facets.TorqueNM.Min, &facets.TorqueNM.Max,
+		&facets.Price.Min, &facets.Price.Max,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get facet ranges: %w", err)
+	}
+
+	return facets, nil
+}
+
+func (r *TrimRepository) facetCounts(filters map[string]interface{}, dim facetDimension) ([]models.FacetCount, error) {
+	filter := buildSearchFilter(filters, dim.filter)
+	query := fmt.Sprintf(`
+		SELECT %[1]s, COUNT(*)
+		FROM trims t%[2]s
+		WHERE %[1]s IS NOT NULL AND %[1]s != ''%[3]s
+		GROUP BY %[1]s
+		ORDER BY COUNT(*) DESC, %[1]s
+	`, dim.column, filter.joins, filter.where)
+
+	rows, err := r.db.Query(query, filter.args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get %s facet: %w", dim.filter, err)
+	}
+	defer rows.Close()
+
+	values := []models.FacetCount{}
+	for rows.Next() {
+		var fc models.FacetCount
+		if err := rows.Scan(&fc.Value, &fc.Count); err != nil {
+			return nil, fmt.Errorf("failed to scan %s facet: %w", dim.filter, err)
+		}
+		values = append(values, fc)
+	}
+
+	return values, rows.Err()
+}
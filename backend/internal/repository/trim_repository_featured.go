@@ -31,7 +31,7 @@ func (r *TrimRepository) GetFeaturedTrims(limit int) ([]*models.Trim, error) {
 		LEFT JOIN generations g ON t.generation_id = g.id
 		LEFT JOIN models m ON g.model_id = m.id
 		LEFT JOIN brands b ON m.brand_id = b.id
-		WHERE t.image_url IS NOT NULL AND t.image_url != ''
+		WHERE t.image_url IS NOT NULL AND t.image_url != '' AND t.deleted_at IS NULL
 		ORDER BY RANDOM()
 		LIMIT ?
 	`
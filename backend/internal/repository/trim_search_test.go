@@ -0,0 +1,107 @@
+package repository
+
+import (
+	"database/sql"
+	"path/filepath"
+	"testing"
+
+	"github.com/emirh/car-specs/backend/migrations"
+
+	_ "modernc.org/sqlite"
+)
+
+// openTestDB returns a database migrated to the latest schema
+func openTestDB(t *testing.T) *sql.DB {
+	t.Helper()
+	db, err := sql.Open("sqlite", filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("failed to open db: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	migrator, err := migrations.NewMigrator(db)
+	if err != nil {
+		t.Fatalf("failed to load migrations: %v", err)
+	}
+	if err := migrator.Up(); err != nil {
+		t.Fatalf("failed to migrate: %v", err)
+	}
+	return db
+}
+
+func mustExec(t *testing.T, db *sql.DB, query string, args ...interface{}) {
+	t.Helper()
+	if _, err := db.Exec(query, args...); err != nil {
+		t.Fatalf("%s: %v", query, err)
+	}
+}
+
+// searchIDs returns the IDs of the trims q finds
+func searchIDs(t *testing.T, repo *TrimRepository, q string) []int64 {
+	t.Helper()
+	trims, total, err := repo.Search(map[string]interface{}{"q": q}, SearchOptions{})
+	if err != nil {
+		t.Fatalf("Search(%q): %v", q, err)
+	}
+	if total != len(trims) {
+		t.Errorf("Search(%q) total = %d, returned %d", q, total, len(trims))
+	}
+	ids := []int64{}
+	for _, trim := range trims {
+		ids = append(ids, trim.ID)
+	}
+	return ids
+}
+
+func TestTrimSearchIndexSync(t *testing.T) {
+	db := openTestDB(t)
+	repo := NewTrimRepository(db)
+
+	mustExec(t, db, `INSERT INTO brands (id, name) VALUES (1, 'Volkswagen')`)
+	mustExec(t, db, `INSERT INTO models (id, brand_id, name) VALUES (1, 1, 'Golf'), (2, 1, 'Passat')`)
+	mustExec(t, db, `INSERT INTO generations (id, model_id, code, name) VALUES (1, 1, 'Mk8', 'Eighth'), (2, 2, 'B8', 'Eighth')`)
+	mustExec(t, db, `INSERT INTO trims (id, generation_id, model_id, name, year, engine_code) VALUES (1, 1, 1, '1.5 eTSI', 2021, 'DPBA')`)
+
+	steps := []struct {
+		name   string
+		update string
+		q      string
+		expect int
+	}{
+		{"insert", ``, "golf mk8 1.5 dpba", 1},
+		{"trim renamed", `UPDATE trims SET name = '2.0 TDI', engine_code = 'DTTA' WHERE id = 1`, "golf 2.0 dtta", 1},
+		{"old trim name gone", ``, "1.5", 0},
+		{"brand renamed", `UPDATE brands SET name = 'VW' WHERE id = 1`, "vw golf", 1},
+		{"old brand name gone", ``, "volkswagen", 0},
+		{"model renamed", `UPDATE models SET name = 'Golf Variant' WHERE id = 1`, "variant", 1},
+		{"generation renamed", `UPDATE generations SET code = 'CD1' WHERE id = 1`, "cd1", 1},
+		{"old generation code gone", ``, "mk8", 0},
+		{"moved to another generation", `UPDATE trims SET generation_id = 2, model_id = 2 WHERE id = 1`, "passat b8 2.0", 1},
+		{"in the trash", `UPDATE trims SET deleted_at = CURRENT_TIMESTAMP WHERE id = 1`, "passat", 0},
+		{"restored", `UPDATE trims SET deleted_at = NULL WHERE id = 1`, "passat", 1},
+		{"deleted", `DELETE FROM trims WHERE id = 1`, "passat", 0},
+	}
+
+	for _, step := range steps {
+		if step.update != "" {
+			mustExec(t, db, step.update)
+		}
+		if got := searchIDs(t, repo, step.q); len(got) != step.expect {
+			t.Errorf("%s: Search(%q) found %v; want %d trim(s)", step.name, step.q, got, step.expect)
+		}
+	}
+}
+
+func TestTrimSearchWithoutTerms(t *testing.T) {
+	db := openTestDB(t)
+	repo := NewTrimRepository(db)
+
+	mustExec(t, db, `INSERT INTO brands (id, name) VALUES (1, 'Audi')`)
+	mustExec(t, db, `INSERT INTO models (id, brand_id, name) VALUES (1, 1, 'A3')`)
+	mustExec(t, db, `INSERT INTO generations (id, model_id, code) VALUES (1, 1, '8Y')`)
+	mustExec(t, db, `INSERT INTO trims (generation_id, model_id, name, year) VALUES (1, 1, '35 TFSI', 2021)`)
+
+	if got := searchIDs(t, repo, "..."); len(got) != 0 {
+		t.Errorf("Search(\"...\") found %v; want nothing", got)
+	}
+}
@@ -0,0 +1,74 @@
+package scraper_test
+
+import (
+	"bytes"
+	"context"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/emirh/car-specs/backend/internal/fetch"
+	"github.com/emirh/car-specs/backend/internal/scraper"
+	"github.com/emirh/car-specs/backend/internal/scraper/scrapertest"
+)
+
+// TestAdapters replays every fixture set under testdata against its adapter
+func TestAdapters(t *testing.T) {
+	jobs, err := filepath.Glob(filepath.Join("testdata", "*", scraper.FixtureJobFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	covered := make(map[string]bool)
+	for _, jobFile := range jobs {
+		dir := filepath.Dir(jobFile)
+		t.Run(filepath.Base(dir), func(t *testing.T) {
+			scrapertest.CheckGolden(t, dir)
+		})
+		covered[scrapertest.Job(t, dir).Source] = true
+	}
+
+	for _, name := range scraper.Names() {
+		if !covered[name] {
+			t.Errorf("no fixture set for the %s adapter", name)
+		}
+	}
+}
+
+// TestRecord re-records a fixture set through its replay server and expects
+// the same pages back
+func TestRecord(t *testing.T) {
+	dir := filepath.Join("testdata", "ultimatespecs-audi-a3")
+	job := scrapertest.Job(t, dir)
+	pages := filepath.Join(dir, scraper.FixturePagesDir)
+	recorded := t.TempDir()
+
+	opts := scrapertest.NewServer(t, pages).Options()
+	opts.Transport = &scraper.RecordingTransport{Dir: recorded, Next: opts.Transport}
+	src, err := scraper.New(job.Source, scraper.NewHTTPFetcher(fetch.New(opts)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := scraper.Crawl(context.Background(), src, job, &scraper.Collector{}, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	err = filepath.WalkDir(pages, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		rel, _ := filepath.Rel(pages, path)
+		want, _ := os.ReadFile(path)
+		got, err := os.ReadFile(filepath.Join(recorded, rel))
+		if err != nil {
+			t.Errorf("%s was not recorded", rel)
+		} else if !bytes.Equal(got, want) {
+			t.Errorf("%s was recorded differently", rel)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
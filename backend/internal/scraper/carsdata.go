@@ -0,0 +1,135 @@
+package scraper
+
+import (
+	"context"
+	"net/url"
+	"path"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/emirh/car-specs/backend/internal/enums"
+	"github.com/emirh/car-specs/backend/internal/models"
+)
+
+// CarsData reads cars-data.com. A model page (e.g. /en/fiat-500) links to a
+// page per generation (/en/fiat-500-2020/123), which links to trim pages
+// ending in -specs/{id}; a trim's specs are on its /tech page.
+type CarsData struct {
+	fetcher Fetcher
+}
+
+// NewCarsData returns the cars-data.com adapter
+func NewCarsData(fetcher Fetcher) Source {
+	return &CarsData{fetcher: fetcher}
+}
+
+func (s *CarsData) Name() string       { return "cars-data" }
+func (s *CarsData) SourceType() string { return models.SourceCarsData }
+
+// Generations lists the model's generation pages. cars-data has no chassis
+// codes, so a generation's code is its URL slug (e.g. "fiat-500-2020").
+func (s *CarsData) Generations(ctx context.Context, job Job) ([]Generation, error) {
+	doc, err := s.fetcher.Fetch(ctx, job.URL)
+	if err != nil {
+		return nil, err
+	}
+
+	prefix := "/en/" + slug(job.Brand) + "-" + slug(job.Model) + "-"
+	seen := make(map[string]bool)
+
+	var gens []Generation
+	doc.Find("a").Each(func(_ int, a *goquery.Selection) {
+		href, _ := a.Attr("href")
+		link := resolve(doc, href)
+		u, err := url.Parse(link)
+		if err != nil || !strings.HasPrefix(u.Path, prefix) || strings.Contains(u.Path, "-specs/") || seen[u.Path] {
+			return
+		}
+		seen[u.Path] = true
+
+		name := strings.Join(strings.Fields(a.Text()), " ")
+		code := strings.TrimPrefix(u.Path, "/en/")
+		if i := strings.Index(code, "/"); i >= 0 {
+			code = code[:i]
+		}
+		if name == "" {
+			name = code
+		}
+		start, end := parseYears(name)
+		if start == 0 {
+			start, end = parseYears(code)
+		}
+		gens = append(gens, Generation{
+			Code:      code,
+			Name:      name,
+			StartYear: start,
+			EndYear:   end,
+			Pages:     []Link{{URL: link, Label: name}},
+		})
+	})
+	return gens, nil
+}
+
+// Trims lists the links to trim pages on the generation page
+func (s *CarsData) Trims(ctx context.Context, job Job, gen Generation) ([]TrimRef, error) {
+	seen := make(map[string]bool)
+
+	var refs []TrimRef
+	for _, page := range gen.Pages {
+		doc, err := s.fetcher.Fetch(ctx, page.URL)
+		if err != nil {
+			return nil, err
+		}
+		doc.Find("a").Each(func(_ int, a *goquery.Selection) {
+			href, _ := a.Attr("href")
+			if !strings.Contains(href, "-specs/") {
+				return
+			}
+			link := strings.TrimSuffix(resolve(doc, href), "/tech")
+			if seen[link] {
+				return
+			}
+			seen[link] = true
+			refs = append(refs, TrimRef{Name: strings.Join(strings.Fields(a.Text()), " "), URL: link})
+		})
+	}
+	return refs, nil
+}
+
+// TrimSpecs reads the trim's /tech page. Its heading is the full trim name.
+func (s *CarsData) TrimSpecs(ctx context.Context, job Job, ref TrimRef) (*Trim, error) {
+	techURL := strings.TrimSuffix(ref.URL, "/") + "/tech"
+	doc, err := s.fetcher.Fetch(ctx, techURL)
+	if err != nil {
+		return nil, err
+	}
+
+	specs := specTable(doc, "tr")
+	for label, value := range specs {
+		if value == "" {
+			delete(specs, label)
+		}
+	}
+
+	name := strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(doc.Find("h1").First().Text()), " technical specs"))
+	if name == "" {
+		name = ref.Name
+	}
+	if name == "" {
+		name = path.Base(ref.URL)
+	}
+
+	trim := &Trim{
+		Name:            name,
+		SourceURL:       techURL,
+		Year:            parseTrimYear(name),
+		ProductionYears: firstSpec(specs, "Production years", "Years", "Model year"),
+		PowerHP:         parsePower(firstSpec(specs, "Max. power", "Power", "Horsepower")),
+		Specs:           specs,
+	}
+	if fuel, ok := enums.FuelType.Normalize(firstSpec(specs, "Fuel type", "Fuel")); ok {
+		trim.FuelType = fuel
+	}
+	trim.StartYear, trim.EndYear = parseYears(trim.ProductionYears)
+	return trim, nil
+}
@@ -0,0 +1,108 @@
+package scraper
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// Fixture sets let adapters be tested offline. A set is a directory holding
+// the job it was recorded for (job.json), every page the crawl fetched
+// (pages/<host>/<path>.html) and the trims the adapter extracted from them
+// (golden.json). cmd/scraper -record writes sets; scrapertest replays them.
+const (
+	FixtureJobFile    = "job.json"
+	FixturePagesDir   = "pages"
+	FixtureGoldenFile = "golden.json"
+)
+
+// FixturePath is the file a page is stored in under a pages directory.
+// Every page gets an .html suffix, so /en/fiat-500-2020/123 and its
+// /en/fiat-500-2020/123/tech page can both be stored.
+func FixturePath(dir string, u *url.URL) string {
+	p := strings.TrimSuffix(path.Clean("/"+u.Path), "/")
+	if p == "" {
+		p = "/index"
+	}
+	if u.RawQuery != "" {
+		p += "_" + url.QueryEscape(u.RawQuery)
+	}
+	if !strings.HasSuffix(p, ".html") {
+		p += ".html"
+	}
+	return filepath.Join(dir, u.Hostname(), filepath.FromSlash(p))
+}
+
+// RecordingTransport saves every page fetched with status 200 under Dir
+type RecordingTransport struct {
+	Dir  string
+	Next http.RoundTripper // http.DefaultTransport when nil
+}
+
+func (t *RecordingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	next := t.Next
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	resp, err := next.RoundTrip(req)
+	if err != nil || resp.StatusCode != http.StatusOK {
+		return resp, err
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+
+	file := FixturePath(t.Dir, req.URL)
+	if err := os.MkdirAll(filepath.Dir(file), 0o755); err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(file, body, 0o644); err != nil {
+		return nil, fmt.Errorf("failed to record %s: %w", req.URL, err)
+	}
+	return resp, nil
+}
+
+// CollectedTrim is a trim with the code of the generation it was found in
+type CollectedTrim struct {
+	Generation string `json:"generation"`
+	*Trim
+}
+
+// Collector is a Sink that keeps what a crawl finds in memory
+type Collector struct {
+	Generations []Generation    `json:"generations"`
+	Trims       []CollectedTrim `json:"trims"`
+}
+
+func (c *Collector) Generation(job Job, gen Generation) error {
+	c.Generations = append(c.Generations, gen)
+	return nil
+}
+
+func (c *Collector) Trim(job Job, gen Generation, trim *Trim) error {
+	c.Trims = append(c.Trims, CollectedTrim{Generation: gen.Code, Trim: trim})
+	return nil
+}
+
+// WriteJSON writes v as indented JSON, the format of job.json and golden.json
+func WriteJSON(file string, v interface{}) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(file), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(file, append(data, '\n'), 0o644)
+}
@@ -0,0 +1,132 @@
+package scraper
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Frontier page statuses
+const (
+	PagePending = "pending" // Found on a generation page, not stored yet
+	PageDone    = "done"
+	PageFailed  = "failed"
+)
+
+// FrontierPage is the crawl state of one trim page
+type FrontierPage struct {
+	Status    string    `json:"status"`
+	Error     string    `json:"error,omitempty"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Frontier is the persisted state of a job's crawl: every trim page found
+// and whether it has been stored. It is saved after every change, so a crawl
+// that stops halfway can resume and skip the trims it already stored.
+// A nil *Frontier tracks nothing.
+type Frontier struct {
+	path string
+
+	mu    sync.Mutex
+	Pages map[string]*FrontierPage `json:"pages"`
+}
+
+// OpenFrontier loads the frontier saved at path when resuming, or starts an
+// empty one that replaces it
+func OpenFrontier(path string, resume bool) (*Frontier, error) {
+	f := &Frontier{path: path, Pages: make(map[string]*FrontierPage)}
+	if !resume {
+		return f, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return f, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read frontier: %w", err)
+	}
+	if err := json.Unmarshal(data, f); err != nil {
+		return nil, fmt.Errorf("failed to parse frontier %s: %w", path, err)
+	}
+	if f.Pages == nil {
+		f.Pages = make(map[string]*FrontierPage)
+	}
+	return f, nil
+}
+
+// Done reports whether the page was stored by this or an earlier run
+func (f *Frontier) Done(url string) bool {
+	if f == nil {
+		return false
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	page, ok := f.Pages[url]
+	return ok && page.Status == PageDone
+}
+
+// Add records newly found pages as pending; pages already known keep their status
+func (f *Frontier) Add(urls []string) error {
+	if f == nil {
+		return nil
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, url := range urls {
+		if _, ok := f.Pages[url]; !ok {
+			f.Pages[url] = &FrontierPage{Status: PagePending, UpdatedAt: time.Now().UTC()}
+		}
+	}
+	return f.save()
+}
+
+// Mark sets a page's status; err is kept as the reason a page failed
+func (f *Frontier) Mark(url, status string, err error) error {
+	if f == nil {
+		return nil
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	page := &FrontierPage{Status: status, UpdatedAt: time.Now().UTC()}
+	if err != nil {
+		page.Error = err.Error()
+	}
+	f.Pages[url] = page
+	return f.save()
+}
+
+// Counts returns the number of pages per status
+func (f *Frontier) Counts() map[string]int {
+	counts := make(map[string]int)
+	if f == nil {
+		return counts
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, page := range f.Pages {
+		counts[page.Status]++
+	}
+	return counts
+}
+
+// save writes the frontier through a temporary file, so a crash mid-write
+// leaves the previous state
+func (f *Frontier) save() error {
+	data, err := json.MarshalIndent(f, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(f.path), 0o755); err != nil {
+		return err
+	}
+	tmp := f.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return fmt.Errorf("failed to save frontier: %w", err)
+	}
+	return os.Rename(tmp, f.path)
+}
@@ -0,0 +1,50 @@
+package scraper
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// LoadJobs reads a JSON array of jobs and checks each names a known source
+// and a brand, model and URL
+func LoadJobs(path string) ([]Job, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read jobs file: %w", err)
+	}
+
+	var jobs []Job
+	if err := json.Unmarshal(data, &jobs); err != nil {
+		return nil, fmt.Errorf("failed to parse jobs file %s: %w", path, err)
+	}
+	for i, job := range jobs {
+		if err := job.Validate(); err != nil {
+			return nil, fmt.Errorf("%s: job %d: %w", path, i+1, err)
+		}
+	}
+	return jobs, nil
+}
+
+// Validate checks the job can be crawled
+func (j Job) Validate() error {
+	if _, ok := adapters[j.Source]; !ok {
+		return fmt.Errorf("unknown source %q", j.Source)
+	}
+	if j.Brand == "" || j.Model == "" {
+		return fmt.Errorf("brand and model are required")
+	}
+	if j.URL == "" {
+		return fmt.Errorf("url is required")
+	}
+	if j.MaxTrims < 0 {
+		return fmt.Errorf("max_trims must not be negative")
+	}
+	return nil
+}
+
+// Key names the job in file names, e.g. "ultimatespecs-audi-a3" for its
+// fixture set and crawl frontier
+func (j Job) Key() string {
+	return j.Source + "-" + slug(j.Brand) + "-" + slug(j.Model)
+}
@@ -0,0 +1,146 @@
+package scraper
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/emirh/car-specs/backend/internal/enums"
+)
+
+var (
+	yearPattern         = regexp.MustCompile(`\b(19|20)\d{2}\b`)
+	yearRangePattern    = regexp.MustCompile(`(\d{4})\s*-\s*(\d{4}|Present)`)
+	singleYearPattern   = regexp.MustCompile(`(\d{4})`)
+	generationPattern   = regexp.MustCompile(`Type\s+(\w+)`)
+	horsepowerPattern   = regexp.MustCompile(`(\d+)\s*PS`)
+	powerAnyUnitPattern = regexp.MustCompile(`(?i)(\d+)\s*(?:PS|hp|bhp|pk)\b`)
+)
+
+// parseTrimYear finds a model year in a trim name, e.g. "A3 35 TFSI 2021"
+func parseTrimYear(name string) int {
+	if m := yearPattern.FindString(name); m != "" {
+		y, _ := strconv.Atoi(m)
+		return y
+	}
+	return 0
+}
+
+// parseGenerationCode takes "8Y" out of "Audi A3 Type 8Y (2020 - Present)"
+func parseGenerationCode(s string) string {
+	if m := generationPattern.FindStringSubmatch(s); len(m) > 1 {
+		return m[1]
+	}
+	return "Unknown"
+}
+
+// parseYears reads "(2013 - 2016)", "2013 - Present" or a single "2024".
+// The end year is nil when the range is open or missing.
+func parseYears(s string) (int, *int) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0, nil
+	}
+
+	if m := yearRangePattern.FindStringSubmatch(s); len(m) > 1 {
+		start, _ := strconv.Atoi(m[1])
+		if m[2] == "Present" {
+			return start, nil
+		}
+		end, _ := strconv.Atoi(m[2])
+		return start, &end
+	}
+
+	if m := singleYearPattern.FindStringSubmatch(s); len(m) > 1 {
+		start, _ := strconv.Atoi(m[1])
+		return start, nil
+	}
+	return 0, nil
+}
+
+// parseBodyStyle maps a body style heading such as "A3 Sportback" to its
+// canonical value, or "" when it names no known body style. The model name
+// is dropped first; failing that, each word is tried ("Sportback e-tron").
+func parseBodyStyle(heading, model string) string {
+	raw := strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(heading), model))
+	if style, ok := enums.BodyStyle.Normalize(raw); ok {
+		return style
+	}
+	for _, word := range strings.Fields(raw) {
+		if style, ok := enums.BodyStyle.Normalize(word); ok {
+			return style
+		}
+	}
+	return ""
+}
+
+// parseFuelType maps a fuel type spec to its canonical value, or "" when it
+// is missing or not recognised
+func parseFuelType(raw string) string {
+	if fuel, ok := enums.FuelType.Normalize(raw); ok {
+		return fuel
+	}
+	return ""
+}
+
+// parseHP reads the PS figure of "110 kW / 150 PS"
+func parseHP(val string) int {
+	if m := horsepowerPattern.FindStringSubmatch(val); len(m) > 1 {
+		v, _ := strconv.Atoi(m[1])
+		return v
+	}
+	return 0
+}
+
+// parsePower reads a horsepower figure in PS, hp, bhp or pk
+func parsePower(val string) int {
+	if m := powerAnyUnitPattern.FindStringSubmatch(val); len(m) > 1 {
+		v, _ := strconv.Atoi(m[1])
+		return v
+	}
+	return 0
+}
+
+// firstSpec returns the value of the first label present in specs
+func firstSpec(specs map[string]string, labels ...string) string {
+	for _, label := range labels {
+		if v := specs[label]; v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// specTable collects the label/value pairs of rows whose first two cells are
+// td (rows headed by a th are skipped). Labels lose their trailing colon.
+func specTable(doc *goquery.Document, rows string) map[string]string {
+	specs := make(map[string]string)
+	doc.Find(rows).Each(func(_ int, tr *goquery.Selection) {
+		label := strings.TrimSpace(tr.ChildrenFiltered("td:nth-child(1)").Text())
+		value := strings.TrimSpace(tr.ChildrenFiltered("td:nth-child(2)").Text())
+		label = strings.TrimSpace(strings.TrimSuffix(label, ":"))
+		if label != "" {
+			specs[label] = value
+		}
+	})
+	return specs
+}
+
+// resolve makes a link absolute against the page it was found on
+func resolve(doc *goquery.Document, href string) string {
+	if doc.Url == nil {
+		return href
+	}
+	u, err := doc.Url.Parse(href)
+	if err != nil {
+		return href
+	}
+	return u.String()
+}
+
+// slug lower-cases a name and joins its words with dashes, the way the
+// sites build their URLs ("Alfa Romeo" -> "alfa-romeo")
+func slug(name string) string {
+	return strings.ToLower(strings.Join(strings.Fields(name), "-"))
+}
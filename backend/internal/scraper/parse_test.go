@@ -0,0 +1,95 @@
+package scraper
+
+import "testing"
+
+func TestParseYears(t *testing.T) {
+	tests := []struct {
+		in    string
+		start int
+		end   int // 0 for none
+	}{
+		{"Audi A3 Type 8V (2012 - 2020)", 2012, 2020},
+		{"2020 - Present", 2020, 0},
+		{"(2024)", 2024, 0},
+		{"2014 -", 2014, 0},
+		{"", 0, 0},
+	}
+	for _, tc := range tests {
+		start, end := parseYears(tc.in)
+		gotEnd := 0
+		if end != nil {
+			gotEnd = *end
+		}
+		if start != tc.start || gotEnd != tc.end {
+			t.Errorf("parseYears(%q) = %d, %d; want %d, %d", tc.in, start, gotEnd, tc.start, tc.end)
+		}
+	}
+}
+
+func TestParsePower(t *testing.T) {
+	tests := []struct {
+		in        string
+		hp, anyHP int
+	}{
+		{"150 PS or 148 bhp or 110 kW @ 5000-6000 rpm", 150, 150},
+		{"110 kW / 150PS", 150, 150},
+		{"63 kW (85 hp)", 0, 85},
+		{"110 kW", 0, 0},
+		{"", 0, 0},
+	}
+	for _, tc := range tests {
+		if got := parseHP(tc.in); got != tc.hp {
+			t.Errorf("parseHP(%q) = %d; want %d", tc.in, got, tc.hp)
+		}
+		if got := parsePower(tc.in); got != tc.anyHP {
+			t.Errorf("parsePower(%q) = %d; want %d", tc.in, got, tc.anyHP)
+		}
+	}
+}
+
+func TestParseNames(t *testing.T) {
+	codes := map[string]string{
+		"Audi A3 Type 8Y (2020 - Present)": "8Y",
+		"Audi A3 Type 8P":                  "8P",
+		"Audi A3 (2020 - Present)":         "Unknown",
+	}
+	for in, expect := range codes {
+		if got := parseGenerationCode(in); got != expect {
+			t.Errorf("parseGenerationCode(%q) = %s; want %s", in, got, expect)
+		}
+	}
+
+	fuels := map[string]string{
+		"Plug-in Hybrid":  "plug_in_hybrid",
+		"Diesel":          "diesel",
+		"Gasoline":        "petrol",
+		"Hydrogen/Petrol": "",
+		"":                "",
+	}
+	for in, expect := range fuels {
+		if got := parseFuelType(in); got != expect {
+			t.Errorf("parseFuelType(%q) = %s; want %s", in, got, expect)
+		}
+	}
+
+	bodyStyles := map[string]string{
+		"A3 Sportback":        "hatchback",
+		"A3 Sedan":            "sedan",
+		"A3 Cabriolet":        "convertible",
+		"A3 Sportback e-tron": "hatchback",
+		"A3":                  "",
+		"A3 3-door":           "",
+	}
+	for in, expect := range bodyStyles {
+		if got := parseBodyStyle(in, "A3"); got != expect {
+			t.Errorf("parseBodyStyle(%q) = %s; want %s", in, got, expect)
+		}
+	}
+
+	if got := parseTrimYear("A3 Sportback 1.4 TFSI 2016"); got != 2016 {
+		t.Errorf("parseTrimYear = %d; want 2016", got)
+	}
+	if got := parseTrimYear("A3 Sportback 35 TFSI"); got != 0 {
+		t.Errorf("parseTrimYear without a year = %d; want 0", got)
+	}
+}
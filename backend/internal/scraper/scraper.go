@@ -1,299 +1,265 @@
+// Package scraper crawls spec sites for the brand/model trees listed in a job file.
+//
+// Each site is a Source adapter that knows how to list a model's generations,
+// list a generation's trims and parse one trim page. Crawl walks that tree for
+// a Job and hands every generation and trim to a Sink, which decides how they
+// are stored. Adding a model to the catalogue means adding a job, not code.
 package scraper
 
 import (
-	"database/sql"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
 	"fmt"
 	"log"
-	"net/http"
+	"sort"
 	"strings"
-	"time"
 
 	"github.com/PuerkitoBio/goquery"
+	"github.com/emirh/car-specs/backend/internal/fetch"
 )
 
-const baseURL = "https://www.cars-data.com"
-
-// Map brands to their URL partials
-var brands = map[string]string{
-	"Fiat": "fiat",
-	"BMW":  "bmw",
-	"Ford": "ford",
+// Job is one brand/model tree to crawl from one source
+type Job struct {
+	// Source is the adapter name, e.g. "ultimatespecs"
+	Source string `json:"source"`
+	Brand  string `json:"brand"`
+	Model  string `json:"model"`
+	// URL is the model page the adapter lists generations from
+	URL string `json:"url"`
+	// Generations limits the crawl to these generation codes (default all)
+	Generations []string `json:"generations,omitempty"`
+	// MaxTrims caps the trims crawled per generation (0 means no cap)
+	MaxTrims int `json:"max_trims,omitempty"`
 }
 
-// Helper to make requests with User-Agent
-func makeRequest(url string) (*http.Response, error) {
-	client := &http.Client{
-		Timeout: 30 * time.Second,
-	}
-	req, err := http.NewRequest("GET", url, nil)
-	if err != nil {
-		return nil, err
-	}
-	// Use a common browser User-Agent
-	req.Header.Set("User-Agent", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36")
-	return client.Do(req)
+// Generation is one generation found on a model page
+type Generation struct {
+	Code      string `json:"code"`
+	Name      string `json:"name"`
+	StartYear int    `json:"start_year"`
+	EndYear   *int   `json:"end_year"`
+	// Pages are the generation's pages that link to its trims, e.g. one per body style
+	Pages []Link `json:"pages"`
 }
 
-func ScrapeAll(db *sql.DB) error {
-	for brand, slug := range brands {
-		log.Printf("Scraping brand: %s...", brand)
-		if err := scrapeBrand(db, brand, slug); err != nil {
-			log.Printf("Error scraping %s: %v", brand, err)
-		}
-	}
-	log.Println("Scraping finished.")
-	return nil
+// Link is a page and the label it was linked with
+type Link struct {
+	URL   string `json:"url"`
+	Label string `json:"label"`
 }
 
-func scrapeBrand(db *sql.DB, brandName, slug string) error {
-	url := fmt.Sprintf("%s/en/%s", baseURL, slug)
-	resp, err := makeRequest(url)
-	if err != nil {
-		return err
-	}
-	defer resp.Body.Close()
-
-	doc, err := goquery.NewDocumentFromReader(resp.Body)
-	if err != nil {
-		return err
-	}
-
-	// Find Models
-	// cars-data lists models in section.models .col-4 or similar.
-	// We'll look for links inside the main content area that look like /en/brand/model
-	var modelLinks []string
-	seenLinks := make(map[string]bool)
+// TrimRef is a trim found on a generation page, before its own page is parsed
+type TrimRef struct {
+	Name string
+	URL  string
+	// Canonical values the listing already tells (empty when it doesn't)
+	FuelType  string
+	BodyStyle string
+}
 
-	// Selector based on observation: links often contain /en/{brand}/
-	pathPrefix := fmt.Sprintf("/en/%s/", slug)
+// Trim is a parsed trim page
+type Trim struct {
+	Name string `json:"name"`
+	// SourceURL is the page the values were read from
+	SourceURL string `json:"source_url"`
+	Year      int    `json:"year"`
+	StartYear int    `json:"start_year"`
+	EndYear   *int   `json:"end_year"`
+	// ProductionYears is the raw text the years were parsed from
+	ProductionYears string `json:"production_years"`
+	FuelType        string `json:"fuel_type"`
+	BodyStyle       string `json:"body_style"`
+	PowerHP         int    `json:"power_hp"`
+	// Specs holds every label/value pair on the page, as printed
+	Specs map[string]string `json:"specs"`
+	// Notes says how a value was derived when it isn't printed on the page, by trim field
+	Notes map[string]string `json:"notes,omitempty"`
+}
 
-	doc.Find("a").Each(func(i int, s *goquery.Selection) {
-		href, exists := s.Attr("href")
-		if exists && strings.Contains(href, pathPrefix) && !seenLinks[href] {
-			// Filter out junk
-			if !strings.Contains(href, "#") &&
-				!strings.Contains(href, "fastest") &&
-				!strings.Contains(href, "sport") &&
-				!strings.Contains(href, "consumption") {
-				modelLinks = append(modelLinks, href)
-				seenLinks[href] = true
-			}
-		}
-	})
+// Fingerprint hashes the values extracted from the page. Two scrapes of a page
+// that yield the same values have the same fingerprint, whatever else (ads,
+// timestamps, markup) changed on it.
+func (t *Trim) Fingerprint() string {
+	values := *t
+	values.SourceURL = ""
+	values.Notes = nil
+	data, _ := json.Marshal(values) // Specs keys are sorted by encoding/json
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
 
-	// Limit to top 5 for demo speed
-	if len(modelLinks) > 5 {
-		modelLinks = modelLinks[:5]
-	}
+// Source is a site adapter
+type Source interface {
+	// Name is the adapter name jobs refer to
+	Name() string
+	// SourceType is the models.Source* type values from this site are cited as
+	SourceType() string
+	Generations(ctx context.Context, job Job) ([]Generation, error)
+	Trims(ctx context.Context, job Job, gen Generation) ([]TrimRef, error)
+	TrimSpecs(ctx context.Context, job Job, ref TrimRef) (*Trim, error)
+}
 
-	for _, link := range modelLinks {
-		// Ensure absolute URL
-		if !strings.HasPrefix(link, "http") {
-			link = baseURL + link
-		}
-		if err := scrapeModel(db, brandName, link); err != nil {
-			log.Printf("Error scraping model %s: %v", link, err)
-		}
-		time.Sleep(1 * time.Second)
-	}
-	return nil
+// Fetcher downloads and parses pages. The returned document's Url is the
+// address the page was finally served from, so relative links resolve.
+type Fetcher interface {
+	Fetch(ctx context.Context, url string) (*goquery.Document, error)
 }
 
-func scrapeModel(db *sql.DB, brandName, modelURL string) error {
-	resp, err := makeRequest(modelURL)
-	if err != nil {
-		return err
-	}
-	defer resp.Body.Close()
+var adapters = map[string]func(Fetcher) Source{
+	"ultimatespecs": NewUltimateSpecs,
+	"cars-data":     NewCarsData,
+}
 
-	doc, err := goquery.NewDocumentFromReader(resp.Body)
-	if err != nil {
-		return err
+// New returns the adapter with the given name
+func New(name string, fetcher Fetcher) (Source, error) {
+	newSource, ok := adapters[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown source %q (known: %s)", name, strings.Join(Names(), ", "))
 	}
+	return newSource(fetcher), nil
+}
 
-	// Model Name from h1
-	rawModelName := doc.Find("h1").Text()
-	modelName := strings.ReplaceAll(rawModelName, " models", "")
-	modelName = strings.TrimSpace(modelName)
-
-	// Find Image
-	imageURL := ""
-	doc.Find("img").Each(func(i int, s *goquery.Selection) {
-		src, exists := s.Attr("src")
-		if exists && imageURL == "" {
-			// Filter out junk images
-			if strings.Contains(src, "/design/") || strings.Contains(src, "logo") || strings.Contains(src, "icon") || strings.Contains(src, "search") {
-				return
-			}
-			// Must contain cars-data
-			if strings.Contains(src, "cars-data") || strings.HasPrefix(src, "http") {
-				imageURL = src
-			} else if strings.HasPrefix(src, "/") {
-				// Relative URL
-				imageURL = baseURL + src
-			}
-		}
-	})
-
-	// Find Generations/Years
-	// Links look like /en/{brand}-{model}-{year}/{id}
-	// We will look for these links in the content
-	var genLinks []string
-	seenGens := make(map[string]bool)
-
-	doc.Find("a").Each(func(i int, s *goquery.Selection) {
-		href, exists := s.Attr("href")
-		// heuristic: /en/{slug}-
-		// e.g. /en/fiat-500e-2020/
-		if exists && strings.Contains(href, "/en/"+strings.ToLower(brandName)+"-") && !seenGens[href] {
-			genLinks = append(genLinks, href)
-			seenGens[href] = true
-		}
-	})
-
-	// Limit 1 generation
-	if len(genLinks) > 1 {
-		genLinks = genLinks[:1]
+// Names lists the adapter names, sorted
+func Names() []string {
+	names := make([]string, 0, len(adapters))
+	for name := range adapters {
+		names = append(names, name)
 	}
+	sort.Strings(names)
+	return names
+}
 
-	for _, link := range genLinks {
-		if !strings.HasPrefix(link, "http") {
-			link = baseURL + link
-		}
-
-		// Insert Vehicle (using Generation as a holder for now)
-		vehicleID, err := insertVehicle(db, brandName, modelName, "Generation "+link, imageURL, link)
-		if err != nil {
-			return err
-		}
+// HTTPFetcher fetches pages through the shared fetch layer, so every adapter
+// gets its rate limits, robots.txt checks, cache and retries
+type HTTPFetcher struct {
+	Client *fetch.Client
+}
 
-		if err := scrapeGeneration(db, vehicleID, link); err != nil {
-			log.Printf("Error scraping generation %s: %v", link, err)
-		}
-		time.Sleep(1 * time.Second)
-	}
-	return nil
+// NewHTTPFetcher returns a fetcher using the client
+func NewHTTPFetcher(client *fetch.Client) *HTTPFetcher {
+	return &HTTPFetcher{Client: client}
 }
 
-func scrapeGeneration(db *sql.DB, vehicleID int64, genURL string) error {
-	resp, err := makeRequest(genURL)
+// Fetch downloads a page and parses it
+func (f *HTTPFetcher) Fetch(ctx context.Context, url string) (*goquery.Document, error) {
+	resp, err := f.Client.Get(ctx, url)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	defer resp.Body.Close()
-
-	doc, err := goquery.NewDocumentFromReader(resp.Body)
+	doc, err := goquery.NewDocumentFromReader(bytes.NewReader(resp.Body))
 	if err != nil {
-		return err
+		return nil, fmt.Errorf("failed to parse %s: %w", url, err)
 	}
+	doc.Url = resp.URL
+	return doc, nil
+}
 
-	// Find Trims
-	// Links to "...-specs/ID"
-	var trimLinks []string
-	seenTrims := make(map[string]bool)
+// Sink stores what a crawl finds
+type Sink interface {
+	Generation(job Job, gen Generation) error
+	Trim(job Job, gen Generation, trim *Trim) error
+}
 
-	doc.Find("a").Each(func(i int, s *goquery.Selection) {
-		href, exists := s.Attr("href")
-		if exists && strings.Contains(href, "-specs/") && !seenTrims[href] {
-			trimLinks = append(trimLinks, href)
-			seenTrims[href] = true
-		}
-	})
+// Stats counts what a crawl did
+type Stats struct {
+	Generations int
+	Trims       int
+	// Skipped counts trims the frontier says an earlier run stored
+	Skipped int
+	// Failed counts generations and trims that could not be fetched, parsed or stored
+	Failed int
+}
 
-	// Limit 3 trims
-	if len(trimLinks) > 3 {
-		trimLinks = trimLinks[:3]
+// Crawl walks a job's generations and trims and hands them to the sink.
+// Only a model page that cannot be listed fails the crawl; a generation or
+// trim that fails is logged and counted, and the crawl moves on. Trims are
+// tracked in the frontier (nil for none), and those it has done are skipped.
+func Crawl(ctx context.Context, src Source, job Job, sink Sink, frontier *Frontier) (Stats, error) {
+	var stats Stats
+	gens, err := src.Generations(ctx, job)
+	if err != nil {
+		return stats, fmt.Errorf("failed to list generations of %s %s: %w", job.Brand, job.Model, err)
 	}
 
-	for _, link := range trimLinks {
-		if !strings.HasPrefix(link, "http") {
-			link = baseURL + link
+	for _, gen := range gens {
+		if !job.wants(gen.Code) {
+			continue
+		}
+		if err := ctx.Err(); err != nil {
+			return stats, err
 		}
+		if err := sink.Generation(job, gen); err != nil {
+			log.Printf("Error saving generation %s: %v", gen.Code, err)
+			stats.Failed++
+			continue
+		}
+		stats.Generations++
 
-		// Use the link text as trim name if available?
-		// We'll scrape the trim page for the real name
-		trimName := "Trim " + link
-		trimID, err := insertTrim(db, vehicleID, trimName, link)
+		refs, err := src.Trims(ctx, job, gen)
 		if err != nil {
-			return err
+			log.Printf("Error listing trims of generation %s: %v", gen.Code, err)
+			stats.Failed++
+			continue
 		}
-
-		// Scrape Tech Specs
-		techURL := link + "/tech"
-		if err := scrapeTrimSpecs(db, trimID, techURL); err != nil {
-			log.Printf("Error scraping trim specs %s: %v", techURL, err)
+		if job.MaxTrims > 0 && len(refs) > job.MaxTrims {
+			refs = refs[:job.MaxTrims]
+		}
+		urls := make([]string, len(refs))
+		for i, ref := range refs {
+			urls[i] = ref.URL
+		}
+		if err := frontier.Add(urls); err != nil {
+			return stats, err
 		}
-		time.Sleep(1 * time.Second)
-	}
-	return nil
-}
-
-func scrapeTrimSpecs(db *sql.DB, trimID int64, techURL string) error {
-	resp, err := makeRequest(techURL)
-	if err != nil {
-		return err
-	}
-	defer resp.Body.Close()
-
-	doc, err := goquery.NewDocumentFromReader(resp.Body)
-	if err != nil {
-		return err
-	}
-
-	// Update trim name
-	h1 := doc.Find("h1").Text()
-	h1 = strings.ReplaceAll(h1, " technical specs", "")
-	_, err = db.Exec("UPDATE trims SET name = ? WHERE id = ?", h1, trimID)
-	if err != nil {
-		log.Printf("Failed to update trim name: %v", err)
-	}
-
-	// Parse Specs
-	// cars-data often uses tables or DLs. Let's try finding dl dt dd
-	// Structure seems to be sections with H2, then DL or Table.
 
-	// Try finding all tr
-	doc.Find("tr").Each(func(i int, s *goquery.Selection) {
-		// key is first td, val is second td? or th/td
-		var key, val string
-		s.Find("td").Each(func(j int, td *goquery.Selection) {
-			if j == 0 {
-				key = strings.TrimSpace(td.Text())
-			} else if j == 1 {
-				val = strings.TrimSpace(td.Text())
+		for _, ref := range refs {
+			if err := ctx.Err(); err != nil {
+				return stats, err
+			}
+			if frontier.Done(ref.URL) {
+				stats.Skipped++
+				continue
 			}
-		})
 
-		if key != "" && val != "" {
-			// Category? Can't easy infer without efficient traversal
-			insertSpec(db, trimID, "Tech", key, val)
+			trim, err := src.TrimSpecs(ctx, job, ref)
+			if err == nil {
+				if err = sink.Trim(job, gen, trim); err != nil {
+					err = fmt.Errorf("failed to save: %w", err)
+				}
+			}
+			if err != nil {
+				log.Printf("Error scraping trim %s (%s): %v", ref.Name, ref.URL, err)
+				stats.Failed++
+				if ctx.Err() != nil {
+					// Interrupted: leave the page pending for the next run
+					return stats, ctx.Err()
+				}
+				if err := frontier.Mark(ref.URL, PageFailed, err); err != nil {
+					return stats, err
+				}
+				continue
+			}
+			if err := frontier.Mark(ref.URL, PageDone, nil); err != nil {
+				return stats, err
+			}
+			stats.Trims++
 		}
-	})
-
-	return nil
-}
-
-// -- Database Helpers --
-
-func insertVehicle(db *sql.DB, brand, model, generation, imageURL, link string) (int64, error) {
-	res, err := db.Exec("INSERT INTO vehicles (brand, model, generation, image_url, link) VALUES (?, ?, ?, ?, ?)", brand, model, generation, imageURL, link)
-	if err != nil {
-		return 0, err
 	}
-	return res.LastInsertId()
+	return stats, nil
 }
 
-func insertTrim(db *sql.DB, vehicleID int64, name, link string) (int64, error) {
-	res, err := db.Exec("INSERT INTO trims (vehicle_id, name, link) VALUES (?, ?, ?)", vehicleID, name, link)
-	if err != nil {
-		return 0, err
+// wants reports whether the job crawls the generation with this code
+func (j Job) wants(code string) bool {
+	if len(j.Generations) == 0 {
+		return true
 	}
-	return res.LastInsertId()
-}
-
-func insertSpec(db *sql.DB, trimID int64, category, name, value string) {
-	_, err := db.Exec("INSERT INTO specs (trim_id, category, name, value) VALUES (?, ?, ?, ?)", trimID, category, name, value)
-	if err != nil {
-		log.Printf("Error inserting spec: %v", err)
+	for _, c := range j.Generations {
+		if strings.EqualFold(c, code) {
+			return true
+		}
 	}
+	return false
 }
@@ -0,0 +1,190 @@
+package scraper
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// fakeSource serves a fixed tree; trims named "broken" fail to parse
+type fakeSource struct {
+	gens  []Generation
+	trims map[string][]TrimRef
+}
+
+func (s *fakeSource) Name() string       { return "fake" }
+func (s *fakeSource) SourceType() string { return "fake" }
+
+func (s *fakeSource) Generations(ctx context.Context, job Job) ([]Generation, error) {
+	return s.gens, nil
+}
+
+func (s *fakeSource) Trims(ctx context.Context, job Job, gen Generation) ([]TrimRef, error) {
+	return s.trims[gen.Code], nil
+}
+
+func (s *fakeSource) TrimSpecs(ctx context.Context, job Job, ref TrimRef) (*Trim, error) {
+	if ref.Name == "broken" {
+		return nil, errors.New("no spec table")
+	}
+	return &Trim{Name: ref.Name, SourceURL: ref.URL}, nil
+}
+
+// recordingSink remembers what it was handed as "code/trim" paths
+type recordingSink struct {
+	saved []string
+}
+
+func (s *recordingSink) Generation(job Job, gen Generation) error {
+	s.saved = append(s.saved, gen.Code)
+	return nil
+}
+
+func (s *recordingSink) Trim(job Job, gen Generation, trim *Trim) error {
+	s.saved = append(s.saved, gen.Code+"/"+trim.Name)
+	return nil
+}
+
+func TestCrawl(t *testing.T) {
+	src := &fakeSource{
+		gens: []Generation{{Code: "8V"}, {Code: "8Y"}},
+		trims: map[string][]TrimRef{
+			"8V": {{Name: "1.4 TFSI"}, {Name: "broken"}, {Name: "2.0 TDI"}},
+			"8Y": {{Name: "35 TFSI"}, {Name: "40 TFSIe"}},
+		},
+	}
+
+	tests := []struct {
+		name   string
+		job    Job
+		expect []string
+		stats  Stats
+	}{
+		{"all", Job{}, []string{"8V", "8V/1.4 TFSI", "8V/2.0 TDI", "8Y", "8Y/35 TFSI", "8Y/40 TFSIe"}, Stats{Generations: 2, Trims: 4, Failed: 1}},
+		{"generation filter", Job{Generations: []string{"8y"}}, []string{"8Y", "8Y/35 TFSI", "8Y/40 TFSIe"}, Stats{Generations: 1, Trims: 2}},
+		{"trim cap", Job{MaxTrims: 1}, []string{"8V", "8V/1.4 TFSI", "8Y", "8Y/35 TFSI"}, Stats{Generations: 2, Trims: 2}},
+	}
+
+	for _, tc := range tests {
+		sink := &recordingSink{}
+		stats, err := Crawl(context.Background(), src, tc.job, sink, nil)
+		if err != nil {
+			t.Fatalf("%s: %v", tc.name, err)
+		}
+		if !reflect.DeepEqual(sink.saved, tc.expect) {
+			t.Errorf("%s: saved %v; want %v", tc.name, sink.saved, tc.expect)
+		}
+		if stats != tc.stats {
+			t.Errorf("%s: stats %+v; want %+v", tc.name, stats, tc.stats)
+		}
+	}
+}
+
+func TestCrawlResume(t *testing.T) {
+	src := &fakeSource{
+		gens:  []Generation{{Code: "8V"}},
+		trims: map[string][]TrimRef{"8V": {{Name: "1.4 TFSI", URL: "/1"}, {Name: "broken", URL: "/2"}, {Name: "2.0 TDI", URL: "/3"}}},
+	}
+	path := filepath.Join(t.TempDir(), "frontier.json")
+
+	frontier, err := OpenFrontier(path, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Crawl(context.Background(), src, Job{}, &recordingSink{}, frontier); err != nil {
+		t.Fatal(err)
+	}
+
+	// The failed trim is retried, the stored ones skipped
+	resumed, err := OpenFrontier(path, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	counts := resumed.Counts()
+	if counts[PageDone] != 2 || counts[PageFailed] != 1 {
+		t.Errorf("saved frontier counts %v; want 2 done and 1 failed", counts)
+	}
+	sink := &recordingSink{}
+	stats, err := Crawl(context.Background(), src, Job{}, sink, resumed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expect := (Stats{Generations: 1, Skipped: 2, Failed: 1}); stats != expect {
+		t.Errorf("resumed stats %+v; want %+v", stats, expect)
+	}
+
+	// Without -resume the frontier starts over
+	fresh, err := OpenFrontier(path, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fresh.Done("/1") {
+		t.Error("a fresh frontier remembers an earlier run")
+	}
+}
+
+func TestFixturePath(t *testing.T) {
+	tests := map[string]string{
+		"https://www.ultimatespecs.com/car-specs/Audi/8Y/A3-35-TFSI.html": "pages/www.ultimatespecs.com/car-specs/Audi/8Y/A3-35-TFSI.html",
+		"https://www.cars-data.com/en/fiat-500-2015/5678":                 "pages/www.cars-data.com/en/fiat-500-2015/5678.html",
+		"https://www.cars-data.com/en/fiat-500-2015/5678/tech":            "pages/www.cars-data.com/en/fiat-500-2015/5678/tech.html",
+		"https://www.cars-data.com/":                                      "pages/www.cars-data.com/index.html",
+		"https://www.cars-data.com/en/search?q=500":                       "pages/www.cars-data.com/en/search_q%3D500.html",
+	}
+	for raw, expect := range tests {
+		u, _ := url.Parse(raw)
+		if got := filepath.ToSlash(FixturePath("pages", u)); got != expect {
+			t.Errorf("FixturePath(%s) = %s; want %s", raw, got, expect)
+		}
+	}
+}
+
+func TestJobValidate(t *testing.T) {
+	valid := Job{Source: "ultimatespecs", Brand: "Audi", Model: "A3", URL: "https://www.ultimatespecs.com/car-specs/Audi-models/Audi-A3"}
+	if err := valid.Validate(); err != nil {
+		t.Errorf("valid job: %v", err)
+	}
+
+	invalid := map[string]Job{
+		"unknown source": {Source: "wikipedia", Brand: "Audi", Model: "A3", URL: valid.URL},
+		"no model":       {Source: "cars-data", Brand: "Fiat", URL: "https://www.cars-data.com/en/fiat-500"},
+		"no url":         {Source: "ultimatespecs", Brand: "Audi", Model: "A3"},
+	}
+	for name, job := range invalid {
+		if err := job.Validate(); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestTrimFingerprint(t *testing.T) {
+	page := func() *Trim {
+		return &Trim{
+			Name: "35 TFSI", SourceURL: "https://example.com/a", Year: 2020, PowerHP: 150,
+			Specs: map[string]string{"Top speed": "224 km/h", "Torque": "250 Nm"},
+			Notes: map[string]string{"fuel_type": "parsed from trim name"},
+		}
+	}
+
+	base := page().Fingerprint()
+	moved := page()
+	moved.SourceURL = "https://example.com/b"
+	moved.Notes = nil
+	if moved.Fingerprint() != base {
+		t.Error("fingerprint depends on the URL or notes")
+	}
+
+	changed := page()
+	changed.Specs["Torque"] = "270 Nm"
+	if changed.Fingerprint() == base {
+		t.Error("fingerprint ignores a changed spec")
+	}
+	changed = page()
+	changed.PowerHP = 163
+	if changed.Fingerprint() == base {
+		t.Error("fingerprint ignores a changed power figure")
+	}
+}
@@ -0,0 +1,182 @@
+// Package scrapertest replays recorded fixture sets so scraper adapters can be
+// tested without the live sites.
+//
+// A fixture set (see scraper.FixtureJobFile) is served from an httptest
+// server. Every request the adapter makes, whatever its host, is answered
+// from the set's pages, and the trims the crawl extracts are compared with
+// the set's golden JSON. Run `go test ./internal/scraper -update` to accept
+// new output after an intended change.
+package scrapertest
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/emirh/car-specs/backend/internal/fetch"
+	"github.com/emirh/car-specs/backend/internal/scraper"
+)
+
+var update = flag.Bool("update", false, "rewrite golden files with the current output")
+
+// Server serves a pages directory as if it were the recorded sites
+type Server struct {
+	*httptest.Server
+	t   testing.TB
+	dir string
+}
+
+// NewServer starts a server for the pages directory, closed when the test ends.
+// A page that was not recorded answers 404 and fails the test.
+func NewServer(t testing.TB, pagesDir string) *Server {
+	t.Helper()
+	s := &Server{t: t, dir: pagesDir}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serve))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *Server) serve(w http.ResponseWriter, r *http.Request) {
+	page := *r.URL
+	page.Host = r.Host
+	body, err := os.ReadFile(scraper.FixturePath(s.dir, &page))
+	if err != nil {
+		s.t.Errorf("no fixture for %s%s", r.Host, r.URL.RequestURI())
+		http.NotFound(w, r)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write(body)
+}
+
+// Fetcher returns a fetcher that sends every request to the server
+func (s *Server) Fetcher() *scraper.HTTPFetcher {
+	return scraper.NewHTTPFetcher(fetch.New(s.Options()))
+}
+
+// Options are fetch options that send every request to the server, without
+// delays, cache or robots.txt checks
+func (s *Server) Options() fetch.Options {
+	target, _ := url.Parse(s.URL)
+	return fetch.Options{Transport: &replayTransport{target: target, next: s.Client().Transport}}
+}
+
+// replayTransport points requests at the fixture server. The Host header keeps
+// the recorded host, and the response reports the original URL, so links and
+// source URLs look exactly as they did live.
+type replayTransport struct {
+	target *url.URL
+	next   http.RoundTripper
+}
+
+func (t *replayTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	out := req.Clone(req.Context())
+	out.URL.Scheme = t.target.Scheme
+	out.URL.Host = t.target.Host
+	out.Host = req.URL.Host
+
+	resp, err := t.next.RoundTrip(out)
+	if err != nil {
+		return nil, err
+	}
+	resp.Request = req
+	return resp, nil
+}
+
+// Job reads the job a fixture set was recorded for
+func Job(t testing.TB, dir string) scraper.Job {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join(dir, scraper.FixtureJobFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var job scraper.Job
+	if err := json.Unmarshal(data, &job); err != nil {
+		t.Fatalf("%s: %v", scraper.FixtureJobFile, err)
+	}
+	if err := job.Validate(); err != nil {
+		t.Fatalf("%s: %v", scraper.FixtureJobFile, err)
+	}
+	return job
+}
+
+// Crawl replays a fixture set's job and returns what the adapter extracted
+func Crawl(t testing.TB, dir string) *scraper.Collector {
+	t.Helper()
+	job := Job(t, dir)
+	server := NewServer(t, filepath.Join(dir, scraper.FixturePagesDir))
+	src, err := scraper.New(job.Source, server.Fetcher())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	collected := &scraper.Collector{}
+	stats, err := scraper.Crawl(context.Background(), src, job, collected, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.Failed > 0 {
+		t.Errorf("%d generations or trims failed", stats.Failed)
+	}
+	return collected
+}
+
+// CheckGolden replays a fixture set and compares the result with its golden
+// file. An empty crawl fails even when the golden file agrees, since that is
+// what a site layout change looks like.
+func CheckGolden(t *testing.T, dir string) {
+	t.Helper()
+	collected := Crawl(t, dir)
+	if len(collected.Trims) == 0 {
+		t.Errorf("no trims extracted from %s", dir)
+	}
+
+	golden := filepath.Join(dir, scraper.FixtureGoldenFile)
+	if *update {
+		if err := scraper.WriteJSON(golden, collected); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+
+	got, err := json.MarshalIndent(collected, "", "  ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, err := os.ReadFile(golden)
+	if err != nil {
+		t.Fatalf("%v (run with -update to create it)", err)
+	}
+	got = append(got, '\n')
+	if !bytes.Equal(got, want) {
+		t.Errorf("output differs from %s (run with -update to accept it):\n%s", golden, firstDifference(string(want), string(got)))
+	}
+}
+
+// firstDifference shows the first line where two outputs part ways
+func firstDifference(want, got string) string {
+	wantLines := strings.Split(want, "\n")
+	gotLines := strings.Split(got, "\n")
+	for i := 0; i < len(wantLines) || i < len(gotLines); i++ {
+		var w, g string
+		if i < len(wantLines) {
+			w = wantLines[i]
+		}
+		if i < len(gotLines) {
+			g = gotLines[i]
+		}
+		if w != g {
+			return fmt.Sprintf("line %d:\n  want: %s\n  got:  %s", i+1, w, g)
+		}
+	}
+	return ""
+}
@@ -0,0 +1,111 @@
+package scraper
+
+import (
+	"context"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/emirh/car-specs/backend/internal/models"
+)
+
+// UltimateSpecs reads ultimatespecs.com. A model page (e.g.
+// /car-specs/Audi-models/Audi-A3) has one div.home_models_line per
+// generation, linking to a page per body style that lists the engines.
+type UltimateSpecs struct {
+	fetcher Fetcher
+}
+
+// NewUltimateSpecs returns the ultimatespecs.com adapter
+func NewUltimateSpecs(fetcher Fetcher) Source {
+	return &UltimateSpecs{fetcher: fetcher}
+}
+
+func (s *UltimateSpecs) Name() string       { return "ultimatespecs" }
+func (s *UltimateSpecs) SourceType() string { return models.SourceUltimateSpecs }
+
+// Generations reads the generation blocks of the model page, e.g.
+// "Audi A3 Type 8Y (2020 - Present)" with its body style links
+func (s *UltimateSpecs) Generations(ctx context.Context, job Job) ([]Generation, error) {
+	doc, err := s.fetcher.Fetch(ctx, job.URL)
+	if err != nil {
+		return nil, err
+	}
+
+	var gens []Generation
+	doc.Find("div.home_models_line").Each(func(_ int, block *goquery.Selection) {
+		title := strings.TrimSpace(block.Find("h2").First().Text())
+		if title == "" {
+			return
+		}
+		start, end := parseYears(title)
+		gen := Generation{Code: parseGenerationCode(title), Name: title, StartYear: start, EndYear: end}
+
+		block.Find("a").Each(func(_ int, a *goquery.Selection) {
+			href, _ := a.Attr("href")
+			bodyStyle := strings.TrimSpace(a.Find("h3").Text())
+			if href != "" && bodyStyle != "" {
+				gen.Pages = append(gen.Pages, Link{URL: resolve(doc, href), Label: bodyStyle})
+			}
+		})
+		gens = append(gens, gen)
+	})
+	return gens, nil
+}
+
+// Trims lists the engine pages linked from each body style page
+func (s *UltimateSpecs) Trims(ctx context.Context, job Job, gen Generation) ([]TrimRef, error) {
+	brandPath := "car-specs/" + strings.Join(strings.Fields(job.Brand), "-")
+	seen := make(map[string]bool)
+
+	var refs []TrimRef
+	for _, page := range gen.Pages {
+		doc, err := s.fetcher.Fetch(ctx, page.URL)
+		if err != nil {
+			return nil, err
+		}
+		doc.Find("div.content-wrapper a").Each(func(_ int, a *goquery.Selection) {
+			href, _ := a.Attr("href")
+			text := strings.TrimSpace(a.Text())
+			// Skip the "Versions" and "View more" navigation links
+			if !strings.Contains(href, brandPath) || strings.Contains(text, "Versions") || strings.Contains(text, "View more") {
+				return
+			}
+			link := resolve(doc, href)
+			if !strings.HasSuffix(link, ".html") || seen[link] {
+				return
+			}
+			seen[link] = true
+			refs = append(refs, TrimRef{
+				Name:      text,
+				URL:       link,
+				BodyStyle: parseBodyStyle(page.Label, job.Model),
+			})
+		})
+	}
+	return refs, nil
+}
+
+// TrimSpecs reads the label/value table of an engine page
+func (s *UltimateSpecs) TrimSpecs(ctx context.Context, job Job, ref TrimRef) (*Trim, error) {
+	doc, err := s.fetcher.Fetch(ctx, ref.URL)
+	if err != nil {
+		return nil, err
+	}
+	specs := specTable(doc, "table tr")
+
+	trim := &Trim{
+		Name:            ref.Name,
+		SourceURL:       ref.URL,
+		Year:            parseTrimYear(ref.Name),
+		ProductionYears: firstSpec(specs, "Production years", "Years"),
+		FuelType:        parseFuelType(firstSpec(specs, "Fuel type", "Fuel")),
+		BodyStyle:       ref.BodyStyle,
+		PowerHP:         parseHP(firstSpec(specs, "Horsepower", "Power")),
+		Specs:           specs,
+	}
+	if trim.BodyStyle != "" {
+		trim.Notes = map[string]string{"body_style": "parsed from body style heading"}
+	}
+	trim.StartYear, trim.EndYear = parseYears(trim.ProductionYears)
+	return trim, nil
+}
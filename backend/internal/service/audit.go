@@ -0,0 +1,175 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os/user"
+
+	"github.com/emirh/car-specs/backend/internal/models"
+	"github.com/emirh/car-specs/backend/internal/repository"
+)
+
+// AuditService reads the audit log of catalogue writes
+type AuditService struct {
+	repo *repository.AuditRepository
+}
+
+func NewAuditService(repo *repository.AuditRepository) *AuditService {
+	return &AuditService{repo: repo}
+}
+
+// CommandActor is the actor of writes made by a command-line tool: the user
+// running it, through source (e.g. "importer:csv")
+func CommandActor(source string) models.Actor {
+	name := "system"
+	if u, err := user.Current(); err == nil && u.Username != "" {
+		name = u.Username
+	}
+	return models.Actor{Name: name, Source: source}
+}
+
+// Default and maximum number of entries ListEntries returns
+const (
+	defaultAuditLimit = 100
+	maxAuditLimit     = 1000
+)
+
+// ListEntries returns audit entries matching filter, newest first
+func (s *AuditService) ListEntries(filter repository.AuditFilter) ([]*models.AuditEntry, error) {
+	if filter.EntityType != "" {
+		if err := validEntityType(filter.EntityType); err != nil {
+			return nil, err
+		}
+	}
+	if filter.Limit <= 0 {
+		filter.Limit = defaultAuditLimit
+	}
+	if filter.Limit > maxAuditLimit {
+		filter.Limit = maxAuditLimit
+	}
+	return s.repo.List(filter)
+}
+
+// TrimHistory returns every recorded write of a trim, newest first. Deleted
+// trims keep their history.
+func (s *TrimService) TrimHistory(id int64) ([]*models.AuditEntry, error) {
+	return s.auditRepo.List(repository.AuditFilter{EntityType: models.EntityTrim, EntityID: id})
+}
+
+// RestoreTrimVersion writes back the version of trim id that audit entry
+// entryID recorded: the trim as it was after that write, or before it for a
+// delete. A trim in the trash is taken out of it; a purged one is created
+// again under its ID. The restored fields are cited to source, like an edit.
+func (s *TrimService) RestoreTrimVersion(id, entryID int64, source *models.SourceDocument) (*models.Trim, error) {
+	entry, err := s.auditRepo.GetByID(entryID)
+	if err != nil {
+		return nil, err
+	}
+	if entry.EntityType != models.EntityTrim || entry.EntityID != id {
+		return nil, fmt.Errorf("audit entry %d is not a version of trim %d", entryID, id)
+	}
+	version := entry.After
+	if entry.Action == models.AuditDelete {
+		version = entry.Before
+	}
+	if string(version) == "null" {
+		return nil, fmt.Errorf("audit entry %d has no version to restore", entryID)
+	}
+
+	// Whether the trim exists, is in the trash or was purged is read in the
+	// transaction that restores it
+	err = s.inTx(func(tx *TrimService) error {
+		existing, err := tx.trimRepo.GetByID(id, false)
+		if errors.Is(err, repository.ErrTrimNotFound) {
+			deleted, err := tx.trimRepo.InTrash(id)
+			if err != nil {
+				return err
+			}
+			if deleted {
+				return tx.undeleteTrimVersion(id, version, source)
+			}
+
+			restored := &models.Trim{}
+			if err := json.Unmarshal(version, restored); err != nil {
+				return fmt.Errorf("failed to decode audit entry %d: %w", entryID, err)
+			}
+			restored.ID = id
+			fields, err := setTrimFields(restored)
+			if err != nil {
+				return err
+			}
+			return tx.createTrim(restored, source, fields, models.AuditRestore)
+		}
+		if err != nil {
+			return err
+		}
+
+		patch, err := restorePatch(existing, version)
+		if err != nil {
+			return err
+		}
+		if len(patch) == 0 {
+			// Already at that version
+			return nil
+		}
+		_, err = tx.patchTrim(id, patch, source, models.AuditRestore)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.GetTrim(id, true)
+}
+
+// undeleteTrimVersion takes trim id out of the trash and writes version back.
+// s must be bound to a transaction (see inTx).
+func (s *TrimService) undeleteTrimVersion(id int64, version json.RawMessage, source *models.SourceDocument) error {
+	if err := s.trimRepo.Undelete(id); err != nil {
+		return err
+	}
+	existing, err := s.trimRepo.GetByID(id, false)
+	if err != nil {
+		return err
+	}
+	patch, err := restorePatch(existing, version)
+	if err != nil {
+		return err
+	}
+	if len(patch) > 0 {
+		_, err = s.patchTrim(id, patch, source, models.AuditRestore)
+		return err
+	}
+	return s.audit(models.AuditRestore, id, nil, existing)
+}
+
+// restorePatch is the merge patch that turns current into version: every
+// editable field whose value differs, with null for fields version lacks
+func restorePatch(current *models.Trim, version json.RawMessage) (map[string]json.RawMessage, error) {
+	encoded, err := json.Marshal(current)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode trim: %w", err)
+	}
+	var now, then map[string]json.RawMessage
+	if err := json.Unmarshal(encoded, &now); err != nil {
+		return nil, fmt.Errorf("failed to encode trim: %w", err)
+	}
+	if err := json.Unmarshal(version, &then); err != nil {
+		return nil, fmt.Errorf("failed to decode trim version: %w", err)
+	}
+
+	patch := make(map[string]json.RawMessage)
+	for _, fields := range []map[string]json.RawMessage{now, then} {
+		for name := range fields {
+			if readOnlyTrimFields[name] || !trimJSONFields[name] || sameJSON(now[name], then[name]) {
+				continue
+			}
+			value, ok := then[name]
+			if !ok {
+				value = json.RawMessage("null")
+			}
+			patch[name] = value
+		}
+	}
+	return patch, nil
+}
@@ -0,0 +1,205 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/emirh/car-specs/backend/internal/merge"
+	"github.com/emirh/car-specs/backend/internal/models"
+	"github.com/emirh/car-specs/backend/internal/repository"
+)
+
+func TestTrimHistory(t *testing.T) {
+	s, _ := newTestTrimService(t)
+	editor := models.Actor{Name: "ayse", Source: models.ActorSourceAPI}
+
+	if _, err := s.As(editor).PatchTrim(1, patchBody(t, `{"power_hp": 163}`), nil); err != nil {
+		t.Fatalf("PatchTrim() error = %v", err)
+	}
+	if err := s.As(editor).DeleteTrim(1); err != nil {
+		t.Fatalf("DeleteTrim() error = %v", err)
+	}
+
+	history, err := s.TrimHistory(1)
+	if err != nil {
+		t.Fatalf("TrimHistory() error = %v", err)
+	}
+	if len(history) != 2 {
+		t.Fatalf("history has %d entries, want 2", len(history))
+	}
+	deleted, patched := history[0], history[1]
+	if deleted.Action != models.AuditDelete || string(deleted.After) != "null" {
+		t.Errorf("newest entry = %s with after %s, want a delete", deleted.Action, deleted.After)
+	}
+	if patched.Action != models.AuditUpdate || patched.Actor != "ayse" || patched.Source != models.ActorSourceAPI {
+		t.Errorf("patch entry = %+v", patched)
+	}
+	if len(patched.Changes) != 1 || patched.Changes[0].Field != "power_hp" ||
+		string(patched.Changes[0].Before) != "150" || string(patched.Changes[0].After) != "163" {
+		t.Errorf("patch changes = %+v, want power_hp 150 -> 163", patched.Changes)
+	}
+}
+
+func TestRestoreTrimVersion(t *testing.T) {
+	s, db := newTestTrimService(t)
+
+	if _, err := s.PatchTrim(1, patchBody(t, `{"power_hp": 163, "torque_nm": 250}`), nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.PatchTrim(1, patchBody(t, `{"power_hp": 170, "torque_nm": null}`), nil); err != nil {
+		t.Fatal(err)
+	}
+	history, err := s.TrimHistory(1)
+	if err != nil || len(history) != 2 {
+		t.Fatalf("TrimHistory() = %d entries, %v", len(history), err)
+	}
+
+	restored, err := s.RestoreTrimVersion(1, history[1].ID, nil)
+	if err != nil {
+		t.Fatalf("RestoreTrimVersion() error = %v", err)
+	}
+	if restored.PowerHP == nil || *restored.PowerHP != 163 || restored.TorqueNM == nil || *restored.TorqueNM != 250 {
+		t.Errorf("restored power_hp = %v, torque_nm = %v, want 163 and 250", restored.PowerHP, restored.TorqueNM)
+	}
+	history, _ = s.TrimHistory(1)
+	if history[0].Action != models.AuditRestore {
+		t.Errorf("newest entry = %s, want restore", history[0].Action)
+	}
+
+	// Restoring the version a delete removed takes the trim out of the trash
+	if err := s.DeleteTrim(1); err != nil {
+		t.Fatal(err)
+	}
+	history, _ = s.TrimHistory(1)
+	restored, err = s.RestoreTrimVersion(1, history[0].ID, nil)
+	if err != nil {
+		t.Fatalf("RestoreTrimVersion() of a delete error = %v", err)
+	}
+	if restored.ID != 1 || restored.PowerHP == nil || *restored.PowerHP != 163 {
+		t.Errorf("undeleted trim = id %d, power_hp %v", restored.ID, restored.PowerHP)
+	}
+
+	// ...and creates it again once it has been purged
+	if err := s.DeleteTrim(1); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec(`DELETE FROM trims WHERE id = 1`); err != nil {
+		t.Fatal(err)
+	}
+	history, _ = s.TrimHistory(1)
+	restored, err = s.RestoreTrimVersion(1, history[0].ID, nil)
+	if err != nil {
+		t.Fatalf("RestoreTrimVersion() of a purged trim error = %v", err)
+	}
+	if restored.ID != 1 || restored.PowerHP == nil || *restored.PowerHP != 163 {
+		t.Errorf("recreated trim = id %d, power_hp %v", restored.ID, restored.PowerHP)
+	}
+
+	if _, err := s.RestoreTrimVersion(2, history[0].ID, nil); err == nil {
+		t.Error("restoring another trim's entry succeeded")
+	}
+}
+
+func TestRestoreFromTrashAudited(t *testing.T) {
+	s, db := newTestTrimService(t)
+	trash := NewTrashService(repository.NewTrashRepository(db), repository.NewAuditRepository(db), repository.NewBrandRepository(db),
+		repository.NewModelRepository(db), repository.NewGenerationRepository(db), repository.NewTrimRepository(db))
+	if err := s.DeleteTrim(1); err != nil {
+		t.Fatalf("DeleteTrim() error = %v", err)
+	}
+
+	// A restore that cannot be audited is not made
+	if _, err := db.Exec(`ALTER TABLE audit_log RENAME TO audit_log_away`); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := trash.Restore(models.EntityTrim, 1); err == nil {
+		t.Fatal("Restore() without an audit log succeeded")
+	}
+	if _, err := db.Exec(`ALTER TABLE audit_log_away RENAME TO audit_log`); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.GetTrim(1, false); err == nil {
+		t.Error("trim was restored without an audit entry")
+	}
+
+	if _, err := trash.Restore(models.EntityTrim, 1); err != nil {
+		t.Fatalf("Restore() error = %v", err)
+	}
+	history, err := s.TrimHistory(1)
+	if err != nil {
+		t.Fatalf("TrimHistory() error = %v", err)
+	}
+	if len(history) != 2 || history[0].Action != models.AuditRestore {
+		t.Errorf("history = %+v, want the restore on top", history)
+	}
+}
+
+// TestCatalogueWritesNeedAudit checks that brand, model and generation writes
+// are not stored when their audit entry can't be
+func TestCatalogueWritesNeedAudit(t *testing.T) {
+	_, db := newTestTrimService(t)
+	auditRepo := repository.NewAuditRepository(db)
+	brands := NewBrandService(repository.NewBrandRepository(db), auditRepo)
+	modelService := NewModelService(repository.NewModelRepository(db), repository.NewBrandRepository(db), auditRepo)
+	generations := NewGenerationService(repository.NewGenerationRepository(db), repository.NewModelRepository(db), auditRepo)
+
+	if _, err := db.Exec(`ALTER TABLE audit_log RENAME TO audit_log_away`); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := brands.CreateBrand("BMW", nil, nil); err == nil {
+		t.Error("CreateBrand() without an audit log succeeded")
+	}
+	if _, err := modelService.UpdateModel(1, 1, "A3 Sportback", nil, nil); err == nil {
+		t.Error("UpdateModel() without an audit log succeeded")
+	}
+	if err := generations.DeleteGeneration(2, false); err == nil {
+		t.Error("DeleteGeneration() without an audit log succeeded")
+	}
+	if _, err := db.Exec(`ALTER TABLE audit_log_away RENAME TO audit_log`); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := brands.GetBrandByName("BMW"); err == nil {
+		t.Error("brand was created without an audit entry")
+	}
+	if model, err := modelService.GetModel(1, false); err != nil || model.Name != "A3" {
+		t.Errorf("model = %+v, %v, want A3 unchanged", model, err)
+	}
+	if _, err := generations.GetGeneration(2); err != nil {
+		t.Errorf("generation was deleted without an audit entry: %v", err)
+	}
+}
+
+// TestIngestNeedsAudit checks that an ingest whose merged values can't be
+// audited leaves neither the values nor the citation behind
+func TestIngestNeedsAudit(t *testing.T) {
+	s, db := newTestTrimService(t)
+	provenance := repository.NewProvenanceRepository(db)
+	merger := NewMergeService(s, provenance, repository.NewMergeConflictRepository(db), merge.DefaultPolicy())
+	power := 163
+	source := &models.SourceDocument{URL: "https://example.com/a3-35-tfsi", SourceType: models.SourceManual}
+
+	if _, err := db.Exec(`ALTER TABLE audit_log RENAME TO audit_log_away`); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := merger.Ingest(1, &models.Trim{Name: "35 TFSI", PowerHP: &power}, source); err == nil {
+		t.Error("Ingest() without an audit log succeeded")
+	}
+	if _, err := db.Exec(`ALTER TABLE audit_log_away RENAME TO audit_log`); err != nil {
+		t.Fatal(err)
+	}
+
+	trim, err := s.GetTrim(1, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if trim.PowerHP == nil || *trim.PowerHP != 150 {
+		t.Errorf("power_hp = %v, want 150 unchanged", trim.PowerHP)
+	}
+	citations, err := provenance.ListFieldCitations(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(citations["power_hp"]) != 0 {
+		t.Errorf("power_hp citations = %+v, want none", citations["power_hp"])
+	}
+}
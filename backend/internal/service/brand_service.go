@@ -1,6 +1,7 @@
 package service
 
 import (
+	"errors"
 	"fmt"
 
 	"github.com/emirh/car-specs/backend/internal/models"
@@ -8,11 +9,38 @@ import (
 )
 
 type BrandService struct {
-	repo *repository.BrandRepository
+	repo      *repository.BrandRepository
+	auditRepo *repository.AuditRepository
+	// actor is recorded in the audit log as the author of writes
+	actor models.Actor
 }
 
-func NewBrandService(repo *repository.BrandRepository) *BrandService {
-	return &BrandService{repo: repo}
+func NewBrandService(repo *repository.BrandRepository, auditRepo *repository.AuditRepository) *BrandService {
+	return &BrandService{repo: repo, auditRepo: auditRepo}
+}
+
+// As returns a copy of s whose writes are audited as made by actor
+func (s *BrandService) As(actor models.Actor) *BrandService {
+	audited := *s
+	audited.actor = actor
+	return &audited
+}
+
+// withTx returns a copy of s whose writes go through tx
+func (s *BrandService) withTx(tx repository.Querier) *BrandService {
+	return &BrandService{
+		repo:      repository.NewBrandRepository(tx),
+		auditRepo: repository.NewAuditRepository(tx),
+		actor:     s.actor,
+	}
+}
+
+// inTx runs fn in one transaction, so a brand write and its audit entry are
+// stored together or not at all
+func (s *BrandService) inTx(fn func(tx *BrandService) error) error {
+	return s.repo.Transact(func(tx repository.Querier) error {
+		return fn(s.withTx(tx))
+	})
 }
 
 // CreateBrand creates a new brand with validation
@@ -22,22 +50,33 @@ func (s *BrandService) CreateBrand(name string, country, logoURL *string) (*mode
 		return nil, fmt.Errorf("brand name is required")
 	}
 
-	// Check if brand already exists
-	existing, _ := s.repo.GetByName(name)
-	if existing != nil {
-		return nil, fmt.Errorf("brand '%s' already exists", name)
-	}
-
 	brand := &models.Brand{
 		Name:    name,
 		Country: country,
 		LogoURL: logoURL,
 	}
 
-	if err := s.repo.Create(brand); err != nil {
-		return nil, fmt.Errorf("failed to create brand: %w", err)
+	err := s.inTx(func(tx *BrandService) error {
+		// Check if brand already exists
+		if _, err := tx.repo.GetByName(name); err == nil {
+			return fmt.Errorf("brand '%s' already exists", name)
+		} else if !errors.Is(err, repository.ErrBrandNotFound) {
+			return err
+		}
+		if deleted, err := tx.repo.InTrash(name); err != nil {
+			return err
+		} else if deleted {
+			return fmt.Errorf("brand '%s' is in the trash, restore it instead", name)
+		}
+
+		if err := tx.repo.Create(brand); err != nil {
+			return fmt.Errorf("failed to create brand: %w", err)
+		}
+		return tx.auditRepo.Record(tx.actor, models.EntityBrand, brand.ID, models.AuditCreate, nil, brand)
+	})
+	if err != nil {
+		return nil, err
 	}
-
 	return brand, nil
 }
 
@@ -75,37 +114,46 @@ func (s *BrandService) UpdateBrand(id int64, name string, country, logoURL *stri
 		return nil, fmt.Errorf("brand name is required")
 	}
 
-	// Check if brand exists
-	brand, err := s.repo.GetByID(id)
+	var brand *models.Brand
+	err := s.inTx(func(tx *BrandService) error {
+		// Check if brand exists
+		var err error
+		if brand, err = tx.repo.GetByID(id); err != nil {
+			return fmt.Errorf("brand not found: %w", err)
+		}
+
+		before := *brand
+
+		// Update fields
+		brand.Name = name
+		brand.Country = country
+		brand.LogoURL = logoURL
+
+		if err := tx.repo.Update(brand); err != nil {
+			return fmt.Errorf("failed to update brand: %w", err)
+		}
+		return tx.auditRepo.Record(tx.actor, models.EntityBrand, id, models.AuditUpdate, &before, brand)
+	})
 	if err != nil {
-		return nil, fmt.Errorf("brand not found: %w", err)
-	}
-
-	// Update fields
-	brand.Name = name
-	brand.Country = country
-	brand.LogoURL = logoURL
-
-	if err := s.repo.Update(brand); err != nil {
-		return nil, fmt.Errorf("failed to update brand: %w", err)
+		return nil, err
 	}
-
 	return brand, nil
 }
 
-// DeleteBrand deletes a brand
+// DeleteBrand moves a brand to the trash with its models, generations and trims
 func (s *BrandService) DeleteBrand(id int64) error {
-	// Check if brand exists
-	_, err := s.repo.GetByID(id)
-	if err != nil {
-		return fmt.Errorf("brand not found: %w", err)
-	}
-
-	if err := s.repo.Delete(id); err != nil {
-		return fmt.Errorf("failed to delete brand: %w", err)
-	}
-
-	return nil
+	return s.inTx(func(tx *BrandService) error {
+		// Check if brand exists
+		brand, err := tx.repo.GetByID(id)
+		if err != nil {
+			return fmt.Errorf("brand not found: %w", err)
+		}
+
+		if err := tx.repo.Delete(id); err != nil {
+			return fmt.Errorf("failed to delete brand: %w", err)
+		}
+		return tx.auditRepo.Record(tx.actor, models.EntityBrand, id, models.AuditDelete, brand, nil)
+	})
 }
 
 // GetOrCreateBrand gets an existing brand or creates it if it doesn't exist
@@ -115,6 +163,9 @@ func (s *BrandService) GetOrCreateBrand(name string, country *string) (*models.B
 	if err == nil {
 		return brand, nil
 	}
+	if !errors.Is(err, repository.ErrBrandNotFound) {
+		return nil, err
+	}
 
 	// Create new brand
 	return s.CreateBrand(name, country, nil)
@@ -0,0 +1,205 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/emirh/car-specs/backend/internal/models"
+	"github.com/emirh/car-specs/backend/internal/repository"
+)
+
+// ChangeService tracks what re-scraped pages change in stored trims: page
+// fingerprints let unchanged pages be skipped, and each field a changed page
+// reports differently becomes a change record that is applied or rejected
+type ChangeService struct {
+	changeRepo     *repository.ChangeRepository
+	trimService    *TrimService
+	provenanceRepo *repository.ProvenanceRepository
+}
+
+func NewChangeService(changeRepo *repository.ChangeRepository, trimService *TrimService, provenanceRepo *repository.ProvenanceRepository) *ChangeService {
+	return &ChangeService{
+		changeRepo:     changeRepo,
+		trimService:    trimService,
+		provenanceRepo: provenanceRepo,
+	}
+}
+
+// As returns a copy of s whose applied changes are audited as made by actor
+func (s *ChangeService) As(actor models.Actor) *ChangeService {
+	audited := *s
+	audited.trimService = s.trimService.As(actor)
+	return &audited
+}
+
+// PageUnchanged reports whether fingerprint matches the one stored for the
+// page at url, recording the check when it does
+func (s *ChangeService) PageUnchanged(url, fingerprint string) (bool, error) {
+	stored, err := s.changeRepo.GetFingerprint(url)
+	if err != nil || stored == nil || stored.Fingerprint != fingerprint {
+		return false, err
+	}
+	return true, s.changeRepo.TouchFingerprint(url)
+}
+
+// RecordPage stores the fingerprint of a page that was scraped into trimID
+func (s *ChangeService) RecordPage(url string, trimID int64, sourceType, fingerprint string) error {
+	return s.changeRepo.SaveFingerprint(url, trimID, sourceType, fingerprint)
+}
+
+// DetectChanges compares the values source reports for a trim (field JSON
+// name -> value) with the stored ones and records a pending change for every
+// field that differs. Fields left out or reported as null are not compared:
+// a page that stops listing a value is no reason to clear it.
+func (s *ChangeService) DetectChanges(trimID int64, values map[string]interface{}, source *models.SourceDocument) ([]*models.TrimChange, error) {
+	trim, err := s.trimService.trimRepo.GetByID(trimID, false)
+	if err != nil {
+		return nil, err
+	}
+	stored, err := trimFieldValues(trim)
+	if err != nil {
+		return nil, err
+	}
+	recorded, err := s.changeRepo.List(repository.ChangeFilter{TrimID: trimID})
+	if err != nil {
+		return nil, err
+	}
+
+	fields := make([]string, 0, len(values))
+	for field, value := range values {
+		if value == nil {
+			continue
+		}
+		if !citableTrimField(field) {
+			return nil, fmt.Errorf("unknown trim field: %s", field)
+		}
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+
+	if err := s.provenanceRepo.EnsureSource(source); err != nil {
+		return nil, fmt.Errorf("failed to record sources: %w", err)
+	}
+
+	changes := []*models.TrimChange{}
+	for _, field := range fields {
+		value, err := json.Marshal(values[field])
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode %s: %w", field, err)
+		}
+		if string(value) == "null" || sameJSON(value, stored[field]) || alreadyRecorded(recorded, field, value, source.ID) {
+			continue
+		}
+
+		old := stored[field]
+		if old == nil {
+			old = json.RawMessage("null")
+		}
+		change := &models.TrimChange{
+			TrimID:   trimID,
+			TrimName: trim.Name,
+			Field:    field,
+			OldValue: old,
+			NewValue: value,
+			Source:   *source,
+		}
+		if err := s.changeRepo.Record(change); err != nil {
+			return nil, err
+		}
+		changes = append(changes, change)
+	}
+	return changes, nil
+}
+
+// alreadyRecorded reports whether source's change of field to value is
+// already pending, or was rejected and shouldn't be raised again
+func alreadyRecorded(changes []*models.TrimChange, field string, value json.RawMessage, sourceID int64) bool {
+	for _, c := range changes {
+		if c.Status != models.ChangePending && c.Status != models.ChangeRejected {
+			continue
+		}
+		if c.Field == field && c.Source.ID == sourceID && sameJSON(c.NewValue, value) {
+			return true
+		}
+	}
+	return false
+}
+
+// SettleChanges marks the pending changes of a trim whose new value the trim
+// now holds (say, because a merge took it) as applied and returns how many
+func (s *ChangeService) SettleChanges(trimID int64) (int, error) {
+	trim, err := s.trimService.trimRepo.GetByID(trimID, false)
+	if err != nil {
+		return 0, err
+	}
+	stored, err := trimFieldValues(trim)
+	if err != nil {
+		return 0, err
+	}
+	pending, err := s.changeRepo.List(repository.ChangeFilter{TrimID: trimID, Status: models.ChangePending})
+	if err != nil {
+		return 0, err
+	}
+
+	settled := 0
+	for _, c := range pending {
+		if !sameJSON(c.NewValue, stored[c.Field]) {
+			continue
+		}
+		if err := s.changeRepo.Resolve(c.ID, models.ChangeApplied, ""); err != nil {
+			return settled, err
+		}
+		settled++
+	}
+	return settled, nil
+}
+
+// ListChanges returns trim changes matching filter. Status is pending,
+// applied, rejected, superseded or empty for all.
+func (s *ChangeService) ListChanges(filter repository.ChangeFilter) ([]*models.TrimChange, error) {
+	switch filter.Status {
+	case "", models.ChangePending, models.ChangeApplied, models.ChangeRejected, models.ChangeSuperseded:
+	default:
+		return nil, fmt.Errorf("invalid status: %s", filter.Status)
+	}
+	return s.changeRepo.List(filter)
+}
+
+// ResolveChange settles a pending change. Applying it writes the new value to
+// the trim, citing both the page it came from and source (the editor);
+// rejecting it keeps the stored value.
+func (s *ChangeService) ResolveChange(id int64, status, note string, source *models.SourceDocument) (*models.TrimChange, error) {
+	change, err := s.changeRepo.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if change.Status != models.ChangePending {
+		return nil, fmt.Errorf("change %d is already %s", id, change.Status)
+	}
+
+	switch status {
+	case models.ChangeRejected:
+		if err := s.changeRepo.Resolve(id, status, note); err != nil {
+			return nil, err
+		}
+	case models.ChangeApplied:
+		err := s.trimService.trimRepo.Transact(func(tx repository.Querier) error {
+			provenance := repository.NewProvenanceRepository(tx)
+			if err := provenance.CiteField(change.TrimID, change.Field, change.NewValue, change.Source.ID, ""); err != nil {
+				return err
+			}
+			patch := map[string]json.RawMessage{change.Field: change.NewValue}
+			if _, err := s.trimService.withTx(tx).PatchTrim(change.TrimID, patch, source); err != nil {
+				return err
+			}
+			return repository.NewChangeRepository(tx).Resolve(id, status, note)
+		})
+		if err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("status must be %s or %s", models.ChangeApplied, models.ChangeRejected)
+	}
+	return s.changeRepo.GetByID(id)
+}
@@ -0,0 +1,150 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/emirh/car-specs/backend/internal/models"
+	"github.com/emirh/car-specs/backend/internal/repository"
+)
+
+func newTestChangeService(t *testing.T) (*ChangeService, *TrimService) {
+	t.Helper()
+	trims, db := newTestTrimService(t)
+	return NewChangeService(repository.NewChangeRepository(db), trims, repository.NewProvenanceRepository(db)), trims
+}
+
+func scrapedPage() *models.SourceDocument {
+	return &models.SourceDocument{URL: "https://example.com/audi-a3-35-tfsi", SourceType: models.SourceUltimateSpecs}
+}
+
+func TestDetectChanges(t *testing.T) {
+	s, _ := newTestChangeService(t)
+
+	// Trim 1 stores year 2021, start_year 2020 and power_hp 150
+	values := map[string]interface{}{"year": 2021, "power_hp": 163, "torque_nm": 250, "end_year": nil}
+	changes, err := s.DetectChanges(1, values, scrapedPage())
+	if err != nil {
+		t.Fatalf("DetectChanges() error = %v", err)
+	}
+	got := map[string]string{}
+	for _, c := range changes {
+		got[c.Field] = string(c.OldValue) + " -> " + string(c.NewValue)
+		if c.Status != models.ChangePending {
+			t.Errorf("%s: status = %s, want pending", c.Field, c.Status)
+		}
+	}
+	want := map[string]string{"power_hp": "150 -> 163", "torque_nm": "null -> 250"}
+	if len(got) != len(want) || got["power_hp"] != want["power_hp"] || got["torque_nm"] != want["torque_nm"] {
+		t.Errorf("changes = %v, want %v", got, want)
+	}
+
+	// The same report again is not a new change
+	again, err := s.DetectChanges(1, values, scrapedPage())
+	if err != nil {
+		t.Fatalf("DetectChanges() error = %v", err)
+	}
+	if len(again) != 0 {
+		t.Errorf("repeated report recorded %d changes", len(again))
+	}
+
+	// A newer value supersedes the pending one
+	if _, err := s.DetectChanges(1, map[string]interface{}{"power_hp": 170}, scrapedPage()); err != nil {
+		t.Fatalf("DetectChanges() error = %v", err)
+	}
+	pending, err := s.ListChanges(repository.ChangeFilter{TrimID: 1, Field: "power_hp", Status: models.ChangePending})
+	if err != nil {
+		t.Fatalf("ListChanges() error = %v", err)
+	}
+	if len(pending) != 1 || string(pending[0].NewValue) != "170" {
+		t.Errorf("pending power_hp changes = %+v, want one to 170", pending)
+	}
+}
+
+func TestResolveChange(t *testing.T) {
+	s, trims := newTestChangeService(t)
+
+	changes, err := s.DetectChanges(1, map[string]interface{}{"power_hp": 163, "torque_nm": 250}, scrapedPage())
+	if err != nil || len(changes) != 2 {
+		t.Fatalf("DetectChanges() = %d changes, %v", len(changes), err)
+	}
+	power, torque := changes[0], changes[1]
+	if power.Field != "power_hp" {
+		power, torque = torque, power
+	}
+
+	editor := &models.SourceDocument{URL: "manual://api", SourceType: models.SourceManual}
+	applied, err := s.ResolveChange(power.ID, models.ChangeApplied, "", editor)
+	if err != nil {
+		t.Fatalf("ResolveChange(applied) error = %v", err)
+	}
+	if applied.Status != models.ChangeApplied || applied.ResolvedAt == nil {
+		t.Errorf("applied change = %+v", applied)
+	}
+	trim, err := trims.GetTrim(1, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if trim.PowerHP == nil || *trim.PowerHP != 163 {
+		t.Errorf("power_hp = %v, want 163", trim.PowerHP)
+	}
+	if _, err := s.ResolveChange(power.ID, models.ChangeRejected, "", editor); err == nil {
+		t.Error("resolving a settled change succeeded")
+	}
+
+	if _, err := s.ResolveChange(torque.ID, models.ChangeRejected, "wrong engine", editor); err != nil {
+		t.Fatalf("ResolveChange(rejected) error = %v", err)
+	}
+	trim, _ = trims.GetTrim(1, false)
+	if trim.TorqueNM != nil {
+		t.Errorf("torque_nm = %d after rejecting its change", *trim.TorqueNM)
+	}
+	// A rejected value is not raised again
+	again, err := s.DetectChanges(1, map[string]interface{}{"torque_nm": 250}, scrapedPage())
+	if err != nil || len(again) != 0 {
+		t.Errorf("DetectChanges() after rejection = %d changes, %v", len(again), err)
+	}
+
+	if _, err := s.ResolveChange(999, models.ChangeApplied, "", editor); err != repository.ErrTrimChangeNotFound {
+		t.Errorf("ResolveChange(999) error = %v, want ErrTrimChangeNotFound", err)
+	}
+}
+
+func TestSettleChanges(t *testing.T) {
+	s, trims := newTestChangeService(t)
+
+	if _, err := s.DetectChanges(1, map[string]interface{}{"power_hp": 163, "torque_nm": 250}, scrapedPage()); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := trims.PatchTrim(1, patchBody(t, `{"power_hp": 163}`), nil); err != nil {
+		t.Fatal(err)
+	}
+	settled, err := s.SettleChanges(1)
+	if err != nil {
+		t.Fatalf("SettleChanges() error = %v", err)
+	}
+	if settled != 1 {
+		t.Errorf("settled %d changes, want 1", settled)
+	}
+	pending, _ := s.ListChanges(repository.ChangeFilter{TrimID: 1, Status: models.ChangePending})
+	if len(pending) != 1 || pending[0].Field != "torque_nm" {
+		t.Errorf("pending = %+v, want torque_nm only", pending)
+	}
+}
+
+func TestPageUnchanged(t *testing.T) {
+	s, _ := newTestChangeService(t)
+	url := scrapedPage().URL
+
+	if same, err := s.PageUnchanged(url, "abc"); err != nil || same {
+		t.Fatalf("PageUnchanged() on a new page = %v, %v", same, err)
+	}
+	if err := s.RecordPage(url, 1, models.SourceUltimateSpecs, "abc"); err != nil {
+		t.Fatal(err)
+	}
+	if same, err := s.PageUnchanged(url, "abc"); err != nil || !same {
+		t.Errorf("PageUnchanged() with the stored fingerprint = %v, %v", same, err)
+	}
+	if same, err := s.PageUnchanged(url, "def"); err != nil || same {
+		t.Errorf("PageUnchanged() with a new fingerprint = %v, %v", same, err)
+	}
+}
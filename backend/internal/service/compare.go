@@ -0,0 +1,254 @@
+package service
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/emirh/car-specs/backend/internal/metrics"
+	"github.com/emirh/car-specs/backend/internal/models"
+)
+
+// MinCompareTrims and MaxCompareTrims bound how many trims CompareTrims accepts
+const (
+	MinCompareTrims = 2
+	MaxCompareTrims = 6
+)
+
+const (
+	betterHigher = "higher"
+	betterLower  = "lower"
+)
+
+// compareField describes how one Trim field is compared
+type compareField struct {
+	key    string
+	unit   string
+	better string
+	number func(t *models.Trim) *float64 // set for numeric fields
+	text   func(t *models.Trim) *string  // set for text fields
+}
+
+type compareSection struct {
+	key    string
+	fields []compareField
+}
+
+// compareSections mirrors the sections of models.Trim, followed by the derived metrics
+var compareSections = append([]compareSection{
+	{"engine", []compareField{
+		{key: "engine_type", text: func(t *models.Trim) *string { return t.EngineType }},
+		{key: "fuel_type", text: func(t *models.Trim) *string { return t.FuelType }},
+		{key: "engine_code", text: func(t *models.Trim) *string { return t.EngineCode }},
+		{key: "displacement_cc", unit: "cc", number: func(t *models.Trim) *float64 { return intValue(t.DisplacementCC) }},
+		{key: "cylinders", number: func(t *models.Trim) *float64 { return intValue(t.Cylinders) }},
+		{key: "cylinder_layout", text: func(t *models.Trim) *string { return t.CylinderLayout }},
+		{key: "power_hp", unit: "hp", better: betterHigher, number: func(t *models.Trim) *float64 { return intValue(t.PowerHP) }},
+		{key: "power_kw", unit: "kW", better: betterHigher, number: func(t *models.Trim) *float64 { return intValue(t.PowerKW) }},
+		{key: "torque_nm", unit: "Nm", better: betterHigher, number: func(t *models.Trim) *float64 { return intValue(t.TorqueNM) }},
+	}},
+	{"performance", []compareField{
+		{key: "acceleration_0_100", unit: "s", better: betterLower, number: func(t *models.Trim) *float64 { return t.Acceleration0To100 }},
+		{key: "top_speed_kmh", unit: "km/h", better: betterHigher, number: func(t *models.Trim) *float64 { return intValue(t.TopSpeedKmh) }},
+		{key: "fuel_consumption_city", unit: "L/100km", better: betterLower, number: func(t *models.Trim) *float64 { return t.FuelConsumptionCity }},
+		{key: "fuel_consumption_highway", unit: "L/100km", better: betterLower, number: func(t *models.Trim) *float64 { return t.FuelConsumptionHwy }},
+		{key: "fuel_consumption_combined", unit: "L/100km", better: betterLower, number: func(t *models.Trim) *float64 { return t.FuelConsumptionComb }},
+		{key: "co2_emissions", unit: "g/km", better: betterLower, number: func(t *models.Trim) *float64 { return intValue(t.CO2Emissions) }},
+		{key: "emission_standard", text: func(t *models.Trim) *string { return t.EmissionStandard }},
+	}},
+	{"transmission", []compareField{
+		{key: "transmission_type", text: func(t *models.Trim) *string { return t.TransmissionType }},
+		{key: "transmission_code", text: func(t *models.Trim) *string { return t.TransmissionCode }},
+		{key: "gears", number: func(t *models.Trim) *float64 { return intValue(t.Gears) }},
+		{key: "drivetrain", text: func(t *models.Trim) *string { return t.Drivetrain }},
+	}},
+	{"dimensions", []compareField{
+		{key: "length_mm", unit: "mm", number: func(t *models.Trim) *float64 { return intValue(t.LengthMM) }},
+		{key: "width_mm", unit: "mm", number: func(t *models.Trim) *float64 { return intValue(t.WidthMM) }},
+		{key: "height_mm", unit: "mm", number: func(t *models.Trim) *float64 { return intValue(t.HeightMM) }},
+		{key: "wheelbase_mm", unit: "mm", number: func(t *models.Trim) *float64 { return intValue(t.WheelbaseMM) }},
+		{key: "ground_clearance_mm", unit: "mm", number: func(t *models.Trim) *float64 { return intValue(t.GroundClearanceMM) }},
+		{key: "curb_weight_kg", unit: "kg", better: betterLower, number: func(t *models.Trim) *float64 { return intValue(t.CurbWeightKG) }},
+		{key: "gross_weight_kg", unit: "kg", number: func(t *models.Trim) *float64 { return intValue(t.GrossWeightKG) }},
+		{key: "luggage_capacity_l", unit: "L", better: betterHigher, number: func(t *models.Trim) *float64 { return intValue(t.LuggageCapacityL) }},
+		{key: "luggage_capacity_max_l", unit: "L", better: betterHigher, number: func(t *models.Trim) *float64 { return intValue(t.LuggageCapacityMaxL) }},
+		{key: "fuel_tank_capacity_l", unit: "L", better: betterHigher, number: func(t *models.Trim) *float64 { return intValue(t.FuelTankCapacityL) }},
+	}},
+	{"wheels", []compareField{
+		{key: "tire_size_front", text: func(t *models.Trim) *string { return t.TireSizeFront }},
+		{key: "tire_size_rear", text: func(t *models.Trim) *string { return t.TireSizeRear }},
+		{key: "wheel_size_inches", unit: "in", number: func(t *models.Trim) *float64 { return t.WheelSizeInches }},
+	}},
+}, derivedSection())
+
+// derivedBetter ranks the derived metrics; power_kw_deviation is a data check, not a ranking
+var derivedBetter = map[string]string{
+	metrics.PowerToWeight.Name:     betterHigher,
+	metrics.TorqueToWeight.Name:    betterHigher,
+	metrics.SpecificOutput.Name:    betterHigher,
+	metrics.RangeKM.Name:           betterHigher,
+	metrics.CargoPerFootprint.Name: betterHigher,
+}
+
+func derivedSection() compareSection {
+	section := compareSection{key: "derived"}
+	for _, m := range metrics.All {
+		section.fields = append(section.fields, compareField{
+			key: m.Name, unit: m.Unit, better: derivedBetter[m.Name], number: m.Value,
+		})
+	}
+	return section
+}
+
+// CompareTrims loads the given trims and lines them up field by field.
+// baselineID selects the trim deltas are relative to; 0 uses the first trim.
+func (s *TrimService) CompareTrims(ids []int64, baselineID int64) (*models.Comparison, error) {
+	if len(ids) < MinCompareTrims || len(ids) > MaxCompareTrims {
+		return nil, fmt.Errorf("compare needs between %d and %d trims", MinCompareTrims, MaxCompareTrims)
+	}
+
+	seen := make(map[int64]bool)
+	trims := make([]*models.Trim, 0, len(ids))
+	for _, id := range ids {
+		if seen[id] {
+			return nil, fmt.Errorf("trim %d is listed more than once", id)
+		}
+		seen[id] = true
+
+		trim, err := s.trimRepo.GetByID(id, true)
+		if err != nil {
+			return nil, fmt.Errorf("trim %d: %w", id, err)
+		}
+		trims = append(trims, trim)
+	}
+
+	if baselineID == 0 {
+		baselineID = ids[0]
+	}
+	if !seen[baselineID] {
+		return nil, fmt.Errorf("baseline trim %d is not among the compared trims", baselineID)
+	}
+
+	return buildComparison(trims, baselineID), nil
+}
+
+// buildComparison aligns trims field by field. baselineID must be one of the trims.
+func buildComparison(trims []*models.Trim, baselineID int64) *models.Comparison {
+	comparison := &models.Comparison{BaselineID: baselineID}
+
+	baseline := 0
+	for i, t := range trims {
+		if t.ID == baselineID {
+			baseline = i
+		}
+		comparison.Trims = append(comparison.Trims, comparisonTrim(t))
+	}
+
+	for _, section := range compareSections {
+		out := models.ComparisonSection{Key: section.key}
+		for _, field := range section.fields {
+			if field.number != nil {
+				out.Fields = append(out.Fields, compareNumbers(trims, field, baseline))
+			} else {
+				out.Fields = append(out.Fields, compareTexts(trims, field))
+			}
+		}
+		comparison.Sections = append(comparison.Sections, out)
+	}
+
+	return comparison
+}
+
+func comparisonTrim(t *models.Trim) models.ComparisonTrim {
+	ct := models.ComparisonTrim{ID: t.ID, Name: t.Name, Year: t.Year, ImageURL: t.ImageURL}
+	if t.Model != nil {
+		ct.Model = t.Model.Name
+		if t.Model.Brand != nil {
+			ct.Brand = t.Model.Brand.Name
+		}
+	}
+	if t.GenerationObj != nil {
+		ct.Generation = t.GenerationObj.Code
+	} else if t.Generation != nil {
+		ct.Generation = *t.Generation
+	}
+	return ct
+}
+
+func compareNumbers(trims []*models.Trim, field compareField, baseline int) models.ComparisonField {
+	out := models.ComparisonField{Key: field.key, Unit: field.unit, Better: field.better}
+
+	values := make([]*float64, len(trims))
+	for i, t := range trims {
+		values[i] = field.number(t)
+	}
+	base := values[baseline]
+
+	// Best is only meaningful for ranked metrics where at least two trims have
+	// data and they don't all agree
+	var best *float64
+	known, distinct := 0, make(map[float64]bool)
+	for _, v := range values {
+		if v == nil {
+			continue
+		}
+		known++
+		distinct[*v] = true
+		if best == nil ||
+			(field.better == betterHigher && *v > *best) ||
+			(field.better == betterLower && *v < *best) {
+			best = v
+		}
+	}
+	rankable := field.better != "" && known >= 2 && len(distinct) > 1
+	out.Differs = len(distinct) > 1 || (known > 0 && known < len(values))
+
+	for i, v := range values {
+		cv := models.ComparisonValue{TrimID: trims[i].ID}
+		if v != nil {
+			cv.Value = *v
+			cv.Best = rankable && *v == *best
+			if base != nil {
+				delta := round2(*v - *base)
+				cv.Delta = &delta
+				if *base != 0 {
+					pct := round2((*v - *base) / math.Abs(*base) * 100)
+					cv.DeltaPercent = &pct
+				}
+			}
+		}
+		out.Values = append(out.Values, cv)
+	}
+
+	return out
+}
+
+func compareTexts(trims []*models.Trim, field compareField) models.ComparisonField {
+	out := models.ComparisonField{Key: field.key}
+
+	distinct := make(map[string]bool)
+	for _, t := range trims {
+		cv := models.ComparisonValue{TrimID: t.ID}
+		if v := field.text(t); v != nil && *v != "" {
+			cv.Value = *v
+			distinct[*v] = true
+		} else {
+			distinct[""] = true
+		}
+		out.Values = append(out.Values, cv)
+	}
+	out.Differs = len(distinct) > 1
+
+	return out
+}
+
+func intValue(v *int) *float64 {
+	if v == nil {
+		return nil
+	}
+	f := float64(*v)
+	return &f
+}
+
+func round2(v float64) float64 {
+	return math.Round(v*100) / 100
+}
@@ -0,0 +1,88 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/emirh/car-specs/backend/internal/models"
+)
+
+func intPtr(v int) *int { return &v }
+
+func findField(c *models.Comparison, key string) *models.ComparisonField {
+	for _, section := range c.Sections {
+		for i := range section.Fields {
+			if section.Fields[i].Key == key {
+				return &section.Fields[i]
+			}
+		}
+	}
+	return nil
+}
+
+func TestBuildComparison(t *testing.T) {
+	petrol, diesel := "Petrol", "Diesel"
+	trims := []*models.Trim{
+		{ID: 1, PowerHP: intPtr(150), CurbWeightKG: intPtr(1300), Cylinders: intPtr(4), FuelType: &petrol},
+		{ID: 2, PowerHP: intPtr(200), CurbWeightKG: intPtr(1300), Cylinders: intPtr(4), FuelType: &diesel},
+		{ID: 3, PowerHP: nil, CurbWeightKG: intPtr(1450), Cylinders: intPtr(4), FuelType: &petrol},
+	}
+
+	c := buildComparison(trims, 1)
+
+	tests := []struct {
+		key     string
+		best    []bool
+		delta   []*float64
+		pct     []*float64
+		differs bool
+	}{
+		// Higher is better; trim 3 has no data
+		{"power_hp", []bool{false, true, false}, []*float64{f(0), f(50), nil}, []*float64{f(0), f(33.33), nil}, true},
+		// Lower is better; ties are all best
+		{"curb_weight_kg", []bool{true, true, false}, []*float64{f(0), f(0), f(150)}, []*float64{f(0), f(0), f(11.54)}, true},
+		// Unranked and identical
+		{"cylinders", []bool{false, false, false}, []*float64{f(0), f(0), f(0)}, []*float64{f(0), f(0), f(0)}, false},
+	}
+
+	for _, tc := range tests {
+		field := findField(c, tc.key)
+		if field == nil {
+			t.Errorf("%s: field missing", tc.key)
+			continue
+		}
+		if field.Differs != tc.differs {
+			t.Errorf("%s: differs = %v; want %v", tc.key, field.Differs, tc.differs)
+		}
+		for i, v := range field.Values {
+			if v.Best != tc.best[i] {
+				t.Errorf("%s[%d]: best = %v; want %v", tc.key, i, v.Best, tc.best[i])
+			}
+			if !equalPtr(v.Delta, tc.delta[i]) {
+				t.Errorf("%s[%d]: delta = %v; want %v", tc.key, i, show(v.Delta), show(tc.delta[i]))
+			}
+			if !equalPtr(v.DeltaPercent, tc.pct[i]) {
+				t.Errorf("%s[%d]: delta_percent = %v; want %v", tc.key, i, show(v.DeltaPercent), show(tc.pct[i]))
+			}
+		}
+	}
+
+	if fuel := findField(c, "fuel_type"); fuel == nil || !fuel.Differs {
+		t.Errorf("fuel_type: expected differs")
+	}
+}
+
+func f(v float64) *float64 { return &v }
+
+func equalPtr(a, b *float64) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+func show(v *float64) interface{} {
+	if v == nil {
+		return nil
+	}
+	return *v
+}
@@ -1,7 +1,10 @@
 package service
 
 import (
+	"errors"
 	"fmt"
+	"math"
+	"strings"
 
 	"github.com/emirh/car-specs/backend/internal/models"
 	"github.com/emirh/car-specs/backend/internal/repository"
@@ -10,15 +13,44 @@ import (
 type GenerationService struct {
 	generationRepo *repository.GenerationRepository
 	modelRepo      *repository.ModelRepository
+	auditRepo      *repository.AuditRepository
+	// actor is recorded in the audit log as the author of writes
+	actor models.Actor
 }
 
-func NewGenerationService(generationRepo *repository.GenerationRepository, modelRepo *repository.ModelRepository) *GenerationService {
+func NewGenerationService(generationRepo *repository.GenerationRepository, modelRepo *repository.ModelRepository, auditRepo *repository.AuditRepository) *GenerationService {
 	return &GenerationService{
 		generationRepo: generationRepo,
 		modelRepo:      modelRepo,
+		auditRepo:      auditRepo,
 	}
 }
 
+// As returns a copy of s whose writes are audited as made by actor
+func (s *GenerationService) As(actor models.Actor) *GenerationService {
+	audited := *s
+	audited.actor = actor
+	return &audited
+}
+
+// withTx returns a copy of s whose writes go through tx
+func (s *GenerationService) withTx(tx repository.Querier) *GenerationService {
+	return &GenerationService{
+		generationRepo: repository.NewGenerationRepository(tx),
+		modelRepo:      repository.NewModelRepository(tx),
+		auditRepo:      repository.NewAuditRepository(tx),
+		actor:          s.actor,
+	}
+}
+
+// inTx runs fn in one transaction, so a generation write, the checks it
+// depends on and its audit entry are stored together or not at all
+func (s *GenerationService) inTx(fn func(tx *GenerationService) error) error {
+	return s.generationRepo.Transact(func(tx repository.Querier) error {
+		return fn(s.withTx(tx))
+	})
+}
+
 // ListGenerationsByModel retrieves all generations for a model with validation
 func (s *GenerationService) ListGenerationsByModel(modelID int64) ([]*models.Generation, error) {
 	// Verify model exists
@@ -48,3 +80,158 @@ func (s *GenerationService) GetGeneration(id int64) (*models.Generation, error)
 func (s *GenerationService) GetTrimCount(generationID int64) (int, error) {
 	return s.generationRepo.GetTrimCount(generationID)
 }
+
+// ErrGenerationHasTrims is returned by DeleteGeneration when trims still
+// reference the generation and cascade was not requested
+var ErrGenerationHasTrims = errors.New("generation still has trims")
+
+// CreateGeneration validates and stores a new generation for modelID
+func (s *GenerationService) CreateGeneration(modelID int64, g *models.Generation) error {
+	return s.inTx(func(tx *GenerationService) error {
+		if _, err := tx.modelRepo.GetByID(modelID, false); err != nil {
+			return fmt.Errorf("model not found: %w", err)
+		}
+		g.ModelID = modelID
+
+		if err := tx.validateGeneration(g); err != nil {
+			return err
+		}
+
+		if err := tx.generationRepo.Create(g); err != nil {
+			return fmt.Errorf("failed to create generation: %w", err)
+		}
+		return tx.auditRepo.Record(tx.actor, models.EntityGeneration, g.ID, models.AuditCreate, nil, g)
+	})
+}
+
+// UpdateGeneration replaces the editable fields of generation id.
+// The generation stays attached to its model.
+func (s *GenerationService) UpdateGeneration(id int64, g *models.Generation) (*models.Generation, error) {
+	var updated *models.Generation
+	err := s.inTx(func(tx *GenerationService) error {
+		existing, err := tx.generationRepo.GetByID(id)
+		if err != nil {
+			return err
+		}
+		g.ID = id
+		g.ModelID = existing.ModelID
+
+		if err := tx.validateGeneration(g); err != nil {
+			return err
+		}
+
+		if err := tx.generationRepo.Update(g); err != nil {
+			if errors.Is(err, repository.ErrGenerationNotFound) {
+				return err
+			}
+			return fmt.Errorf("failed to update generation: %w", err)
+		}
+		if updated, err = tx.generationRepo.GetByID(id); err != nil {
+			return err
+		}
+		return tx.auditRepo.Record(tx.actor, models.EntityGeneration, id, models.AuditUpdate, existing, updated)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return updated, nil
+}
+
+// DeleteGeneration moves generation id to the trash. It refuses while trims
+// exist unless cascade is set, in which case the trims go with it. The trims
+// are counted in the transaction that deletes, so none can be added between.
+func (s *GenerationService) DeleteGeneration(id int64, cascade bool) error {
+	return s.inTx(func(tx *GenerationService) error {
+		existing, err := tx.generationRepo.GetByID(id)
+		if err != nil {
+			return err
+		}
+
+		if !cascade {
+			count, err := tx.generationRepo.GetTrimCount(id)
+			if err != nil {
+				return err
+			}
+			if count > 0 {
+				return fmt.Errorf("%w: %d trims reference it, delete them first or use cascade", ErrGenerationHasTrims, count)
+			}
+		}
+
+		if err := tx.generationRepo.Delete(id); err != nil {
+			return err
+		}
+		return tx.auditRepo.Record(tx.actor, models.EntityGeneration, id, models.AuditDelete, existing, nil)
+	})
+}
+
+// validateGeneration checks g on its own and against the model's other generations
+func (s *GenerationService) validateGeneration(g *models.Generation) error {
+	if err := validateGenerationFields(g); err != nil {
+		return err
+	}
+
+	siblings, err := s.generationRepo.ListByModel(g.ModelID)
+	if err != nil {
+		return fmt.Errorf("failed to list generations: %w", err)
+	}
+	for _, other := range siblings {
+		if other.ID != g.ID && strings.EqualFold(other.Code, g.Code) {
+			return fmt.Errorf("generation code %q already exists for this model", g.Code)
+		}
+	}
+	for _, other := range siblings {
+		if other.ID == g.ID {
+			continue
+		}
+		if generationsOverlap(g, other) {
+			return fmt.Errorf("years %s overlap generation %s (%s)", generationYears(g), other.Code, generationYears(other))
+		}
+	}
+	return nil
+}
+
+// validateGenerationFields checks the fields of g that don't depend on other rows
+func validateGenerationFields(g *models.Generation) error {
+	g.Code = strings.TrimSpace(g.Code)
+	if g.Code == "" {
+		return fmt.Errorf("generation code is required")
+	}
+	if g.StartYear == 0 {
+		return fmt.Errorf("start_year is required")
+	}
+	if g.EndYear != nil && *g.EndYear < g.StartYear {
+		return fmt.Errorf("end_year must not be before start_year")
+	}
+	// A generation is current exactly when it has no end year yet
+	if g.IsCurrent && g.EndYear != nil {
+		return fmt.Errorf("a current generation must not have an end_year")
+	}
+	if !g.IsCurrent && g.EndYear == nil {
+		return fmt.Errorf("end_year is required unless is_current is set")
+	}
+	return nil
+}
+
+// generationsOverlap reports whether two year ranges share more than a
+// changeover year: successors commonly start in the year the predecessor ends.
+// An open end_year runs indefinitely.
+func generationsOverlap(a, b *models.Generation) bool {
+	if a.StartYear == b.StartYear {
+		return true
+	}
+	return a.StartYear < generationEnd(b) && b.StartYear < generationEnd(a)
+}
+
+func generationEnd(g *models.Generation) int {
+	if g.EndYear == nil {
+		return math.MaxInt
+	}
+	return *g.EndYear
+}
+
+func generationYears(g *models.Generation) string {
+	if g.EndYear == nil {
+		return fmt.Sprintf("%d-", g.StartYear)
+	}
+	return fmt.Sprintf("%d-%d", g.StartYear, *g.EndYear)
+}
@@ -0,0 +1,61 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/emirh/car-specs/backend/internal/models"
+)
+
+func TestValidateGenerationFields(t *testing.T) {
+	tests := []struct {
+		name    string
+		gen     models.Generation
+		wantErr bool
+	}{
+		{"ended", models.Generation{Code: "8V", StartYear: 2012, EndYear: intPtr(2020)}, false},
+		{"current", models.Generation{Code: "8Y", StartYear: 2020, IsCurrent: true}, false},
+		{"single year", models.Generation{Code: "X", StartYear: 2016, EndYear: intPtr(2016)}, false},
+		{"missing code", models.Generation{Code: "  ", StartYear: 2020, IsCurrent: true}, true},
+		{"missing start", models.Generation{Code: "8Y", IsCurrent: true}, true},
+		{"end before start", models.Generation{Code: "8V", StartYear: 2020, EndYear: intPtr(2012)}, true},
+		{"current with end", models.Generation{Code: "8Y", StartYear: 2020, EndYear: intPtr(2024), IsCurrent: true}, true},
+		{"ended without end", models.Generation{Code: "8V", StartYear: 2012}, true},
+	}
+
+	for _, tt := range tests {
+		err := validateGenerationFields(&tt.gen)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: validateGenerationFields() error = %v, wantErr %v", tt.name, err, tt.wantErr)
+		}
+	}
+}
+
+func TestGenerationsOverlap(t *testing.T) {
+	gen := func(start int, end *int) *models.Generation {
+		return &models.Generation{StartYear: start, EndYear: end}
+	}
+
+	tests := []struct {
+		name string
+		a, b *models.Generation
+		want bool
+	}{
+		{"disjoint", gen(1996, intPtr(2003)), gen(2004, intPtr(2012)), false},
+		{"shared changeover year", gen(1996, intPtr(2003)), gen(2003, intPtr(2012)), false},
+		{"overlapping", gen(2003, intPtr(2012)), gen(2010, intPtr(2020)), true},
+		{"contained", gen(2003, intPtr(2012)), gen(2005, intPtr(2006)), true},
+		{"open successor", gen(2012, intPtr(2020)), gen(2020, nil), false},
+		{"open overlaps", gen(2012, intPtr(2020)), gen(2018, nil), true},
+		{"two open", gen(2012, nil), gen(2020, nil), true},
+		{"same single year", gen(2016, intPtr(2016)), gen(2016, intPtr(2016)), true},
+	}
+
+	for _, tt := range tests {
+		if got := generationsOverlap(tt.a, tt.b); got != tt.want {
+			t.Errorf("%s: generationsOverlap() = %v, want %v", tt.name, got, tt.want)
+		}
+		if got := generationsOverlap(tt.b, tt.a); got != tt.want {
+			t.Errorf("%s (swapped): generationsOverlap() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
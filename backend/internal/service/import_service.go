@@ -1,6 +1,6 @@
 package service
 
-import "gorm.io/gorm"
+import "database/sql"
 
 // CarJSON represents a simplified vehicle structure for import
 type CarJSON struct {
@@ -11,7 +11,7 @@ type CarJSON struct {
 }
 
 // ImportCarData imports a batch of car data (placeholder implementation)
-func ImportCarData(db *gorm.DB, data []CarJSON) error {
+func ImportCarData(db *sql.DB, data []CarJSON) error {
 	// This function is required for legacy importer code to compile.
 	// Current scraping logic uses Repositories with database/sql.
 	return nil
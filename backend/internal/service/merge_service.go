@@ -0,0 +1,194 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/emirh/car-specs/backend/internal/merge"
+	"github.com/emirh/car-specs/backend/internal/models"
+	"github.com/emirh/car-specs/backend/internal/repository"
+)
+
+// MergeService settles trim fields that several sources report values for
+type MergeService struct {
+	trimService    *TrimService
+	provenanceRepo *repository.ProvenanceRepository
+	conflictRepo   *repository.MergeConflictRepository
+	policy         merge.Policy
+}
+
+func NewMergeService(trimService *TrimService, provenanceRepo *repository.ProvenanceRepository, conflictRepo *repository.MergeConflictRepository, policy merge.Policy) *MergeService {
+	return &MergeService{
+		trimService:    trimService,
+		provenanceRepo: provenanceRepo,
+		conflictRepo:   conflictRepo,
+		policy:         policy,
+	}
+}
+
+// As returns a copy of s whose merged values are audited as written by actor
+func (s *MergeService) As(actor models.Actor) *MergeService {
+	audited := *s
+	audited.trimService = s.trimService.As(actor)
+	return &audited
+}
+
+// withTx returns a copy of s whose reads and writes go through tx
+func (s *MergeService) withTx(tx repository.Querier) *MergeService {
+	return &MergeService{
+		trimService:    s.trimService.withTx(tx),
+		provenanceRepo: repository.NewProvenanceRepository(tx),
+		conflictRepo:   repository.NewMergeConflictRepository(tx),
+		policy:         s.policy,
+	}
+}
+
+// inTx runs fn in one transaction, so a merge works from the trim and
+// citations as they are, and its citation, conflicts and merged values are
+// stored together or not at all
+func (s *MergeService) inTx(fn func(tx *MergeService) error) error {
+	return s.trimService.trimRepo.Transact(func(tx repository.Querier) error {
+		return fn(s.withTx(tx))
+	})
+}
+
+// Ingest records candidate's values as source's report for an existing trim
+// and merges them in. Importers use it instead of skipping trims they have seen.
+func (s *MergeService) Ingest(trimID int64, candidate *models.Trim, source *models.SourceDocument) (*models.MergeResult, error) {
+	if err := normalizeTrimEnums(candidate); err != nil {
+		return nil, err
+	}
+	candidate.ID = trimID
+	var result *models.MergeResult
+	err := s.inTx(func(tx *MergeService) error {
+		if err := tx.trimService.citeTrim(candidate, source, nil); err != nil {
+			return err
+		}
+		var err error
+		result, err = tx.mergeTrim(trimID, false)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// MergeTrim resolves every cited field of a trim under the merge policy.
+// Winning values are written, disagreements are recorded as open conflicts
+// and fields that no longer disagree have their conflict resolved. A dry run
+// only reports what would happen.
+func (s *MergeService) MergeTrim(trimID int64, dryRun bool) (*models.MergeResult, error) {
+	var result *models.MergeResult
+	err := s.inTx(func(tx *MergeService) error {
+		var err error
+		result, err = tx.mergeTrim(trimID, dryRun)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// mergeTrim is MergeTrim on a transaction-bound service
+func (s *MergeService) mergeTrim(trimID int64, dryRun bool) (*models.MergeResult, error) {
+	trim, err := s.trimService.trimRepo.GetByID(trimID, false)
+	if err != nil {
+		return nil, err
+	}
+	stored, err := trimFieldValues(trim)
+	if err != nil {
+		return nil, err
+	}
+	citations, err := s.provenanceRepo.ListFieldCitations(trimID)
+	if err != nil {
+		return nil, err
+	}
+
+	fields := make([]string, 0, len(citations))
+	for field := range citations {
+		if citableTrimField(field) && !s.policy.Ignored(field) {
+			fields = append(fields, field)
+		}
+	}
+	sort.Strings(fields)
+
+	result := &models.MergeResult{TrimID: trimID, DryRun: dryRun, Fields: []models.FieldMerge{}}
+	patch := make(map[string]json.RawMessage)
+	var settled []string // Fields whose open conflict, if any, is over
+	now := time.Now().UTC()
+
+	for _, field := range fields {
+		candidates := citations[field]
+		for i := range candidates {
+			candidates[i].Current = sameJSON(candidates[i].Value, stored[field])
+		}
+
+		decision := s.policy.Resolve(field, candidates, now)
+		if decision.Winner == nil {
+			continue
+		}
+
+		previous := stored[field]
+		if previous == nil {
+			previous = json.RawMessage("null")
+		}
+		outcome := models.FieldMerge{
+			Field:    field,
+			Value:    decision.Winner.Value,
+			Source:   decision.Winner.Source,
+			Previous: previous,
+		}
+
+		switch {
+		case decision.Conflict:
+			outcome.Status = models.MergeConflicted
+			if !dryRun {
+				if err := s.conflictRepo.Open(trimID, field, previous, candidates); err != nil {
+					return nil, err
+				}
+			}
+		case sameJSON(decision.Winner.Value, previous):
+			outcome.Status = models.MergeUnchanged
+		default:
+			outcome.Status = models.MergeApplied
+			patch[field] = decision.Winner.Value
+		}
+		if !decision.Conflict {
+			settled = append(settled, field)
+		}
+		result.Fields = append(result.Fields, outcome)
+	}
+	if dryRun {
+		return result, nil
+	}
+
+	if len(patch) > 0 {
+		// The values are already cited to their sources, so nothing new to cite
+		if _, err := s.trimService.PatchTrim(trimID, patch, nil); err != nil {
+			return nil, fmt.Errorf("failed to apply merged values: %w", err)
+		}
+	}
+	for _, field := range settled {
+		if err := s.conflictRepo.Resolve(trimID, field); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+// ListConflicts returns merge conflicts, optionally for one trim. status is
+// open, resolved or all.
+func (s *MergeService) ListConflicts(trimID int64, status string) ([]*models.MergeConflict, error) {
+	switch status {
+	case models.ConflictOpen, models.ConflictResolved:
+	case "all":
+		status = ""
+	default:
+		return nil, fmt.Errorf("invalid status: %s (use %s, %s or all)", status, models.ConflictOpen, models.ConflictResolved)
+	}
+	return s.conflictRepo.List(repository.ConflictFilter{TrimID: trimID, Status: status})
+}
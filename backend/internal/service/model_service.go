@@ -3,6 +3,7 @@ package service
 import (
 	"fmt"
 
+	"github.com/emirh/car-specs/backend/internal/enums"
 	"github.com/emirh/car-specs/backend/internal/models"
 	"github.com/emirh/car-specs/backend/internal/repository"
 )
@@ -10,26 +11,52 @@ import (
 type ModelService struct {
 	modelRepo *repository.ModelRepository
 	brandRepo *repository.BrandRepository
+	auditRepo *repository.AuditRepository
+	// actor is recorded in the audit log as the author of writes
+	actor models.Actor
 }
 
-func NewModelService(modelRepo *repository.ModelRepository, brandRepo *repository.BrandRepository) *ModelService {
+func NewModelService(modelRepo *repository.ModelRepository, brandRepo *repository.BrandRepository, auditRepo *repository.AuditRepository) *ModelService {
 	return &ModelService{
 		modelRepo: modelRepo,
 		brandRepo: brandRepo,
+		auditRepo: auditRepo,
 	}
 }
 
+// As returns a copy of s whose writes are audited as made by actor
+func (s *ModelService) As(actor models.Actor) *ModelService {
+	audited := *s
+	audited.actor = actor
+	return &audited
+}
+
+// withTx returns a copy of s whose writes go through tx
+func (s *ModelService) withTx(tx repository.Querier) *ModelService {
+	return &ModelService{
+		modelRepo: repository.NewModelRepository(tx),
+		brandRepo: repository.NewBrandRepository(tx),
+		auditRepo: repository.NewAuditRepository(tx),
+		actor:     s.actor,
+	}
+}
+
+// inTx runs fn in one transaction, so a model write and its audit entry are
+// stored together or not at all
+func (s *ModelService) inTx(fn func(tx *ModelService) error) error {
+	return s.modelRepo.Transact(func(tx repository.Querier) error {
+		return fn(s.withTx(tx))
+	})
+}
+
 // CreateModel creates a new model with validation
 func (s *ModelService) CreateModel(brandID int64, name string, bodyStyle, segment *string) (*models.Model, error) {
 	// Validation
 	if name == "" {
 		return nil, fmt.Errorf("model name is required")
 	}
-
-	// Verify brand exists
-	_, err := s.brandRepo.GetByID(brandID)
-	if err != nil {
-		return nil, fmt.Errorf("brand not found: %w", err)
+	if err := enums.BodyStyle.NormalizeField(&bodyStyle); err != nil {
+		return nil, err
 	}
 
 	model := &models.Model{
@@ -39,10 +66,25 @@ func (s *ModelService) CreateModel(brandID int64, name string, bodyStyle, segmen
 		Segment:   segment,
 	}
 
-	if err := s.modelRepo.Create(model); err != nil {
-		return nil, fmt.Errorf("failed to create model: %w", err)
-	}
+	err := s.inTx(func(tx *ModelService) error {
+		// Verify brand exists
+		if _, err := tx.brandRepo.GetByID(brandID); err != nil {
+			return fmt.Errorf("brand not found: %w", err)
+		}
+		if deleted, err := tx.modelRepo.InTrash(brandID, name); err != nil {
+			return err
+		} else if deleted {
+			return fmt.Errorf("model '%s' is in the trash, restore it instead", name)
+		}
 
+		if err := tx.modelRepo.Create(model); err != nil {
+			return fmt.Errorf("failed to create model: %w", err)
+		}
+		return tx.auditRepo.Record(tx.actor, models.EntityModel, model.ID, models.AuditCreate, nil, model)
+	})
+	if err != nil {
+		return nil, err
+	}
 	return model, nil
 }
 
@@ -76,47 +118,58 @@ func (s *ModelService) UpdateModel(id int64, brandID int64, name string, bodySty
 	if name == "" {
 		return nil, fmt.Errorf("model name is required")
 	}
-
-	// Check if model exists
-	model, err := s.modelRepo.GetByID(id, false)
-	if err != nil {
-		return nil, fmt.Errorf("model not found: %w", err)
+	if err := enums.BodyStyle.NormalizeField(&bodyStyle); err != nil {
+		return nil, err
 	}
 
-	// Verify brand exists if changing
-	if brandID != model.BrandID {
-		_, err := s.brandRepo.GetByID(brandID)
-		if err != nil {
-			return nil, fmt.Errorf("brand not found: %w", err)
+	var model *models.Model
+	err := s.inTx(func(tx *ModelService) error {
+		// Check if model exists
+		var err error
+		if model, err = tx.modelRepo.GetByID(id, false); err != nil {
+			return fmt.Errorf("model not found: %w", err)
 		}
-	}
 
-	// Update fields
-	model.BrandID = brandID
-	model.Name = name
-	model.BodyStyle = bodyStyle
-	model.Segment = segment
+		// Verify brand exists if changing
+		if brandID != model.BrandID {
+			if _, err := tx.brandRepo.GetByID(brandID); err != nil {
+				return fmt.Errorf("brand not found: %w", err)
+			}
+		}
 
-	if err := s.modelRepo.Update(model); err != nil {
-		return nil, fmt.Errorf("failed to update model: %w", err)
-	}
+		before := *model
 
-	return model, nil
-}
+		// Update fields
+		model.BrandID = brandID
+		model.Name = name
+		model.BodyStyle = bodyStyle
+		model.Segment = segment
 
-// DeleteModel deletes a model
-func (s *ModelService) DeleteModel(id int64) error {
-	// Check if model exists
-	_, err := s.modelRepo.GetByID(id, false)
+		if err := tx.modelRepo.Update(model); err != nil {
+			return fmt.Errorf("failed to update model: %w", err)
+		}
+		return tx.auditRepo.Record(tx.actor, models.EntityModel, id, models.AuditUpdate, &before, model)
+	})
 	if err != nil {
-		return fmt.Errorf("model not found: %w", err)
+		return nil, err
 	}
+	return model, nil
+}
 
-	if err := s.modelRepo.Delete(id); err != nil {
-		return fmt.Errorf("failed to delete model: %w", err)
-	}
+// DeleteModel moves a model to the trash with its generations and trims
+func (s *ModelService) DeleteModel(id int64) error {
+	return s.inTx(func(tx *ModelService) error {
+		// Check if model exists
+		model, err := tx.modelRepo.GetByID(id, false)
+		if err != nil {
+			return fmt.Errorf("model not found: %w", err)
+		}
 
-	return nil
+		if err := tx.modelRepo.Delete(id); err != nil {
+			return fmt.Errorf("failed to delete model: %w", err)
+		}
+		return tx.auditRepo.Record(tx.actor, models.EntityModel, id, models.AuditDelete, model, nil)
+	})
 }
 
 // ListVehiclesByName retrieves aggregated vehicles for a brand
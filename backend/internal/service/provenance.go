@@ -0,0 +1,170 @@
+package service
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/emirh/car-specs/backend/internal/models"
+)
+
+// GetCitations returns the sources of a trim's values. A non-empty field
+// limits the result to that trim field and leaves out the key-value specs.
+func (s *TrimService) GetCitations(trimID int64, field string) (*models.TrimCitations, error) {
+	trim, err := s.trimRepo.GetByID(trimID, false)
+	if err != nil {
+		return nil, err
+	}
+
+	current, err := trimFieldValues(trim)
+	if err != nil {
+		return nil, err
+	}
+	if field != "" && !citableTrimField(field) {
+		return nil, fmt.Errorf("unknown trim field: %s", field)
+	}
+
+	fields, err := s.provenanceRepo.ListFieldCitations(trimID)
+	if err != nil {
+		return nil, err
+	}
+	for name, citations := range fields {
+		if field != "" && name != field {
+			delete(fields, name)
+			continue
+		}
+		for i := range citations {
+			citations[i].Current = sameJSON(citations[i].Value, current[name])
+		}
+	}
+
+	result := &models.TrimCitations{TrimID: trimID, Fields: fields}
+	if field == "" {
+		specs, err := s.provenanceRepo.ListSpecCitations(trimID)
+		if err != nil {
+			return nil, err
+		}
+		result.Specs = specs
+	}
+	return result, nil
+}
+
+// citeTrim cites source for the given fields of trim (fields that are not
+// citable are skipped), or for every field with a value when fields is nil
+func (s *TrimService) citeTrim(trim *models.Trim, source *models.SourceDocument, fields []string) error {
+	if source == nil {
+		return nil
+	}
+
+	values, err := trimFieldValues(trim)
+	if err != nil {
+		return err
+	}
+
+	cited := make(map[string]interface{})
+	if fields == nil {
+		for name, value := range values {
+			cited[name] = value
+		}
+	} else {
+		for _, name := range fields {
+			if !citableTrimField(name) {
+				continue
+			}
+			if value, ok := values[name]; ok {
+				cited[name] = value
+			} else {
+				cited[name] = nil // Cleared
+			}
+		}
+	}
+
+	if err := s.provenanceRepo.EnsureSource(source); err != nil {
+		return fmt.Errorf("failed to record sources: %w", err)
+	}
+	if err := s.provenanceRepo.CiteFields(trim.ID, cited, source.ID, ""); err != nil {
+		return fmt.Errorf("failed to record sources: %w", err)
+	}
+	return nil
+}
+
+// trimFieldValues returns the JSON encoding of every citable trim field that
+// has a value, keyed by JSON name
+func trimFieldValues(trim *models.Trim) (map[string]json.RawMessage, error) {
+	encoded, err := json.Marshal(trim)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode trim: %w", err)
+	}
+	values := make(map[string]json.RawMessage)
+	if err := json.Unmarshal(encoded, &values); err != nil {
+		return nil, fmt.Errorf("failed to encode trim: %w", err)
+	}
+	for name := range values {
+		if !citableTrimField(name) {
+			delete(values, name)
+		}
+	}
+	return values, nil
+}
+
+// setTrimFields returns the citable fields set on trim. A zero value in a
+// non-nullable field (market "", seating_capacity 0) counts as not set.
+func setTrimFields(trim *models.Trim) ([]string, error) {
+	values, err := trimFieldValues(trim)
+	if err != nil {
+		return nil, err
+	}
+	fields := []string{}
+	for name, value := range values {
+		switch string(value) {
+		case "null", `""`, "0", "false":
+			continue
+		}
+		fields = append(fields, name)
+	}
+	return fields, nil
+}
+
+// bookkeepingTrimFields are non-nullable trim fields that writers fill with
+// defaults (market "TR", seating_capacity 5, ...) rather than reported data
+var bookkeepingTrimFields = map[string]bool{
+	"market": true, "currency": true, "seating_capacity": true, "is_facelift": true,
+}
+
+// citableTrimField reports whether name is a Trim field that holds spec data
+// (as opposed to IDs, timestamps, bookkeeping defaults and computed or joined values)
+func citableTrimField(name string) bool {
+	if readOnlyTrimFields[name] || bookkeepingTrimFields[name] || name == "generation_id" {
+		return false
+	}
+	return trimJSONFields[name]
+}
+
+// trimJSONFields holds the JSON names of all Trim fields
+var trimJSONFields = func() map[string]bool {
+	fields := make(map[string]bool)
+	t := reflect.TypeOf(models.Trim{})
+	for i := 0; i < t.NumField(); i++ {
+		name, _, _ := strings.Cut(t.Field(i).Tag.Get("json"), ",")
+		if name != "" && name != "-" {
+			fields[name] = true
+		}
+	}
+	return fields
+}()
+
+func sameJSON(a, b json.RawMessage) bool {
+	if len(b) == 0 {
+		b = json.RawMessage("null")
+	}
+	if len(a) == 0 {
+		a = json.RawMessage("null")
+	}
+	var ca, cb bytes.Buffer
+	if json.Compact(&ca, a) != nil || json.Compact(&cb, b) != nil {
+		return false
+	}
+	return bytes.Equal(ca.Bytes(), cb.Bytes())
+}
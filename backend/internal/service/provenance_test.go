@@ -0,0 +1,71 @@
+package service
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/emirh/car-specs/backend/internal/models"
+)
+
+func TestCitableTrimField(t *testing.T) {
+	tests := []struct {
+		field string
+		want  bool
+	}{
+		{"acceleration_0_100", true},
+		{"power_hp", true},
+		{"name", true},
+		{"id", false},
+		{"market", false},
+		{"currency", false},
+		{"seating_capacity", false},
+		{"is_facelift", false},
+		{"generation_id", false},
+		{"updated_at", false},
+		{"derived", false},
+		{"display", false},
+		{"no_such_field", false},
+	}
+
+	for _, tt := range tests {
+		if got := citableTrimField(tt.field); got != tt.want {
+			t.Errorf("citableTrimField(%q) = %v, want %v", tt.field, got, tt.want)
+		}
+	}
+}
+
+func TestTrimFieldValues(t *testing.T) {
+	trim := &models.Trim{ID: 7, Name: "1.5 TSI", Year: 2022, GenerationID: 3, PowerHP: intPtr(150)}
+	values, err := trimFieldValues(trim)
+	if err != nil {
+		t.Fatalf("trimFieldValues() error = %v", err)
+	}
+
+	if string(values["power_hp"]) != "150" {
+		t.Errorf("power_hp = %s, want 150", values["power_hp"])
+	}
+	for _, field := range []string{"id", "generation_id"} {
+		if _, ok := values[field]; ok {
+			t.Errorf("%s should not be citable", field)
+		}
+	}
+}
+
+func TestSameJSON(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want bool
+	}{
+		{"150", "150", true},
+		{`{"a": 1}`, `{"a":1}`, true},
+		{"null", "", true},
+		{"150", "151", false},
+		{"null", "0", false},
+	}
+
+	for _, tt := range tests {
+		if got := sameJSON(json.RawMessage(tt.a), json.RawMessage(tt.b)); got != tt.want {
+			t.Errorf("sameJSON(%q, %q) = %v, want %v", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,114 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/emirh/car-specs/backend/internal/models"
+	"github.com/emirh/car-specs/backend/internal/repository"
+	"github.com/emirh/car-specs/backend/internal/validation"
+)
+
+// ReviewService validates trims on write and manages the review queue
+type ReviewService struct {
+	reviewRepo     *repository.ReviewRepository
+	trimRepo       *repository.TrimRepository
+	generationRepo *repository.GenerationRepository
+}
+
+func NewReviewService(reviewRepo *repository.ReviewRepository, trimRepo *repository.TrimRepository, generationRepo *repository.GenerationRepository) *ReviewService {
+	return &ReviewService{
+		reviewRepo:     reviewRepo,
+		trimRepo:       trimRepo,
+		generationRepo: generationRepo,
+	}
+}
+
+// withTx returns a copy of s whose repositories run in tx
+func (s *ReviewService) withTx(tx repository.Querier) *ReviewService {
+	return NewReviewService(repository.NewReviewRepository(tx), repository.NewTrimRepository(tx), repository.NewGenerationRepository(tx))
+}
+
+// ReviewTrim runs the validation rules over in and updates the trim's review
+// issues. source is the source type of the write being reviewed (may be empty).
+// The generation is looked up when in doesn't carry it (Trim.GenerationObj
+// only holds its code and name, not its years).
+func (s *ReviewService) ReviewTrim(in validation.Input, source string) ([]validation.Issue, error) {
+	if in.Generation == nil && in.Trim.GenerationID != 0 {
+		generation, err := s.generationRepo.GetByID(in.Trim.GenerationID)
+		if err != nil && !errors.Is(err, repository.ErrGenerationNotFound) {
+			return nil, err
+		}
+		in.Generation = generation
+	}
+
+	issues := validation.Validate(in)
+	found := make([]*models.ReviewIssue, 0, len(issues))
+	for _, issue := range issues {
+		value, err := json.Marshal(issue.Value)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode %s: %w", issue.Field, err)
+		}
+		found = append(found, &models.ReviewIssue{
+			Rule:       issue.Rule,
+			Field:      issue.Field,
+			Value:      value,
+			Confidence: issue.Confidence,
+			Message:    issue.Message,
+		})
+	}
+
+	if err := s.reviewRepo.Sync(in.Trim.ID, source, found); err != nil {
+		return nil, err
+	}
+	return issues, nil
+}
+
+// ReviewTrimByID reviews a stored trim, for write paths that bypass TrimService
+func (s *ReviewService) ReviewTrimByID(trimID int64, source string) ([]validation.Issue, error) {
+	trim, err := s.trimRepo.GetByID(trimID, false)
+	if err != nil {
+		return nil, err
+	}
+	return s.ReviewTrim(validation.Input{Trim: trim}, source)
+}
+
+// ListIssues returns review issues matching filter
+func (s *ReviewService) ListIssues(filter repository.ReviewFilter) ([]*models.ReviewIssue, error) {
+	if filter.Status != "" && !validIssueStatus(filter.Status) {
+		return nil, fmt.Errorf("invalid status: %s", filter.Status)
+	}
+	return s.reviewRepo.List(filter)
+}
+
+// ResolveIssue sets the status of one issue; status open reopens it
+func (s *ReviewService) ResolveIssue(id int64, status, note string) (*models.ReviewIssue, error) {
+	if !validIssueStatus(status) {
+		return nil, fmt.Errorf("invalid status: %s", status)
+	}
+	if err := s.reviewRepo.Resolve(id, status, note); err != nil {
+		return nil, err
+	}
+	return s.reviewRepo.GetByID(id)
+}
+
+// ResolveIssues settles every open issue of a trim or generation at once,
+// optionally only those of one rule or field
+func (s *ReviewService) ResolveIssues(filter repository.ReviewFilter, status, note string) (int64, error) {
+	if filter.TrimID == 0 && filter.GenerationID == 0 {
+		return 0, fmt.Errorf("trim_id or generation_id is required")
+	}
+	if status == models.IssueOpen || !validIssueStatus(status) {
+		return 0, fmt.Errorf("status must be %s, %s or %s", models.IssueAccepted, models.IssueFixed, models.IssueIgnored)
+	}
+	return s.reviewRepo.ResolveMatching(filter, status, note)
+}
+
+func validIssueStatus(status string) bool {
+	switch status {
+	case models.IssueOpen, models.IssueAccepted, models.IssueFixed, models.IssueIgnored:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,127 @@
+package service
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/emirh/car-specs/backend/internal/models"
+	"github.com/emirh/car-specs/backend/internal/repository"
+)
+
+// DefaultRetention is how long deleted rows stay in the trash before a purge
+// removes them
+const DefaultRetention = 30 * 24 * time.Hour
+
+// TrashService lists, restores and purges deleted brands, models,
+// generations and trims
+type TrashService struct {
+	repo           *repository.TrashRepository
+	auditRepo      *repository.AuditRepository
+	brandRepo      *repository.BrandRepository
+	modelRepo      *repository.ModelRepository
+	generationRepo *repository.GenerationRepository
+	trimRepo       *repository.TrimRepository
+	// actor is recorded in the audit log as the author of restores
+	actor models.Actor
+}
+
+func NewTrashService(repo *repository.TrashRepository, auditRepo *repository.AuditRepository, brandRepo *repository.BrandRepository, modelRepo *repository.ModelRepository, generationRepo *repository.GenerationRepository, trimRepo *repository.TrimRepository) *TrashService {
+	return &TrashService{
+		repo:           repo,
+		auditRepo:      auditRepo,
+		brandRepo:      brandRepo,
+		modelRepo:      modelRepo,
+		generationRepo: generationRepo,
+		trimRepo:       trimRepo,
+	}
+}
+
+// As returns a copy of s whose restores are audited as made by actor
+func (s *TrashService) As(actor models.Actor) *TrashService {
+	audited := *s
+	audited.actor = actor
+	return &audited
+}
+
+// withTx returns a copy of s whose repositories run in tx
+func (s *TrashService) withTx(tx repository.Querier) *TrashService {
+	return &TrashService{
+		repo:           repository.NewTrashRepository(tx),
+		auditRepo:      repository.NewAuditRepository(tx),
+		brandRepo:      repository.NewBrandRepository(tx),
+		modelRepo:      repository.NewModelRepository(tx),
+		generationRepo: repository.NewGenerationRepository(tx),
+		trimRepo:       repository.NewTrimRepository(tx),
+		actor:          s.actor,
+	}
+}
+
+// inTx runs fn in one transaction, so a restore and its audit entry are
+// stored together or not at all
+func (s *TrashService) inTx(fn func(tx *TrashService) error) error {
+	return s.repo.Transact(func(tx repository.Querier) error {
+		return fn(s.withTx(tx))
+	})
+}
+
+// ListTrash returns the deleted rows of entityType ("" for all), most recently deleted first
+func (s *TrashService) ListTrash(entityType string) ([]*models.TrashItem, error) {
+	if entityType != "" {
+		if err := validEntityType(entityType); err != nil {
+			return nil, err
+		}
+	}
+	return s.repo.List(entityType)
+}
+
+// Restore takes a row out of the trash along with the rows deleted with it,
+// and returns it. Its parent must not be in the trash.
+func (s *TrashService) Restore(entityType string, id int64) (interface{}, error) {
+	if err := validEntityType(entityType); err != nil {
+		return nil, err
+	}
+	var restored interface{}
+	err := s.inTx(func(tx *TrashService) error {
+		if err := tx.repo.Restore(entityType, id); err != nil {
+			return err
+		}
+
+		var err error
+		switch entityType {
+		case models.EntityBrand:
+			restored, err = tx.brandRepo.GetByID(id)
+		case models.EntityModel:
+			restored, err = tx.modelRepo.GetByID(id, false)
+		case models.EntityGeneration:
+			restored, err = tx.generationRepo.GetByID(id)
+		case models.EntityTrim:
+			restored, err = tx.trimRepo.GetByID(id, false)
+		}
+		if err != nil {
+			return err
+		}
+		return tx.auditRepo.Record(tx.actor, entityType, id, models.AuditRestore, nil, restored)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return restored, nil
+}
+
+// Purge permanently removes rows deleted longer than retention ago. With
+// dryRun it only counts them.
+func (s *TrashService) Purge(retention time.Duration, dryRun bool) (models.PurgeCounts, error) {
+	if retention < 0 {
+		return nil, fmt.Errorf("retention must not be negative")
+	}
+	return s.repo.Purge(time.Now().Add(-retention), dryRun)
+}
+
+// validEntityType checks entityType names an audited entity
+func validEntityType(entityType string) error {
+	switch entityType {
+	case models.EntityBrand, models.EntityModel, models.EntityGeneration, models.EntityTrim:
+		return nil
+	}
+	return fmt.Errorf("invalid entity type: %s", entityType)
+}
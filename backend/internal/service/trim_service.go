@@ -1,56 +1,118 @@
 package service
 
 import (
+	"bytes"
+	"encoding/json"
+	"errors"
 	"fmt"
 
+	"github.com/emirh/car-specs/backend/internal/enums"
+	"github.com/emirh/car-specs/backend/internal/metrics"
 	"github.com/emirh/car-specs/backend/internal/models"
 	"github.com/emirh/car-specs/backend/internal/repository"
+	"github.com/emirh/car-specs/backend/internal/validation"
 )
 
 type TrimService struct {
-	trimRepo  *repository.TrimRepository
-	modelRepo *repository.ModelRepository
+	trimRepo       *repository.TrimRepository
+	modelRepo      *repository.ModelRepository
+	generationRepo *repository.GenerationRepository
+	provenanceRepo *repository.ProvenanceRepository
+	reviews        *ReviewService
+	auditRepo      *repository.AuditRepository
+	// actor is recorded in the audit log as the author of writes
+	actor models.Actor
 }
 
-func NewTrimService(trimRepo *repository.TrimRepository, modelRepo *repository.ModelRepository) *TrimService {
+func NewTrimService(trimRepo *repository.TrimRepository, modelRepo *repository.ModelRepository, generationRepo *repository.GenerationRepository, provenanceRepo *repository.ProvenanceRepository, reviews *ReviewService, auditRepo *repository.AuditRepository) *TrimService {
 	return &TrimService{
-		trimRepo:  trimRepo,
-		modelRepo: modelRepo,
+		trimRepo:       trimRepo,
+		modelRepo:      modelRepo,
+		generationRepo: generationRepo,
+		provenanceRepo: provenanceRepo,
+		reviews:        reviews,
+		auditRepo:      auditRepo,
 	}
 }
 
-// CreateTrim creates a new trim with validation
-func (s *TrimService) CreateTrim(trim *models.Trim) error {
-	// Validation
-	if trim.Name == "" {
-		return fmt.Errorf("trim name is required")
-	}
-	if trim.Year == 0 {
-		return fmt.Errorf("year is required")
-	}
-	if trim.GenerationID == 0 {
-		return fmt.Errorf("generation_id is required")
+// As returns a copy of s whose writes are audited as made by actor
+func (s *TrimService) As(actor models.Actor) *TrimService {
+	audited := *s
+	audited.actor = actor
+	return &audited
+}
+
+// withTx returns a copy of s whose writes go through tx
+func (s *TrimService) withTx(tx repository.Querier) *TrimService {
+	return &TrimService{
+		trimRepo:       repository.NewTrimRepository(tx),
+		modelRepo:      s.modelRepo,
+		generationRepo: repository.NewGenerationRepository(tx),
+		provenanceRepo: repository.NewProvenanceRepository(tx),
+		reviews:        s.reviews.withTx(tx),
+		auditRepo:      repository.NewAuditRepository(tx),
+		actor:          s.actor,
 	}
+}
+
+// audit records a write of trim id in the audit log
+func (s *TrimService) audit(action string, id int64, before, after *models.Trim) error {
+	return s.auditRepo.Record(s.actor, models.EntityTrim, id, action, before, after)
+}
 
-	// Note: We could validate generation exists here, but skipping for simplicity
-	// since the database foreign key will enforce it anyway
+// inTx runs fn in one transaction, so a trim write, its citations and its
+// review issues are stored together or not at all
+func (s *TrimService) inTx(fn func(tx *TrimService) error) error {
+	return s.trimRepo.Transact(func(tx repository.Querier) error {
+		return fn(s.withTx(tx))
+	})
+}
 
-	// Business logic: Set defaults
-	if trim.Market == "" {
-		trim.Market = "TR"
-	}
-	if trim.Currency == "" {
-		trim.Currency = "TRY"
+// CreateTrim creates a new trim with validation and cites source for the
+// given fields, or for every value the caller set when fields is nil. source
+// may be nil when the caller has nothing to cite. Data-quality issues of the
+// new trim go to the review queue.
+func (s *TrimService) CreateTrim(trim *models.Trim, source *models.SourceDocument, fields []string) error {
+	// The database assigns the ID; only restoring a deleted trim keeps one
+	trim.ID = 0
+	return s.createTrim(trim, source, fields, models.AuditCreate)
+}
+
+// createTrim validates and stores trim, audited as action
+func (s *TrimService) createTrim(trim *models.Trim, source *models.SourceDocument, fields []string, action string) error {
+	// Validation
+	if err := validateTrim(trim); err != nil {
+		return err
 	}
-	if trim.SeatingCapacity == 0 {
-		trim.SeatingCapacity = 5
+	if fields == nil {
+		// Cite what the caller set, not the defaults filled in below
+		var err error
+		if fields, err = setTrimFields(trim); err != nil {
+			return err
+		}
 	}
 
-	if err := s.trimRepo.Create(trim); err != nil {
-		return fmt.Errorf("failed to create trim: %w", err)
-	}
+	return s.inTx(func(tx *TrimService) error {
+		if err := tx.setModelFromGeneration(trim); err != nil {
+			return err
+		}
 
-	return nil
+		// Business logic: Set defaults
+		applyTrimDefaults(trim)
+
+		if err := tx.trimRepo.Create(trim); err != nil {
+			return fmt.Errorf("failed to create trim: %w", err)
+		}
+		metrics.Apply(trim)
+
+		if err := tx.citeTrim(trim, source, fields); err != nil {
+			return err
+		}
+		if err := tx.review(trim, source); err != nil {
+			return err
+		}
+		return tx.audit(action, trim.ID, nil, trim)
+	})
 }
 
 // GetTrim retrieves a trim by ID with optional relationships
@@ -59,16 +121,25 @@ func (s *TrimService) GetTrim(id int64, includeRelations bool) (*models.Trim, er
 	if err != nil {
 		return nil, fmt.Errorf("failed to get trim: %w", err)
 	}
+	metrics.Apply(trim)
 	return trim, nil
 }
 
-// SearchTrims searches for trims with filters
-func (s *TrimService) SearchTrims(filters map[string]interface{}) ([]*models.Trim, error) {
-	trims, err := s.trimRepo.Search(filters)
+// SearchTrims searches for trims with filters, returning one page and the total match count
+func (s *TrimService) SearchTrims(filters map[string]interface{}, opts repository.SearchOptions) ([]*models.Trim, int, error) {
+	if opts.Sort != "" && !repository.IsSearchSortField(opts.Sort) {
+		return nil, 0, fmt.Errorf("invalid sort field: %s", opts.Sort)
+	}
+	if opts.Limit < 0 || opts.Offset < 0 {
+		return nil, 0, fmt.Errorf("limit and offset must not be negative")
+	}
+
+	trims, total, err := s.trimRepo.Search(filters, opts)
 	if err != nil {
-		return nil, fmt.Errorf("failed to search trims: %w", err)
+		return nil, 0, fmt.Errorf("failed to search trims: %w", err)
 	}
-	return trims, nil
+	metrics.Apply(trims...)
+	return trims, total, nil
 }
 
 // ListTrimsByModel retrieves all trims for a model
@@ -83,6 +154,7 @@ func (s *TrimService) ListTrimsByModel(modelID int64) ([]*models.Trim, error) {
 	if err != nil {
 		return nil, fmt.Errorf("failed to list trims: %w", err)
 	}
+	metrics.Apply(trims...)
 	return trims, nil
 }
 
@@ -93,44 +165,292 @@ func (s *TrimService) ListTrimsByGeneration(genID int64) ([]*models.Trim, error)
 	if err != nil {
 		return nil, fmt.Errorf("failed to list trims: %w", err)
 	}
+	metrics.Apply(trims...)
 	return trims, nil
 }
 
-// DeleteTrim deletes a trim
-func (s *TrimService) DeleteTrim(id int64) error {
-	// Check if trim exists
-	_, err := s.trimRepo.GetByID(id, false)
+// UpdateTrim replaces every editable field of trim id (PUT semantics).
+// Fields left nil are cleared. source is cited for the given fields (the
+// ones in the request), or for every field set when fields is nil.
+func (s *TrimService) UpdateTrim(id int64, trim *models.Trim, source *models.SourceDocument, fields []string) (*models.Trim, error) {
+	trim.ID = id
+	if err := validateTrim(trim); err != nil {
+		return nil, err
+	}
+	if fields == nil {
+		var err error
+		if fields, err = setTrimFields(trim); err != nil {
+			return nil, err
+		}
+	}
+
+	var updated *models.Trim
+	err := s.inTx(func(tx *TrimService) error {
+		// Read in the transaction, so the audit entry has what is replaced
+		existing, err := tx.trimRepo.GetByID(id, false)
+		if err != nil {
+			return err
+		}
+		if err := tx.setModelFromGeneration(trim); err != nil {
+			return err
+		}
+		applyTrimDefaults(trim)
+
+		if updated, err = tx.saveTrim(trim); err != nil {
+			return err
+		}
+		if err := tx.citeTrim(updated, source, fields); err != nil {
+			return err
+		}
+		if err := tx.review(updated, source); err != nil {
+			return err
+		}
+		return tx.audit(models.AuditUpdate, id, existing, updated)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return updated, nil
+}
+
+// readOnlyTrimFields cannot be changed through PatchTrim
+var readOnlyTrimFields = map[string]bool{
+	"id": true, "model_id": true, "created_at": true, "updated_at": true,
+	"derived": true, "display": true, "model": true, "generation_obj": true, "specs": true,
+}
+
+// PatchTrim applies a JSON merge patch to trim id: fields present in patch are
+// replaced, an explicit null clears the field, absent fields keep their value.
+// Only the patched fields are cited to source.
+func (s *TrimService) PatchTrim(id int64, patch map[string]json.RawMessage, source *models.SourceDocument) (*models.Trim, error) {
+	return s.patchTrim(id, patch, source, models.AuditUpdate)
+}
+
+// patchTrim applies patch to trim id, audited as action
+func (s *TrimService) patchTrim(id int64, patch map[string]json.RawMessage, source *models.SourceDocument, action string) (*models.Trim, error) {
+	if len(patch) == 0 {
+		return nil, fmt.Errorf("patch must change at least one field")
+	}
+	for field := range patch {
+		if readOnlyTrimFields[field] {
+			return nil, fmt.Errorf("%s cannot be changed", field)
+		}
+	}
+
+	patched := make([]string, 0, len(patch))
+	for field := range patch {
+		patched = append(patched, field)
+	}
+
+	// The patch is applied to the trim as read in the transaction that
+	// writes it, so the merge and the audit entry see the same row
+	var updated *models.Trim
+	err := s.inTx(func(tx *TrimService) error {
+		existing, err := tx.trimRepo.GetByID(id, false)
+		if err != nil {
+			return err
+		}
+		trim, err := mergeTrimPatch(existing, patch)
+		if err != nil {
+			return err
+		}
+		trim.ID = id
+		if err := validateTrim(trim); err != nil {
+			return err
+		}
+		if err := tx.setModelFromGeneration(trim); err != nil {
+			return err
+		}
+		// An explicit null on a non-nullable field resets it to its default
+		applyTrimDefaults(trim)
+
+		if updated, err = tx.saveTrim(trim); err != nil {
+			return err
+		}
+		if err := tx.citeTrim(updated, source, patched); err != nil {
+			return err
+		}
+		if err := tx.review(updated, source); err != nil {
+			return err
+		}
+		return tx.audit(action, id, existing, updated)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return updated, nil
+}
+
+// mergeTrimPatch returns existing with patch applied. It round-trips through
+// JSON so the patch is interpreted exactly like a request body.
+func mergeTrimPatch(existing *models.Trim, patch map[string]json.RawMessage) (*models.Trim, error) {
+	current, err := json.Marshal(existing)
 	if err != nil {
-		return fmt.Errorf("trim not found: %w", err)
+		return nil, fmt.Errorf("failed to encode trim: %w", err)
+	}
+	merged := make(map[string]json.RawMessage)
+	if err := json.Unmarshal(current, &merged); err != nil {
+		return nil, fmt.Errorf("failed to encode trim: %w", err)
+	}
+	for field, value := range patch {
+		merged[field] = value
+	}
+	body, err := json.Marshal(merged)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode trim: %w", err)
+	}
+
+	trim := &models.Trim{}
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(trim); err != nil {
+		return nil, fmt.Errorf("invalid patch: %w", err)
 	}
+	return trim, nil
+}
 
-	if err := s.trimRepo.Delete(id); err != nil {
-		return fmt.Errorf("failed to delete trim: %w", err)
+// setModelFromGeneration points trim at the model of its generation, so
+// moving a trim to another model's generation moves it to that model too
+func (s *TrimService) setModelFromGeneration(trim *models.Trim) error {
+	generation, err := s.generationRepo.GetByID(trim.GenerationID)
+	if errors.Is(err, repository.ErrGenerationNotFound) {
+		return fmt.Errorf("generation %d does not exist", trim.GenerationID)
 	}
+	if err != nil {
+		return err
+	}
+	trim.ModelID = generation.ModelID
+	return nil
+}
 
+// review re-validates trim after a write and updates its review issues
+func (s *TrimService) review(trim *models.Trim, source *models.SourceDocument) error {
+	sourceType := ""
+	if source != nil {
+		sourceType = source.SourceType
+	}
+	if _, err := s.reviews.ReviewTrim(validation.Input{Trim: trim}, sourceType); err != nil {
+		return fmt.Errorf("failed to review trim: %w", err)
+	}
 	return nil
 }
 
+// saveTrim writes trim and returns the stored row with its relations
+func (s *TrimService) saveTrim(trim *models.Trim) (*models.Trim, error) {
+	if err := s.trimRepo.Update(trim); err != nil {
+		if errors.Is(err, repository.ErrTrimNotFound) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to update trim: %w", err)
+	}
+	return s.GetTrim(trim.ID, true)
+}
+
+// DeleteTrim moves a trim to the trash. It can be restored from there, or
+// from the audit log once it has been purged.
+func (s *TrimService) DeleteTrim(id int64) error {
+	return s.inTx(func(tx *TrimService) error {
+		existing, err := tx.trimRepo.GetByID(id, false)
+		if err != nil {
+			return fmt.Errorf("trim not found: %w", err)
+		}
+		if err := tx.trimRepo.Delete(id); err != nil {
+			return fmt.Errorf("failed to delete trim: %w", err)
+		}
+		return tx.audit(models.AuditDelete, id, existing, nil)
+	})
+}
+
 // GetFeatured retrieves random trims with images for the homepage
 func (s *TrimService) GetFeatured(limit int) ([]*models.Trim, error) {
 	trims, err := s.trimRepo.GetFeaturedTrims(limit)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get featured trims: %w", err)
 	}
+	metrics.Apply(trims...)
 	return trims, nil
 }
 
-// GetSearchFacets returns available filter options for search
-func (s *TrimService) GetSearchFacets() (map[string]interface{}, error) {
-	// This would query distinct values for filters
-	// For now, returning a placeholder
-	facets := map[string]interface{}{
-		"fuel_types":    []string{},
-		"transmissions": []string{},
-		"min_year":      0,
-		"max_year":      0,
-		"min_hp":        0,
-		"max_hp":        0,
+// GetSearchFacets returns the filter options (counts and ranges) for a search
+func (s *TrimService) GetSearchFacets(filters map[string]interface{}) (*models.SearchFacets, error) {
+	facets, err := s.trimRepo.SearchFacets(filters)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get search facets: %w", err)
 	}
 	return facets, nil
 }
+
+// validateTrim checks the fields every stored trim needs and normalises enums
+func validateTrim(trim *models.Trim) error {
+	if trim.Name == "" {
+		return fmt.Errorf("trim name is required")
+	}
+	if trim.Year == 0 {
+		return fmt.Errorf("year is required")
+	}
+	if trim.GenerationID == 0 {
+		return fmt.Errorf("generation_id is required")
+	}
+	if trim.StartYear != nil && trim.EndYear != nil && *trim.EndYear < *trim.StartYear {
+		return fmt.Errorf("end_year must not be before start_year")
+	}
+
+	for name, v := range map[string]*int{
+		"displacement_cc": trim.DisplacementCC, "cylinders": trim.Cylinders,
+		"power_hp": trim.PowerHP, "power_kw": trim.PowerKW, "torque_nm": trim.TorqueNM,
+		"top_speed_kmh": trim.TopSpeedKmh, "co2_emissions": trim.CO2Emissions, "gears": trim.Gears,
+		"length_mm": trim.LengthMM, "width_mm": trim.WidthMM, "height_mm": trim.HeightMM,
+		"wheelbase_mm": trim.WheelbaseMM, "ground_clearance_mm": trim.GroundClearanceMM,
+		"curb_weight_kg": trim.CurbWeightKG, "gross_weight_kg": trim.GrossWeightKG,
+		"luggage_capacity_l": trim.LuggageCapacityL, "luggage_capacity_max_l": trim.LuggageCapacityMaxL,
+		"fuel_tank_capacity_l": trim.FuelTankCapacityL, "doors": trim.Doors,
+	} {
+		if v != nil && *v < 0 {
+			return fmt.Errorf("%s must not be negative", name)
+		}
+	}
+	for name, v := range map[string]*float64{
+		"acceleration_0_100": trim.Acceleration0To100, "fuel_consumption_city": trim.FuelConsumptionCity,
+		"fuel_consumption_highway": trim.FuelConsumptionHwy, "fuel_consumption_combined": trim.FuelConsumptionComb,
+		"wheel_size_inches": trim.WheelSizeInches, "msrp_price": trim.MSRPPrice,
+	} {
+		if v != nil && *v < 0 {
+			return fmt.Errorf("%s must not be negative", name)
+		}
+	}
+
+	return normalizeTrimEnums(trim)
+}
+
+// applyTrimDefaults fills the non-nullable fields callers usually leave out
+func applyTrimDefaults(trim *models.Trim) {
+	if trim.Market == "" {
+		trim.Market = "TR"
+	}
+	if trim.Currency == "" {
+		trim.Currency = "TRY"
+	}
+	if trim.SeatingCapacity == 0 {
+		trim.SeatingCapacity = 5
+	}
+}
+
+// normalizeTrimEnums maps fuel, transmission, drivetrain and emission standard
+// onto their canonical values, rejecting values that are not recognised
+func normalizeTrimEnums(trim *models.Trim) error {
+	fields := []struct {
+		enum  enums.Enum
+		value **string
+	}{
+		{enums.FuelType, &trim.FuelType},
+		{enums.Transmission, &trim.TransmissionType},
+		{enums.Drivetrain, &trim.Drivetrain},
+		{enums.EmissionStandard, &trim.EmissionStandard},
+	}
+	for _, f := range fields {
+		if err := f.enum.NormalizeField(f.value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
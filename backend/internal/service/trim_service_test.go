@@ -0,0 +1,254 @@
+package service
+
+import (
+	"database/sql"
+	"encoding/json"
+	"path/filepath"
+	"testing"
+
+	"github.com/emirh/car-specs/backend/internal/models"
+	"github.com/emirh/car-specs/backend/internal/repository"
+	"github.com/emirh/car-specs/backend/migrations"
+
+	_ "modernc.org/sqlite"
+)
+
+func TestValidateTrim(t *testing.T) {
+	valid := func() *models.Trim {
+		return &models.Trim{Name: "1.5 TSI", Year: 2022, GenerationID: 1}
+	}
+	dizel := "Dizel"
+	unknown := "steam"
+
+	tests := []struct {
+		name    string
+		modify  func(t *models.Trim)
+		wantErr bool
+	}{
+		{"valid", func(t *models.Trim) {}, false},
+		{"missing name", func(t *models.Trim) { t.Name = "" }, true},
+		{"missing year", func(t *models.Trim) { t.Year = 0 }, true},
+		{"missing generation", func(t *models.Trim) { t.GenerationID = 0 }, true},
+		{"negative power", func(t *models.Trim) { t.PowerHP = intPtr(-1) }, true},
+		{"zero power", func(t *models.Trim) { t.PowerHP = intPtr(0) }, false},
+		{"end before start", func(t *models.Trim) { t.StartYear, t.EndYear = intPtr(2020), intPtr(2019) }, true},
+		{"open-ended years", func(t *models.Trim) { t.StartYear = intPtr(2020) }, false},
+		{"fuel alias", func(t *models.Trim) { t.FuelType = &dizel }, false},
+		{"unknown fuel", func(t *models.Trim) { t.FuelType = &unknown }, true},
+	}
+
+	for _, tt := range tests {
+		trim := valid()
+		tt.modify(trim)
+		err := validateTrim(trim)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: validateTrim() error = %v, wantErr %v", tt.name, err, tt.wantErr)
+		}
+	}
+}
+
+func TestValidateTrimNormalisesEnums(t *testing.T) {
+	dizel := "Dizel"
+	trim := &models.Trim{Name: "2.0 TDI", Year: 2022, GenerationID: 1, FuelType: &dizel}
+	if err := validateTrim(trim); err != nil {
+		t.Fatalf("validateTrim() error = %v", err)
+	}
+	if *trim.FuelType != "diesel" {
+		t.Errorf("FuelType = %q, want diesel", *trim.FuelType)
+	}
+}
+
+// newTestTrimService returns a TrimService over a migrated database holding
+// two models with one generation each and trim 1 in generation 1
+func newTestTrimService(t *testing.T) (*TrimService, *sql.DB) {
+	t.Helper()
+	db, err := sql.Open("sqlite", filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("failed to open db: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	migrator, err := migrations.NewMigrator(db)
+	if err != nil {
+		t.Fatalf("failed to load migrations: %v", err)
+	}
+	if err := migrator.Up(); err != nil {
+		t.Fatalf("failed to migrate: %v", err)
+	}
+
+	for _, stmt := range []string{
+		`INSERT INTO brands (id, name) VALUES (1, 'Audi')`,
+		`INSERT INTO models (id, brand_id, name) VALUES (1, 1, 'A3'), (2, 1, 'A4')`,
+		`INSERT INTO generations (id, model_id, code, start_year) VALUES (1, 1, '8Y', 2020), (2, 2, 'B9', 2015)`,
+		`INSERT INTO trims (id, generation_id, model_id, name, year, start_year, power_hp, power_kw, market)
+			VALUES (1, 1, 1, '35 TFSI', 2021, 2020, 150, 110, 'DE')`,
+	} {
+		if _, err := db.Exec(stmt); err != nil {
+			t.Fatalf("%s: %v", stmt, err)
+		}
+	}
+
+	trimRepo := repository.NewTrimRepository(db)
+	generationRepo := repository.NewGenerationRepository(db)
+	reviews := NewReviewService(repository.NewReviewRepository(db), trimRepo, generationRepo)
+	return NewTrimService(trimRepo, repository.NewModelRepository(db), generationRepo, repository.NewProvenanceRepository(db), reviews, repository.NewAuditRepository(db)), db
+}
+
+func patchBody(t *testing.T, body string) map[string]json.RawMessage {
+	t.Helper()
+	patch := make(map[string]json.RawMessage)
+	if err := json.Unmarshal([]byte(body), &patch); err != nil {
+		t.Fatalf("invalid patch %s: %v", body, err)
+	}
+	return patch
+}
+
+func TestPatchTrimNullVersusAbsent(t *testing.T) {
+	s, _ := newTestTrimService(t)
+
+	trim, err := s.PatchTrim(1, patchBody(t, `{"power_hp": null, "torque_nm": 250}`), nil)
+	if err != nil {
+		t.Fatalf("PatchTrim() error = %v", err)
+	}
+	if trim.PowerHP != nil {
+		t.Errorf("power_hp = %d after explicit null, want nil", *trim.PowerHP)
+	}
+	if trim.TorqueNM == nil || *trim.TorqueNM != 250 {
+		t.Errorf("torque_nm = %v, want 250", trim.TorqueNM)
+	}
+	// Absent fields keep their value
+	if trim.PowerKW == nil || *trim.PowerKW != 110 {
+		t.Errorf("power_kw = %v, want 110 (untouched)", trim.PowerKW)
+	}
+	if trim.Name != "35 TFSI" || trim.Market != "DE" {
+		t.Errorf("name, market = %q, %q; want untouched 35 TFSI, DE", trim.Name, trim.Market)
+	}
+	if trim.StartYear == nil || *trim.StartYear != 2020 {
+		t.Errorf("start_year = %v, want 2020 (untouched)", trim.StartYear)
+	}
+}
+
+func TestPatchTrimNullResetsDefaults(t *testing.T) {
+	s, _ := newTestTrimService(t)
+
+	trim, err := s.PatchTrim(1, patchBody(t, `{"market": null, "seating_capacity": null}`), nil)
+	if err != nil {
+		t.Fatalf("PatchTrim() error = %v", err)
+	}
+	if trim.Market != "TR" || trim.SeatingCapacity != 5 {
+		t.Errorf("market, seating_capacity = %q, %d; want defaults TR, 5", trim.Market, trim.SeatingCapacity)
+	}
+}
+
+func TestPatchTrimRejects(t *testing.T) {
+	s, _ := newTestTrimService(t)
+
+	for _, body := range []string{
+		`{}`,
+		`{"model_id": 2}`,
+		`{"name": null}`,
+		`{"no_such_field": 1}`,
+		`{"power_hp": "150"}`,
+		`{"generation_id": 99}`,
+	} {
+		if _, err := s.PatchTrim(1, patchBody(t, body), nil); err == nil {
+			t.Errorf("PatchTrim(%s) succeeded, want error", body)
+		}
+	}
+	if _, err := s.PatchTrim(99, patchBody(t, `{"power_hp": 1}`), nil); err != repository.ErrTrimNotFound {
+		t.Errorf("PatchTrim(99) error = %v, want ErrTrimNotFound", err)
+	}
+}
+
+func TestPatchTrimMovesModelWithGeneration(t *testing.T) {
+	s, db := newTestTrimService(t)
+
+	if _, err := s.PatchTrim(1, patchBody(t, `{"generation_id": 2}`), nil); err != nil {
+		t.Fatalf("PatchTrim() error = %v", err)
+	}
+	var generationID, modelID int64
+	if err := db.QueryRow(`SELECT generation_id, model_id FROM trims WHERE id = 1`).Scan(&generationID, &modelID); err != nil {
+		t.Fatalf("failed to read trim: %v", err)
+	}
+	if generationID != 2 || modelID != 2 {
+		t.Errorf("generation_id, model_id = %d, %d; want 2, 2", generationID, modelID)
+	}
+}
+
+func TestCreateTrimStoresEveryColumn(t *testing.T) {
+	s, db := newTestTrimService(t)
+
+	code := "DQ381"
+	trim := &models.Trim{
+		Name: "40 TDI", Year: 2022, GenerationID: 2,
+		StartYear: intPtr(2021), EndYear: intPtr(2023), TransmissionCode: &code,
+	}
+	if err := s.CreateTrim(trim, nil, nil); err != nil {
+		t.Fatalf("CreateTrim() error = %v", err)
+	}
+
+	var modelID int64
+	var start, end int
+	var storedCode string
+	err := db.QueryRow(`SELECT model_id, start_year, end_year, transmission_code FROM trims WHERE id = ?`, trim.ID).
+		Scan(&modelID, &start, &end, &storedCode)
+	if err != nil {
+		t.Fatalf("failed to read trim: %v", err)
+	}
+	if modelID != 2 || start != 2021 || end != 2023 || storedCode != code {
+		t.Errorf("stored model_id, start_year, end_year, transmission_code = %d, %d, %d, %q", modelID, start, end, storedCode)
+	}
+}
+
+func TestCreateTrimCitesOnlySentFields(t *testing.T) {
+	s, _ := newTestTrimService(t)
+
+	source := &models.SourceDocument{URL: "manual://test", SourceType: models.SourceManual}
+	trim := &models.Trim{Name: "40 TDI", Year: 2022, GenerationID: 2, PowerHP: intPtr(204)}
+	if err := s.CreateTrim(trim, source, []string{"name", "year", "generation_id", "power_hp"}); err != nil {
+		t.Fatalf("CreateTrim() error = %v", err)
+	}
+
+	citations, err := s.provenanceRepo.ListFieldCitations(trim.ID)
+	if err != nil {
+		t.Fatalf("ListFieldCitations() error = %v", err)
+	}
+	for _, field := range []string{"name", "year", "power_hp"} {
+		if len(citations[field]) != 1 {
+			t.Errorf("%s has %d citations, want 1", field, len(citations[field]))
+		}
+	}
+	for _, field := range []string{"market", "currency", "seating_capacity", "is_facelift", "generation_id"} {
+		if len(citations[field]) != 0 {
+			t.Errorf("%s was cited although the caller did not send it", field)
+		}
+	}
+}
+
+func TestTrimWriteIsAtomic(t *testing.T) {
+	s, db := newTestTrimService(t)
+	// Make the review step fail after the trim row is written
+	if _, err := db.Exec(`DROP TABLE review_issues`); err != nil {
+		t.Fatalf("failed to drop review_issues: %v", err)
+	}
+
+	source := &models.SourceDocument{URL: "manual://test", SourceType: models.SourceManual}
+	trim := &models.Trim{Name: "40 TDI", Year: 2022, GenerationID: 2, PowerHP: intPtr(204)}
+	if err := s.CreateTrim(trim, source, nil); err == nil {
+		t.Fatal("CreateTrim() succeeded although the review failed")
+	}
+	if _, err := s.PatchTrim(1, patchBody(t, `{"power_hp": 999}`), source); err == nil {
+		t.Fatal("PatchTrim() succeeded although the review failed")
+	}
+
+	var trims, citations, power int
+	err := db.QueryRow(`
+		SELECT (SELECT COUNT(*) FROM trims), (SELECT COUNT(*) FROM trim_field_sources),
+			(SELECT power_hp FROM trims WHERE id = 1)`).Scan(&trims, &citations, &power)
+	if err != nil {
+		t.Fatalf("failed to count rows: %v", err)
+	}
+	if trims != 1 || citations != 0 || power != 150 {
+		t.Errorf("after failed writes: %d trims, %d citations, power_hp %d; want 1, 0, 150", trims, citations, power)
+	}
+}
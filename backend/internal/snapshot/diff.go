@@ -0,0 +1,210 @@
+package snapshot
+
+import (
+	"database/sql"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/emirh/car-specs/backend/internal/export"
+	"github.com/emirh/car-specs/backend/migrations"
+)
+
+// TrimRef names a trim in a diff
+type TrimRef struct {
+	ID         int64  `json:"id"`
+	Brand      string `json:"brand"`
+	Model      string `json:"model"`
+	Generation string `json:"generation"`
+	Name       string `json:"name"`
+	Year       int    `json:"year"`
+	Market     string `json:"market"`
+}
+
+func (r TrimRef) String() string {
+	return fmt.Sprintf("%s %s %s %s %d (%s)", r.Brand, r.Model, r.Generation, r.Name, r.Year, r.Market)
+}
+
+// FieldChange is one value that differs between two snapshots, as exported
+// to CSV (see export.CSVColumns)
+type FieldChange struct {
+	Field  string `json:"field"`
+	Before string `json:"before"`
+	After  string `json:"after"`
+}
+
+type TrimChange struct {
+	TrimRef
+	Changes []FieldChange `json:"changes"`
+}
+
+// Diff is what changed between two snapshots. Trims are matched by ID, and
+// trims in the trash count as removed.
+type Diff struct {
+	From    string       `json:"from"`
+	To      string       `json:"to"`
+	Added   []TrimRef    `json:"added"`
+	Removed []TrimRef    `json:"removed"`
+	Changed []TrimChange `json:"changed"`
+}
+
+// ignoredColumns are exported values a diff leaves out: they change when a
+// trim is saved or cited again, not when its data does
+var ignoredColumns = map[string]bool{"id": true, "updated_at": true, "sources": true}
+
+// exportedTrim is a trim as read from a snapshot
+type exportedTrim struct {
+	ref    TrimRef
+	values []string
+}
+
+// Compare lists the trims added, removed and changed from old to new. Both
+// snapshots are verified against their manifests, and read from temporary
+// copies migrated to this build's schema, so snapshots taken by older
+// releases can be compared too.
+func Compare(old, new *Snapshot) (*Diff, error) {
+	before, order, err := readTrims(old)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", old.Name(), err)
+	}
+	after, newOrder, err := readTrims(new)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", new.Name(), err)
+	}
+
+	diff := &Diff{From: old.Name(), To: new.Name(), Added: []TrimRef{}, Removed: []TrimRef{}, Changed: []TrimChange{}}
+	for _, id := range order {
+		if _, ok := after[id]; !ok {
+			diff.Removed = append(diff.Removed, before[id].ref)
+		}
+	}
+	for _, id := range newOrder {
+		a := after[id]
+		b, ok := before[id]
+		if !ok {
+			diff.Added = append(diff.Added, a.ref)
+			continue
+		}
+		var changes []FieldChange
+		for i, c := range export.CSVColumns {
+			if !ignoredColumns[c.Name] && b.values[i] != a.values[i] {
+				changes = append(changes, FieldChange{Field: c.Name, Before: b.values[i], After: a.values[i]})
+			}
+		}
+		if len(changes) > 0 {
+			diff.Changed = append(diff.Changed, TrimChange{TrimRef: a.ref, Changes: changes})
+		}
+	}
+	return diff, nil
+}
+
+// readTrims returns the trims of s keyed by ID, and the IDs in export order
+func readTrims(s *Snapshot) (map[int64]*exportedTrim, []int64, error) {
+	if err := s.Verify(); err != nil {
+		return nil, nil, err
+	}
+	db, cleanup, err := openCopy(s.Database())
+	if err != nil {
+		return nil, nil, err
+	}
+	defer cleanup()
+
+	trims := make(map[int64]*exportedTrim)
+	var order []int64
+	err = export.Each(db, nil, func(r *export.Record) error {
+		values, err := r.Flatten()
+		if err != nil {
+			return err
+		}
+		trims[r.ID] = &exportedTrim{
+			ref: TrimRef{
+				ID: r.ID, Brand: r.Brand.Name, Model: r.Model.Name, Generation: r.Generation.Code,
+				Name: r.Name, Year: r.Year, Market: r.Market,
+			},
+			values: values,
+		}
+		order = append(order, r.ID)
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return trims, order, nil
+}
+
+// openCopy opens a temporary copy of the database at path, migrated to this
+// build's schema, so the snapshot itself is never written to
+func openCopy(path string) (*sql.DB, func(), error) {
+	dir, err := os.MkdirTemp("", "snapshot-")
+	if err != nil {
+		return nil, nil, err
+	}
+	cleanup := func() { os.RemoveAll(dir) }
+
+	file := filepath.Join(dir, DatabaseFile)
+	if err := copyFile(path, file); err != nil {
+		cleanup()
+		return nil, nil, err
+	}
+	// A bare database may still have writes in its WAL
+	if _, err := os.Stat(path + "-wal"); err == nil {
+		if err := copyFile(path+"-wal", file+"-wal"); err != nil {
+			cleanup()
+			return nil, nil, err
+		}
+	}
+
+	db, err := sql.Open("sqlite", "file:"+file)
+	if err != nil {
+		cleanup()
+		return nil, nil, err
+	}
+	closeAll := func() {
+		db.Close()
+		cleanup()
+	}
+
+	migrator, err := migrations.NewMigrator(db)
+	if err != nil {
+		closeAll()
+		return nil, nil, err
+	}
+	current, err := migrator.CurrentVersion()
+	if err != nil {
+		closeAll()
+		return nil, nil, err
+	}
+	if current > migrator.Latest() {
+		closeAll()
+		return nil, nil, fmt.Errorf("schema version %d is newer than this build knows (%d)", current, migrator.Latest())
+	}
+	if err := migrator.Up(); err != nil {
+		closeAll()
+		return nil, nil, fmt.Errorf("failed to migrate a copy of %s: %w", path, err)
+	}
+	return db, closeAll, nil
+}
+
+func copyFile(from, to string) error {
+	in, err := os.Open(from)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.Create(to)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// Summary is the one-line count of a diff
+func (d *Diff) Summary() string {
+	return fmt.Sprintf("%d added, %d removed, %d changed", len(d.Added), len(d.Removed), len(d.Changed))
+}
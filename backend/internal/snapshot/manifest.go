@@ -0,0 +1,164 @@
+package snapshot
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/emirh/car-specs/backend/migrations"
+)
+
+// ManifestVersion is the version of the manifest format this build writes
+const ManifestVersion = 1
+
+// Manifest describes a snapshot. Counts leave out rows in the trash.
+type Manifest struct {
+	ManifestVersion int       `json:"manifest_version"`
+	Version         string    `json:"version"`
+	CreatedAt       time.Time `json:"created_at"`
+	// SchemaVersion is the migration the database was at
+	SchemaVersion int    `json:"schema_version"`
+	File          string `json:"file"`
+	Size          int64  `json:"size"`
+	SHA256        string `json:"sha256"`
+
+	Totals Totals        `json:"totals"`
+	Brands []BrandCounts `json:"brands"`
+	// SourceRuns are the sources whose writes the snapshot includes
+	SourceRuns []SourceRun `json:"source_runs"`
+}
+
+type Totals struct {
+	Brands          int `json:"brands"`
+	Models          int `json:"models"`
+	Generations     int `json:"generations"`
+	Trims           int `json:"trims"`
+	Specs           int `json:"specs"`
+	SourceDocuments int `json:"source_documents"`
+}
+
+type BrandCounts struct {
+	Name        string `json:"name"`
+	Models      int    `json:"models"`
+	Generations int    `json:"generations"`
+	Trims       int    `json:"trims"`
+}
+
+// SourceRun sums up the audit log of one source (api, importer:csv,
+// scraper:ultimatespecs, ...): how many writes it made and when
+type SourceRun struct {
+	Source       string    `json:"source"`
+	Writes       int       `json:"writes"`
+	FirstWriteAt time.Time `json:"first_write_at"`
+	LastWriteAt  time.Time `json:"last_write_at"`
+}
+
+// buildManifest counts what db holds. The file fields are left to the caller.
+func buildManifest(db *sql.DB) (*Manifest, error) {
+	m := &Manifest{ManifestVersion: ManifestVersion, Brands: []BrandCounts{}, SourceRuns: []SourceRun{}}
+
+	migrator, err := migrations.NewMigrator(db)
+	if err != nil {
+		return nil, err
+	}
+	if m.SchemaVersion, err = migrator.CurrentVersion(); err != nil {
+		return nil, err
+	}
+
+	err = db.QueryRow(`
+		SELECT
+			(SELECT COUNT(*) FROM brands WHERE deleted_at IS NULL),
+			(SELECT COUNT(*) FROM models WHERE deleted_at IS NULL),
+			(SELECT COUNT(*) FROM generations WHERE deleted_at IS NULL),
+			(SELECT COUNT(*) FROM trims WHERE deleted_at IS NULL),
+			(SELECT COUNT(*) FROM specs WHERE trim_id IN (SELECT id FROM trims WHERE deleted_at IS NULL)),
+			(SELECT COUNT(*) FROM source_documents)
+	`).Scan(&m.Totals.Brands, &m.Totals.Models, &m.Totals.Generations, &m.Totals.Trims, &m.Totals.Specs, &m.Totals.SourceDocuments)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count the catalogue: %w", err)
+	}
+
+	rows, err := db.Query(`
+		SELECT
+			b.name,
+			(SELECT COUNT(*) FROM models m WHERE m.brand_id = b.id AND m.deleted_at IS NULL),
+			(SELECT COUNT(*) FROM generations g JOIN models m ON g.model_id = m.id
+				WHERE m.brand_id = b.id AND g.deleted_at IS NULL),
+			(SELECT COUNT(*) FROM trims t JOIN generations g ON t.generation_id = g.id JOIN models m ON g.model_id = m.id
+				WHERE m.brand_id = b.id AND t.deleted_at IS NULL)
+		FROM brands b
+		WHERE b.deleted_at IS NULL
+		ORDER BY b.name
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count brands: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var b BrandCounts
+		if err := rows.Scan(&b.Name, &b.Models, &b.Generations, &b.Trims); err != nil {
+			return nil, fmt.Errorf("failed to scan brand counts: %w", err)
+		}
+		m.Brands = append(m.Brands, b)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to count brands: %w", err)
+	}
+
+	if m.SourceRuns, err = sourceRuns(db); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func sourceRuns(db *sql.DB) ([]SourceRun, error) {
+	rows, err := db.Query(`
+		SELECT source, COUNT(*), MIN(created_at), MAX(created_at)
+		FROM audit_log
+		GROUP BY source
+		ORDER BY source
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list source runs: %w", err)
+	}
+	defer rows.Close()
+
+	runs := []SourceRun{}
+	for rows.Next() {
+		var run SourceRun
+		var first, last string
+		if err := rows.Scan(&run.Source, &run.Writes, &first, &last); err != nil {
+			return nil, fmt.Errorf("failed to scan source run: %w", err)
+		}
+		// MIN and MAX lose the column type, so the times come back as text
+		if run.FirstWriteAt, err = parseTimestamp(first); err != nil {
+			return nil, err
+		}
+		if run.LastWriteAt, err = parseTimestamp(last); err != nil {
+			return nil, err
+		}
+		runs = append(runs, run)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list source runs: %w", err)
+	}
+	return runs, nil
+}
+
+// timestampLayouts are the ways SQLite and the driver store a TIMESTAMP
+var timestampLayouts = []string{
+	"2006-01-02 15:04:05.999999999-07:00",
+	"2006-01-02T15:04:05.999999999-07:00",
+	"2006-01-02 15:04:05",
+	"2006-01-02T15:04:05Z",
+	time.RFC3339Nano,
+}
+
+func parseTimestamp(s string) (time.Time, error) {
+	for _, layout := range timestampLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t.UTC(), nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("cannot read timestamp %q", s)
+}
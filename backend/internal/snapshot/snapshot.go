@@ -0,0 +1,216 @@
+// Package snapshot makes point-in-time releases of the catalogue. A snapshot
+// is a directory holding a consistent copy of the database, written with
+// VACUUM INTO, and a manifest with its checksum, schema version and counts.
+// Two snapshots can be diffed to list the trims a release added, removed or
+// changed.
+package snapshot
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	_ "modernc.org/sqlite"
+)
+
+// Files of a snapshot directory
+const (
+	DatabaseFile = "vehicles.db"
+	ManifestFile = "manifest.json"
+)
+
+// VersionFormat names snapshots by the time they were taken, unless a
+// version is given
+const VersionFormat = "20060102_150405"
+
+// ErrChecksumMismatch is returned when a snapshot's database is not the one
+// its manifest describes
+var ErrChecksumMismatch = errors.New("snapshot checksum mismatch")
+
+// Snapshot is a snapshot directory, or a bare database file such as a
+// hand-made backup (Manifest is nil then)
+type Snapshot struct {
+	Path     string
+	Manifest *Manifest
+}
+
+// Database returns the path of the snapshot's database
+func (s *Snapshot) Database() string {
+	if s.Manifest == nil {
+		return s.Path
+	}
+	return filepath.Join(s.Path, s.Manifest.File)
+}
+
+// Name is the snapshot's version, or the file name of a bare database
+func (s *Snapshot) Name() string {
+	if s.Manifest == nil {
+		return filepath.Base(s.Path)
+	}
+	return s.Manifest.Version
+}
+
+// Create copies db into dir/version and writes its manifest. The copy is
+// made with VACUUM INTO, which reads in one transaction, so it is consistent
+// while the catalogue is being written to.
+func Create(db *sql.DB, dir, version string) (*Snapshot, error) {
+	now := time.Now().UTC()
+	if version == "" {
+		version = now.Format(VersionFormat)
+	}
+	if version != filepath.Base(version) || version == "." || version == ".." {
+		return nil, fmt.Errorf("invalid snapshot version %q", version)
+	}
+
+	path := filepath.Join(dir, version)
+	if _, err := os.Stat(path); err == nil {
+		return nil, fmt.Errorf("snapshot %s already exists", path)
+	}
+	if err := os.MkdirAll(path, 0o755); err != nil {
+		return nil, err
+	}
+
+	snapshot, err := create(db, path, version, now)
+	if err != nil {
+		os.RemoveAll(path)
+		return nil, err
+	}
+	return snapshot, nil
+}
+
+func create(db *sql.DB, path, version string, now time.Time) (*Snapshot, error) {
+	file := filepath.Join(path, DatabaseFile)
+	if _, err := db.Exec("VACUUM INTO ?", file); err != nil {
+		return nil, fmt.Errorf("failed to copy the database: %w", err)
+	}
+
+	manifest, err := describe(file)
+	if err != nil {
+		return nil, err
+	}
+	manifest.Version = version
+	manifest.CreatedAt = now
+	manifest.File = DatabaseFile
+	if manifest.SHA256, manifest.Size, err = checksum(file); err != nil {
+		return nil, err
+	}
+
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(filepath.Join(path, ManifestFile), append(data, '\n'), 0o644); err != nil {
+		return nil, err
+	}
+	return &Snapshot{Path: path, Manifest: manifest}, nil
+}
+
+// describe builds the manifest of the database copy at file. The copy is
+// switched out of WAL mode first, so it is a single self-contained file
+// whose checksum stays put.
+func describe(file string) (*Manifest, error) {
+	db, err := sql.Open("sqlite", "file:"+file)
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	if _, err := db.Exec("PRAGMA journal_mode = DELETE"); err != nil {
+		return nil, fmt.Errorf("failed to set the snapshot's journal mode: %w", err)
+	}
+	return buildManifest(db)
+}
+
+// Open reads the snapshot at path: a snapshot directory, or a database file
+func Open(path string) (*Snapshot, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return &Snapshot{Path: path}, nil
+	}
+
+	data, err := os.ReadFile(filepath.Join(path, ManifestFile))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the manifest: %w", err)
+	}
+	manifest := &Manifest{}
+	if err := json.Unmarshal(data, manifest); err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", filepath.Join(path, ManifestFile), err)
+	}
+	if manifest.ManifestVersion > ManifestVersion {
+		return nil, fmt.Errorf("%s is manifest version %d, this build reads up to %d", path, manifest.ManifestVersion, ManifestVersion)
+	}
+	return &Snapshot{Path: path, Manifest: manifest}, nil
+}
+
+// Verify checks the snapshot's database against the checksum in its
+// manifest. A bare database has nothing to check against.
+func (s *Snapshot) Verify() error {
+	if s.Manifest == nil {
+		return nil
+	}
+	sum, size, err := checksum(s.Database())
+	if err != nil {
+		return err
+	}
+	if sum != s.Manifest.SHA256 || size != s.Manifest.Size {
+		return fmt.Errorf("%w: %s is %d bytes with sha256 %s, the manifest says %d bytes with %s",
+			ErrChecksumMismatch, s.Database(), size, sum, s.Manifest.Size, s.Manifest.SHA256)
+	}
+	return nil
+}
+
+// List returns the snapshots in dir, oldest first
+func List(dir string) ([]*Snapshot, error) {
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var snapshots []*Snapshot
+	for _, e := range entries {
+		if !e.IsDir() {
+			continue
+		}
+		if _, err := os.Stat(filepath.Join(dir, e.Name(), ManifestFile)); err != nil {
+			continue
+		}
+		s, err := Open(filepath.Join(dir, e.Name()))
+		if err != nil {
+			return nil, err
+		}
+		snapshots = append(snapshots, s)
+	}
+	sort.Slice(snapshots, func(i, j int) bool {
+		return snapshots[i].Manifest.CreatedAt.Before(snapshots[j].Manifest.CreatedAt)
+	})
+	return snapshots, nil
+}
+
+func checksum(file string) (string, int64, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return "", 0, err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	size, err := io.Copy(h, f)
+	if err != nil {
+		return "", 0, err
+	}
+	return hex.EncodeToString(h.Sum(nil)), size, nil
+}
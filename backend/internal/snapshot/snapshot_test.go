@@ -0,0 +1,160 @@
+package snapshot
+
+import (
+	"database/sql"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/emirh/car-specs/backend/internal/importer"
+	"github.com/emirh/car-specs/backend/migrations"
+	_ "modernc.org/sqlite"
+)
+
+func openTestDB(t *testing.T) *sql.DB {
+	t.Helper()
+	db, err := sql.Open("sqlite", filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("failed to open db: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	migrator, err := migrations.NewMigrator(db)
+	if err != nil {
+		t.Fatalf("failed to load migrations: %v", err)
+	}
+	if err := migrator.Up(); err != nil {
+		t.Fatalf("failed to migrate: %v", err)
+	}
+	return db
+}
+
+// importLines imports JSON Lines in the export format into db
+func importLines(t *testing.T, db *sql.DB, lines string) {
+	t.Helper()
+	p, err := importer.LoadProfile("../../data/import_profiles/export.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "trims.jsonl")
+	if err := os.WriteFile(path, []byte(lines), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := importer.Import(db, p, path, importer.Options{MaxErrors: 0}); err != nil {
+		t.Fatalf("Import() error = %v", err)
+	}
+}
+
+const seed = `{"brand": {"name": "Audi"}, "model": {"name": "A3"}, "generation": {"code": "8Y"}, "name": "35 TFSI", "year": 2021, "power_hp": 150}
+{"brand": {"name": "Audi"}, "model": {"name": "A4"}, "generation": {"code": "B9"}, "name": "40 TDI", "year": 2021, "power_hp": 204}
+{"brand": {"name": "BMW"}, "model": {"name": "3 Series"}, "generation": {"code": "G20"}, "name": "320i", "year": 2022, "power_hp": 184}
+`
+
+func TestCreate(t *testing.T) {
+	db := openTestDB(t)
+	importLines(t, db, seed)
+	dir := t.TempDir()
+
+	s, err := Create(db, dir, "2026.1")
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	m := s.Manifest
+	migrator, _ := migrations.NewMigrator(db)
+	if m.Version != "2026.1" || m.ManifestVersion != ManifestVersion || m.SchemaVersion != migrator.Latest() {
+		t.Errorf("manifest = %+v", m)
+	}
+	if m.Totals.Brands != 2 || m.Totals.Models != 3 || m.Totals.Trims != 3 {
+		t.Errorf("totals = %+v, want 2 brands, 3 models and 3 trims", m.Totals)
+	}
+	if len(m.Brands) != 2 || m.Brands[0] != (BrandCounts{Name: "Audi", Models: 2, Generations: 2, Trims: 2}) {
+		t.Errorf("brands = %+v", m.Brands)
+	}
+	if len(m.SourceRuns) != 1 || m.SourceRuns[0].Source != "importer:csv" || m.SourceRuns[0].Writes == 0 || m.SourceRuns[0].LastWriteAt.IsZero() {
+		t.Errorf("source runs = %+v, want the import", m.SourceRuns)
+	}
+
+	// The manifest on disk is the one returned
+	opened, err := Open(s.Path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if opened.Manifest.SHA256 != m.SHA256 || opened.Manifest.Totals != m.Totals {
+		t.Errorf("Open() = %+v, want %+v", opened.Manifest, m)
+	}
+	if err := opened.Verify(); err != nil {
+		t.Errorf("Verify() error = %v", err)
+	}
+	if snapshots, err := List(dir); err != nil || len(snapshots) != 1 || snapshots[0].Name() != "2026.1" {
+		t.Errorf("List() = %v, %v", snapshots, err)
+	}
+
+	if _, err := Create(db, dir, "2026.1"); err == nil {
+		t.Error("Create() of an existing version succeeded")
+	}
+	if _, err := Create(db, dir, "../escape"); err == nil {
+		t.Error("Create() of a version outside the directory succeeded")
+	}
+
+	f, err := os.OpenFile(s.Database(), os.O_APPEND|os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Write([]byte("tampered"))
+	f.Close()
+	if err := opened.Verify(); !errors.Is(err, ErrChecksumMismatch) {
+		t.Errorf("Verify() of a modified snapshot = %v, want ErrChecksumMismatch", err)
+	}
+	if _, err := Compare(opened, opened); !errors.Is(err, ErrChecksumMismatch) {
+		t.Errorf("Compare() of a modified snapshot = %v, want ErrChecksumMismatch", err)
+	}
+}
+
+func TestCompare(t *testing.T) {
+	db := openTestDB(t)
+	importLines(t, db, seed)
+	dir := t.TempDir()
+
+	old, err := Create(db, dir, "v1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	importLines(t, db, `{"brand": {"name": "BMW"}, "model": {"name": "3 Series"}, "generation": {"code": "G20"}, "name": "330e", "year": 2022, "power_hp": 292}
+`)
+	if _, err := db.Exec(`UPDATE trims SET power_hp = 163 WHERE name = '35 TFSI'`); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec(`UPDATE trims SET deleted_at = CURRENT_TIMESTAMP WHERE name = '40 TDI'`); err != nil {
+		t.Fatal(err)
+	}
+	new, err := Create(db, dir, "v2")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	diff, err := Compare(old, new)
+	if err != nil {
+		t.Fatalf("Compare() error = %v", err)
+	}
+	if diff.From != "v1" || diff.To != "v2" || diff.Summary() != "1 added, 1 removed, 1 changed" {
+		t.Fatalf("Compare() = %+v", diff)
+	}
+	if diff.Added[0].Name != "330e" || diff.Removed[0].Name != "40 TDI" {
+		t.Errorf("added %+v, removed %+v", diff.Added, diff.Removed)
+	}
+	changed := diff.Changed[0]
+	if changed.Name != "35 TFSI" || len(changed.Changes) != 1 || changed.Changes[0] != (FieldChange{Field: "power_hp", Before: "150", After: "163"}) {
+		t.Errorf("changed = %+v, want power_hp 150 -> 163", changed)
+	}
+
+	// A bare database file, such as a hand-made backup, compares too
+	bare := &Snapshot{Path: old.Database()}
+	if diff, err := Compare(bare, new); err != nil || diff.From != DatabaseFile || len(diff.Changed) != 1 {
+		t.Errorf("Compare(bare database) = %+v, %v", diff, err)
+	}
+	if err := old.Verify(); err != nil {
+		t.Errorf("Compare() modified the snapshot it read: %v", err)
+	}
+}
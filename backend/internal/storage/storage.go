@@ -0,0 +1,170 @@
+// Package storage opens vehicles.db the same way for every command under cmd/.
+//
+// Connections use modernc.org/sqlite with WAL journaling, a busy timeout and
+// foreign keys set on every pooled connection. Open refuses to hand out a
+// database whose schema is not at the latest migration, so binaries built from
+// different revisions cannot silently write to an older or newer schema.
+package storage
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/emirh/car-specs/backend/internal/config"
+	"github.com/emirh/car-specs/backend/internal/migrate"
+	"github.com/emirh/car-specs/backend/migrations"
+	_ "modernc.org/sqlite"
+)
+
+// ErrSchemaOutdated is returned by Open when the database needs `migrate up`
+var ErrSchemaOutdated = errors.New("database schema is out of date")
+
+// ErrSchemaTooNew is returned by Open when the database was migrated by a newer build
+var ErrSchemaTooNew = errors.New("database schema is newer than this build")
+
+// Open opens the configured database and verifies its schema is at the latest version
+func Open(cfg *config.Config) (*sql.DB, error) {
+	db, err := OpenUnchecked(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := CheckSchema(db); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return db, nil
+}
+
+// OpenAndMigrate opens the configured database and applies pending migrations first.
+// Used by commands that bootstrap a database (setup, scraper).
+func OpenAndMigrate(cfg *config.Config) (*sql.DB, error) {
+	db, err := OpenUnchecked(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	migrator, err := migrations.NewMigrator(db)
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	if err := migrator.Up(); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to migrate database: %w", err)
+	}
+
+	if err := CheckSchema(db); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return db, nil
+}
+
+// OpenUnchecked opens the configured database without looking at the schema version.
+// Only cmd/migrate should need this.
+func OpenUnchecked(cfg *config.Config) (*sql.DB, error) {
+	dbCfg := cfg.Database
+	if dbCfg.Path == "" {
+		return nil, fmt.Errorf("database path is required")
+	}
+
+	db, err := sql.Open("sqlite", DSN(dbCfg))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+
+	db.SetMaxOpenConns(dbCfg.MaxOpenConns)
+	db.SetMaxIdleConns(dbCfg.MaxIdleConns)
+	db.SetConnMaxLifetime(dbCfg.ConnMaxLifetime)
+
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to ping database %s: %w", dbCfg.Path, err)
+	}
+
+	if err := verifyPragmas(db, dbCfg); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return db, nil
+}
+
+// DSN builds the modernc.org/sqlite connection string. The _pragma parameters
+// are applied to every new connection in the pool, not just the first one.
+func DSN(cfg config.DatabaseConfig) string {
+	params := url.Values{}
+	params.Add("_pragma", "journal_mode(WAL)")
+	params.Add("_pragma", fmt.Sprintf("busy_timeout(%d)", cfg.BusyTimeout.Milliseconds()))
+	params.Add("_pragma", "foreign_keys(1)")
+	params.Add("_pragma", "synchronous(NORMAL)")
+	// Take the write lock at BEGIN so concurrent writers wait on busy_timeout
+	// instead of failing with SQLITE_BUSY halfway through a transaction
+	params.Set("_txlock", "immediate")
+
+	// SQLite decodes %XX in URI file names, so ?, # and % in the path are escaped
+	path := (&url.URL{Path: cfg.Path}).EscapedPath()
+	return (&url.URL{Scheme: "file", Opaque: path, RawQuery: params.Encode()}).String()
+}
+
+// verifyPragmas reads the pragmas back so a driver or DSN change can't drop them silently
+func verifyPragmas(db *sql.DB, cfg config.DatabaseConfig) error {
+	var journalMode string
+	if err := db.QueryRow("PRAGMA journal_mode").Scan(&journalMode); err != nil {
+		return fmt.Errorf("failed to read journal_mode: %w", err)
+	}
+	if !strings.EqualFold(journalMode, "wal") {
+		return fmt.Errorf("expected journal_mode=wal, got %s", journalMode)
+	}
+
+	var foreignKeys int
+	if err := db.QueryRow("PRAGMA foreign_keys").Scan(&foreignKeys); err != nil {
+		return fmt.Errorf("failed to read foreign_keys: %w", err)
+	}
+	if foreignKeys != 1 {
+		return fmt.Errorf("expected foreign_keys=1, got %d", foreignKeys)
+	}
+
+	var busyTimeout int64
+	if err := db.QueryRow("PRAGMA busy_timeout").Scan(&busyTimeout); err != nil {
+		return fmt.Errorf("failed to read busy_timeout: %w", err)
+	}
+	if busyTimeout != cfg.BusyTimeout.Milliseconds() {
+		return fmt.Errorf("expected busy_timeout=%d, got %d", cfg.BusyTimeout.Milliseconds(), busyTimeout)
+	}
+
+	return nil
+}
+
+// CheckSchema fails unless every known migration is applied, unmodified, and nothing newer is
+func CheckSchema(db *sql.DB) error {
+	migrator, err := migrations.NewMigrator(db)
+	if err != nil {
+		return err
+	}
+	return checkSchema(migrator)
+}
+
+func checkSchema(migrator *migrate.Migrator) error {
+	if err := migrator.Verify(); err != nil {
+		return err
+	}
+
+	current, err := migrator.CurrentVersion()
+	if err != nil {
+		return err
+	}
+
+	latest := migrator.Latest()
+	switch {
+	case current < latest:
+		return fmt.Errorf("%w: at version %d, expected %d (run `go run ./cmd/migrate up`)", ErrSchemaOutdated, current, latest)
+	case current > latest:
+		return fmt.Errorf("%w: at version %d, this build knows up to %d", ErrSchemaTooNew, current, latest)
+	}
+	return nil
+}
@@ -0,0 +1,25 @@
+package storage
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/emirh/car-specs/backend/internal/config"
+)
+
+func TestOpenPathWithURICharacters(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "vehicles ?#%41.db")
+	cfg := &config.Config{Database: config.DatabaseConfig{Path: path, BusyTimeout: time.Second, MaxOpenConns: 1, MaxIdleConns: 1}}
+
+	db, err := OpenAndMigrate(cfg)
+	if err != nil {
+		t.Fatalf("OpenAndMigrate() error = %v", err)
+	}
+	db.Close()
+
+	if _, err := os.Stat(path); err != nil {
+		t.Errorf("database was not created at %s: %v", path, err)
+	}
+}
@@ -0,0 +1,187 @@
+// Package validation finds data-quality issues in trims: values that are
+// missing, implausible or inconsistent with each other.
+//
+// Unlike the checks in service.validateTrim, issues never block a write. They
+// are stored in the review queue (review_issues) for an editor to accept,
+// fix or ignore.
+package validation
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/emirh/car-specs/backend/internal/metrics"
+	"github.com/emirh/car-specs/backend/internal/models"
+)
+
+// Confidence levels of a value flagged by an issue
+const (
+	ConfidenceLow     = "low"     // Present but probably wrong
+	ConfidenceMissing = "missing" // Not present at all
+)
+
+// Issue is one problem found in a trim
+type Issue struct {
+	Rule       string
+	Field      string
+	Value      interface{}
+	Confidence string
+	Message    string
+}
+
+// Input is what the rules look at. Only Trim is required.
+type Input struct {
+	Trim *models.Trim
+	// Generation the trim belongs to, for rules that compare against it
+	Generation *models.Generation
+	// ProductionYears is the raw production years text a scraper parsed the
+	// trim's years from, if any (e.g. "2012 - 2016")
+	ProductionYears string
+}
+
+// Rule is a named check over a single trim
+type Rule struct {
+	Name        string
+	Description string
+	Check       func(in Input) []Issue
+}
+
+// Rules run by Validate, in order
+var Rules = []Rule{
+	MissingStartYear,
+	MissingPower,
+	YearRange,
+	FutureStartYear,
+	UnparsedEndYear,
+	GenerationYears,
+	PowerKWMismatch,
+}
+
+// Validate runs every rule over in
+func Validate(in Input) []Issue {
+	issues := []Issue{}
+	for _, rule := range Rules {
+		issues = append(issues, rule.Run(in)...)
+	}
+	return issues
+}
+
+// Run checks in and tags the issues found with the rule's name
+func (r Rule) Run(in Input) []Issue {
+	issues := r.Check(in)
+	for i := range issues {
+		issues[i].Rule = r.Name
+	}
+	return issues
+}
+
+// futureYearMargin is how far ahead of the current year a trim may start
+// (manufacturers announce model years early)
+const futureYearMargin = 2
+
+var (
+	MissingStartYear = Rule{
+		Name:        "missing_start_year",
+		Description: "the production start year is unknown",
+		Check: func(in Input) []Issue {
+			if in.Trim.StartYear != nil && *in.Trim.StartYear != 0 {
+				return nil
+			}
+			return []Issue{{
+				Field: "start_year", Value: nil, Confidence: ConfidenceMissing,
+				Message: "Production start year is unknown",
+			}}
+		},
+	}
+	MissingPower = Rule{
+		Name:        "missing_power",
+		Description: "horsepower is unknown",
+		Check: func(in Input) []Issue {
+			if in.Trim.PowerHP != nil && *in.Trim.PowerHP != 0 {
+				return nil
+			}
+			return []Issue{{
+				Field: "power_hp", Value: nil, Confidence: ConfidenceMissing,
+				Message: "Horsepower is unknown",
+			}}
+		},
+	}
+	YearRange = Rule{
+		Name:        "year_range",
+		Description: "end_year is before start_year",
+		Check: func(in Input) []Issue {
+			start, end := in.Trim.StartYear, in.Trim.EndYear
+			if start == nil || end == nil || *start <= *end {
+				return nil
+			}
+			return []Issue{{
+				Field: "end_year", Value: *end, Confidence: ConfidenceLow,
+				Message: fmt.Sprintf("End year %d is before start year %d", *end, *start),
+			}}
+		},
+	}
+	FutureStartYear = Rule{
+		Name:        "future_start_year",
+		Description: "start_year is more than 2 years ahead",
+		Check: func(in Input) []Issue {
+			limit := time.Now().Year() + futureYearMargin
+			if in.Trim.StartYear == nil || *in.Trim.StartYear <= limit {
+				return nil
+			}
+			return []Issue{{
+				Field: "start_year", Value: *in.Trim.StartYear, Confidence: ConfidenceLow,
+				Message: fmt.Sprintf("Start year is after %d", limit),
+			}}
+		},
+	}
+	UnparsedEndYear = Rule{
+		Name:        "unparsed_end_year",
+		Description: "scraped production years have an end year that was not parsed",
+		Check: func(in Input) []Issue {
+			raw := in.ProductionYears
+			if raw == "" || in.Trim.EndYear != nil || strings.Contains(raw, "Present") {
+				return nil
+			}
+			return []Issue{{
+				Field: "end_year", Value: nil, Confidence: ConfidenceLow,
+				Message: fmt.Sprintf("Production years are %q but the end year could not be parsed", raw),
+			}}
+		},
+	}
+	GenerationYears = Rule{
+		Name:        "generation_years",
+		Description: "start_year is outside the generation's years",
+		Check: func(in Input) []Issue {
+			g, start := in.Generation, in.Trim.StartYear
+			if g == nil || start == nil || g.StartYear == 0 {
+				return nil
+			}
+			if *start >= g.StartYear && (g.EndYear == nil || *start <= *g.EndYear) {
+				return nil
+			}
+			end := "present"
+			if g.EndYear != nil {
+				end = fmt.Sprint(*g.EndYear)
+			}
+			return []Issue{{
+				Field: "start_year", Value: *start, Confidence: ConfidenceLow,
+				Message: fmt.Sprintf("Start year is outside generation %s (%d-%s)", g.Code, g.StartYear, end),
+			}}
+		},
+	}
+	PowerKWMismatch = Rule{
+		Name:        "power_kw_mismatch",
+		Description: "power_kw and power_hp differ by more than metrics.PowerKWTolerance",
+		Check: func(in Input) []Issue {
+			deviation := metrics.PowerKWDeviation.Value(in.Trim)
+			if deviation == nil || *deviation <= metrics.PowerKWTolerance {
+				return nil
+			}
+			return []Issue{{
+				Field: "power_kw", Value: *in.Trim.PowerKW, Confidence: ConfidenceLow,
+				Message: fmt.Sprintf("power_kw is %.1f%% off power_hp (%d hp)", *deviation, *in.Trim.PowerHP),
+			}}
+		},
+	}
+)
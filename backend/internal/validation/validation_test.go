@@ -0,0 +1,56 @@
+package validation
+
+import (
+	"testing"
+	"time"
+
+	"github.com/emirh/car-specs/backend/internal/models"
+)
+
+func intPtr(v int) *int { return &v }
+
+func TestValidate(t *testing.T) {
+	complete := func() *models.Trim {
+		return &models.Trim{Name: "1.5 TSI", Year: 2020, StartYear: intPtr(2020), PowerHP: intPtr(150)}
+	}
+	gen := &models.Generation{Code: "8Y", StartYear: 2020}
+
+	tests := []struct {
+		name      string
+		modify    func(t *models.Trim)
+		input     func(in *Input)
+		wantRules []string
+	}{
+		{"complete trim", func(t *models.Trim) {}, nil, nil},
+		{"missing start year", func(t *models.Trim) { t.StartYear = nil }, nil, []string{"missing_start_year"}},
+		{"missing power", func(t *models.Trim) { t.PowerHP = intPtr(0) }, nil, []string{"missing_power"}},
+		{"end before start", func(t *models.Trim) { t.EndYear = intPtr(2019) }, nil, []string{"year_range"}},
+		{"far future", func(t *models.Trim) { t.StartYear = intPtr(time.Now().Year() + 5) }, nil, []string{"future_start_year"}},
+		{"unparsed end year", func(t *models.Trim) {}, func(in *Input) { in.ProductionYears = "2020 - ?" }, []string{"unparsed_end_year"}},
+		{"still in production", func(t *models.Trim) {}, func(in *Input) { in.ProductionYears = "2020 - Present" }, nil},
+		{"inside generation", func(t *models.Trim) {}, func(in *Input) { in.Generation = gen }, nil},
+		{"before generation", func(t *models.Trim) { t.StartYear = intPtr(2018) }, func(in *Input) { in.Generation = gen }, []string{"generation_years"}},
+		{"consistent kw", func(t *models.Trim) { t.PowerKW = intPtr(110) }, nil, nil},
+		{"kw mismatch", func(t *models.Trim) { t.PowerKW = intPtr(150) }, nil, []string{"power_kw_mismatch"}},
+	}
+
+	for _, tt := range tests {
+		trim := complete()
+		tt.modify(trim)
+		in := Input{Trim: trim}
+		if tt.input != nil {
+			tt.input(&in)
+		}
+
+		issues := Validate(in)
+		if len(issues) != len(tt.wantRules) {
+			t.Errorf("%s: got %d issues (%v), want %v", tt.name, len(issues), issues, tt.wantRules)
+			continue
+		}
+		for i, issue := range issues {
+			if issue.Rule != tt.wantRules[i] {
+				t.Errorf("%s: issue %d rule = %s, want %s", tt.name, i, issue.Rule, tt.wantRules[i])
+			}
+		}
+	}
+}
@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS specs;
+DROP TABLE IF EXISTS trims;
+DROP TABLE IF EXISTS generations;
+DROP TABLE IF EXISTS models;
+DROP TABLE IF EXISTS brands;
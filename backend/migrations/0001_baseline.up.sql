@@ -1,3 +1,6 @@
+-- Baseline schema: Brands → Models → Generations → Trims (+ specs)
+-- Uses IF NOT EXISTS so it can be adopted by databases created before migrations were tracked.
+
 CREATE TABLE IF NOT EXISTS brands (
     id INTEGER PRIMARY KEY AUTOINCREMENT,
     name TEXT UNIQUE NOT NULL,
@@ -45,7 +48,7 @@ CREATE TABLE IF NOT EXISTS trims (
     fuel_type TEXT,
     is_facelift BOOLEAN DEFAULT 0,
     market TEXT DEFAULT 'Global',
-    
+
     -- Engine & Performance
     power_hp INTEGER,
     power_kw INTEGER,
@@ -56,19 +59,19 @@ CREATE TABLE IF NOT EXISTS trims (
     engine_code TEXT,
     acceleration_0_100 REAL,
     top_speed_kmh INTEGER,
-    
+
     -- Consumption & Emissions
     fuel_consumption_city REAL,
     fuel_consumption_highway REAL,
     fuel_consumption_combined REAL,
     co2_emissions INTEGER,
     emission_standard TEXT,
-    
+
     -- Transmission & Drivetrain
     transmission_type TEXT,
     gears INTEGER,
     drivetrain TEXT,
-    
+
     -- Dimensions & Weight
     length_mm INTEGER,
     width_mm INTEGER,
@@ -80,19 +83,19 @@ CREATE TABLE IF NOT EXISTS trims (
     luggage_capacity_l INTEGER,
     luggage_capacity_max_l INTEGER,
     fuel_tank_capacity_l INTEGER,
-    
+
     -- Wheels & Tires
     tire_size_front TEXT,
     tire_size_rear TEXT,
     wheel_size_inches REAL,
-    
+
     -- Additional
     seating_capacity INTEGER DEFAULT 5,
     doors INTEGER DEFAULT 5,
     image_url TEXT,
     msrp_price REAL,
     currency TEXT DEFAULT 'USD',
-    
+
     created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
     updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
     FOREIGN KEY(generation_id) REFERENCES generations(id),
@@ -107,3 +110,9 @@ CREATE TABLE IF NOT EXISTS specs (
     value TEXT NOT NULL,
     FOREIGN KEY(trim_id) REFERENCES trims(id)
 );
+
+CREATE INDEX IF NOT EXISTS idx_models_brand_id ON models(brand_id);
+CREATE INDEX IF NOT EXISTS idx_generations_model_id ON generations(model_id);
+CREATE INDEX IF NOT EXISTS idx_trims_generation_id ON trims(generation_id);
+CREATE INDEX IF NOT EXISTS idx_trims_model_id ON trims(model_id);
+CREATE INDEX IF NOT EXISTS idx_specs_trim_id ON specs(trim_id);
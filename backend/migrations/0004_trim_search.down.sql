@@ -0,0 +1,7 @@
+DROP TRIGGER IF EXISTS trims_fts_brand_update;
+DROP TRIGGER IF EXISTS trims_fts_model_update;
+DROP TRIGGER IF EXISTS trims_fts_generation_update;
+DROP TRIGGER IF EXISTS trims_fts_delete;
+DROP TRIGGER IF EXISTS trims_fts_update;
+DROP TRIGGER IF EXISTS trims_fts_insert;
+DROP TABLE IF EXISTS trims_fts;
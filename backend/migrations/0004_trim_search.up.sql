@@ -0,0 +1,74 @@
+-- Full-text index over everything a user might type to find a trim:
+-- brand, model, generation code and name, trim name, engine and transmission codes.
+-- '.' is a token character so displacements like "1.5" stay a single term.
+CREATE VIRTUAL TABLE IF NOT EXISTS trims_fts USING fts5(
+    trim_id UNINDEXED,
+    brand,
+    model,
+    generation_code,
+    generation_name,
+    trim,
+    engine_code,
+    transmission_code,
+    tokenize = "unicode61 remove_diacritics 2 tokenchars '.'"
+);
+
+INSERT INTO trims_fts (trim_id, brand, model, generation_code, generation_name, trim, engine_code, transmission_code)
+SELECT t.id, b.name, m.name, g.code, g.name, t.name, t.engine_code, t.transmission_code
+FROM trims t
+LEFT JOIN generations g ON t.generation_id = g.id
+LEFT JOIN models m ON g.model_id = m.id
+LEFT JOIN brands b ON m.brand_id = b.id;
+
+-- Keep the index in sync with trims
+CREATE TRIGGER IF NOT EXISTS trims_fts_insert AFTER INSERT ON trims BEGIN
+    INSERT INTO trims_fts (trim_id, brand, model, generation_code, generation_name, trim, engine_code, transmission_code)
+    SELECT NEW.id, b.name, m.name, g.code, g.name, NEW.name, NEW.engine_code, NEW.transmission_code
+    FROM (SELECT 1)
+    LEFT JOIN generations g ON g.id = NEW.generation_id
+    LEFT JOIN models m ON g.model_id = m.id
+    LEFT JOIN brands b ON m.brand_id = b.id;
+END;
+
+CREATE TRIGGER IF NOT EXISTS trims_fts_update AFTER UPDATE OF generation_id, name, engine_code, transmission_code ON trims BEGIN
+    DELETE FROM trims_fts WHERE trim_id = OLD.id;
+    INSERT INTO trims_fts (trim_id, brand, model, generation_code, generation_name, trim, engine_code, transmission_code)
+    SELECT NEW.id, b.name, m.name, g.code, g.name, NEW.name, NEW.engine_code, NEW.transmission_code
+    FROM (SELECT 1)
+    LEFT JOIN generations g ON g.id = NEW.generation_id
+    LEFT JOIN models m ON g.model_id = m.id
+    LEFT JOIN brands b ON m.brand_id = b.id;
+END;
+
+CREATE TRIGGER IF NOT EXISTS trims_fts_delete AFTER DELETE ON trims BEGIN
+    DELETE FROM trims_fts WHERE trim_id = OLD.id;
+END;
+
+-- Renaming a parent changes the indexed text of every trim below it
+CREATE TRIGGER IF NOT EXISTS trims_fts_generation_update AFTER UPDATE OF code, name, model_id ON generations BEGIN
+    UPDATE trims_fts SET generation_code = NEW.code, generation_name = NEW.name
+    WHERE trim_id IN (SELECT id FROM trims WHERE generation_id = NEW.id);
+    UPDATE trims_fts SET
+        model = (SELECT name FROM models WHERE id = NEW.model_id),
+        brand = (SELECT b.name FROM models m JOIN brands b ON m.brand_id = b.id WHERE m.id = NEW.model_id)
+    WHERE trim_id IN (SELECT id FROM trims WHERE generation_id = NEW.id);
+END;
+
+CREATE TRIGGER IF NOT EXISTS trims_fts_model_update AFTER UPDATE OF name, brand_id ON models BEGIN
+    UPDATE trims_fts SET
+        model = NEW.name,
+        brand = (SELECT name FROM brands WHERE id = NEW.brand_id)
+    WHERE trim_id IN (
+        SELECT t.id FROM trims t JOIN generations g ON t.generation_id = g.id WHERE g.model_id = NEW.id
+    );
+END;
+
+CREATE TRIGGER IF NOT EXISTS trims_fts_brand_update AFTER UPDATE OF name ON brands BEGIN
+    UPDATE trims_fts SET brand = NEW.name
+    WHERE trim_id IN (
+        SELECT t.id FROM trims t
+        JOIN generations g ON t.generation_id = g.id
+        JOIN models m ON g.model_id = m.id
+        WHERE m.brand_id = NEW.id
+    );
+END;
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS trim_field_sources;
+DROP TABLE IF EXISTS spec_sources;
+DROP TABLE IF EXISTS source_documents;
@@ -0,0 +1,42 @@
+-- Where spec values came from. A source document is one page, API query or
+-- file; trim fields and key-value specs cite it with the value it reported.
+
+CREATE TABLE IF NOT EXISTS source_documents (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    url TEXT NOT NULL UNIQUE,
+    title TEXT,
+    source_type TEXT NOT NULL,
+    market_scope TEXT,
+    retrieved_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS spec_sources (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    spec_id INTEGER NOT NULL,
+    source_document_id INTEGER NOT NULL,
+    page TEXT,
+    note TEXT,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE(spec_id, source_document_id),
+    FOREIGN KEY(spec_id) REFERENCES specs(id) ON DELETE CASCADE,
+    FOREIGN KEY(source_document_id) REFERENCES source_documents(id) ON DELETE CASCADE
+);
+
+-- value is the JSON encoding of what the source reported for the field
+-- (NULL when the source says it is unknown)
+CREATE TABLE IF NOT EXISTS trim_field_sources (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    trim_id INTEGER NOT NULL,
+    field TEXT NOT NULL,
+    source_document_id INTEGER NOT NULL,
+    value TEXT,
+    note TEXT,
+    recorded_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE(trim_id, field, source_document_id),
+    FOREIGN KEY(trim_id) REFERENCES trims(id) ON DELETE CASCADE,
+    FOREIGN KEY(source_document_id) REFERENCES source_documents(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_spec_sources_spec_id ON spec_sources(spec_id);
+CREATE INDEX IF NOT EXISTS idx_trim_field_sources_trim_id ON trim_field_sources(trim_id, field);
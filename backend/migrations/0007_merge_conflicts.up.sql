@@ -0,0 +1,19 @@
+-- Trim fields whose sources disagree beyond the merge tolerance. The field
+-- keeps its stored value until an editor settles it; at most one conflict per
+-- trim field is open at a time.
+
+CREATE TABLE IF NOT EXISTS merge_conflicts (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    trim_id INTEGER NOT NULL,
+    field TEXT NOT NULL,
+    stored_value TEXT,
+    candidates TEXT NOT NULL,
+    status TEXT NOT NULL DEFAULT 'open',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    resolved_at TIMESTAMP,
+    FOREIGN KEY(trim_id) REFERENCES trims(id) ON DELETE CASCADE
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_merge_conflicts_open ON merge_conflicts(trim_id, field) WHERE status = 'open';
+CREATE INDEX IF NOT EXISTS idx_merge_conflicts_status ON merge_conflicts(status);
@@ -0,0 +1,24 @@
+-- Data-quality issues found by internal/validation, one row per trim, rule
+-- and field. Writes re-validate the trim: new issues are opened, open ones
+-- that are gone become fixed, and an accepted or ignored issue only reopens
+-- when the flagged value changes.
+
+CREATE TABLE IF NOT EXISTS review_issues (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    trim_id INTEGER NOT NULL,
+    rule TEXT NOT NULL,
+    field TEXT NOT NULL,
+    value TEXT,
+    confidence TEXT NOT NULL,
+    message TEXT NOT NULL,
+    source TEXT,
+    status TEXT NOT NULL DEFAULT 'open',
+    note TEXT,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    resolved_at TIMESTAMP,
+    UNIQUE(trim_id, rule, field),
+    FOREIGN KEY(trim_id) REFERENCES trims(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_review_issues_status ON review_issues(status);
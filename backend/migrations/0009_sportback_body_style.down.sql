@@ -0,0 +1,2 @@
+-- Canonical values remain readable text; nothing to undo
+SELECT 1;
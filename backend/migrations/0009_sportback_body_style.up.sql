@@ -0,0 +1,3 @@
+-- The old scraper stored "Sportback" as a body style. 0005_canonical_enums
+-- ran before it was a known alias and left those rows untouched.
+UPDATE models SET body_style = 'hatchback' WHERE LOWER(body_style) = 'sportback';
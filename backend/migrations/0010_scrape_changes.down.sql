@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS trim_changes;
+DROP TABLE IF EXISTS page_fingerprints;
@@ -0,0 +1,34 @@
+-- Incremental re-scraping. page_fingerprints remembers a hash of what was
+-- extracted from each trim page, so pages that haven't changed are skipped.
+-- trim_changes holds the field-level differences a changed page showed
+-- against the stored trim, pending until applied or rejected.
+
+CREATE TABLE IF NOT EXISTS page_fingerprints (
+    url TEXT PRIMARY KEY,
+    trim_id INTEGER NOT NULL,
+    source_type TEXT NOT NULL,
+    fingerprint TEXT NOT NULL,
+    first_seen_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    checked_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    changed_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY(trim_id) REFERENCES trims(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS trim_changes (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    trim_id INTEGER NOT NULL,
+    field TEXT NOT NULL,
+    old_value TEXT,
+    new_value TEXT,
+    source_document_id INTEGER NOT NULL,
+    status TEXT NOT NULL DEFAULT 'pending',
+    note TEXT,
+    detected_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    resolved_at TIMESTAMP,
+    FOREIGN KEY(trim_id) REFERENCES trims(id) ON DELETE CASCADE,
+    FOREIGN KEY(source_document_id) REFERENCES source_documents(id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_page_fingerprints_trim ON page_fingerprints(trim_id);
+CREATE INDEX IF NOT EXISTS idx_trim_changes_trim ON trim_changes(trim_id, field);
+CREATE INDEX IF NOT EXISTS idx_trim_changes_status ON trim_changes(status);
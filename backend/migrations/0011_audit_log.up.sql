@@ -0,0 +1,19 @@
+-- Every create, update, delete and restore of a brand, model, generation or
+-- trim, with who made it (actor), through what (source: api, importer:csv,
+-- scraper:ultimatespecs, ...) and the row as JSON before and after. Entries
+-- outlive the rows they describe, so there is no foreign key.
+
+CREATE TABLE IF NOT EXISTS audit_log (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    entity_type TEXT NOT NULL,
+    entity_id INTEGER NOT NULL,
+    action TEXT NOT NULL,
+    actor TEXT NOT NULL,
+    source TEXT NOT NULL,
+    before_data TEXT,
+    after_data TEXT,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_audit_log_entity ON audit_log(entity_type, entity_id);
+CREATE INDEX IF NOT EXISTS idx_audit_log_source ON audit_log(source);
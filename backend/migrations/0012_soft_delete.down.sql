@@ -0,0 +1,10 @@
+-- Rows still in the trash become live again
+DROP INDEX IF EXISTS idx_trims_deleted_at;
+DROP INDEX IF EXISTS idx_generations_deleted_at;
+DROP INDEX IF EXISTS idx_models_deleted_at;
+DROP INDEX IF EXISTS idx_brands_deleted_at;
+
+ALTER TABLE trims DROP COLUMN deleted_at;
+ALTER TABLE generations DROP COLUMN deleted_at;
+ALTER TABLE models DROP COLUMN deleted_at;
+ALTER TABLE brands DROP COLUMN deleted_at;
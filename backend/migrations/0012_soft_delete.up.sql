@@ -0,0 +1,14 @@
+-- Soft deletion. A deleted brand, model, generation or trim keeps its row with
+-- deleted_at set; everything below it that was still live gets the same
+-- deleted_at, so restoring it brings back exactly what was deleted with it.
+-- cmd/purge removes rows deleted longer ago than the retention period.
+
+ALTER TABLE brands ADD COLUMN deleted_at TIMESTAMP;
+ALTER TABLE models ADD COLUMN deleted_at TIMESTAMP;
+ALTER TABLE generations ADD COLUMN deleted_at TIMESTAMP;
+ALTER TABLE trims ADD COLUMN deleted_at TIMESTAMP;
+
+CREATE INDEX IF NOT EXISTS idx_brands_deleted_at ON brands(deleted_at);
+CREATE INDEX IF NOT EXISTS idx_models_deleted_at ON models(deleted_at);
+CREATE INDEX IF NOT EXISTS idx_generations_deleted_at ON generations(deleted_at);
+CREATE INDEX IF NOT EXISTS idx_trims_deleted_at ON trims(deleted_at);
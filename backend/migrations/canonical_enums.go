@@ -0,0 +1,75 @@
+package migrations
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/emirh/car-specs/backend/internal/enums"
+	"github.com/emirh/car-specs/backend/internal/migrate"
+)
+
+// enumColumns are the free-text columns that hold enum values
+var enumColumns = []struct {
+	table  string
+	column string
+	enum   enums.Enum
+}{
+	{"trims", "fuel_type", enums.FuelType},
+	{"trims", "transmission_type", enums.Transmission},
+	{"trims", "drivetrain", enums.Drivetrain},
+	{"trims", "emission_standard", enums.EmissionStandard},
+	{"models", "body_style", enums.BodyStyle},
+}
+
+// canonicalEnums rewrites the values stored by the scrapers and importers
+// ("Benzin", "Gasoline", "Dizel", "AWD", ...) to the canonical enum values.
+// It uses the same mapping as the services validate with, so it lives in Go.
+// Values that are not recognised are left untouched for the data check to report.
+// Rolling back is a no-op: the canonical values remain readable text.
+var canonicalEnums = migrate.Migration{
+	Version:  5,
+	Name:     "canonical_enums",
+	Revision: "1",
+	UpFunc: func(tx *sql.Tx) error {
+		for _, c := range enumColumns {
+			values, err := distinctValues(tx, c.table, c.column)
+			if err != nil {
+				return err
+			}
+			for _, raw := range values {
+				canonical, ok := c.enum.Normalize(raw)
+				if !ok || canonical == raw {
+					continue
+				}
+				var value interface{} = canonical
+				if canonical == "" {
+					value = nil
+				}
+				query := fmt.Sprintf("UPDATE %s SET %s = ? WHERE %s = ?", c.table, c.column, c.column)
+				if _, err := tx.Exec(query, value, raw); err != nil {
+					return fmt.Errorf("failed to normalise %s.%s %q: %w", c.table, c.column, raw, err)
+				}
+			}
+		}
+		return nil
+	},
+	DownFunc: func(tx *sql.Tx) error { return nil },
+}
+
+func distinctValues(tx *sql.Tx, table, column string) ([]string, error) {
+	rows, err := tx.Query(fmt.Sprintf("SELECT DISTINCT %s FROM %s WHERE %s IS NOT NULL", column, table, column))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s.%s: %w", table, column, err)
+	}
+	defer rows.Close()
+
+	var values []string
+	for rows.Next() {
+		var v string
+		if err := rows.Scan(&v); err != nil {
+			return nil, fmt.Errorf("failed to read %s.%s: %w", table, column, err)
+		}
+		values = append(values, v)
+	}
+	return values, rows.Err()
+}
@@ -0,0 +1,153 @@
+// Package migrations holds the versioned schema migrations for vehicles.db.
+//
+// SQL migrations are embedded from NNNN_name.up.sql / NNNN_name.down.sql files.
+// Migrations that need to inspect the live schema first (SQLite has no
+// ADD COLUMN IF NOT EXISTS) are registered in Go below.
+package migrations
+
+import (
+	"database/sql"
+	"embed"
+	"fmt"
+
+	"github.com/emirh/car-specs/backend/internal/migrate"
+)
+
+//go:embed *.sql
+var files embed.FS
+
+// All returns every migration, SQL and Go
+func All() ([]migrate.Migration, error) {
+	migrations, err := migrate.Load(files)
+	if err != nil {
+		return nil, err
+	}
+	for i := range migrations {
+		if migrations[i].Version == transmissionTypesVersion {
+			migrations[i].DownFunc = transmissionTypesDown
+			migrations[i].Revision = "1"
+		}
+	}
+	return append(migrations, legacyColumns, canonicalEnums), nil
+}
+
+// NewMigrator returns a Migrator for db loaded with every migration
+func NewMigrator(db *sql.DB) (*migrate.Migrator, error) {
+	migrations, err := All()
+	if err != nil {
+		return nil, err
+	}
+	return migrate.New(db, migrations)
+}
+
+type column struct {
+	table      string
+	name       string
+	definition string
+	droppable  bool
+}
+
+// Columns that were added ad hoc by 005/011 and scripts such as update_8y_trims_v2.go,
+// so some databases already have them and some don't
+var legacyColumnList = []column{
+	{"generations", "image_url", "TEXT", true},
+	{"generations", "description", "TEXT", true},
+	{"generations", "is_current", "BOOLEAN DEFAULT 0", true},
+	{"generations", "platform", "TEXT", true},
+	{"trims", "transmission_code", "TEXT", true},
+	// SQLite cannot drop a column that is part of a foreign key, so this one stays on rollback
+	{"trims", "transmission_type_id", "INTEGER REFERENCES transmission_types(id)", false},
+}
+
+// legacyColumnsAdded records the columns 0003_legacy_columns added, so
+// rolling it back drops those and keeps the ones the database already had
+const legacyColumnsAdded = "legacy_columns_added"
+
+var legacyColumns = migrate.Migration{
+	Version:  3,
+	Name:     "legacy_columns",
+	Revision: "1",
+	UpFunc: func(tx *sql.Tx) error {
+		_, err := tx.Exec(`CREATE TABLE IF NOT EXISTS ` + legacyColumnsAdded + ` (
+			table_name TEXT NOT NULL,
+			column_name TEXT NOT NULL,
+			PRIMARY KEY (table_name, column_name)
+		)`)
+		if err != nil {
+			return fmt.Errorf("failed to create %s: %w", legacyColumnsAdded, err)
+		}
+		for _, c := range legacyColumnList {
+			exists, err := columnExists(tx, c.table, c.name)
+			if err != nil {
+				return err
+			}
+			if exists {
+				continue
+			}
+			if _, err := tx.Exec(fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", c.table, c.name, c.definition)); err != nil {
+				return fmt.Errorf("failed to add %s.%s: %w", c.table, c.name, err)
+			}
+			_, err = tx.Exec(`INSERT INTO `+legacyColumnsAdded+` (table_name, column_name) VALUES (?, ?)`, c.table, c.name)
+			if err != nil {
+				return fmt.Errorf("failed to record %s.%s: %w", c.table, c.name, err)
+			}
+		}
+		return nil
+	},
+	DownFunc: func(tx *sql.Tx) error {
+		for i := len(legacyColumnList) - 1; i >= 0; i-- {
+			c := legacyColumnList[i]
+			if !c.droppable {
+				continue
+			}
+			var added int
+			err := tx.QueryRow(`SELECT COUNT(*) FROM `+legacyColumnsAdded+` WHERE table_name = ? AND column_name = ?`,
+				c.table, c.name).Scan(&added)
+			if err != nil {
+				return fmt.Errorf("failed to read %s: %w", legacyColumnsAdded, err)
+			}
+			if added == 0 {
+				continue
+			}
+			if _, err := tx.Exec(fmt.Sprintf("ALTER TABLE %s DROP COLUMN %s", c.table, c.name)); err != nil {
+				return fmt.Errorf("failed to drop %s.%s: %w", c.table, c.name, err)
+			}
+		}
+		if _, err := tx.Exec(`DROP TABLE ` + legacyColumnsAdded); err != nil {
+			return fmt.Errorf("failed to drop %s: %w", legacyColumnsAdded, err)
+		}
+		return nil
+	},
+}
+
+// transmissionTypesVersion is 0002_transmission_types, which is rolled back
+// in Go: trims.transmission_type_id references its table and SQLite cannot
+// drop that column (see legacyColumnList), so the references are cleared
+// before the table is dropped
+const transmissionTypesVersion = 2
+
+func transmissionTypesDown(tx *sql.Tx) error {
+	exists, err := columnExists(tx, "trims", "transmission_type_id")
+	if err != nil {
+		return err
+	}
+	if exists {
+		if _, err := tx.Exec(`UPDATE trims SET transmission_type_id = NULL WHERE transmission_type_id IS NOT NULL`); err != nil {
+			return fmt.Errorf("failed to clear trims.transmission_type_id: %w", err)
+		}
+	}
+	if _, err := tx.Exec(`DROP TABLE IF EXISTS transmission_types`); err != nil {
+		return fmt.Errorf("failed to drop transmission_types: %w", err)
+	}
+	return nil
+}
+
+// columnExists reports whether table has a column called name
+func columnExists(tx *sql.Tx, table, name string) (bool, error) {
+	var count int
+	err := tx.QueryRow(`SELECT COUNT(*) FROM pragma_table_info(?) WHERE name = ?`, table, name).Scan(&count)
+	if err != nil {
+		return false, fmt.Errorf("failed to inspect %s: %w", table, err)
+	}
+	return count > 0, nil
+}
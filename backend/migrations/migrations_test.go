@@ -0,0 +1,91 @@
+package migrations
+
+import (
+	"database/sql"
+	"path/filepath"
+	"testing"
+
+	_ "modernc.org/sqlite"
+)
+
+func hasColumn(t *testing.T, db *sql.DB, table, name string) bool {
+	t.Helper()
+	var count int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM pragma_table_info(?) WHERE name = ?`, table, name).Scan(&count); err != nil {
+		t.Fatalf("failed to inspect %s: %v", table, err)
+	}
+	return count > 0
+}
+
+// TestLegacyRollback rolls a database that predates the migrations back to
+// the baseline: columns it already had stay, and no trim is left referencing
+// a dropped transmission type
+func TestLegacyRollback(t *testing.T) {
+	db, err := sql.Open("sqlite", "file:"+filepath.Join(t.TempDir(), "test.db")+"?_pragma=foreign_keys(1)")
+	if err != nil {
+		t.Fatalf("failed to open db: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	// A generations table from before the migrations, with image_url added by hand
+	_, err = db.Exec(`
+		CREATE TABLE generations (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			model_id INTEGER NOT NULL,
+			code TEXT,
+			name TEXT,
+			start_year INTEGER,
+			end_year INTEGER,
+			is_facelift BOOLEAN DEFAULT 0,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			image_url TEXT
+		);
+		INSERT INTO generations (model_id, code, image_url) VALUES (1, '8Y', 'https://example.com/8y.jpg');
+	`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	migrator, err := NewMigrator(db)
+	if err != nil {
+		t.Fatalf("failed to load migrations: %v", err)
+	}
+	if err := migrator.Up(); err != nil {
+		t.Fatalf("Up() error = %v", err)
+	}
+	_, err = db.Exec(`
+		INSERT INTO brands (id, name) VALUES (1, 'Audi');
+		INSERT INTO models (id, brand_id, name) VALUES (1, 1, 'A3');
+		INSERT INTO trims (generation_id, model_id, name, year, transmission_type_id)
+			SELECT 1, 1, '35 TFSI', 2021, id FROM transmission_types WHERE code = 'DQ200';
+	`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := migrator.To(1); err != nil {
+		t.Fatalf("To(1) error = %v", err)
+	}
+	if !hasColumn(t, db, "generations", "image_url") {
+		t.Error("rollback dropped generations.image_url, which the database had before")
+	}
+	var imageURL string
+	if err := db.QueryRow(`SELECT image_url FROM generations WHERE code = '8Y'`).Scan(&imageURL); err != nil || imageURL == "" {
+		t.Errorf("image_url after rollback = %q, %v", imageURL, err)
+	}
+	for _, column := range []string{"description", "is_current", "platform"} {
+		if hasColumn(t, db, "generations", column) {
+			t.Errorf("rollback kept generations.%s, which 0003 added", column)
+		}
+	}
+
+	rows, err := db.Query(`PRAGMA foreign_key_check`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	if rows.Next() {
+		t.Error("rollback left foreign keys pointing at dropped rows")
+	}
+}
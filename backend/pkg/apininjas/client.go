@@ -41,6 +41,16 @@ func NewClient() *Client {
 	}
 }
 
+// QueryURL is the request FetchCars sends for makeName and year (0 for any year).
+// It holds no credentials, so it can be stored as the source of the data.
+func QueryURL(makeName string, year int) string {
+	reqURL := fmt.Sprintf("%s?make=%s", BaseURL, makeName)
+	if year > 0 {
+		reqURL = fmt.Sprintf("%s&year=%d", reqURL, year)
+	}
+	return reqURL
+}
+
 // FetchCars fetches cars with optional filters.
 // If year is 0, it is ignored.
 func (c *Client) FetchCars(makeName string, year int) ([]Car, error) {
@@ -48,13 +58,8 @@ func (c *Client) FetchCars(makeName string, year int) ([]Car, error) {
 		return nil, fmt.Errorf("API_NINJAS_KEY environment variable is not set")
 	}
 
-	reqURL := fmt.Sprintf("%s?make=%s", BaseURL, makeName)
-	if year > 0 {
-		reqURL = fmt.Sprintf("%s&year=%d", reqURL, year)
-	}
 	// Limit is still restricted on free tier, avoiding it.
-
-	req, err := http.NewRequest("GET", reqURL, nil)
+	req, err := http.NewRequest("GET", QueryURL(makeName, year), nil)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
@@ -1,3 +1,5 @@
+//go:build ignore
+
 package main
 
 import (
@@ -9,7 +11,7 @@ import (
 
 func main() {
 	// Try opening with standard relative path which works for server
-	db, err := sql.Open("sqlite", "./car_specs.db")
+	db, err := sql.Open("sqlite", "./vehicles.db")
 	if err != nil {
 		log.Fatalf("Failed to open database: %v", err)
 	}
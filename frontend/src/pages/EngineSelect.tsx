@@ -10,6 +10,7 @@ interface Trim {
     displacement_cc?: number;
     power_hp?: number;
     image_url?: string;
+    display?: Record<string, string>;
 }
 
 const API_BASE_URL = (import.meta as any).env?.VITE_API_BASE_URL || 'http://localhost:8080';
@@ -56,7 +57,7 @@ export default function EngineSelect() {
             } else if (trim.displacement_cc && trim.fuel_type) {
                 // Fallback to cc + fuel
                 const vol = (trim.displacement_cc / 1000).toFixed(1);
-                engineLabel = `${vol}L ${trim.fuel_type}`;
+                engineLabel = `${vol}L ${trim.display?.fuel_type ?? trim.fuel_type}`;
             } else {
                 engineLabel = trim.name.split(' ').slice(1, 3).join(' '); // Rough fallback
             }
@@ -10,6 +10,7 @@ interface FeaturedTrim {
     fuel_type?: string;
     transmission_type?: string;
     power_hp?: number;
+    display?: Record<string, string>;
     model?: {
         id: number;
         name: string;
@@ -30,7 +31,7 @@ function Home() {
     // Fetch featured trims
     useEffect(() => {
         setFeaturedLoading(true);
-        fetch(`${API_BASE_URL}/api/featured`)
+        fetch(`${API_BASE_URL}/api/featured?locale=tr`)
             .then(async (res) => {
                 if (!res.ok) throw new Error('Failed to fetch featured');
                 return res.json();
@@ -174,19 +175,19 @@ function Home() {
                                     <div className="absolute bottom-0 left-0 right-0 p-5 translate-y-2 group-hover:translate-y-0 transition-transform duration-300">
                                         <div className="flex items-center justify-between mb-1">
                                             <span className="text-xs font-bold px-2 py-1 rounded bg-primary/20 text-primary uppercase tracking-wider">
-                                                {trim.model?.brand?.name}
+                                                {trim.display?.brand ?? trim.model?.brand?.name}
                                             </span>
                                             <span className="text-xs font-medium text-gray-400">{trim.year}</span>
                                         </div>
                                         <h3 className="text-lg font-bold text-white leading-tight mb-2 truncate">
-                                            {trim.model?.name} {trim.name}
+                                            {trim.display?.model ?? trim.model?.name} {trim.display?.name ?? trim.name}
                                         </h3>
                                         <div className="flex items-center gap-3 text-xs text-gray-300 opacity-0 group-hover:opacity-100 transition-opacity duration-300 delay-75">
                                             <span className="font-semibold">{trim.power_hp} HP</span>
                                             {trim.fuel_type && (
                                                 <>
                                                     <span className="w-1 h-1 rounded-full bg-gray-500" />
-                                                    <span className="font-medium text-gray-200">{trim.fuel_type}</span>
+                                                    <span className="font-medium text-gray-200">{trim.display?.fuel_type ?? trim.fuel_type}</span>
                                                 </>
                                             )}
                                         </div>
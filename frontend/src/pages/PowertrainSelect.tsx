@@ -14,6 +14,7 @@ interface Trim {
         gears?: number;
         drive?: string;
     };
+    display?: Record<string, string>;
 }
 
 interface Vehicle {
@@ -111,10 +112,10 @@ function PowertrainSelect() {
             if (pt.displacement_cc) {
                 pieces.push(`${(pt.displacement_cc / 1000).toFixed(1)}L`);
             }
-            if (pt.fuel_type) pieces.push(pt.fuel_type);
+            if (pt.fuel_type) pieces.push(t.display?.fuel_type ?? pt.fuel_type);
             if (pt.power_hp) pieces.push(`${pt.power_hp} hp`);
-            if (pt.transmission_type) pieces.push(pt.transmission_type);
-            if (pt.drive) pieces.push(pt.drive);
+            if (pt.transmission_type) pieces.push(t.display?.transmission_type ?? pt.transmission_type);
+            if (pt.drive) pieces.push(t.display?.drivetrain ?? pt.drive);
             const label = pieces.filter(Boolean).join(' • ');
             return label || t.name;
         }
@@ -14,6 +14,7 @@ interface Trim {
     year: number;
     start_year?: number;
     end_year?: number;
+    display?: Record<string, string>;
 }
 
 interface Generation {
@@ -85,7 +86,7 @@ function TrimSelect() {
                 setGeneration(genData);
 
                 // Fetch trims for this generation
-                const trimRes = await fetch(`${API_BASE_URL}/api/generations/${targetGenId}/trims`);
+                const trimRes = await fetch(`${API_BASE_URL}/api/generations/${targetGenId}/trims?locale=tr`);
                 if (!trimRes.ok) throw new Error('Motor seçenekleri yüklenemedi');
                 const trimData = await trimRes.json();
 
@@ -178,7 +179,7 @@ function TrimSelect() {
                                 {/* Trim Header */}
                                 <div className="mb-4">
                                     <h3 className="text-2xl font-bold text-white mb-1 group-hover:text-primary transition-colors">
-                                        {trim.name.replace(/Tfsi/g, 'TFSI').replace(/Tdi/g, 'TDI')}
+                                        {(trim.display?.name ?? trim.name).replace(/Tfsi/g, 'TFSI').replace(/Tdi/g, 'TDI')}
                                     </h3>
                                     <p className="text-gray-400 text-sm">
                                         {trim.end_year
@@ -192,7 +193,7 @@ function TrimSelect() {
                                     {trim.fuel_type && (
                                         <div className="flex justify-between items-center pb-2 border-b border-white/10">
                                             <span className="text-gray-400 text-sm">Yakıt</span>
-                                            <span className="text-white font-medium">{trim.fuel_type}</span>
+                                            <span className="text-white font-medium">{trim.display?.fuel_type ?? trim.fuel_type}</span>
                                         </div>
                                     )}
                                     {trim.power_hp && (
@@ -216,7 +217,7 @@ function TrimSelect() {
                                     {trim.transmission_type && (
                                         <div className="flex justify-between items-center pb-2 border-b border-white/10">
                                             <span className="text-gray-400 text-sm">Şanzıman</span>
-                                            <span className="text-white font-medium">{trim.transmission_type}</span>
+                                            <span className="text-white font-medium">{trim.display?.transmission_type ?? trim.transmission_type}</span>
                                         </div>
                                     )}
                                     {trim.transmission_code && (
@@ -228,7 +229,7 @@ function TrimSelect() {
                                     {trim.drivetrain && (
                                         <div className="flex justify-between items-center pb-2 border-b border-white/10">
                                             <span className="text-gray-400 text-sm">Çekiş</span>
-                                            <span className="text-white font-medium">{trim.drivetrain}</span>
+                                            <span className="text-white font-medium">{trim.display?.drivetrain ?? trim.drivetrain}</span>
                                         </div>
                                     )}
                                 </div>
@@ -109,7 +109,7 @@ function VehicleDetails() {
                     if (!generation) throw new Error(`Generation '${generationCode}' not found`);
 
                     // 4. Fetch Trims
-                    const trimRes = await fetch(`${API_BASE_URL}/api/generations/${generation.id}/trims`);
+                    const trimRes = await fetch(`${API_BASE_URL}/api/generations/${generation.id}/trims?locale=tr`);
                     if (!trimRes.ok) throw new Error('Failed to load trims');
                     const trimsData = await trimRes.json();
                     const trims = Array.isArray(trimsData) ? trimsData : (trimsData.value || []);
@@ -426,7 +426,7 @@ function VehicleDetails() {
                                                 {isActive && <Check size={16} className="text-primary" />}
                                             </div>
                                             <div className="text-xs text-slate-500 flex items-center gap-2">
-                                                {tAny.year} • {tAny.fuel_type || 'N/A'} • {tAny.transmission_type || 'N/A'}
+                                                {tAny.year} • {tAny.display?.fuel_type || tAny.fuel_type || 'N/A'} • {tAny.display?.transmission_type || tAny.transmission_type || 'N/A'}
                                             </div>
                                         </button>
                                     );